@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func NewApplyCmd() *cobra.Command {
+	var filename string
+	opts := CreateOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply a lab manifest to a running lab",
+		Long: `Apply compares a Lab manifest with the running lab and creates, resizes, or deletes
+only the servers and volumes that differ. If the lab doesn't exist, it is created.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if filename == "" {
+				return fmt.Errorf("-f flag must be specified")
+			}
+			return applyFromFile(cmd.Context(), filename, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&filename, "filename", "f", "", "Path to the YAML manifest file")
+	cmd.Flags().BoolVar(&opts.SkipDNS, "skip-dns", false, "skip DNS records creation")
+	cmd.Flags().BoolVar(&opts.SkipInstall, "skip-install", false, "skip lab installation for new labs")
+	cmd.Flags().BoolVar(&opts.NoRollback, "no-rollback", false, "keep created resources if creating a new lab fails")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", 0, "number of servers or volumes created in parallel (default from config)")
+
+	return cmd
+}
+
+func applyFromFile(ctx context.Context, filename string, opts CreateOpts) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+
+	// Handle multiple documents in YAML
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewBuffer(data), 4096)
+	for {
+		resource := &types.Resource{}
+		if err := decoder.Decode(resource); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("error decoding YAML: %w", err)
+		}
+		if resource.Kind != "Lab" {
+			return fmt.Errorf("apply supports only Lab resources, got: %s", resource.Kind)
+		}
+		lab := &types.Lab{
+			TypeMeta:   resource.TypeMeta,
+			ObjectMeta: resource.ObjectMeta,
+		}
+		if err := convertToStruct(resource.Spec, &lab.Spec); err != nil {
+			return fmt.Errorf("error parsing Lab spec: %w", err)
+		}
+		if err := applyLab(ctx, lab, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyLab(ctx context.Context, lab *types.Lab, opts CreateOpts) error {
+	if lab.ObjectMeta.Name == "" {
+		return fmt.Errorf("lab name is required")
+	}
+	if lab.ObjectMeta.Labels == nil {
+		lab.ObjectMeta.Labels = make(map[string]string)
+	}
+	if lab.Spec.Provider == "" {
+		lab.Spec.Provider = config.DefaultLocalProvider
+	}
+	if err := useLabManager(lab.Spec.Provider); err != nil {
+		return err
+	}
+
+	if opts.Concurrency > 0 {
+		labSvc.Concurrency = opts.Concurrency
+	}
+
+	existing, err := labToApply(ctx, lab.ObjectMeta.Name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		fmt.Printf("Lab %s doesn't exist, creating it...\n", lab.ObjectMeta.Name)
+		_, err := createLab(ctx, lab, opts)
+		return err
+	}
+
+	// New resources get the same labels as the existing ones, including the TTL
+	lab.ObjectMeta.Labels = labelutil.MergeLabels(existing.ObjectMeta.Labels, lab.ObjectMeta.Labels)
+	lab.ObjectMeta.Labels["owner"] = labelutil.SanitizeValue(cfg.Owner)
+	lab.ObjectMeta.Labels["organization"] = labelutil.SanitizeValue(cfg.Organization)
+	lab.ObjectMeta.Labels["email"] = labelutil.SanitizeValue(cfg.Email)
+	lab.ObjectMeta.Labels["lab_name"] = lab.ObjectMeta.Name
+	if lab.ObjectMeta.Labels["delete_after"] == "" {
+		ttl := defaultIfEmpty(lab.Spec.TTL, config.DefaultTTL)
+		duration, err := timeutil.TtlToDuration(ttl)
+		if err != nil {
+			return fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		lab.ObjectMeta.Labels["delete_after"] = timeutil.FormatDeleteAfter(time.Now().Add(duration))
+	}
+
+	fmt.Printf("Lab %s: Applying changes using provider %s...\n", lab.ObjectMeta.Name, lab.Spec.Provider)
+	plan, err := labSvc.Apply(ctx, lab)
+	if plan != nil {
+		printPlan(plan)
+	}
+	if err != nil {
+		return err
+	}
+	if plan.Empty() {
+		fmt.Printf("Lab %s is up to date.\n", lab.ObjectMeta.Name)
+		return nil
+	}
+
+	newServers := newServerNames(plan)
+	if providerSvc.Capabilities().NeedsPublicDNS && !opts.SkipDNS && len(newServers) > 0 {
+		fmt.Printf("Lab %s: Creating DNS records for new servers...\n", lab.ObjectMeta.Name)
+		for _, server := range lab.Status.Servers {
+			if !newServers[server.ObjectMeta.Name] {
+				continue
+			}
+			if err := addServerDNSRecord(strings.ToLower(lab.ObjectMeta.Name), server); err != nil {
+				return err
+			}
+		}
+	}
+	deletedServers := deletedServerNames(plan)
+	if providerSvc.Capabilities().NeedsPublicDNS && !opts.SkipDNS && len(deletedServers) > 0 {
+		fmt.Printf("Lab %s: Deleting DNS records of deleted servers...\n", lab.ObjectMeta.Name)
+		removeServerDNSRecords(strings.ToLower(lab.ObjectMeta.Name), deletedServers)
+	}
+	if len(newServers) > 0 || len(deletedServers) > 0 {
+		fmt.Printf("Lab %s: Updating ansible inventory file...\n", lab.ObjectMeta.Name)
+		if err := labSvc.CreateAnsibleInventoryFile(lab); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Lab %s: Changes applied.\n", lab.ObjectMeta.Name)
+	return nil
+}
+
+// labToApply returns the lab a manifest is applied to, or nil if the lab has to be created:
+// it isn't stored and has no resources on the provider, or it has no servers left
+func labToApply(ctx context.Context, labName string) (*types.Lab, error) {
+	existing, err := labSvc.Lookup(ctx, labName)
+	if errors.Is(err, lab.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab %s: %w", labName, err)
+	}
+	if len(existing.Status.Servers) == 0 {
+		return nil, nil
+	}
+	return existing, nil
+}
+
+// newServerNames returns the names of the servers the plan creates
+func newServerNames(plan *lab.Plan) map[string]bool {
+	names := make(map[string]bool)
+	for _, action := range plan.Filter(lab.ActionCreate, "Server") {
+		names[action.Name] = true
+	}
+	return names
+}
+
+// deletedServerNames returns the names of the servers the plan deletes
+func deletedServerNames(plan *lab.Plan) map[string]bool {
+	names := make(map[string]bool)
+	for _, action := range plan.Filter(lab.ActionDelete, "Server") {
+		names[action.Name] = true
+	}
+	return names
+}
+
+func printPlan(plan *lab.Plan) {
+	if plan.Empty() {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tKIND\tNAME\tDETAIL")
+	for _, action := range plan.Actions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", action.Type, action.Kind, action.Name, action.Detail)
+	}
+	w.Flush()
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+)
+
+func NewBackupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up labs and manage the backups",
+	}
+
+	cmd.AddCommand(NewBackupLabCmd(), NewBackupListCmd(), NewBackupDeleteCmd())
+	return cmd
+}
+
+func NewBackupLabCmd() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "lab [name]",
+		Short: "Back up a lab to restore it later as a new lab",
+		Long: `Save the lab record, Ansible inventory, kubeconfig, and admin keys to ~/.storctl/backups/<backup>,
+with the snapshots the provider can take: server snapshot images on Hetzner Cloud
+and copies of the Lima disks. Hetzner Cloud has no volume snapshots, so restored
+Hetzner labs get empty volumes. Stop the Lima VMs before backing up a Lima lab.`,
+		Example: `  storctl backup lab mylab --name golden-demo
+  storctl restore golden-demo --name demo-1`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := initProvider(useProvider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			backup, err := labSvc.Backup(cmd.Context(), args[0], lab.BackupOpts{Name: name})
+			if err != nil {
+				return fmt.Errorf("error backing up lab: %w", err)
+			}
+			fmt.Printf("Lab %s saved as backup %s with %d snapshots\n", args[0], backup.Name, len(backup.Snapshots))
+			for _, note := range backup.Notes {
+				fmt.Printf("Note: %s\n", note)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "backup name (default <lab>-<time>)")
+	return cmd
+}
+
+func NewBackupListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the lab backups",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backups, err := lab.ListBackups()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tLAB\tPROVIDER\tCREATED\tSNAPSHOTS\tFILES")
+			for _, backup := range backups {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", backup.Name, backup.Lab.ObjectMeta.Name, backup.Provider,
+					formatTime(backup.Created), len(backup.Snapshots), orNA(strings.Join(backup.Files, ",")))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func NewBackupDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete [backup]",
+		Short: "Delete a backup with its server snapshots",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backup, err := lab.LoadBackup(args[0])
+			if err != nil {
+				return err
+			}
+			err = initProvider(backup.Provider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			if err := labSvc.DeleteBackup(cmd.Context(), backup.Name); err != nil {
+				return fmt.Errorf("error deleting backup: %w", err)
+			}
+			fmt.Printf("Deleted backup %s\n", backup.Name)
+			return nil
+		},
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,6 +16,9 @@ import (
 type CreateOpts struct {
 	SkipDNS     bool
 	SkipInstall bool
+	NoRollback  bool
+	Resume      bool
+	Concurrency int
 }
 
 func NewCreateCmd() *cobra.Command {
@@ -26,7 +30,7 @@ func NewCreateCmd() *cobra.Command {
 		Short: "Create resources (key, server, volume, lab)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if filename != "" {
-				return createFromFile(filename, opts)
+				return createFromFile(cmd.Context(), filename, opts)
 			}
 			return fmt.Errorf("either -f flag or a resource type must be specified")
 		},
@@ -36,6 +40,8 @@ func NewCreateCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&filename, "filename", "f", "", "Path to the YAML manifest file")
 	cmd.Flags().BoolVar(&opts.SkipDNS, "skip-dns", false, "skip DNS records creation")
 	cmd.Flags().BoolVar(&opts.SkipInstall, "skip-install", false, "skip lab installation")
+	cmd.Flags().BoolVar(&opts.NoRollback, "no-rollback", false, "keep created resources if lab creation fails")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", 0, "number of servers or volumes created in parallel (default from config)")
 
 	// Add subcommands for direct resource creation
 	cmd.AddCommand(
@@ -48,7 +54,7 @@ func NewCreateCmd() *cobra.Command {
 	return cmd
 }
 
-func createFromFile(filename string, opts CreateOpts) error {
+func createFromFile(ctx context.Context, filename string, opts CreateOpts) error {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("error reading file: %w", err)
@@ -65,7 +71,7 @@ func createFromFile(filename string, opts CreateOpts) error {
 			return fmt.Errorf("error decoding YAML: %w", err)
 		}
 
-		if err := processResource(resource, opts); err != nil {
+		if err := processResource(ctx, resource, opts); err != nil {
 			return err
 		}
 	}
@@ -73,7 +79,7 @@ func createFromFile(filename string, opts CreateOpts) error {
 	return nil
 }
 
-func processResource(resource *types.Resource, opts CreateOpts) error {
+func processResource(ctx context.Context, resource *types.Resource, opts CreateOpts) error {
 	switch resource.Kind {
 	case "Server":
 		server := types.Server{
@@ -84,7 +90,7 @@ func processResource(resource *types.Resource, opts CreateOpts) error {
 		if err := convertToStruct(resource.Spec, &server.Spec); err != nil {
 			return fmt.Errorf("error parsing Server spec: %w", err)
 		}
-		_, err := createServer(&server)
+		_, err := createServer(ctx, &server)
 		return err
 	case "Volume":
 		volume := types.Volume{
@@ -94,7 +100,7 @@ func processResource(resource *types.Resource, opts CreateOpts) error {
 		if err := convertToStruct(resource.Spec, &volume.Spec); err != nil {
 			return fmt.Errorf("error parsing Volume spec: %w", err)
 		}
-		return createVolume(&volume)
+		return createVolume(ctx, &volume)
 	case "Key":
 		key := types.SSHKey{
 			TypeMeta:   resource.TypeMeta,
@@ -103,7 +109,7 @@ func processResource(resource *types.Resource, opts CreateOpts) error {
 		if err := convertToStruct(resource.Spec, &key.Spec); err != nil {
 			return fmt.Errorf("error parsing Key spec: %w", err)
 		}
-		_, err := createKey(&key)
+		_, err := createKey(ctx, &key)
 		return err
 	case "Lab":
 		lab := &types.Lab{
@@ -113,7 +119,7 @@ func processResource(resource *types.Resource, opts CreateOpts) error {
 		if err := convertToStruct(resource.Spec, &lab.Spec); err != nil {
 			return fmt.Errorf("error parsing Lab spec: %w", err)
 		}
-		_, err := createLab(lab, opts)
+		_, err := createLab(ctx, lab, opts)
 		if err != nil {
 			return err
 		}
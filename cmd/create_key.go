@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -34,7 +35,7 @@ func NewCreateKeyCmd() *cobra.Command {
 					Labels: labels,
 				},
 			}
-			key, err := createKey(keyResource)
+			key, err := createKey(cmd.Context(), keyResource)
 			if err != nil {
 				return err
 			}
@@ -48,7 +49,7 @@ func NewCreateKeyCmd() *cobra.Command {
 	return cmd
 }
 
-func createKey(key *types.SSHKey) (*types.SSHKey, error) {
+func createKey(ctx context.Context, key *types.SSHKey) (*types.SSHKey, error) {
 	keyManager := ssh.NewManager(cfg)
 	keyName := key.ObjectMeta.Name
 	if keyName == "" {
@@ -80,13 +81,13 @@ func createKey(key *types.SSHKey) (*types.SSHKey, error) {
 	}
 
 	// Check if key already exists on the provider
-	keyExists, err := providerSvc.CloudKeyExists(keyName)
+	keyExists, err := providerSvc.CloudKeyExists(ctx, keyName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if key exists on provider: %w", err)
 	}
 	if keyExists {
 		fmt.Printf("SSH key %s already exists on the provider\n", keyName)
-		cloudKey, err := providerSvc.GetSSHKey(keyName)
+		cloudKey, err := providerSvc.GetSSHKey(ctx, keyName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get key from provider: %w", err)
 		}
@@ -95,7 +96,7 @@ func createKey(key *types.SSHKey) (*types.SSHKey, error) {
 			return key, nil
 		} else {
 			fmt.Printf("SSH key %s already exists on the provider but is different from the local key. Replacing it.\n", keyName)
-			status := providerSvc.DeleteSSHKey(keyName, true)
+			status := providerSvc.DeleteSSHKey(ctx, keyName, true)
 			if status.Error != nil {
 				return nil, fmt.Errorf("failed to delete key from provider: %w", status.Error)
 			}
@@ -117,7 +118,7 @@ func createKey(key *types.SSHKey) (*types.SSHKey, error) {
 	labels["delete_after"] = timeutil.FormatDeleteAfter(time.Now().Add(duration))
 	labels["owner"] = labelutil.SanitizeValue(cfg.Owner)
 	// Upload public key to provider
-	key, err = providerSvc.CreateSSHKey(options.SSHKeyCreateOpts{
+	key, err = providerSvc.CreateSSHKey(ctx, options.SSHKeyCreateOpts{
 		Name:      keyName,
 		PublicKey: key.Spec.PublicKey,
 		Labels:    labels,
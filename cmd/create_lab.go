@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
 	"github.com/pavelanni/storctl/internal/types"
 	"github.com/pavelanni/storctl/internal/util/labelutil"
 	"github.com/pavelanni/storctl/internal/util/timeutil"
@@ -30,9 +32,24 @@ func NewCreateLabCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "lab [name]",
 		Short: "Create a new lab environment",
-		Args:  cobra.ExactArgs(1),
+		Long: `Create a new lab environment from a template.
+
+Lab creation runs in phases: keys, servers, readiness, volumes, dns, inventory, and playbook.
+If a phase fails, the lab is saved in the Failed state and --resume continues from that phase.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name = args[0]
+			if opts.Resume {
+				lab, err := failedLab(cmd.Context(), name)
+				if err != nil {
+					return err
+				}
+				_, err = createLab(cmd.Context(), lab, opts)
+				if err != nil {
+					return fmt.Errorf("error resuming lab: %w", err)
+				}
+				return nil
+			}
 			lab, err := labFromTemplate(template, name, provider, location, ttl, playbook)
 			if err != nil {
 				return fmt.Errorf("error parsing lab template: %w", err)
@@ -40,7 +57,7 @@ func NewCreateLabCmd() *cobra.Command {
 			if provider != "" {
 				lab.Spec.Provider = provider // override the provider in the template
 			}
-			_, err = createLab(lab, opts)
+			_, err = createLab(cmd.Context(), lab, opts)
 			if err != nil {
 				return fmt.Errorf("error creating lab: %w", err)
 			}
@@ -56,79 +73,123 @@ func NewCreateLabCmd() *cobra.Command {
 	cmd.Flags().StringVar(&playbook, "playbook", "site.yml", "playbook to use")
 	cmd.Flags().BoolVar(&opts.SkipDNS, "skip-dns", false, "skip DNS records creation")
 	cmd.Flags().BoolVar(&opts.SkipInstall, "skip-install", false, "skip lab installation")
+	cmd.Flags().BoolVar(&opts.NoRollback, "no-rollback", false, "keep created resources if lab creation fails")
+	cmd.Flags().BoolVar(&opts.Resume, "resume", false, "resume the creation of a failed lab from the failed phase")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", 0, "number of servers or volumes created in parallel (default from config)")
 
 	return cmd
 }
 
-func createLab(lab *types.Lab, opts CreateOpts) (*types.Lab, error) {
-	lab.ObjectMeta.Labels["owner"] = labelutil.SanitizeValue(cfg.Owner)
-	lab.ObjectMeta.Labels["organization"] = labelutil.SanitizeValue(cfg.Organization)
-	lab.ObjectMeta.Labels["email"] = labelutil.SanitizeValue(cfg.Email)
-	lab.ObjectMeta.Labels["lab_name"] = lab.ObjectMeta.Name
-	ttl := lab.Spec.TTL
-	if ttl == "" {
-		ttl = config.DefaultTTL
-	}
-	duration, err := timeutil.TtlToDuration(ttl)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse ttl: %w", err)
+func createLab(ctx context.Context, lab *types.Lab, opts CreateOpts) (*types.Lab, error) {
+	// a resumed lab keeps its labels so new resources get the same TTL
+	if !opts.Resume {
+		lab.ObjectMeta.Labels["owner"] = labelutil.SanitizeValue(cfg.Owner)
+		lab.ObjectMeta.Labels["organization"] = labelutil.SanitizeValue(cfg.Organization)
+		lab.ObjectMeta.Labels["email"] = labelutil.SanitizeValue(cfg.Email)
+		lab.ObjectMeta.Labels["lab_name"] = lab.ObjectMeta.Name
+		ttl := lab.Spec.TTL
+		if ttl == "" {
+			ttl = config.DefaultTTL
+		}
+		duration, err := timeutil.TtlToDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		lab.ObjectMeta.Labels["delete_after"] = timeutil.FormatDeleteAfter(time.Now().Add(duration))
 	}
-	lab.ObjectMeta.Labels["delete_after"] = timeutil.FormatDeleteAfter(time.Now().Add(duration))
 
-	err = initProvider(lab.Spec.Provider)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize provider: %w", err)
+	if err := useLabManager(lab.Spec.Provider); err != nil {
+		return nil, err
 	}
-	err = initLabManager()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize lab manager: %w", err)
+	if opts.Concurrency > 0 {
+		labSvc.Concurrency = opts.Concurrency
 	}
 
 	fmt.Printf("Lab %s: Creating lab resources using provider %s...\n", lab.ObjectMeta.Name, lab.Spec.Provider)
 	labSvc.Logger.Info("Creating new lab",
 		"name", lab.ObjectMeta.Name,
 		"nodes", len(lab.Spec.Servers))
-	labSvc.Logger.Debug("Lab configuration", "lab", lab) // Detailed config for debugging
-	if err := labSvc.Create(lab); err != nil {           // labSvc is a package variable created in root.go
+	labSvc.Logger.Debug("Lab configuration", "lab", lab)                 // Detailed config for debugging
+	if err := labSvc.Create(ctx, lab, labCreateOpts(opts)); err != nil { // labSvc is a package variable created in root.go
 		return nil, err
 	}
 	// get the lab again to get the status
-	labUpdated, err := labSvc.Get(lab.ObjectMeta.Name)
+	labUpdated, err := labSvc.Get(ctx, lab.ObjectMeta.Name)
 	if err != nil {
 		return nil, err
 	}
 	lab.Status = labUpdated.Status
 
-	if lab.Spec.Provider != "lima" && !opts.SkipDNS { // we don't need DNS records for local VMs
-		fmt.Printf("Lab %s: Creating DNS records...\n", lab.ObjectMeta.Name)
-		if err := addDNSRecords(lab); err != nil {
-			return nil, err
+	if providerSvc.Capabilities().NeedsPublicDNS && !opts.SkipDNS { // we don't need DNS records for local VMs and containers
+		err = labSvc.RunPhase(lab, types.PhaseDNS, func() error {
+			fmt.Printf("Lab %s: Creating DNS records...\n", lab.ObjectMeta.Name)
+			return addDNSRecords(lab)
+		})
+		if err != nil {
+			return nil, resumeHint(lab, err)
 		}
 	}
 	if opts.SkipInstall {
 		fmt.Printf("Lab %s: Skipping lab software installation.\n", lab.ObjectMeta.Name)
 		return lab, nil
 	}
-	fmt.Printf("Lab %s: Creating ansible inventory file...\n", lab.ObjectMeta.Name)
-	err = labSvc.CreateAnsibleInventoryFile(lab)
+	err = labSvc.RunPhase(lab, types.PhaseInventory, func() error {
+		fmt.Printf("Lab %s: Creating ansible inventory file...\n", lab.ObjectMeta.Name)
+		return labSvc.CreateAnsibleInventoryFile(lab)
+	})
 	if err != nil {
-		return nil, err
+		return nil, resumeHint(lab, err)
 	}
-	if lab.Spec.Ansible.Playbook != "" {
-		fmt.Printf("Lab %s: Running Ansible playbook %s...\n", lab.ObjectMeta.Name, lab.Spec.Ansible.Playbook)
-	} else {
+	if lab.Spec.Ansible.Playbook == "" {
 		fmt.Printf("Lab %s: No playbook specified. Skipping Ansible configuration.\n", lab.ObjectMeta.Name)
+		return lab, nil
 	}
-
-	if lab.Spec.Ansible.Playbook != "" {
-		err = labSvc.RunAnsiblePlaybook(lab)
-		if err != nil {
-			return nil, err
-		}
+	err = labSvc.RunPhase(lab, types.PhasePlaybook, func() error {
+		fmt.Printf("Lab %s: Running Ansible playbook %s...\n", lab.ObjectMeta.Name, lab.Spec.Ansible.Playbook)
+		return labSvc.RunAnsiblePlaybook(ctx, lab)
+	})
+	if err != nil {
+		return nil, resumeHint(lab, err)
 	}
 	return lab, nil
 }
 
+// resumeHint adds the command to resume the lab creation to the error
+func resumeHint(lab *types.Lab, err error) error {
+	return fmt.Errorf("%w\nresume with 'storctl create lab %s --resume'", err, lab.ObjectMeta.Name)
+}
+
+// failedLab returns the stored lab whose creation can be resumed.
+// It's read from the storage the lab manager saves it to.
+func failedLab(ctx context.Context, labName string) (*types.Lab, error) {
+	storage, err := openLabStorage()
+	if err != nil {
+		return nil, err
+	}
+	l, err := storage.Get(labName)
+	if err != nil {
+		return nil, err
+	}
+	if len(l.Status.Phases) == 0 {
+		return nil, fmt.Errorf("lab %s has no creation progress to resume", labName)
+	}
+	if l.Status.State != types.LabStateFailed {
+		return nil, fmt.Errorf("lab %s is not in the %s state", labName, types.LabStateFailed)
+	}
+	if l.ObjectMeta.Labels == nil {
+		l.ObjectMeta.Labels = make(map[string]string)
+	}
+	return l, nil
+}
+
+// labCreateOpts returns the lab manager options for the command options
+func labCreateOpts(opts CreateOpts) lab.CreateOpts {
+	return lab.CreateOpts{
+		NoRollback: opts.NoRollback,
+		Resume:     opts.Resume,
+	}
+}
+
 func labFromTemplate(template, name, provider, location, ttl, playbook string) (*types.Lab, error) {
 	// Check if the template file exists
 	if _, err := os.Stat(template); os.IsNotExist(err) {
@@ -171,19 +232,10 @@ func addDNSRecords(lab *types.Lab) error {
 		labName = "no-lab"
 	}
 	labName = strings.ToLower(labName)
-	for i, server := range lab.Status.Servers {
-		serverName := strings.ToLower(server.Name)
-		// remove the leading labName with "-" from the serverName
-		serverName = strings.TrimPrefix(serverName, labName+"-")
-		err := dnsSvc.AddRecord(cfg.DNS.ZoneID,
-			strings.Join([]string{serverName, labName}, "."),
-			"A",
-			server.Status.PublicNet.IPv4.IP,
-			false)
-		if err != nil {
+	for _, server := range lab.Status.Servers {
+		if err := addServerDNSRecord(labName, server); err != nil {
 			return err
 		}
-		lab.Status.Servers[i].Status.PublicNet.FQDN = strings.Join([]string{serverName, labName, cfg.DNS.Domain}, ".")
 	}
 	// Add a DNS record for 'aistor.' using the IP of the control plane server
 	cpPublicNet := lab.Status.Servers[0].Status.PublicNet
@@ -197,6 +249,35 @@ func addDNSRecords(lab *types.Lab) error {
 	return nil
 }
 
+// addServerDNSRecord adds an A record <server>.<lab> for the server and sets its FQDN
+func addServerDNSRecord(labName string, server *types.Server) error {
+	serverName := strings.ToLower(server.Name)
+	// remove the leading labName with "-" from the serverName
+	serverName = strings.TrimPrefix(serverName, labName+"-")
+	err := dnsSvc.AddRecord(cfg.DNS.ZoneID,
+		strings.Join([]string{serverName, labName}, "."),
+		"A",
+		server.Status.PublicNet.IPv4.IP,
+		false)
+	if err != nil {
+		return err
+	}
+	server.Status.PublicNet.FQDN = strings.Join([]string{serverName, labName, cfg.DNS.Domain}, ".")
+	return nil
+}
+
+// removeServerDNSRecords deletes the A records <server>.<lab> of the servers, e.g. the ones a plan deleted.
+// The servers are gone already, so a record that can't be deleted is only reported.
+func removeServerDNSRecords(labName string, serverNames map[string]bool) {
+	for serverName := range serverNames {
+		shortName := strings.TrimPrefix(strings.ToLower(serverName), labName+"-")
+		name := strings.Join([]string{shortName, labName, cfg.DNS.Domain}, ".")
+		if err := dnsSvc.DeleteRecordsByName(cfg.DNS.ZoneID, name); err != nil {
+			fmt.Printf("Warning: failed to delete the DNS record %s: %v\n", name, err)
+		}
+	}
+}
+
 // Helper function to handle default string values
 func defaultIfEmpty(value, defaultValue string) string {
 	if value == "" {
@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
 	"github.com/pavelanni/storctl/internal/lab/mock"
+	"github.com/pavelanni/storctl/internal/logger"
+	providermock "github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
 	"github.com/pavelanni/storctl/internal/types"
 )
 
@@ -21,7 +28,7 @@ func TestCreateLabCmd(t *testing.T) {
 			name: "successful lab creation",
 			args: []string{"test-lab", "--template", "lab.yaml"},
 			mockSetup: func(m *mock.Manager) {
-				m.CreateFunc = func(lab *types.Lab) error {
+				m.CreateFunc = func(ctx context.Context, lab *types.Lab, opts lab.CreateOpts) error {
 					if lab.ObjectMeta.Name != "test-lab" {
 						t.Errorf("expected lab name 'test-lab', got '%s'", lab.ObjectMeta.Name)
 					}
@@ -34,7 +41,7 @@ func TestCreateLabCmd(t *testing.T) {
 			name: "provider error",
 			args: []string{"test-lab", "--template", "lab.yaml"},
 			mockSetup: func(m *mock.Manager) {
-				m.CreateFunc = func(lab *types.Lab) error {
+				m.CreateFunc = func(ctx context.Context, lab *types.Lab, opts lab.CreateOpts) error {
 					return types.NewError("provider error", "failed to create lab")
 				}
 			},
@@ -85,6 +92,84 @@ func TestCreateLabCmd(t *testing.T) {
 	}
 }
 
+func TestCreateLabResumeKeepsResources(t *testing.T) {
+	var deleted []string
+	provider := &providermock.MockProvider{
+		NameFunc: func() string { return "lima" },
+		CreateVolumeFunc: func(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
+			return &types.Volume{ObjectMeta: types.ObjectMeta{Name: opts.Name}}, nil
+		},
+		CreateServerFunc: func(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+			if opts.Name == "test-node-01" {
+				return nil, errors.New("out of capacity")
+			}
+			return &types.Server{ObjectMeta: types.ObjectMeta{Name: opts.Name}}, nil
+		},
+		DeleteServerFunc: func(ctx context.Context, name string, force bool) *types.ServerDeleteStatus {
+			deleted = append(deleted, "Server "+name)
+			return &types.ServerDeleteStatus{Deleted: true}
+		},
+		DeleteVolumeFunc: func(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
+			deleted = append(deleted, "Volume "+name)
+			return &types.VolumeDeleteStatus{Deleted: true}
+		},
+	}
+
+	originalCfg, originalProvider, originalManager, originalStorage := cfg, providerSvc, labSvc, labStorage
+	defer func() {
+		cfg, providerSvc, labSvc, labStorage = originalCfg, originalProvider, originalManager, originalStorage
+	}()
+	cfg = &config.Config{
+		Owner:   "test-owner",
+		Storage: config.StorageConfig{Path: filepath.Join(t.TempDir(), "labs.db"), Bucket: "labs"},
+	}
+	labStorage = nil
+	storage, err := openLabStorage()
+	if err != nil {
+		t.Fatalf("failed to open lab storage: %v", err)
+	}
+	defer closeLabStorage()
+	providerSvc = provider
+	labSvc = &lab.ManagerSvc{Provider: provider, Storage: storage, Logger: logger.Get()}
+
+	l := &types.Lab{
+		ObjectMeta: types.ObjectMeta{Name: "test", Labels: map[string]string{}},
+		Spec: types.LabSpec{
+			Provider: "lima",
+			Servers: []*types.LabServerSpec{
+				{Name: "cp", ServerType: "cx22", Image: "ubuntu-24.04"},
+				{Name: "node-01", ServerType: "cx22", Image: "ubuntu-24.04"},
+			},
+			Volumes: []*types.LabVolumeSpec{{Name: "volume-01", Server: "node-01", Size: 100}},
+		},
+	}
+	if _, err := createLab(context.Background(), l, CreateOpts{NoRollback: true, SkipInstall: true}); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	// the resumed run fails in the same phase
+	failed, err := failedLab(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("failedLab() error = %v", err)
+	}
+	if _, err := createLab(context.Background(), failed, CreateOpts{Resume: true, SkipInstall: true}); err == nil {
+		t.Fatal("expected the resumed run to fail")
+	}
+	if len(deleted) != 0 {
+		t.Errorf("a failed resume deleted the resources of the earlier run: %v", deleted)
+	}
+	stored, err := storage.Get("test")
+	if err != nil {
+		t.Fatalf("the lab record was removed: %v", err)
+	}
+	if stored.Status.State != types.LabStateFailed {
+		t.Errorf("expected the lab in the %s state, got %s", types.LabStateFailed, stored.Status.State)
+	}
+	if len(stored.Status.Resources) != 2 {
+		t.Errorf("expected the volume and the server of the earlier run in the journal, got %d resources", len(stored.Status.Resources))
+	}
+}
+
 // Helper function to check if a string contains another string
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -47,7 +48,7 @@ func NewCreateServerCmd() *cobra.Command {
 					SSHKeyNames: sshKeyNames,
 				},
 			}
-			result, err := createServer(server)
+			result, err := createServer(cmd.Context(), server)
 			if err != nil {
 				return err
 			}
@@ -67,7 +68,7 @@ func NewCreateServerCmd() *cobra.Command {
 	return cmd
 }
 
-func createServer(server *types.Server) (*types.Server, error) {
+func createServer(ctx context.Context, server *types.Server) (*types.Server, error) {
 	err := initProvider(server.Spec.Provider)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize provider: %w", err)
@@ -103,7 +104,8 @@ func createServer(server *types.Server) (*types.Server, error) {
 	labels["delete_after"] = timeutil.FormatDeleteAfter(time.Now().Add(duration))
 	labels["owner"] = labelutil.SanitizeValue(cfg.Owner)
 
-	if server.Spec.Provider != "lima" {
+	managesSSHKeys := providerSvc.Capabilities().ManagesSSHKeys
+	if managesSSHKeys {
 		// create the ssh keys locally
 		for _, sshKeyName := range server.Spec.SSHKeyNames {
 			_, err := sshManager.CreateLocalKeyPair(sshKeyName)
@@ -111,7 +113,7 @@ func createServer(server *types.Server) (*types.Server, error) {
 				return nil, fmt.Errorf("failed to create local ssh key: %w", err)
 			}
 		}
-		sshKeys, err = providerSvc.KeyNamesToSSHKeys(server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
+		sshKeys, err = providerSvc.KeyNamesToSSHKeys(ctx, server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
 			Labels: labels,
 		})
 		if err != nil {
@@ -119,14 +121,14 @@ func createServer(server *types.Server) (*types.Server, error) {
 		}
 	}
 
-	opts, err := providerSvc.ServerToCreateOpts(server)
+	opts, err := providerSvc.ServerToCreateOpts(ctx, server)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert server to create opts: %w", err)
 	}
-	if server.Spec.Provider != "lima" {
+	if managesSSHKeys {
 		opts.SSHKeys = sshKeys
 	}
-	result, err := providerSvc.CreateServer(opts)
+	result, err := providerSvc.CreateServer(ctx, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create server: %w", err)
 	}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -46,7 +47,7 @@ func NewCreateVolumeCmd() *cobra.Command {
 					Format:    format,
 				},
 			}
-			return createVolume(volume)
+			return createVolume(cmd.Context(), volume)
 		},
 	}
 
@@ -59,7 +60,7 @@ func NewCreateVolumeCmd() *cobra.Command {
 	return cmd
 }
 
-func createVolume(volume *types.Volume) error {
+func createVolume(ctx context.Context, volume *types.Volume) error {
 	err := initProvider(volume.Spec.Provider)
 	if err != nil {
 		return fmt.Errorf("failed to initialize provider: %w", err)
@@ -88,7 +89,7 @@ func createVolume(volume *types.Volume) error {
 	labels["delete_after"] = timeutil.FormatDeleteAfter(time.Now().Add(duration))
 	labels["owner"] = labelutil.SanitizeValue(cfg.Owner)
 
-	_, err = providerSvc.CreateVolume(options.VolumeCreateOpts{
+	_, err = providerSvc.CreateVolume(ctx, options.VolumeCreateOpts{
 		Name:       volume.ObjectMeta.Name,
 		Size:       volume.Spec.Size,
 		ServerName: volume.Spec.ServerName,
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+)
+
+func NewDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Inspect or repair the lab storage",
+		Long: `Inspect or repair the local bbolt lab storage.
+The lab records are migrated to the current schema version when the storage is opened;
+these commands open it as is.`,
+	}
+
+	cmd.AddCommand(newDBMigrateCmd(), newDBCheckCmd(), newDBDumpCmd())
+	return cmd
+}
+
+func newDBMigrateCmd() *cobra.Command {
+	opts := lab.MigrateOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the lab records to the current schema version",
+		Long: `Migrate the lab records to the current schema version.
+The storage is copied to <path>.v<version>.bak before any record is changed.
+Use --all to run all the migrations again, to repair the records saved by an older storctl.`,
+		Example: `  storctl db migrate --dry-run
+  storctl db migrate --all`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			storage, err := openBoltStorage()
+			if err != nil {
+				return err
+			}
+			defer storage.Close()
+			result, err := storage.Migrate(opts)
+			if err != nil {
+				return fmt.Errorf("error migrating lab storage: %w", err)
+			}
+			for _, migration := range result.Applied {
+				fmt.Printf("Migration %s\n", migration)
+			}
+			for _, name := range result.Migrated {
+				fmt.Printf("Migrated lab %s\n", name)
+			}
+			for _, skipped := range result.Skipped {
+				fmt.Printf("Skipped lab %s\n", skipped)
+			}
+			prefix := "Migrated"
+			if opts.DryRun {
+				prefix = "Would migrate (dry run)"
+			}
+			fmt.Printf("%s %d labs from schema version %d to %d\n", prefix, len(result.Migrated), result.From, result.To)
+			if result.Backup != "" {
+				fmt.Printf("Saved the previous lab storage to %s\n", result.Backup)
+			}
+			if len(result.Skipped) > 0 {
+				return fmt.Errorf("skipped %d labs that can't be migrated, fix or delete them and run storctl db migrate again", len(result.Skipped))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "only show the labs that would be migrated")
+	cmd.Flags().BoolVar(&opts.All, "all", false, "run all the migrations, not only the ones after the stored schema version")
+	return cmd
+}
+
+func newDBCheckCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Check the lab records",
+		Long: `Check that the lab records match the current lab schema.
+Records with unknown fields, invalid JSON, a name other than their key, or no provider are reported.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			storage, err := openBoltStorage()
+			if err != nil {
+				return err
+			}
+			defer storage.Close()
+			result, err := storage.Check()
+			if err != nil {
+				return fmt.Errorf("error checking lab storage: %w", err)
+			}
+			fmt.Printf("Schema version %d (current %d), %d labs\n", result.Version, lab.SchemaVersion, result.Labs)
+			if len(result.Problems) > 0 {
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "LAB\tPROBLEM")
+				for _, problem := range result.Problems {
+					fmt.Fprintf(w, "%s\t%s\n", problem.Lab, problem.Problem)
+				}
+				w.Flush()
+				return fmt.Errorf("found %d problems in the lab storage", len(result.Problems))
+			}
+			if result.Version < lab.SchemaVersion {
+				return fmt.Errorf("lab storage needs a migration, run storctl db migrate")
+			}
+			return nil
+		},
+	}
+}
+
+func newDBDumpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "dump",
+		Short: "Print the lab records as JSON",
+		Long: `Print the schema version and the raw lab records as JSON.
+Records that aren't valid JSON are printed as strings.`,
+		Example: `  storctl db dump > labs.json`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			storage, err := openBoltStorage()
+			if err != nil {
+				return err
+			}
+			defer storage.Close()
+			return storage.Dump(os.Stdout)
+		},
+	}
+}
+
+// openBoltStorage opens the lab storage without migrating it
+func openBoltStorage() (*lab.BoltStorage, error) {
+	if backend := cfg.Storage.Backend; backend != "" && backend != config.StorageBackendBbolt {
+		return nil, fmt.Errorf("the db commands work with the %s storage backend, not %s", config.StorageBackendBbolt, backend)
+	}
+	storage, err := lab.OpenBoltStorage(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lab storage: %w", err)
+	}
+	return storage, nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -24,7 +25,7 @@ func NewDeleteCmd() *cobra.Command {
 		Short: "Delete resources (key, server, volume, lab)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if filename != "" {
-				return deleteFromFile(filename, assumeYes, skipTimeCheck)
+				return deleteFromFile(cmd.Context(), filename, assumeYes, skipTimeCheck)
 			}
 			return fmt.Errorf("either -f flag or a resource type must be specified")
 		},
@@ -46,7 +47,7 @@ func NewDeleteCmd() *cobra.Command {
 	return cmd
 }
 
-func deleteFromFile(filename string, assumeYes, skipTimeCheck bool) error {
+func deleteFromFile(ctx context.Context, filename string, assumeYes, skipTimeCheck bool) error {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("error reading file: %w", err)
@@ -63,7 +64,7 @@ func deleteFromFile(filename string, assumeYes, skipTimeCheck bool) error {
 			return fmt.Errorf("error decoding YAML: %w", err)
 		}
 
-		if err := processDeleteResource(resource, assumeYes, skipTimeCheck); err != nil {
+		if err := processDeleteResource(ctx, resource, assumeYes, skipTimeCheck); err != nil {
 			return err
 		}
 	}
@@ -71,7 +72,7 @@ func deleteFromFile(filename string, assumeYes, skipTimeCheck bool) error {
 	return nil
 }
 
-func processDeleteResource(resource *types.Resource, assumeYes, skipTimeCheck bool) error {
+func processDeleteResource(ctx context.Context, resource *types.Resource, assumeYes, skipTimeCheck bool) error {
 	if !askForConfirmation(resource) {
 		return nil
 	}
@@ -81,17 +82,17 @@ func processDeleteResource(resource *types.Resource, assumeYes, skipTimeCheck bo
 	}
 	switch resource.Kind {
 	case "Server":
-		if status := providerSvc.DeleteServer(resourceName, skipTimeCheck); status.Error != nil {
+		if status := providerSvc.DeleteServer(ctx, resourceName, skipTimeCheck); status.Error != nil {
 			return fmt.Errorf("failed to delete server: %w", status.Error)
 		}
 		return nil
 	case "Volume":
-		if status := providerSvc.DeleteVolume(resourceName, skipTimeCheck); status.Error != nil {
+		if status := providerSvc.DeleteVolume(ctx, resourceName, skipTimeCheck); status.Error != nil {
 			return fmt.Errorf("failed to delete volume: %w", status.Error)
 		}
 		return nil
 	case "Key":
-		if status := providerSvc.DeleteSSHKey(resourceName, skipTimeCheck); status.Error != nil {
+		if status := providerSvc.DeleteSSHKey(ctx, resourceName, skipTimeCheck); status.Error != nil {
 			return fmt.Errorf("failed to delete key: %w", status.Error)
 		}
 		return nil
@@ -100,7 +101,7 @@ func processDeleteResource(resource *types.Resource, assumeYes, skipTimeCheck bo
 		if err != nil {
 			return fmt.Errorf("failed to create lab manager: %w", err)
 		}
-		if err := labManager.Delete(resourceName, skipTimeCheck); err != nil {
+		if err := labManager.Delete(ctx, resourceName, skipTimeCheck); err != nil {
 			return fmt.Errorf("failed to delete lab: %w", err)
 		}
 		return nil
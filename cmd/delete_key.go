@@ -24,7 +24,7 @@ func NewDeleteSSHKeyCmd() *cobra.Command {
 			}
 
 			// Delete the key using cloud provider
-			status := providerSvc.DeleteSSHKey(keyName, skipTimeCheck)
+			status := providerSvc.DeleteSSHKey(cmd.Context(), keyName, skipTimeCheck)
 			if status.Error != nil {
 				return fmt.Errorf("failed to delete key: %w", status.Error)
 			}
@@ -30,10 +30,13 @@ func NewDeleteLabCmd() *cobra.Command {
 				return err
 			}
 			// Delete the lab using lab manager
-			if err := labSvc.Delete(labName, skipTimeCheck); err != nil {
+			if err := labSvc.Delete(cmd.Context(), labName, skipTimeCheck); err != nil {
 				return fmt.Errorf("error deleting lab: %w", err)
 			}
 
+			if err := removeLabKubeconfig(labName); err != nil {
+				fmt.Printf("Warning: failed to remove the lab kubeconfig: %v\n", err)
+			}
 			fmt.Printf("Successfully deleted lab %s\n", labName)
 			return nil
 		},
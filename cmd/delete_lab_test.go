@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"testing"
 
 	"github.com/pavelanni/storctl/internal/lab/mock"
@@ -21,7 +22,7 @@ func TestDeleteLabCmd(t *testing.T) {
 			args:  []string{"test-lab"},
 			flags: []string{"--yes"}, // Skip confirmation
 			mockSetup: func(m *mock.Manager) {
-				m.DeleteFunc = func(name string, force bool) error {
+				m.DeleteFunc = func(ctx context.Context, name string, force bool) error {
 					if name != "test-lab" {
 						t.Errorf("expected lab name 'test-lab', got '%s'", name)
 					}
@@ -35,7 +36,7 @@ func TestDeleteLabCmd(t *testing.T) {
 			args:  []string{"test-lab"},
 			flags: []string{"--yes", "--force"},
 			mockSetup: func(m *mock.Manager) {
-				m.DeleteFunc = func(name string, force bool) error {
+				m.DeleteFunc = func(ctx context.Context, name string, force bool) error {
 					if !force {
 						t.Error("expected force flag to be true")
 					}
@@ -49,7 +50,7 @@ func TestDeleteLabCmd(t *testing.T) {
 			args:  []string{"nonexistent-lab"},
 			flags: []string{"--yes"},
 			mockSetup: func(m *mock.Manager) {
-				m.DeleteFunc = func(name string, force bool) error {
+				m.DeleteFunc = func(ctx context.Context, name string, force bool) error {
 					return types.NewError("NOT_FOUND", "lab not found")
 				}
 			},
@@ -61,7 +62,7 @@ func TestDeleteLabCmd(t *testing.T) {
 			args:  []string{},
 			flags: []string{"--yes"},
 			mockSetup: func(m *mock.Manager) {
-				m.DeleteFunc = func(name string, force bool) error {
+				m.DeleteFunc = func(ctx context.Context, name string, force bool) error {
 					t.Error("DeleteLab should not be called when lab name is missing")
 					return nil
 				}
@@ -74,7 +75,7 @@ func TestDeleteLabCmd(t *testing.T) {
 			args:  []string{"test-lab"},
 			flags: []string{"--yes"},
 			mockSetup: func(m *mock.Manager) {
-				m.DeleteFunc = func(name string, force bool) error {
+				m.DeleteFunc = func(ctx context.Context, name string, force bool) error {
 					return types.NewError("PROVIDER_ERROR", "failed to delete lab")
 				}
 			},
@@ -23,7 +23,7 @@ func NewDeleteServerCmd() *cobra.Command {
 			}
 
 			// Delete the server using cloud provider
-			status := providerSvc.DeleteServer(serverName, skipTimeCheck)
+			status := providerSvc.DeleteServer(cmd.Context(), serverName, skipTimeCheck)
 			if status.Error != nil {
 				return fmt.Errorf("failed to delete server: %w", status.Error)
 			}
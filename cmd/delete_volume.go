@@ -23,7 +23,7 @@ func NewDeleteVolumeCmd() *cobra.Command {
 			}
 
 			// Delete the volume using cloud provider
-			status := providerSvc.DeleteVolume(volumeName, skipTimeCheck)
+			status := providerSvc.DeleteVolume(cmd.Context(), volumeName, skipTimeCheck)
 			if status.Error != nil {
 				return fmt.Errorf("failed to delete volume: %w", status.Error)
 			}
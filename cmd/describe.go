@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/util/output"
+	"github.com/spf13/cobra"
+)
+
+type DescribeOpts struct {
+	Output string
+}
+
+func NewDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "describe",
+		Short: "Show details of resources (lab)",
+	}
+
+	cmd.AddCommand(NewDescribeLabCmd())
+	return cmd
+}
+
+func NewDescribeLabCmd() *cobra.Command {
+	opts := DescribeOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "lab [name]",
+		Short: "Show the lab topology and the live health of its servers and volumes",
+		Long: `Show the lab servers with their roles, types, addresses, and attached volumes,
+the DNS records, the inventory and kubeconfig paths, the last playbook run,
+the time left until deletion, and the recent lab events.
+The health of the servers and volumes is read from the provider.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := initProvider(useProvider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			d, err := labSvc.Describe(cmd.Context(), args[0], lab.DescribeOpts{DNSDomain: cfg.DNS.Domain})
+			if err != nil {
+				return fmt.Errorf("error describing lab: %w", err)
+			}
+			switch defaultIfEmpty(opts.Output, cfg.OutputFormat) {
+			case "json":
+				return output.JSON(d, os.Stdout)
+			case "yaml":
+				return output.YAML(d, os.Stdout)
+			default:
+				printDescription(d, os.Stdout)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Output format (text|json|yaml)")
+	return cmd
+}
+
+func printDescription(d *lab.Description, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", d.Name)
+	fmt.Fprintf(w, "Provider:\t%s\n", d.Provider)
+	fmt.Fprintf(w, "Location:\t%s\n", orNA(d.Location))
+	fmt.Fprintf(w, "Owner:\t%s\n", orNA(d.Owner))
+	fmt.Fprintf(w, "State:\t%s\n", orNA(d.State))
+	fmt.Fprintf(w, "Created:\t%s\n", formatTime(d.Created))
+	fmt.Fprintf(w, "Delete after:\t%s (%s left)\n", formatTime(d.DeleteAfter), d.TTLRemaining)
+	if d.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", d.Error)
+	}
+	fmt.Fprintf(w, "Inventory:\t%s\n", orNA(d.Inventory))
+	fmt.Fprintf(w, "Kubeconfig:\t%s\n", orNA(d.Kubeconfig))
+	if d.Playbook != nil {
+		result := orNA(d.Playbook.State)
+		if !d.Playbook.Finished.IsZero() {
+			result += " at " + formatTime(d.Playbook.Finished)
+		}
+		if d.Playbook.Error != "" {
+			result += ": " + d.Playbook.Error
+		}
+		fmt.Fprintf(w, "Playbook:\t%s (%s)\n", d.Playbook.Playbook, result)
+		if d.Playbook.LogPath != "" {
+			fmt.Fprintf(w, "Playbook log:\t%s\n", d.Playbook.LogPath)
+		}
+		for _, task := range d.Playbook.FailedTasks {
+			fmt.Fprintf(w, "Failed task:\t%s\n", task)
+		}
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nServers:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tROLE\tTYPE\tCORES\tMEMORY\tDISK\tIP\tFQDN\tVOLUMES\tHEALTH")
+	for _, s := range d.Servers {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%.2fGB\t%dGB\t%s\t%s\t%s\t%s\n",
+			s.Name, orNA(s.Role), s.Type, s.Cores, s.Memory, s.Disk, orNA(s.IP), orNA(s.FQDN), orNA(strings.Join(s.Volumes, ",")), s.Health)
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nVolumes:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tSERVER\tSIZE\tFORMAT\tAUTOMOUNT\tHEALTH")
+	for _, v := range d.Volumes {
+		fmt.Fprintf(w, "  %s\t%s\t%dGB\t%s\t%t\t%s\n", v.Name, orNA(v.Server), v.Size, orNA(v.Format), v.Automount, v.Health)
+	}
+	w.Flush()
+
+	if len(d.DNSRecords) > 0 {
+		fmt.Fprintln(out, "\nDNS records:")
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, record := range d.DNSRecords {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", record.Name, record.Type, record.Content)
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintln(out, "\nEvents:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, event := range d.Events {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", formatTime(event.Time), event.Reason, event.Message)
+	}
+	w.Flush()
+}
+
+// orNA returns N/A for empty values
+func orNA(value string) string {
+	return defaultIfEmpty(value, "N/A")
+}
+
+// formatTime formats t in local time or returns N/A for the zero time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "N/A"
+	}
+	return t.Local().Format(time.RFC3339)
+}
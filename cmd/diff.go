@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/output"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+type DiffOpts struct {
+	SkipDNS bool
+	Output  string
+}
+
+func NewDiffCmd() *cobra.Command {
+	var filename string
+	opts := DiffOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show what create, apply, or delete would change",
+		Long: `Diff shows the servers, volumes, SSH keys, and DNS records that would be created,
+resized, or deleted without changing anything.
+
+With -f it shows what 'apply -f' would do with the Lab manifest.
+Use 'diff lab' to see what 'create lab' or 'delete lab' would do.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if filename == "" {
+				return fmt.Errorf("either -f flag or a resource type must be specified")
+			}
+			return diffFromFile(cmd.Context(), filename, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&filename, "filename", "f", "", "Path to the YAML manifest file")
+	cmd.PersistentFlags().BoolVar(&opts.SkipDNS, "skip-dns", false, "don't show DNS records")
+	cmd.PersistentFlags().StringVarP(&opts.Output, "output", "o", "", "Output format (table|json)")
+
+	cmd.AddCommand(NewDiffLabCmd(&opts))
+
+	return cmd
+}
+
+func NewDiffLabCmd(opts *DiffOpts) *cobra.Command {
+	var (
+		template string
+		provider string
+		location string
+		ttl      string
+		playbook string
+		del      bool
+		force    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lab [name]",
+		Short: "Show what creating or deleting a lab would change",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if del {
+				return diffDeleteLab(cmd.Context(), args[0], force, *opts)
+			}
+			lab, err := labFromTemplate(template, args[0], provider, location, ttl, playbook)
+			if err != nil {
+				return fmt.Errorf("error parsing lab template: %w", err)
+			}
+			if provider != "" {
+				lab.Spec.Provider = provider // override the provider in the template
+			}
+			return diffCreateLab(cmd.Context(), lab, *opts)
+		},
+	}
+
+	defaultTemplate := filepath.Join(os.Getenv("HOME"), config.DefaultConfigDir, config.DefaultTemplateDir, "lab.yaml")
+	cmd.Flags().StringVar(&template, "template", defaultTemplate, "lab template to use")
+	cmd.Flags().StringVar(&provider, "provider", config.DefaultLocalProvider, "provider to use")
+	cmd.Flags().StringVar(&location, "location", config.DefaultLocalLocation, "location to use")
+	cmd.Flags().StringVar(&ttl, "ttl", config.DefaultTTL, "ttl to use")
+	cmd.Flags().StringVar(&playbook, "playbook", "site.yml", "playbook to use")
+	cmd.Flags().BoolVar(&del, "delete", false, "show what deleting the lab would remove")
+	cmd.Flags().BoolVar(&force, "force", false, "don't check DeleteAfter time with --delete")
+
+	return cmd
+}
+
+func diffFromFile(ctx context.Context, filename string, opts DiffOpts) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewBuffer(data), 4096)
+	for {
+		resource := &types.Resource{}
+		if err := decoder.Decode(resource); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("error decoding YAML: %w", err)
+		}
+		if resource.Kind != "Lab" {
+			return fmt.Errorf("diff supports only Lab resources, got: %s", resource.Kind)
+		}
+		lab := &types.Lab{
+			TypeMeta:   resource.TypeMeta,
+			ObjectMeta: resource.ObjectMeta,
+		}
+		if err := convertToStruct(resource.Spec, &lab.Spec); err != nil {
+			return fmt.Errorf("error parsing Lab spec: %w", err)
+		}
+		if err := diffApplyLab(ctx, lab, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffApplyLab shows what apply would do: create the lab if it doesn't exist or change it otherwise
+func diffApplyLab(ctx context.Context, l *types.Lab, opts DiffOpts) error {
+	if l.ObjectMeta.Name == "" {
+		return fmt.Errorf("lab name is required")
+	}
+	if l.Spec.Provider == "" {
+		l.Spec.Provider = config.DefaultLocalProvider
+	}
+	if err := useLabManager(l.Spec.Provider); err != nil {
+		return err
+	}
+	existing, err := labToApply(ctx, l.ObjectMeta.Name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		plan, err := labSvc.PlanCreate(ctx, l, planOpts(opts))
+		if err != nil {
+			return err
+		}
+		return writePlan(plan, opts)
+	}
+	plan, err := labSvc.PlanApply(ctx, l, planOpts(opts))
+	if err != nil {
+		return err
+	}
+	return writePlan(plan, opts)
+}
+
+func diffCreateLab(ctx context.Context, l *types.Lab, opts DiffOpts) error {
+	if err := useLabManager(l.Spec.Provider); err != nil {
+		return err
+	}
+	plan, err := labSvc.PlanCreate(ctx, l, planOpts(opts))
+	if err != nil {
+		return err
+	}
+	return writePlan(plan, opts)
+}
+
+func diffDeleteLab(ctx context.Context, labName string, force bool, opts DiffOpts) error {
+	if err := useLabManager(useProvider); err != nil {
+		return err
+	}
+	plan, err := labSvc.PlanDelete(ctx, labName, force)
+	if err != nil {
+		return err
+	}
+	return writePlan(plan, opts)
+}
+
+// planOpts returns the plan options; the lab manager skips DNS records for providers without public DNS
+func planOpts(opts DiffOpts) lab.PlanOpts {
+	if opts.SkipDNS {
+		return lab.PlanOpts{}
+	}
+	return lab.PlanOpts{DNSDomain: cfg.DNS.Domain}
+}
+
+func writePlan(plan *lab.Plan, opts DiffOpts) error {
+	format := defaultIfEmpty(opts.Output, cfg.OutputFormat)
+	switch format {
+	case "json":
+		return output.JSON(plan, os.Stdout)
+	default:
+		if plan.Empty() {
+			fmt.Printf("Lab %s: No changes.\n", plan.Lab)
+			return nil
+		}
+		printPlan(plan)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+)
+
+type ExecOpts struct {
+	All  bool
+	Role string
+	Node string
+}
+
+func NewExecCmd() *cobra.Command {
+	opts := ExecOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "exec [lab] [--all|--role role|--node node] -- [command]",
+		Short: "Run a command on lab nodes",
+		Long: `Run a command on the lab nodes in parallel over SSH and print the output
+prefixed with the node names. Select all nodes with --all, the nodes of a role
+(control_plane or nodes, the same as the Ansible inventory groups) with --role,
+or one node with --node. The arguments are quoted, so each one reaches the node
+as given; use sh -c for pipes and redirects.`,
+		Example: `  storctl exec mylab --all -- uptime
+  storctl exec mylab --role nodes -- lsblk
+  storctl exec mylab --node cp -- sh -c "journalctl -u k3s | tail"`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			labName := args[0]
+			command := shellJoin(args[1:])
+			err := initProvider(useProvider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			nodes, err := labSvc.Nodes(cmd.Context(), labName)
+			if err != nil {
+				return fmt.Errorf("error getting lab nodes: %w", err)
+			}
+			nodes, err = selectNodes(nodes, labName, opts)
+			if err != nil {
+				return err
+			}
+			return lab.Exec(cmd.Context(), nodes, command, os.Stdout, os.Stderr)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.All, "all", false, "run on all nodes")
+	cmd.Flags().StringVar(&opts.Role, "role", "", "run on the nodes of this role (control_plane or nodes)")
+	cmd.Flags().StringVar(&opts.Node, "node", "", "run on this node")
+	return cmd
+}
+
+// shellJoin quotes the arguments for the remote shell and joins them,
+// as SSH passes the command to the shell on the node as one string
+func shellJoin(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote returns the argument in single quotes unless it has only characters the shell doesn't interpret
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+// selectNodes returns the nodes selected by exactly one of --all, --role, and --node
+func selectNodes(nodes []*lab.Node, labName string, opts ExecOpts) ([]*lab.Node, error) {
+	switch {
+	case opts.All && opts.Role == "" && opts.Node == "":
+		return nodes, nil
+	case !opts.All && opts.Role != "" && opts.Node == "":
+		if opts.Role != lab.RoleControlPlane && opts.Role != lab.RoleNodes {
+			return nil, fmt.Errorf("unknown role %s, use %s or %s", opts.Role, lab.RoleControlPlane, lab.RoleNodes)
+		}
+		selected := make([]*lab.Node, 0, len(nodes))
+		for _, node := range nodes {
+			if node.Role == opts.Role {
+				selected = append(selected, node)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("lab %s has no %s nodes", labName, opts.Role)
+		}
+		return selected, nil
+	case !opts.All && opts.Role == "" && opts.Node != "":
+		node, err := lab.FindNode(nodes, labName, opts.Node)
+		if err != nil {
+			return nil, err
+		}
+		return []*lab.Node{node}, nil
+	}
+	return nil, fmt.Errorf("use one of --all, --role, or --node")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "plain arguments", args: []string{"lsblk", "-o", "NAME,SIZE", "/dev/vdb"}, want: "lsblk -o NAME,SIZE /dev/vdb"},
+		{name: "sh -c script", args: []string{"sh", "-c", "echo a; echo b"}, want: "sh -c 'echo a; echo b'"},
+		{name: "single quote", args: []string{"echo", "it's"}, want: `echo 'it'"'"'s'`},
+		{name: "empty argument", args: []string{"printf", "%s", ""}, want: "printf %s ''"},
+		{name: "glob and variable", args: []string{"ls", "*.log", "$HOME"}, want: "ls '*.log' '$HOME'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shellJoin(tt.args); got != tt.want {
+				t.Errorf("shellJoin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellJoinRunsAsGiven(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	output, err := exec.Command(sh, "-c", shellJoin([]string{"sh", "-c", "echo a; echo b", "it's"})).Output()
+	if err != nil {
+		t.Fatalf("failed to run the command: %v", err)
+	}
+	if string(output) != "a\nb\n" {
+		t.Errorf("output = %q, want %q", output, "a\nb\n")
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// passphraseEnv sets the bundle passphrase without a prompt
+const passphraseEnv = "STORCTL_PASSPHRASE"
+
+func NewExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a lab to hand it to another user",
+	}
+
+	cmd.AddCommand(NewExportLabCmd())
+	return cmd
+}
+
+func NewExportLabCmd() *cobra.Command {
+	var (
+		outputFile string
+		encrypt    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lab [name]",
+		Short: "Package a lab as a bundle that 'storctl import' registers on another machine",
+		Long: `Package the lab record, the lab template, the Ansible inventory, the kubeconfig, and the lab
+admin keys as a tar.gz bundle. With --encrypt the admin private key is encrypted with a passphrase,
+asked for or read from $` + passphraseEnv + `. Anyone with the bundle and the passphrase can log in
+to the lab servers, so share it like a key.`,
+		Example: `  storctl export lab mylab -o mylab.tar.gz --encrypt
+  storctl import mylab.tar.gz`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			labName := args[0]
+			outputFile = defaultIfEmpty(outputFile, labName+".tar.gz")
+			opts := lab.ExportOpts{}
+			if encrypt {
+				passphrase, err := readPassphrase(true)
+				if err != nil {
+					return err
+				}
+				opts.Passphrase = passphrase
+			}
+			err := initProvider(useProvider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			f, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+			if err != nil {
+				return fmt.Errorf("error creating bundle: %w", err)
+			}
+			manifest, err := labSvc.Export(cmd.Context(), labName, f, opts)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(outputFile)
+				return fmt.Errorf("error exporting lab: %w", err)
+			}
+			fmt.Printf("Lab %s exported to %s (%s)\n", labName, outputFile, strings.Join(manifest.Files, ", "))
+			if !manifest.EncryptedKey {
+				fmt.Println("The bundle has the lab admin private key, share it like a key or use --encrypt")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "bundle file (default <lab>.tar.gz)")
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "encrypt the admin private key with a passphrase")
+	return cmd
+}
+
+// readPassphrase reads the bundle passphrase from the environment or the terminal.
+// A new passphrase is asked twice.
+func readPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to ask for the passphrase, set $%s", passphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("passphrase is empty")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("error reading passphrase: %w", err)
+		}
+		if string(again) != string(passphrase) {
+			return "", fmt.Errorf("passphrases don't match")
+		}
+	}
+	return string(passphrase), nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+	"github.com/spf13/cobra"
+)
+
+func NewExtendCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "extend",
+		Short: "Extend the TTL of resources (lab)",
+	}
+
+	cmd.AddCommand(NewExtendLabCmd())
+	return cmd
+}
+
+func NewExtendLabCmd() *cobra.Command {
+	var ttl string
+
+	cmd := &cobra.Command{
+		Use:   "lab [name]",
+		Short: "Push the lab deletion time forward",
+		Long: `Add the TTL to the delete_after label of all lab servers, volumes, and SSH keys,
+or to the current time if the lab has expired. The lab can't be extended past
+max_lifetime from the config file (168h by default, 0 means no limit) since its creation.
+The owner from the config file is recorded as the one who extended the lab.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			labName := args[0]
+			duration, err := timeutil.TtlToDuration(ttl)
+			if err != nil {
+				return fmt.Errorf("failed to parse ttl: %w", err)
+			}
+			maxLifetime, err := timeutil.TtlToDuration(defaultIfEmpty(cfg.MaxLifetime, config.DefaultMaxLifetime))
+			if err != nil {
+				return fmt.Errorf("failed to parse max_lifetime: %w", err)
+			}
+
+			err = initProvider(useProvider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			extended, err := labSvc.Extend(cmd.Context(), labName, lab.ExtendOpts{
+				TTL:         duration,
+				MaxLifetime: maxLifetime,
+				By:          cfg.Owner,
+			})
+			if err != nil {
+				return fmt.Errorf("error extending lab: %w", err)
+			}
+
+			fmt.Printf("Lab %s will be deleted after %s\n", labName, extended.Status.DeleteAfter.Local().Format(time.RFC1123))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&ttl, "ttl", "", "time to add to the lab TTL, like 4h")
+	cmd.MarkFlagRequired("ttl")
+	return cmd
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"text/tabwriter"
@@ -17,21 +18,21 @@ func NewGetKeyCmd() *cobra.Command {
 		Long:  `Display information about SSH keys including lab name, age, and deletion time.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				return listKeys()
+				return listKeys(cmd.Context())
 			}
-			return getKey(args[0])
+			return getKey(cmd.Context(), args[0])
 		},
 	}
 
 	return cmd
 }
 
-func listKeys() error {
+func listKeys(ctx context.Context) error {
 	err := initProvider(useProvider)
 	if err != nil {
 		return err
 	}
-	keys, err := providerSvc.AllSSHKeys()
+	keys, err := providerSvc.AllSSHKeys(ctx)
 	if err != nil {
 		return err
 	}
@@ -58,12 +59,12 @@ func listKeys() error {
 	return w.Flush()
 }
 
-func getKey(name string) error {
+func getKey(ctx context.Context, name string) error {
 	err := initProvider(useProvider)
 	if err != nil {
 		return err
 	}
-	key, err := providerSvc.GetSSHKey(name)
+	key, err := providerSvc.GetSSHKey(ctx, name)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"text/tabwriter"
@@ -21,7 +22,7 @@ func NewGetLabCmd() *cobra.Command {
 			if len(args) == 0 {
 				return listLabs()
 			}
-			return getLab(args[0])
+			return getLab(cmd.Context(), args[0])
 		},
 	}
 
@@ -79,7 +80,7 @@ func listLabs() error {
 	return nil
 }
 
-func getLab(labName string) error {
+func getLab(ctx context.Context, labName string) error {
 	err := initProvider(useProvider)
 	if err != nil {
 		return err
@@ -88,7 +89,7 @@ func getLab(labName string) error {
 	if err != nil {
 		return err
 	}
-	lab, err := labSvc.Get(labName)
+	lab, err := labSvc.Get(ctx, labName)
 	if err != nil {
 		return err
 	}
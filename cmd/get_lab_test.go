@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -96,7 +97,7 @@ func TestGetLabCmd(t *testing.T) {
 			args:         []string{"test-lab-1"},
 			outputFormat: "table",
 			mockSetup: func(m *mock.Manager) {
-				m.GetFunc = func(name string) (*types.Lab, error) {
+				m.GetFunc = func(ctx context.Context, name string) (*types.Lab, error) {
 					if name == "test-lab-1" {
 						return testLabs[0], nil
 					}
@@ -110,7 +111,7 @@ func TestGetLabCmd(t *testing.T) {
 			args:         []string{"test-lab-1"},
 			outputFormat: "json",
 			mockSetup: func(m *mock.Manager) {
-				m.GetFunc = func(name string) (*types.Lab, error) {
+				m.GetFunc = func(ctx context.Context, name string) (*types.Lab, error) {
 					return testLabs[0], nil
 				}
 			},
@@ -121,7 +122,7 @@ func TestGetLabCmd(t *testing.T) {
 			args:         []string{"test-lab-1"},
 			outputFormat: "yaml",
 			mockSetup: func(m *mock.Manager) {
-				m.GetFunc = func(name string) (*types.Lab, error) {
+				m.GetFunc = func(ctx context.Context, name string) (*types.Lab, error) {
 					return testLabs[0], nil
 				}
 			},
@@ -131,7 +132,7 @@ func TestGetLabCmd(t *testing.T) {
 			name: "lab not found",
 			args: []string{"nonexistent-lab"},
 			mockSetup: func(m *mock.Manager) {
-				m.GetFunc = func(name string) (*types.Lab, error) {
+				m.GetFunc = func(ctx context.Context, name string) (*types.Lab, error) {
 					return nil, types.NewError("NOT_FOUND", "lab not found")
 				}
 			},
@@ -153,7 +154,7 @@ func TestGetLabCmd(t *testing.T) {
 			name: "get lab from cloud",
 			args: []string{"test-lab-1", "--from-cloud"},
 			mockSetup: func(m *mock.Manager) {
-				m.GetFromCloudFunc = func(name string) (*types.Lab, error) {
+				m.GetFromCloudFunc = func(ctx context.Context, name string) (*types.Lab, error) {
 					if name == "test-lab-1" {
 						return testLabs[0], nil
 					}
@@ -166,7 +167,7 @@ func TestGetLabCmd(t *testing.T) {
 			name: "get lab from cloud - not found",
 			args: []string{"nonexistent-lab", "--from-cloud"},
 			mockSetup: func(m *mock.Manager) {
-				m.GetFromCloudFunc = func(name string) (*types.Lab, error) {
+				m.GetFromCloudFunc = func(ctx context.Context, name string) (*types.Lab, error) {
 					return nil, types.NewError("NOT_FOUND", "lab not found in cloud")
 				}
 			},
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"text/tabwriter"
@@ -19,21 +20,21 @@ func NewGetServerCmd() *cobra.Command {
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				return listServers()
+				return listServers(cmd.Context())
 			}
-			return getServer(args[0])
+			return getServer(cmd.Context(), args[0])
 		},
 	}
 
 	return cmd
 }
 
-func listServers() error {
+func listServers(ctx context.Context) error {
 	err := initProvider(useProvider)
 	if err != nil {
 		return err
 	}
-	servers, err := providerSvc.AllServers()
+	servers, err := providerSvc.AllServers(ctx)
 	if err != nil {
 		return err
 	}
@@ -55,12 +56,12 @@ func listServers() error {
 	return w.Flush()
 }
 
-func getServer(serverID string) error {
+func getServer(ctx context.Context, serverID string) error {
 	err := initProvider(useProvider)
 	if err != nil {
 		return err
 	}
-	server, err := providerSvc.GetServer(serverID)
+	server, err := providerSvc.GetServer(ctx, serverID)
 	if err != nil {
 		return err
 	}
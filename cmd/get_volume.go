@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"text/tabwriter"
@@ -19,21 +20,21 @@ func NewGetVolumeCmd() *cobra.Command {
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				return listVolumes()
+				return listVolumes(cmd.Context())
 			}
-			return getVolume(args[0])
+			return getVolume(cmd.Context(), args[0])
 		},
 	}
 
 	return cmd
 }
 
-func listVolumes() error {
+func listVolumes(ctx context.Context) error {
 	err := initProvider(useProvider)
 	if err != nil {
 		return err
 	}
-	volumes, err := providerSvc.AllVolumes()
+	volumes, err := providerSvc.AllVolumes(ctx)
 	if err != nil {
 		return err
 	}
@@ -56,12 +57,12 @@ func listVolumes() error {
 	return w.Flush()
 }
 
-func getVolume(volumeID string) error {
+func getVolume(ctx context.Context, volumeID string) error {
 	err := initProvider(useProvider)
 	if err != nil {
 		return err
 	}
-	volume, err := providerSvc.GetVolume(volumeID)
+	volume, err := providerSvc.GetVolume(ctx, volumeID)
 	if err != nil {
 		return err
 	}
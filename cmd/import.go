@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+)
+
+func NewImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import [bundle]",
+		Short: "Register a lab exported with 'storctl export lab'",
+		Long: `Register the lab from the bundle in the local lab storage and write its admin keys, Ansible
+inventory, and kubeconfig, so get, ssh, install, and delete work for the lab on this machine.
+The provider credentials must give access to the project the lab runs in.`,
+		Example: `  storctl import mylab.tar.gz`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("error opening bundle: %w", err)
+			}
+			defer f.Close()
+			bundle, err := lab.ReadBundle(f)
+			if err != nil {
+				return err
+			}
+			opts := lab.ImportOpts{}
+			if bundle.Manifest.EncryptedKey {
+				passphrase, err := readPassphrase(false)
+				if err != nil {
+					return err
+				}
+				opts.Passphrase = passphrase
+			}
+			err = initProvider(bundle.Manifest.Provider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			imported, err := labSvc.Import(cmd.Context(), bundle, opts)
+			if err != nil {
+				return fmt.Errorf("error importing lab: %w", err)
+			}
+			fmt.Printf("Lab %s imported from %s (exported %s by %s)\n", imported.ObjectMeta.Name, args[0],
+				formatTime(bundle.Manifest.Exported), orNA(bundle.Manifest.ExportedBy))
+			return nil
+		},
+	}
+
+	return cmd
+}
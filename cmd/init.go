@@ -68,6 +68,30 @@ func createConfig() error {
 			Name:     config.DefaultLocalProvider,
 			Location: config.DefaultLocalLocation,
 		},
+		{
+			Name:     config.DefaultContainerProvider,
+			Location: config.DefaultLocalLocation,
+		},
+		{
+			Name:     config.DefaultLibvirtProvider,
+			Location: config.DefaultLocalLocation,
+			URI:      config.DefaultLibvirtURI,
+			Pool:     config.DefaultLibvirtPool,
+			Network:  config.DefaultLibvirtNetwork,
+		},
+		{
+			Name: config.DefaultOpenStackProvider,
+			Credentials: map[string]string{
+				"auth_url":     "",
+				"username":     "",
+				"password":     "",
+				"project_name": "",
+			},
+		},
+		{
+			Name:     config.DefaultAWSProvider,
+			Location: config.DefaultAWSRegion,
+		},
 		{
 			Name:     config.DefaultCloudProvider,
 			Location: config.DefaultCloudLocation,
@@ -83,6 +107,7 @@ func createConfig() error {
 	defaultCfg.Owner = config.DefaultOwner
 	defaultCfg.Storage.Path = filepath.Join(os.Getenv("HOME"), config.DefaultConfigDir, config.DefaultLabStorageFile)
 	defaultCfg.Storage.Bucket = config.DefaultLabBucket
+	defaultCfg.Concurrency = config.DefaultConcurrency
 
 	// Marshal the default config to YAML and write it to the default config file
 	cfgBytes, err := yaml.Marshal(defaultCfg)
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -31,7 +32,7 @@ func NewInstallLabCmd() *cobra.Command {
 			if len(args) == 0 {
 				return fmt.Errorf("lab name is required")
 			}
-			return installLab(args[0], opts)
+			return installLab(cmd.Context(), args[0], opts)
 		},
 	}
 
@@ -42,7 +43,7 @@ func NewInstallLabCmd() *cobra.Command {
 	return cmd
 }
 
-func installLab(labName string, opts InstallLabOpts) error {
+func installLab(ctx context.Context, labName string, opts InstallLabOpts) error {
 	if opts.Inventory == "" {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
@@ -72,7 +73,7 @@ func installLab(labName string, opts InstallLabOpts) error {
 	if err != nil {
 		return fmt.Errorf("error creating lab manager: %w", err)
 	}
-	lab, err := labSvc.Get(labName) // get from the storage
+	lab, err := labSvc.Get(ctx, labName) // get from the storage
 	if err != nil {
 		return fmt.Errorf("error getting lab: %w", err)
 	}
@@ -82,7 +83,7 @@ func installLab(labName string, opts InstallLabOpts) error {
 	if opts.Inventory != "" {
 		lab.Spec.Ansible.Inventory = opts.Inventory
 	}
-	err = labSvc.RunAnsiblePlaybook(lab)
+	err = labSvc.RunAnsiblePlaybook(ctx, lab)
 	if err != nil {
 		return fmt.Errorf("error running ansible playbook: %w", err)
 	}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/util/kubeconfig"
+	"github.com/spf13/cobra"
+)
+
+type KubeconfigOpts struct {
+	Refresh    bool
+	UseIP      bool
+	Merge      bool
+	Remove     bool
+	Context    string
+	Kubeconfig string
+}
+
+func NewKubeconfigCmd() *cobra.Command {
+	opts := KubeconfigOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "kubeconfig [lab]",
+		Short: "Print the path of the lab kubeconfig",
+		Long: `Print the path of the lab kubeconfig in ~/.storctl/kubeconfigs. If it's missing,
+it's fetched from the control plane over SSH. The server URL points to the public
+DNS name of the control plane, or to its IP for labs without DNS records.
+
+With --merge, the lab cluster is also added to ~/.kube/config as the storctl-<lab> context;
+--remove deletes that context. Deleting the lab removes it too.`,
+		Example: `  export KUBECONFIG=$(storctl kubeconfig mylab)
+  storctl kubeconfig mylab --merge && kubectl config use-context storctl-mylab
+  storctl kubeconfig mylab --remove`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			labName := args[0]
+			if opts.Merge && opts.Remove {
+				return fmt.Errorf("--merge and --remove can't be used together")
+			}
+			contextName := defaultIfEmpty(opts.Context, lab.KubeContextName(labName))
+			target := opts.Kubeconfig
+			if target == "" {
+				var err error
+				if target, err = kubeconfig.DefaultPath(); err != nil {
+					return err
+				}
+			}
+			if opts.Remove {
+				removed, err := lab.RemoveKubeContext(target, contextName)
+				if err != nil {
+					return err
+				}
+				if !removed {
+					return fmt.Errorf("%s has no context %s", target, contextName)
+				}
+				fmt.Fprintf(os.Stderr, "Removed context %s from %s\n", contextName, target)
+				return nil
+			}
+
+			err := initProvider(useProvider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			path, err := labSvc.Kubeconfig(cmd.Context(), labName, lab.KubeconfigOpts{
+				Refresh: opts.Refresh,
+				UseIP:   opts.UseIP,
+			})
+			if err != nil {
+				return fmt.Errorf("error getting kubeconfig: %w", err)
+			}
+			if opts.Merge {
+				if err := lab.MergeKubeconfig(path, target, contextName); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Merged context %s into %s\n", contextName, target)
+			}
+			fmt.Println(path)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Refresh, "refresh", false, "fetch the kubeconfig from the control plane even if it exists")
+	cmd.Flags().BoolVar(&opts.UseIP, "use-ip", false, "point the server URL to the control plane IP instead of its DNS name")
+	cmd.Flags().BoolVar(&opts.Merge, "merge", false, "merge the lab cluster into the user kubeconfig as a context")
+	cmd.Flags().BoolVar(&opts.Remove, "remove", false, "remove the lab context from the user kubeconfig")
+	cmd.Flags().StringVar(&opts.Context, "context", "", "name of the merged context (default storctl-<lab>)")
+	cmd.Flags().StringVar(&opts.Kubeconfig, "kubeconfig", "", "user kubeconfig to merge into (default ~/.kube/config)")
+	return cmd
+}
+
+// removeLabKubeconfig removes the kubeconfig of the deleted lab and its context from the user kubeconfig
+func removeLabKubeconfig(labName string) error {
+	path, err := lab.KubeconfigPath(labName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing kubeconfig %s: %w", path, err)
+	}
+	target, err := kubeconfig.DefaultPath()
+	if err != nil {
+		return err
+	}
+	removed, err := lab.RemoveKubeContext(target, lab.KubeContextName(labName))
+	if err != nil {
+		return err
+	}
+	if removed {
+		fmt.Printf("Removed context %s from %s\n", lab.KubeContextName(labName), target)
+	}
+	return nil
+}
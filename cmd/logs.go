@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+)
+
+type LogsOpts struct {
+	Run    int
+	Follow bool
+	List   bool
+}
+
+func NewLogsCmd() *cobra.Command {
+	opts := LogsOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "logs [lab]",
+		Short: "Show the Ansible playbook logs of a lab",
+		Long: `Show the output of the lab playbook runs saved in ~/.storctl/logs/<lab>.
+Without --run, the last run is shown. Runs are numbered from the oldest one, see --list.
+The logs are read from the files, so they can be followed while the playbook runs.`,
+		Example: `  storctl logs mylab
+  storctl logs mylab --list
+  storctl logs mylab --run 2
+  storctl logs mylab --follow`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runs, err := lab.LogRuns(args[0])
+			if err != nil {
+				return err
+			}
+			if opts.List {
+				printLogRuns(os.Stdout, runs)
+				return nil
+			}
+			run := runs[len(runs)-1]
+			if opts.Run != 0 {
+				if opts.Run < 1 || opts.Run > len(runs) {
+					return fmt.Errorf("lab %s has runs 1 to %d", args[0], len(runs))
+				}
+				run = runs[opts.Run-1]
+			}
+			if opts.Follow {
+				return lab.FollowLog(cmd.Context(), run.Path, os.Stdout, 500*time.Millisecond)
+			}
+			file, err := os.Open(run.Path)
+			if err != nil {
+				return fmt.Errorf("error opening log: %w", err)
+			}
+			defer file.Close()
+			_, err = io.Copy(os.Stdout, file)
+			return err
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.Run, "run", 0, "number of the run to show (default the last one)")
+	cmd.Flags().BoolVarP(&opts.Follow, "follow", "f", false, "keep showing the log until the playbook finishes")
+	cmd.Flags().BoolVar(&opts.List, "list", false, "list the runs")
+	return cmd
+}
+
+func printLogRuns(out io.Writer, runs []*lab.LogRun) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tSTARTED\tPLAYBOOK\tRESULT\tFAILED TASKS")
+	for _, run := range runs {
+		result := "running"
+		if run.Finished {
+			result = run.Summary
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", run.Number, formatTime(run.Started), run.Playbook, result, orNA(strings.Join(run.FailedTasks, ", ")))
+	}
+	w.Flush()
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/notifier"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+	"github.com/spf13/cobra"
+)
+
+func NewNotifyCmd() *cobra.Command {
+	var (
+		interval time.Duration
+		window   string
+		once     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Notify the lab owner before their labs expire",
+		Long: `Run until interrupted and notify the owner from the config file about their labs
+that will be deleted within the window. The labs of other owners in a shared lab storage
+are skipped. The notifications are sent to the email, webhook, and Slack webhook
+from the notify section of the config file. Each lab is notified once for every
+deletion time, so a lab that was extended is notified again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("window") {
+				window = defaultIfEmpty(cfg.Notify.Window, config.DefaultNotifyWindow)
+			}
+			windowDuration, err := timeutil.TtlToDuration(window)
+			if err != nil {
+				return fmt.Errorf("failed to parse window: %w", err)
+			}
+			sinks := notifier.NewSinks(cfg)
+			if len(sinks) == 0 {
+				return fmt.Errorf("no notification sinks configured, add smtp, webhook_url, or slack_webhook_url to the notify section of the config file")
+			}
+			// notify runs for long, so it doesn't lock the lab storage between the checks
+			storage, err := lab.NewDaemonStorage(cfg)
+			if err != nil {
+				return fmt.Errorf("error opening lab storage: %w", err)
+			}
+			defer storage.Close()
+
+			manager := &lab.ManagerSvc{Storage: storage, Logger: logger.Get()}
+			n := notifier.New(manager, sinks, windowDuration, cfg.Owner, cfg.Email)
+			if once {
+				sent, err := n.Check(cmd.Context())
+				fmt.Printf("Notifications sent: %d\n", len(sent))
+				return err
+			}
+			return n.Run(cmd.Context(), interval)
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 10*time.Minute, "time between the checks")
+	cmd.Flags().StringVar(&window, "window", config.DefaultNotifyWindow, "notify about labs that expire within this period")
+	cmd.Flags().BoolVar(&once, "once", false, "check once and exit, e.g. when run from cron")
+	return cmd
+}
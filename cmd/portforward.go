@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+)
+
+type PortForwardOpts struct {
+	AIStor bool
+	Node   string
+}
+
+func NewPortForwardCmd() *cobra.Command {
+	opts := PortForwardOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "port-forward [lab] [local:remote | local:host:remote]...",
+		Short: "Forward local ports to a lab through an SSH tunnel",
+		Long: `Forward local ports through an SSH tunnel to the control plane node, using the lab admin key.
+A remote port without a host is on the node itself; the host is resolved on the node.
+--aistor forwards the AIStor console (8444) and the object store S3 API (30001) and console (31001)
+NodePorts. Set these NodePorts in the object store Inbound Traffic direct access settings first.
+Press Ctrl-C to stop forwarding.`,
+		Example: `  storctl port-forward mylab --aistor
+  storctl port-forward mylab 6443:6443 9090:10.43.0.20:80`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			labName := args[0]
+			forwards := make([]lab.Forward, 0, len(args)-1)
+			for _, spec := range args[1:] {
+				forward, err := lab.ParseForward(spec)
+				if err != nil {
+					return err
+				}
+				forwards = append(forwards, forward)
+			}
+			if len(forwards) == 0 && !opts.AIStor {
+				return fmt.Errorf("no ports to forward, add local:remote or --aistor")
+			}
+
+			err := initProvider(useProvider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			nodes, err := labSvc.Nodes(cmd.Context(), labName)
+			if err != nil {
+				return fmt.Errorf("error getting lab nodes: %w", err)
+			}
+			node, err := forwardNode(nodes, labName, opts.Node)
+			if err != nil {
+				return err
+			}
+			if opts.AIStor {
+				aistorForwards, err := lab.AIStorForwards(cmd.Context(), node)
+				if err != nil {
+					return err
+				}
+				forwards = append(forwards, aistorForwards...)
+			}
+			return lab.PortForward(cmd.Context(), node, forwards, os.Stdout)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.AIStor, "aistor", false, "forward the AIStor console and the object store NodePorts")
+	cmd.Flags().StringVar(&opts.Node, "node", "", "tunnel through this node instead of the control plane")
+	return cmd
+}
+
+// forwardNode returns the node to tunnel through: the named one or the first control plane node
+func forwardNode(nodes []*lab.Node, labName, name string) (*lab.Node, error) {
+	if name != "" {
+		return lab.FindNode(nodes, labName, name)
+	}
+	for _, node := range nodes {
+		if node.Role == lab.RoleControlPlane {
+			return node, nil
+		}
+	}
+	return nil, fmt.Errorf("lab %s has no control plane node", labName)
+}
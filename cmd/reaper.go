@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/reaper"
+	"github.com/spf13/cobra"
+)
+
+type ReaperOpts struct {
+	reaper.Opts
+	Interval time.Duration
+	Once     bool
+}
+
+func NewReaperCmd() *cobra.Command {
+	opts := ReaperOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "reaper",
+		Short: "Delete expired labs periodically",
+		Long: `Run until interrupted and delete the labs, servers, volumes, and SSH keys
+whose delete_after label has passed. All configured providers are checked
+unless --provider is set. Labs that expire within the grace period are logged as warnings.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the reaper runs for long, so it doesn't lock the lab storage between the checks
+			storage, err := lab.NewDaemonStorage(cfg)
+			if err != nil {
+				return fmt.Errorf("error opening lab storage: %w", err)
+			}
+			defer storage.Close()
+			managers, err := labManagers(storage, selectedProviders(cmd))
+			if err != nil {
+				return err
+			}
+			r := reaper.New(managers, opts.Opts)
+			if opts.Once {
+				report, err := r.Reap(cmd.Context())
+				fmt.Printf("Expired: %d, expiring within %s: %d\n", len(report.Deleted), opts.Grace, len(report.Expiring))
+				return err
+			}
+			return r.Run(cmd.Context(), opts.Interval)
+		},
+	}
+
+	cmd.Flags().DurationVar(&opts.Interval, "interval", 10*time.Minute, "time between the checks")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "only log the labs and resources that would be deleted")
+	cmd.Flags().StringVar(&opts.Owner, "owner", "", "only delete the labs and resources of this owner, as set in the config file")
+	cmd.Flags().DurationVar(&opts.Grace, "grace", time.Hour, "warn about labs that expire within this period")
+	cmd.Flags().BoolVar(&opts.Once, "once", false, "check once and exit, e.g. when run from cron")
+	return cmd
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+)
+
+func NewRestoreCmd() *cobra.Command {
+	var (
+		name string
+		ttl  string
+	)
+	opts := CreateOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "restore [backup]",
+		Short: "Create a new lab from a backup",
+		Long: `Create a new lab from a backup taken with 'storctl backup lab'. The servers are created
+from the server snapshots and the volumes from the volume copies in the backup, if it has them.
+The lab is then installed as a new lab unless --skip-install is set.`,
+		Example: `  storctl restore golden-demo --name demo-1 --ttl 4h`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return fmt.Errorf("--name flag must be specified")
+			}
+			backup, err := lab.LoadBackup(args[0])
+			if err != nil {
+				return err
+			}
+			if exists, err := labExists(name); err != nil {
+				return err
+			} else if exists {
+				return fmt.Errorf("lab %s already exists", name)
+			}
+			restored, err := lab.RestoredLab(backup, name)
+			if err != nil {
+				return err
+			}
+			if ttl != "" {
+				restored.Spec.TTL = ttl
+			}
+
+			err = initProvider(backup.Provider)
+			if err != nil {
+				return err
+			}
+			if err := lab.RestoreVolumes(cmd.Context(), providerSvc, backup, restored); err != nil {
+				return err
+			}
+			fmt.Printf("Lab %s: Restoring from backup %s...\n", name, backup.Name)
+			_, err = createLab(cmd.Context(), restored, opts)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "name of the new lab")
+	cmd.Flags().StringVar(&ttl, "ttl", "", "time to live of the new lab (default the TTL of the backed up lab)")
+	cmd.Flags().BoolVar(&opts.SkipDNS, "skip-dns", false, "skip DNS records creation")
+	cmd.Flags().BoolVar(&opts.SkipInstall, "skip-install", false, "skip lab installation")
+	cmd.Flags().BoolVar(&opts.NoRollback, "no-rollback", false, "keep created resources if lab creation fails")
+	return cmd
+}
+
+// labExists returns true if the lab is in the lab storage
+func labExists(labName string) (bool, error) {
+	storage, err := openLabStorage()
+	if err != nil {
+		return false, err
+	}
+	_, err = storage.Get(labName)
+	if errors.Is(err, lab.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking lab %s: %w", labName, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// unreachableStorage fails to read the labs, like an unreachable bucket
+type unreachableStorage struct {
+	lab.Storage
+}
+
+func (s unreachableStorage) Get(labName string) (*types.Lab, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestLabExists(t *testing.T) {
+	originalCfg, originalStorage := cfg, labStorage
+	defer func() {
+		cfg, labStorage = originalCfg, originalStorage
+	}()
+	cfg = &config.Config{
+		Storage: config.StorageConfig{Path: filepath.Join(t.TempDir(), "labs.db"), Bucket: "labs"},
+	}
+	labStorage = nil
+	storage, err := openLabStorage()
+	if err != nil {
+		t.Fatalf("failed to open lab storage: %v", err)
+	}
+	defer closeLabStorage()
+	if err := storage.Save(&types.Lab{ObjectMeta: types.ObjectMeta{Name: "stored"}}); err != nil {
+		t.Fatalf("failed to save lab: %v", err)
+	}
+
+	if exists, err := labExists("stored"); err != nil || !exists {
+		t.Errorf("labExists(stored) = %v, %v; want true, nil", exists, err)
+	}
+	if exists, err := labExists("missing"); err != nil || exists {
+		t.Errorf("labExists(missing) = %v, %v; want false, nil", exists, err)
+	}
+
+	labStorage = unreachableStorage{storage}
+	if _, err := labExists("stored"); err == nil {
+		t.Errorf("labExists with unreachable storage returned no error")
+	}
+	labStorage = storage
+}
@@ -3,11 +3,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/pavelanni/storctl/internal/config"
 	"github.com/pavelanni/storctl/internal/dns"
@@ -25,7 +29,10 @@ var (
 	useProvider string
 	dnsSvc      *dns.CloudflareDNSProvider
 	labSvc      *lab.ManagerSvc
+	labStorage  lab.Storage // opened once, the bbolt file is locked while it's open
 	logLevel    string
+	timeout     time.Duration
+	cancelRun   context.CancelFunc = func() {}
 )
 
 func NewRootCmd() *cobra.Command {
@@ -37,6 +44,12 @@ func NewRootCmd() *cobra.Command {
 			logLevel := logger.ParseLevel(viper.GetString("log_level"))
 			logger.Initialize(logLevel)
 
+			if timeout > 0 {
+				ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+				cmd.SetContext(ctx)
+				cancelRun = cancel
+			}
+
 			// Skip other initializations for init command
 			if cmd.Name() == "init" {
 				return nil
@@ -58,6 +71,7 @@ func NewRootCmd() *cobra.Command {
 	cmd.PersistentFlags().StringVar(&cfgFile, "config", defaultConfigFile, "config file")
 	cmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "logging level (debug, info, warn, error)")
 	cmd.PersistentFlags().StringVar(&useProvider, "provider", config.DefaultLocalProvider, "Provider to use")
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "cancel the command after this duration, e.g. 30m (0 means no timeout)")
 	err := viper.BindPFlag("log_level", cmd.PersistentFlags().Lookup("log-level"))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error binding log level flag: %v\n", err)
@@ -70,9 +84,26 @@ func NewRootCmd() *cobra.Command {
 		NewDeleteCmd(),
 		NewConfigCmd(),
 		NewCreateCmd(),
+		NewApplyCmd(),
+		NewDiffCmd(),
 		NewSyncCmd(),
 		NewVersionCmd(),
 		NewInstallCmd(),
+		NewReaperCmd(),
+		NewExtendCmd(),
+		NewNotifyCmd(),
+		NewDescribeCmd(),
+		NewSSHCmd(),
+		NewExecCmd(),
+		NewPortForwardCmd(),
+		NewScaleCmd(),
+		NewKubeconfigCmd(),
+		NewLogsCmd(),
+		NewBackupCmd(),
+		NewRestoreCmd(),
+		NewExportCmd(),
+		NewImportCmd(),
+		NewDBCmd(),
 	)
 
 	return cmd
@@ -114,6 +145,24 @@ func checkPrerequisites(provider string) error {
 		}
 	}
 
+	if provider == "container" {
+		// Check if docker or podman is installed
+		_, dockerErr := exec.LookPath("docker")
+		_, podmanErr := exec.LookPath("podman")
+		if dockerErr != nil && podmanErr != nil {
+			return fmt.Errorf("neither docker nor podman is installed. Please follow the instructions at https://docs.docker.com/engine/install/ or https://podman.io/docs/installation")
+		}
+	}
+
+	if provider == "libvirt" {
+		// Check if virsh and virt-install are installed
+		for _, tool := range []string{"virsh", "virt-install"} {
+			if _, err := exec.LookPath(tool); err != nil {
+				return fmt.Errorf("%s is not installed. Please install the libvirt client tools and virt-install from your distribution packages, see https://libvirt.org/", tool)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -186,14 +235,96 @@ func initDNS() {
 }
 
 func initLabManager() error {
-	var err error
-	labSvc, err = lab.NewManager(providerSvc, cfg)
+	storage, err := openLabStorage()
 	if err != nil {
 		return fmt.Errorf("error initializing lab manager: %w", err)
 	}
+	labSvc = lab.NewManagerWithStorage(providerSvc, cfg, storage)
 	return nil
 }
 
+// useLabManager initializes the provider and the lab manager unless the lab manager already uses the provider,
+// so the labs of a manifest and a lab created by apply share one manager
+func useLabManager(providerName string) error {
+	if labSvc != nil && labSvc.Provider != nil && labSvc.Provider.Name() == providerName {
+		return nil
+	}
+	if err := initProvider(providerName); err != nil {
+		return fmt.Errorf("failed to initialize provider: %w", err)
+	}
+	if err := initLabManager(); err != nil {
+		return fmt.Errorf("failed to initialize lab manager: %w", err)
+	}
+	return nil
+}
+
+// openLabStorage opens the lab storage on the first call and returns the same storage after that,
+// as a second open of the bbolt file waits for the lock forever
+func openLabStorage() (lab.Storage, error) {
+	if labStorage != nil {
+		return labStorage, nil
+	}
+	storage, err := lab.NewLabStorage(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lab storage: %w", err)
+	}
+	labStorage = storage
+	return labStorage, nil
+}
+
+// closeLabStorage closes the lab storage if it was opened
+func closeLabStorage() {
+	if labStorage == nil {
+		return
+	}
+	if err := labStorage.Close(); err != nil {
+		logger.Get().Warn("failed to close lab storage", "error", err)
+	}
+	labStorage = nil
+}
+
+// selectedProviders returns the --provider if it's set, otherwise all configured providers
+func selectedProviders(cmd *cobra.Command) []string {
+	if cmd.Flags().Changed("provider") {
+		return []string{useProvider}
+	}
+	providerNames := make([]string, 0, len(cfg.Providers))
+	for _, providerConfig := range cfg.Providers {
+		providerNames = append(providerNames, providerConfig.Name)
+	}
+	return providerNames
+}
+
+// labManagers returns the lab managers of the providers sharing the lab storage.
+// Providers that can't be initialized are skipped.
+func labManagers(storage lab.Storage, providerNames []string) ([]*lab.ManagerSvc, error) {
+	managers := make([]*lab.ManagerSvc, 0, len(providerNames))
+	for _, providerName := range providerNames {
+		cloudProvider, err := provider.NewProvider(*cfg, providerName)
+		if err != nil {
+			logger.Get().Warn("skipping provider", "provider", providerName, "error", err)
+			continue
+		}
+		managers = append(managers, lab.NewManagerWithStorage(cloudProvider, cfg, storage))
+	}
+	if len(managers) == 0 {
+		return nil, fmt.Errorf("no providers to check")
+	}
+	return managers, nil
+}
+
+// Execute runs the root command.
+// SIGINT or SIGTERM cancels the command context, so the provider calls stop
+// and the partially created resources are cleaned up; a second signal exits immediately.
 func Execute() error {
-	return NewRootCmd().Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// restore the default signal handling
+		stop()
+	}()
+	defer func() { cancelRun() }()
+	defer closeLabStorage()
+	return NewRootCmd().ExecuteContext(ctx)
 }
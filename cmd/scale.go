@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/spf13/cobra"
+)
+
+type ScaleOpts struct {
+	Nodes         int
+	DrivesPerNode int
+	NoDrain       bool
+	SkipDNS       bool
+	SkipInstall   bool
+}
+
+func NewScaleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scale",
+		Short: "Scale resources (lab)",
+	}
+
+	cmd.AddCommand(NewScaleLabCmd())
+	return cmd
+}
+
+func NewScaleLabCmd() *cobra.Command {
+	opts := ScaleOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "lab [name]",
+		Short: "Add or remove worker nodes and drives",
+		Long: `Scale the lab to the given number of worker nodes and drives per node.
+New servers and volumes follow the lab naming scheme (<lab>-node-NN, <lab>-volume-NN)
+and copy the type, image, and volume size of the existing ones. Once they are ready,
+the Ansible inventory is updated, the join playbook adds the new nodes to the cluster,
+and the drives playbook adds the new drives of the existing nodes to DirectPV. When scaling down, the last nodes are drained and removed first;
+drives can't be removed from the nodes that are kept.`,
+		Example: `  storctl scale lab mylab --nodes 4
+  storctl scale lab mylab --nodes 4 --drives-per-node 6`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("nodes") {
+				return fmt.Errorf("--nodes flag must be specified")
+			}
+			return scaleLab(cmd, args[0], opts)
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.Nodes, "nodes", 0, "number of worker nodes")
+	cmd.Flags().IntVar(&opts.DrivesPerNode, "drives-per-node", 0, "number of drives on each worker node (default: keep the current number)")
+	cmd.Flags().BoolVar(&opts.NoDrain, "no-drain", false, "remove nodes without draining them")
+	cmd.Flags().BoolVar(&opts.SkipDNS, "skip-dns", false, "skip DNS records creation")
+	cmd.Flags().BoolVar(&opts.SkipInstall, "skip-install", false, "skip joining the new nodes and drives to the cluster")
+	return cmd
+}
+
+func scaleLab(cmd *cobra.Command, labName string, opts ScaleOpts) error {
+	ctx := cmd.Context()
+	err := initProvider(useProvider)
+	if err != nil {
+		return err
+	}
+	err = initLabManager()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Lab %s: Scaling to %d worker nodes...\n", labName, opts.Nodes)
+	result, err := labSvc.Scale(ctx, labName, lab.ScaleOpts{
+		Nodes:         opts.Nodes,
+		DrivesPerNode: opts.DrivesPerNode,
+		NoDrain:       opts.NoDrain,
+	})
+	if result != nil {
+		printPlan(result.Plan)
+	}
+	if err != nil {
+		return fmt.Errorf("error scaling lab: %w", err)
+	}
+	if result.Plan.Empty() {
+		fmt.Printf("Lab %s already has this size.\n", labName)
+		return nil
+	}
+	scaled := result.Lab
+
+	deletedServers := deletedServerNames(result.Plan)
+	if providerSvc.Capabilities().NeedsPublicDNS && !opts.SkipDNS && len(deletedServers) > 0 {
+		fmt.Printf("Lab %s: Deleting DNS records of deleted servers...\n", labName)
+		removeServerDNSRecords(strings.ToLower(labName), deletedServers)
+	}
+	newServers := newServerNames(result.Plan)
+	if providerSvc.Capabilities().NeedsPublicDNS && !opts.SkipDNS && len(newServers) > 0 {
+		fmt.Printf("Lab %s: Creating DNS records for new servers...\n", labName)
+		for _, server := range scaled.Status.Servers {
+			if !newServers[server.ObjectMeta.Name] {
+				continue
+			}
+			if err := addServerDNSRecord(strings.ToLower(labName), server); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("Lab %s: Updating ansible inventory file...\n", labName)
+	if err := labSvc.CreateAnsibleInventoryFile(scaled); err != nil {
+		return err
+	}
+
+	if !opts.SkipInstall && len(result.JoinServers) > 0 {
+		hosts := inventoryNames(scaled, result.JoinServers)
+		fmt.Printf("Lab %s: Joining %s to the cluster...\n", labName, strings.Join(hosts, ", "))
+		if err := labSvc.RunJoinPlaybook(ctx, scaled, hosts); err != nil {
+			return fmt.Errorf("error running join playbook: %w", err)
+		}
+	}
+	if !opts.SkipInstall && len(result.DriveServers) > 0 {
+		hosts := inventoryNames(scaled, result.DriveServers)
+		fmt.Printf("Lab %s: Adding the new drives of %s to DirectPV...\n", labName, strings.Join(hosts, ", "))
+		if err := labSvc.RunDrivesPlaybook(ctx, scaled, hosts); err != nil {
+			return fmt.Errorf("error running drives playbook: %w", err)
+		}
+	}
+	fmt.Printf("Lab %s: Scaled to %d worker nodes.\n", labName, opts.Nodes)
+	return nil
+}
+
+// inventoryNames returns the inventory names of the named lab servers in the order of the lab servers
+func inventoryNames(l *types.Lab, serverNames []string) []string {
+	names := make(map[string]bool)
+	for _, name := range serverNames {
+		names[name] = true
+	}
+	hosts := make([]string, 0, len(serverNames))
+	for _, server := range l.Status.Servers {
+		if names[server.ObjectMeta.Name] {
+			hosts = append(hosts, lab.InventoryName(server))
+		}
+	}
+	return hosts
+}
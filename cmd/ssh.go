@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/spf13/cobra"
+)
+
+func NewSSHCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ssh [lab] [node]",
+		Short: "Open an interactive shell on a lab node",
+		Long: `Log in to a lab node over SSH with the same user and key as the Ansible inventory.
+The node is the server name or its name in the lab template, like cp or node-01.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			labName, nodeName := args[0], args[1]
+			err := initProvider(useProvider)
+			if err != nil {
+				return err
+			}
+			err = initLabManager()
+			if err != nil {
+				return err
+			}
+			nodes, err := labSvc.Nodes(cmd.Context(), labName)
+			if err != nil {
+				return fmt.Errorf("error getting lab nodes: %w", err)
+			}
+			node, err := lab.FindNode(nodes, labName, nodeName)
+			if err != nil {
+				return err
+			}
+			return node.Shell(os.Stdin, os.Stdout, os.Stderr)
+		},
+	}
+
+	return cmd
+}
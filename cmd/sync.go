@@ -2,20 +2,79 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/pavelanni/storctl/internal/lab"
 	"github.com/spf13/cobra"
 )
 
 func NewSyncCmd() *cobra.Command {
-	return &cobra.Command{
+	opts := lab.SyncOpts{}
+
+	cmd := &cobra.Command{
 		Use:   "sync",
 		Short: "Sync labs",
-		Args:  cobra.NoArgs,
+		Long: `Update the stored labs from the servers and volumes found on the providers.
+The servers, volumes, owner, and delete_after of the labs come from the providers;
+the rest of the lab records, like the Ansible files and the playbook runs, is kept.
+Labs found on a provider that aren't stored are added as Discovered, and stored labs
+whose resources are gone are marked as Gone. All configured providers are synced
+unless --provider is set.`,
+		Example: `  storctl sync --dry-run
+  storctl sync --provider hetzner`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := labSvc.SyncLabs(); err != nil {
-				return fmt.Errorf("error syncing labs: %w", err)
+			storage, err := openLabStorage()
+			if err != nil {
+				return err
+			}
+			managers, err := labManagers(storage, selectedProviders(cmd))
+			if err != nil {
+				return err
 			}
+			reports := make([]*lab.SyncReport, 0, len(managers))
+			for _, manager := range managers {
+				if !manager.Provider.Capabilities().SupportsLabels {
+					fmt.Printf("Skipping provider %s: its labs can't be found by labels\n", manager.Provider.Name())
+					continue
+				}
+				report, err := manager.SyncLabs(cmd.Context(), opts)
+				if report != nil {
+					reports = append(reports, report)
+				}
+				if err != nil {
+					printSyncReports(reports, opts.DryRun)
+					return fmt.Errorf("error syncing labs of provider %s: %w", manager.Provider.Name(), err)
+				}
+			}
+			printSyncReports(reports, opts.DryRun)
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "only show what would change")
+	return cmd
+}
+
+func printSyncReports(reports []*lab.SyncReport, dryRun bool) {
+	counts := make(map[string]int)
+	unchanged := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tLAB\tACTION\tCHANGES")
+	for _, report := range reports {
+		for _, change := range report.Changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", report.Provider, change.Lab, change.Action, strings.Join(change.Changes, "; "))
+			counts[change.Action]++
+		}
+		unchanged += report.Unchanged
+	}
+	w.Flush()
+	prefix := "Synced"
+	if dryRun {
+		prefix = "Would sync (dry run)"
+	}
+	fmt.Printf("%s %d providers: %d discovered, %d updated, %d gone, %d skipped, %d unchanged\n", prefix, len(reports),
+		counts[lab.SyncDiscovered], counts[lab.SyncUpdated], counts[lab.SyncGone], counts[lab.SyncSkipped], unchanged)
 }
@@ -22,18 +22,36 @@ type Config struct {
 	OutputFormat string           `mapstructure:"output_format" yaml:"output_format"`
 	LogLevel     string           `mapstructure:"log_level" yaml:"log_level"`
 	Ansible      AnsibleConfig    `mapstructure:"ansible" yaml:"ansible"`
+	Concurrency  int              `mapstructure:"concurrency" yaml:"concurrency"`   // resources created in parallel
+	MaxLifetime  string           `mapstructure:"max_lifetime" yaml:"max_lifetime"` // longest time a lab can live since its creation
+	Notify       NotifyConfig     `mapstructure:"notify" yaml:"notify,omitempty"`
 }
 
 type StorageConfig struct {
-	Path   string `mapstructure:"path" yaml:"path"`
-	Bucket string `mapstructure:"bucket" yaml:"bucket"`
+	Backend string `mapstructure:"backend" yaml:"backend,omitempty"` // bbolt (default) or s3
+	Path    string `mapstructure:"path" yaml:"path"`                 // bbolt file
+	Bucket  string `mapstructure:"bucket" yaml:"bucket"`             // bbolt bucket or S3 bucket
+	// The S3 backend settings
+	Endpoint    string            `mapstructure:"endpoint" yaml:"endpoint,omitempty"`       // S3-compatible endpoint URL like AIStor or MinIO, AWS S3 if empty
+	Region      string            `mapstructure:"region" yaml:"region,omitempty"`           // bucket region
+	Prefix      string            `mapstructure:"prefix" yaml:"prefix,omitempty"`           // key prefix of the lab objects
+	Credentials map[string]string `mapstructure:"credentials" yaml:"credentials,omitempty"` // access_key_id, secret_access_key, or profile
 }
 
 type ProviderConfig struct {
-	Name        string            `mapstructure:"name"`
-	Location    string            `mapstructure:"location"`
-	Token       string            `mapstructure:"token"`
-	Credentials map[string]string `mapstructure:"credentials"`
+	Name          string            `mapstructure:"name"`
+	Location      string            `mapstructure:"location"`
+	Token         string            `mapstructure:"token"`
+	Credentials   map[string]string `mapstructure:"credentials"`
+	Concurrency   int               `mapstructure:"concurrency" yaml:"concurrency,omitempty"`       // overrides the global concurrency for this provider
+	RateLimit     float64           `mapstructure:"rate_limit" yaml:"rate_limit,omitempty"`         // API requests per second, 0 means the provider default
+	Runtime       string            `mapstructure:"runtime" yaml:"runtime,omitempty"`               // docker or podman for the container provider
+	URI           string            `mapstructure:"uri" yaml:"uri,omitempty"`                       // connection URI for the libvirt provider
+	Pool          string            `mapstructure:"pool" yaml:"pool,omitempty"`                     // storage pool for the libvirt provider
+	Network       string            `mapstructure:"network" yaml:"network,omitempty"`               // network for the libvirt and OpenStack providers
+	Subnet        string            `mapstructure:"subnet" yaml:"subnet,omitempty"`                 // subnet ID for the AWS provider
+	SecurityGroup string            `mapstructure:"security_group" yaml:"security_group,omitempty"` // security group ID for the AWS provider
+	ServerTypes   map[string]string `mapstructure:"server_types" yaml:"server_types,omitempty"`     // lab server types to provider instance types
 }
 
 type DNSConfig struct {
@@ -43,10 +61,53 @@ type DNSConfig struct {
 	ZoneID   string `mapstructure:"zone_id"`
 }
 
+// NotifyConfig is where the lab owners are notified before their labs expire
+type NotifyConfig struct {
+	Window          string     `mapstructure:"window" yaml:"window,omitempty"`                       // notify about the labs that expire within this time
+	SMTP            SMTPConfig `mapstructure:"smtp" yaml:"smtp,omitempty"`                           // email to the config email address
+	WebhookURL      string     `mapstructure:"webhook_url" yaml:"webhook_url,omitempty"`             // JSON POST of the notification
+	SlackWebhookURL string     `mapstructure:"slack_webhook_url" yaml:"slack_webhook_url,omitempty"` // Slack-compatible incoming webhook
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host" yaml:"host,omitempty"`
+	Port     int    `mapstructure:"port" yaml:"port,omitempty"`
+	Username string `mapstructure:"username" yaml:"username,omitempty"`
+	Password string `mapstructure:"password" yaml:"password,omitempty"`
+	From     string `mapstructure:"from" yaml:"from,omitempty"` // the config email address if empty
+}
+
 type AnsibleConfig struct {
 	ConfigFile string `mapstructure:"config_file"`
 }
 
+// ProviderConcurrency returns the number of resources created in parallel with the named provider
+func (c *Config) ProviderConcurrency(name string) int {
+	for _, provider := range c.Providers {
+		if provider.Name == name && provider.Concurrency > 0 {
+			return provider.Concurrency
+		}
+	}
+	if c.Concurrency > 0 {
+		return c.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+// ProviderRateLimit returns the number of API requests per second allowed for the named provider.
+// Zero means no limit.
+func (c *Config) ProviderRateLimit(name string) float64 {
+	for _, provider := range c.Providers {
+		if provider.Name == name && provider.RateLimit > 0 {
+			return provider.RateLimit
+		}
+	}
+	if name == DefaultLocalProvider || name == DefaultContainerProvider || name == DefaultLibvirtProvider {
+		return 0
+	}
+	return DefaultCloudRateLimit
+}
+
 // LoadConfig reads configuration from file and environment variables
 func LoadConfig(configPath string) (*Config, error) {
 	v := viper.New()
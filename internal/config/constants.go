@@ -37,6 +37,18 @@ const (
 	// DefaultLabStorageFile is the default file for storing labs
 	DefaultLabStorageFile = "labs.db"
 
+	// StorageBackendBbolt keeps the labs in the local bbolt file, the default
+	StorageBackendBbolt = "bbolt"
+
+	// StorageBackendS3 keeps the labs as objects in an S3-compatible bucket shared by the team
+	StorageBackendS3 = "s3"
+
+	// DefaultS3StoragePrefix is the default key prefix of the lab objects in the S3 bucket
+	DefaultS3StoragePrefix = "storctl/labs/"
+
+	// DefaultS3StorageRegion is the default region of the S3 bucket
+	DefaultS3StorageRegion = "us-east-1"
+
 	// DefaultAnsibleDir is the default directory for storing ansible files
 	DefaultAnsibleDir = "ansible"
 
@@ -52,8 +64,23 @@ const (
 	// DefaultAnsibleExtraVarsFile is the default ansible extra vars file
 	DefaultAnsibleExtraVarsFile = "extra_vars.yml"
 
+	// DefaultKubeconfigDir is the default directory for the lab kubeconfigs fetched by the playbooks
+	DefaultKubeconfigDir = "kubeconfigs"
+
+	// DefaultLogsDir is the default directory for the playbook run logs, one subdirectory per lab
+	DefaultLogsDir = "logs"
+
+	// DefaultBackupsDir is the default directory for the lab backups, one subdirectory per backup
+	DefaultBackupsDir = "backups"
+
 	// DefaultLimaDir is the default directory for storing lima VM configs
 	DefaultLimaDir = "lima"
+
+	// DefaultContainerDir is the default directory for storing container volumes and keys
+	DefaultContainerDir = "containers"
+
+	// DefaultLibvirtDir is the default directory for storing libvirt volume metadata and keys
+	DefaultLibvirtDir = "libvirt"
 )
 
 // Provider related constants
@@ -61,12 +88,39 @@ const (
 	// DefaultLocalProvider is the default provider for a local machine
 	DefaultLocalProvider = "lima"
 
+	// DefaultContainerProvider is the provider for Docker and Podman containers
+	DefaultContainerProvider = "container"
+
+	// DefaultContainerRuntime is the container runtime used when both Docker and Podman are installed
+	DefaultContainerRuntime = "docker"
+
+	// DefaultLibvirtProvider is the provider for libvirt/QEMU VMs
+	DefaultLibvirtProvider = "libvirt"
+
+	// DefaultLibvirtURI is the default libvirt connection URI
+	DefaultLibvirtURI = "qemu:///system"
+
+	// DefaultLibvirtPool is the default libvirt storage pool
+	DefaultLibvirtPool = "default"
+
+	// DefaultLibvirtNetwork is the default libvirt network
+	DefaultLibvirtNetwork = "default"
+
 	// DefaultLocalLocation is the default location
 	DefaultLocalLocation = "local"
 
 	// DefaultCloudProvider is the default cloud provider
 	DefaultCloudProvider = "hetzner"
 
+	// DefaultOpenStackProvider is the provider for OpenStack clouds
+	DefaultOpenStackProvider = "openstack"
+
+	// DefaultAWSProvider is the provider for AWS EC2
+	DefaultAWSProvider = "aws"
+
+	// DefaultAWSRegion is the AWS region used when the provider has no location
+	DefaultAWSRegion = "us-east-1"
+
 	// DefaultCloudLocation is the default cloud location
 	DefaultCloudLocation = "nbg1"
 
@@ -87,6 +141,13 @@ const (
 
 	// DefaultCredentials is the default credentials
 	DefaultCredentials = "NOT USED WITH THIS PROVIDER"
+
+	// DefaultConcurrency is the default number of servers or volumes created in parallel
+	DefaultConcurrency = 4
+
+	// DefaultCloudRateLimit is the default number of cloud API requests per second
+	// Hetzner Cloud allows 3600 requests per hour
+	DefaultCloudRateLimit = 1.0
 )
 
 // DNS related constants
@@ -108,6 +169,15 @@ const (
 
 	// DefaultKeyTTL is the default time-to-live for SSH keys
 	DefaultTTL = "1h"
+
+	// DefaultMaxLifetime is the longest time a lab can be extended to since its creation
+	DefaultMaxLifetime = "168h"
+
+	// DefaultNotifyWindow is how long before the expiration the lab owners are notified
+	DefaultNotifyWindow = "2h"
+
+	// DefaultSMTPPort is the default SMTP submission port
+	DefaultSMTPPort = 587
 )
 
 // Volume related constants
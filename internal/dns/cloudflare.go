@@ -65,3 +65,17 @@ func (c *CloudflareDNSProvider) DeleteRecord(zoneID, recordID string) error {
 	}
 	return nil
 }
+
+// DeleteRecordsByName deletes all the records with the full name, e.g. cp.lab.example.com
+func (c *CloudflareDNSProvider) DeleteRecordsByName(zoneID, name string) error {
+	records, _, err := c.api.ListDNSRecords(context.Background(), cloudflare.ZoneIdentifier(zoneID), cloudflare.ListDNSRecordsParams{Name: name})
+	if err != nil {
+		return fmt.Errorf("error listing records: %w", err)
+	}
+	for _, record := range records {
+		if err := c.DeleteRecord(zoneID, record.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
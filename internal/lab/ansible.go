@@ -1,6 +1,7 @@
 package lab
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/pavelanni/storctl/internal/config"
 	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/serverchecker"
 )
 
 // Host represents a single server
@@ -32,18 +34,52 @@ type Inventory struct {
 	} `json:"all"`
 }
 
+// JoinPlaybook joins new worker nodes to the lab cluster
+const JoinPlaybook = "scale-join.yml"
+
+// DrivesPlaybook adds the new drives of the nodes already in the lab cluster to DirectPV
+const DrivesPlaybook = "scale-drives.yml"
+
+// Server roles, the same as the inventory groups
+const (
+	RoleControlPlane = "control_plane"
+	RoleNodes        = "nodes"
+)
+
+// serverRole returns the inventory group of the lab server
+func serverRole(server *types.Server) string {
+	return roleOf(server.ObjectMeta.Name)
+}
+
+// roleOf returns the inventory group of the server by its name: the control plane server name ends with cp
+func roleOf(name string) string {
+	if strings.HasSuffix(name, "cp") {
+		return RoleControlPlane
+	}
+	return RoleNodes
+}
+
+// sshLogin returns the user and the private key to log in to the lab servers with
+func (m *ManagerSvc) sshLogin(labName string) (user, keyPath string) {
+	capabilities := m.Provider.Capabilities()
+	keyPath = capabilities.SSHKeyPath
+	if keyPath == "" {
+		keyPath = serverchecker.AdminKeyPath(labName)
+	}
+	return capabilities.SSHUser, keyPath
+}
+
 func (m *ManagerSvc) CreateAnsibleInventoryFile(lab *types.Lab) error {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("error getting home directory: %w", err)
 	}
-	ansibleUser := config.DefaultAdminUser
-	ansibleSSHPrivateKeyFile := filepath.Join(homeDir, config.DefaultConfigDir, config.DefaultKeysDir, strings.Join([]string{lab.ObjectMeta.Name, "admin"}, "-"))
-	if m.Provider.Name() == "lima" {
+	capabilities := m.Provider.Capabilities()
+	ansibleUser, ansibleSSHPrivateKeyFile := m.sshLogin(lab.ObjectMeta.Name)
+	if !capabilities.NeedsPublicDNS {
+		// servers without public DNS names can't get certificates
 		lab.Spec.CertManager = false
 		lab.Spec.LetsEncrypt = "none"
-		ansibleUser = os.Getenv("USER")
-		ansibleSSHPrivateKeyFile = filepath.Join(homeDir, ".lima", "_config", "user")
 	}
 
 	allVars := map[string]any{
@@ -80,7 +116,7 @@ func (m *ManagerSvc) CreateAnsibleInventoryFile(lab *types.Lab) error {
 		m.Logger.Debug("Adding server to inventory",
 			"hostname", server.Status.PublicNet.FQDN,
 			"cloud name", server.ObjectMeta.Name)
-		if strings.HasSuffix(server.ObjectMeta.Name, "cp") {
+		if serverRole(server) == RoleControlPlane {
 			controlPlaneGroup.Hosts[server.Status.PublicNet.FQDN] = Host{
 				AnsibleHost: server.Status.PublicNet.IPv4.IP,
 			}
@@ -90,8 +126,8 @@ func (m *ManagerSvc) CreateAnsibleInventoryFile(lab *types.Lab) error {
 			}
 		}
 	}
-	inventory.All.Children["control_plane"] = controlPlaneGroup
-	inventory.All.Children["nodes"] = workerGroup
+	inventory.All.Children[RoleControlPlane] = controlPlaneGroup
+	inventory.All.Children[RoleNodes] = workerGroup
 	inventory.All.Vars = allVars
 
 	jsonData, err := json.MarshalIndent(inventory, "", "  ")
@@ -107,32 +143,13 @@ func (m *ManagerSvc) CreateAnsibleInventoryFile(lab *types.Lab) error {
 	return os.WriteFile(ansibleInventoryFile, jsonData, 0644)
 }
 
-func (m *ManagerSvc) RunAnsiblePlaybook(lab *types.Lab) error {
-	var ansiblePlaybookFile, ansibleInventoryFile string
+func (m *ManagerSvc) RunAnsiblePlaybook(ctx context.Context, lab *types.Lab) error {
 	if lab.Spec.Ansible.Playbook == "" {
 		return fmt.Errorf("ansible playbook not set")
 	}
-
-	homeDir, err := os.UserHomeDir()
+	ansiblePlaybookFile, ansibleInventoryFile, err := ansibleFiles(lab, lab.Spec.Ansible.Playbook)
 	if err != nil {
-		return fmt.Errorf("error getting home directory: %w", err)
-	}
-	if !filepath.IsAbs(lab.Spec.Ansible.Playbook) {
-		ansiblePlaybookFile = filepath.Join(homeDir,
-			config.DefaultConfigDir,
-			config.DefaultAnsibleDir,
-			"playbooks",
-			lab.Spec.Ansible.Playbook)
-	} else {
-		ansiblePlaybookFile = lab.Spec.Ansible.Playbook
-	}
-	if !filepath.IsAbs(lab.Spec.Ansible.Inventory) {
-		ansibleInventoryFile = filepath.Join(homeDir,
-			config.DefaultConfigDir,
-			config.DefaultAnsibleDir,
-			lab.Spec.Ansible.Inventory)
-	} else {
-		ansibleInventoryFile = lab.Spec.Ansible.Inventory
+		return err
 	}
 	m.Logger.Info("Running Ansible playbook", "playbook", ansiblePlaybookFile, "inventory", ansibleInventoryFile)
 	if err := checkAnsibleAvailable(); err != nil {
@@ -150,17 +167,85 @@ func (m *ManagerSvc) RunAnsiblePlaybook(lab *types.Lab) error {
 	if err != nil {
 		return fmt.Errorf("error saving lab %s: %w", lab.ObjectMeta.Name, err)
 	}
-	cmd := exec.Command("ansible-playbook", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = append(os.Environ(), "ANSIBLE_STDOUT_CALLBACK=debug")
+	return m.runAnsiblePlaybook(ctx, lab, ansiblePlaybookFile, args)
+}
+
+// RunJoinPlaybook runs the join playbook limited to the control plane and the new nodes.
+// The hosts are the inventory names of the new nodes.
+func (m *ManagerSvc) RunJoinPlaybook(ctx context.Context, lab *types.Lab, hosts []string) error {
+	return m.runScalePlaybook(ctx, lab, JoinPlaybook, hosts, hosts, "new_nodes")
+}
+
+// RunDrivesPlaybook runs the drives playbook on the control plane for the nodes with new drives,
+// without reinstalling the nodes. The hosts are the inventory names of the nodes.
+func (m *ManagerSvc) RunDrivesPlaybook(ctx context.Context, lab *types.Lab, hosts []string) error {
+	return m.runScalePlaybook(ctx, lab, DrivesPlaybook, nil, hosts, "drive_nodes")
+}
 
-	return cmd.Run()
+// runScalePlaybook runs the scale playbook limited to the control plane and the limit hosts,
+// with the nodes passed in the nodesVar extra variable
+func (m *ManagerSvc) runScalePlaybook(ctx context.Context, lab *types.Lab, playbook string, limitHosts, nodes []string, nodesVar string) error {
+	ansiblePlaybookFile, ansibleInventoryFile, err := ansibleFiles(lab, playbook)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(ansiblePlaybookFile); err != nil {
+		return fmt.Errorf("playbook %s not found, run 'storctl init --overwrite' to update the playbooks: %w", playbook, err)
+	}
+	if err := checkAnsibleAvailable(); err != nil {
+		return fmt.Errorf("error checking if ansible-playbook is available: %w", err)
+	}
+	limit := make([]string, 0, len(limitHosts)+1)
+	for _, server := range lab.Status.Servers {
+		if serverRole(server) == RoleControlPlane {
+			limit = append(limit, InventoryName(server))
+		}
+	}
+	limit = append(limit, limitHosts...)
+	extraVars, err := json.Marshal(map[string]any{
+		"inventory_path": ansibleInventoryFile,
+		nodesVar:         nodes,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling extra vars: %w", err)
+	}
+	m.Logger.Info("Running Ansible playbook", "playbook", ansiblePlaybookFile, "inventory", ansibleInventoryFile, "limit", limit)
+	return m.runAnsiblePlaybook(ctx, lab, ansiblePlaybookFile, []string{
+		"-i", ansibleInventoryFile,
+		"--limit", strings.Join(limit, ","),
+		"--extra-vars", string(extraVars),
+		ansiblePlaybookFile,
+	})
+}
+
+// ansibleFiles returns the full paths of the playbook and the lab inventory.
+// Relative paths are in the storctl ansible directory.
+func ansibleFiles(lab *types.Lab, playbook string) (string, string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	ansiblePlaybookFile := playbook
+	if !filepath.IsAbs(playbook) {
+		ansiblePlaybookFile = filepath.Join(homeDir,
+			config.DefaultConfigDir,
+			config.DefaultAnsibleDir,
+			"playbooks",
+			playbook)
+	}
+	ansibleInventoryFile := lab.Spec.Ansible.Inventory
+	if !filepath.IsAbs(ansibleInventoryFile) {
+		ansibleInventoryFile = filepath.Join(homeDir,
+			config.DefaultConfigDir,
+			config.DefaultAnsibleDir,
+			lab.Spec.Ansible.Inventory)
+	}
+	return ansiblePlaybookFile, ansibleInventoryFile, nil
 }
 
 // checkAnsibleAvailable verifies that ansible-playbook is installed
 func checkAnsibleAvailable() error {
-	_, err := exec.LookPath("ansible-playbook")
+	_, err := exec.LookPath(ansiblePlaybookCommand)
 	if err != nil {
 		return fmt.Errorf("ansible-playbook not found in PATH: %w", err)
 	}
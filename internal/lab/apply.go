@@ -0,0 +1,226 @@
+package lab
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/serverchecker"
+)
+
+// Apply brings an existing lab in line with its spec.
+// It creates, resizes, and deletes only the servers and volumes that differ
+// and saves the updated lab in the local storage.
+func (m *ManagerSvc) Apply(ctx context.Context, lab *types.Lab) (*Plan, error) {
+	plan, err := m.PlanApply(ctx, lab, PlanOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan changes: %w", err)
+	}
+	if err := m.executePlan(ctx, lab, plan); err != nil {
+		return plan, fmt.Errorf("failed to apply changes: %w", err)
+	}
+	if err := m.refreshLab(ctx, lab); err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
+
+func (m *ManagerSvc) executePlan(ctx context.Context, lab *types.Lab, plan *Plan) error {
+	// delete volumes first, then servers
+	for _, action := range plan.Filter(ActionDelete, "Volume") {
+		fmt.Printf("Deleting volume %s...\n", action.Name)
+		status := m.Provider.DeleteVolume(ctx, action.Name, true)
+		if status.Error != nil {
+			return fmt.Errorf("failed to delete volume %s: %w", action.Name, status.Error)
+		}
+	}
+	for _, action := range plan.Filter(ActionDelete, "Server") {
+		fmt.Printf("Deleting server %s...\n", action.Name)
+		status := m.Provider.DeleteServer(ctx, action.Name, true)
+		if status.Error != nil {
+			return fmt.Errorf("failed to delete server %s: %w", action.Name, status.Error)
+		}
+	}
+
+	newServers := plan.Filter(ActionCreate, "Server")
+	newVolumes := plan.Filter(ActionCreate, "Volume")
+	createVolumes := func() error {
+		return m.createInParallel(ctx, "volume", actionNames(newVolumes), func(i int) error {
+			_, err := m.Provider.CreateVolume(ctx, volumeCreateOpts(lab, newVolumes[i].volume))
+			return err
+		})
+	}
+	capabilities := m.Provider.Capabilities()
+	if !capabilities.VolumesAttachAfterBoot {
+		// disks are attached when the VM is created, so volumes go first
+		if err := createVolumes(); err != nil {
+			return err
+		}
+		additionalDisks := make(map[string][]options.AdditionalDisk)
+		for _, action := range newVolumes {
+			serverName := resourceName(lab.ObjectMeta.Name, action.volume.Server)
+			additionalDisks[serverName] = append(additionalDisks[serverName], options.AdditionalDisk{
+				Name:   action.Name,
+				Format: false,
+			})
+		}
+		err := m.createInParallel(ctx, "server", actionNames(newServers), func(i int) error {
+			opts := serverCreateOpts(lab, newServers[i].server)
+			opts.AdditionalDisks = additionalDisks[newServers[i].Name]
+			_, err := m.Provider.CreateServer(ctx, opts)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return m.resizeVolumes(ctx, plan)
+	}
+	if len(newServers) > 0 {
+		sshKeys, userData, err := m.labSSHKeys(ctx, lab)
+		if err != nil {
+			return err
+		}
+		servers := make([]*types.Server, len(newServers))
+		err = m.createInParallel(ctx, "server", actionNames(newServers), func(i int) error {
+			opts := serverCreateOpts(lab, newServers[i].server)
+			opts.SSHKeys = sshKeys
+			opts.UserData = userData
+			server, err := m.Provider.CreateServer(ctx, opts)
+			servers[i] = server
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if capabilities.ReadinessCheck {
+			fmt.Println("Waiting for servers to be ready...")
+			results, err := serverchecker.CheckServers(ctx, servers, m.Logger, 30*time.Minute, 20)
+			if err != nil {
+				return fmt.Errorf("failed to check servers: %w", err)
+			}
+			for _, result := range results {
+				if !result.Ready {
+					return fmt.Errorf("server %s not ready", result.Server.ObjectMeta.Name)
+				}
+			}
+		}
+	}
+	if err := createVolumes(); err != nil {
+		return err
+	}
+	return m.resizeVolumes(ctx, plan)
+}
+
+func (m *ManagerSvc) resizeVolumes(ctx context.Context, plan *Plan) error {
+	for _, action := range plan.Filter(ActionResize, "Volume") {
+		fmt.Printf("Resizing volume %s to %dGB...\n", action.Name, action.volume.Size)
+		if _, err := m.Provider.ResizeVolume(ctx, action.Name, action.volume.Size); err != nil {
+			return fmt.Errorf("failed to resize volume %s: %w", action.Name, err)
+		}
+	}
+	return nil
+}
+
+// actionNames returns the resource names of the actions
+func actionNames(actions []Action) []string {
+	names := make([]string, len(actions))
+	for i, action := range actions {
+		names[i] = action.Name
+	}
+	return names
+}
+
+// labSSHKeys returns the cloud SSH keys and the cloud-init user data for new lab servers.
+// The lab admin key must already exist on the cloud.
+func (m *ManagerSvc) labSSHKeys(ctx context.Context, lab *types.Lab) ([]*types.SSHKey, string, error) {
+	labAdminKeyName := resourceName(lab.ObjectMeta.Name, "admin")
+	labAdminKey, err := m.Provider.GetSSHKey(ctx, labAdminKeyName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get lab admin key %s: %w", labAdminKeyName, err)
+	}
+	sshKeys := []*types.SSHKey{
+		{ObjectMeta: types.ObjectMeta{Name: config.DefaultAdminKeyName}},
+		labAdminKey,
+	}
+	return sshKeys, fmt.Sprintf(config.DefaultCloudInitUserData, labAdminKey.Spec.PublicKey), nil
+}
+
+// refreshLab updates the lab status from the provider and saves the lab.
+// Status fields only known locally are kept from the stored lab.
+func (m *ManagerSvc) refreshLab(ctx context.Context, lab *types.Lab) error {
+	current, err := m.getLabFromProvider(ctx, lab.ObjectMeta.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get lab from provider: %w", err)
+	}
+	if stored, err := m.Storage.Get(lab.ObjectMeta.Name); err == nil {
+		lab.Status = stored.Status
+		if lab.Spec.Ansible.Inventory == "" {
+			lab.Spec.Ansible = stored.Spec.Ansible
+		}
+		// FQDNs come from DNS records, not from the provider, so keep the stored ones
+		fqdns := make(map[string]string)
+		for _, server := range stored.Status.Servers {
+			if server.Status.PublicNet != nil {
+				fqdns[server.ObjectMeta.Name] = server.Status.PublicNet.FQDN
+			}
+		}
+		for _, server := range current.Status.Servers {
+			if server.Status.PublicNet != nil && server.Status.PublicNet.FQDN == "" {
+				server.Status.PublicNet.FQDN = fqdns[server.ObjectMeta.Name]
+			}
+		}
+	}
+	lab.Status.Servers = current.Status.Servers
+	lab.Status.Volumes = current.Status.Volumes
+	if lab.Status.State == "" {
+		lab.Status.State = current.Status.State
+	}
+	if lab.Status.Created.IsZero() {
+		lab.Status.Created = current.Status.Created
+	}
+	if lab.Status.DeleteAfter.IsZero() {
+		lab.Status.DeleteAfter = current.Status.DeleteAfter
+	}
+	if lab.Status.Owner == "" {
+		lab.Status.Owner = current.Status.Owner
+	}
+	if err := m.Storage.Save(lab); err != nil {
+		return fmt.Errorf("failed to save lab: %w", err)
+	}
+	return nil
+}
+
+// serverCreateOpts returns the provider options to create a lab server
+func serverCreateOpts(lab *types.Lab, serverSpec *types.LabServerSpec) options.ServerCreateOpts {
+	return options.ServerCreateOpts{
+		Name:     resourceName(lab.ObjectMeta.Name, serverSpec.Name),
+		Type:     serverSpec.ServerType,
+		Image:    serverSpec.Image,
+		Location: lab.Spec.Location,
+		Provider: lab.Spec.Provider,
+		Labels:   lab.ObjectMeta.Labels,
+	}
+}
+
+// volumeCreateOpts returns the provider options to create a lab volume
+func volumeCreateOpts(lab *types.Lab, volumeSpec *types.LabVolumeSpec) options.VolumeCreateOpts {
+	automount := volumeSpec.Automount
+	if !automount { // if not specified, default to false
+		automount = config.DefaultVolumeAutomount
+	}
+	format := volumeSpec.Format
+	if format == "" { // if not specified, default to xfs
+		format = config.DefaultVolumeFormat
+	}
+	return options.VolumeCreateOpts{
+		Name:       resourceName(lab.ObjectMeta.Name, volumeSpec.Name),
+		Size:       volumeSpec.Size,
+		ServerName: resourceName(lab.ObjectMeta.Name, volumeSpec.Server),
+		Automount:  automount,
+		Format:     format,
+		Labels:     lab.ObjectMeta.Labels,
+	}
+}
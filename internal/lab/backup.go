@@ -0,0 +1,401 @@
+package lab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/parallel"
+	"github.com/pavelanni/storctl/internal/util/serverchecker"
+)
+
+const (
+	// backupFile is the backup description in the backup directory
+	backupFile = "backup.json"
+	// backupFilesDir keeps the inventory, kubeconfig, and keys of the lab
+	backupFilesDir = "files"
+	// backupDisksDir keeps the copies of the volumes
+	backupDisksDir = "disks"
+)
+
+// Backup is a saved lab that can be restored as a new lab
+type Backup struct {
+	Name      string      `json:"name"`
+	Lab       *types.Lab  `json:"lab"` // the lab record
+	Provider  string      `json:"provider"`
+	Created   time.Time   `json:"created"`
+	Snapshots []*Snapshot `json:"snapshots"`
+	Files     []string    `json:"files"`           // the lab files in the files directory
+	Notes     []string    `json:"notes,omitempty"` // what the backup doesn't include
+}
+
+// Snapshot is a saved lab server or volume
+type Snapshot struct {
+	Kind string `json:"kind"` // Server or Volume
+	Name string `json:"name"` // the name in the lab spec, like cp or volume-01
+	ID   string `json:"id"`   // the provider image, or the copy relative to the backup directory
+}
+
+// BackupOpts are the options for Backup
+type BackupOpts struct {
+	Name string // the lab name with the time if empty
+}
+
+// BackupDir returns the directory of the backup
+func BackupDir(name string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, config.DefaultConfigDir, config.DefaultBackupsDir, name), nil
+}
+
+// Backup saves the lab record, inventory, kubeconfig, and keys, and the snapshots
+// of the lab servers and volumes the provider can take.
+// If it fails, the snapshots taken so far are deleted.
+func (m *ManagerSvc) Backup(ctx context.Context, labName string, opts BackupOpts) (*Backup, error) {
+	lab, err := m.Get(ctx, labName)
+	if err != nil {
+		return nil, err
+	}
+	if lab.Spec.Provider != m.Provider.Name() {
+		return nil, fmt.Errorf("lab %s runs on %s, not %s", labName, lab.Spec.Provider, m.Provider.Name())
+	}
+	name := opts.Name
+	if name == "" {
+		name = labName + "-" + time.Now().Format(logTimeFormat)
+	}
+	dir, err := BackupDir(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("backup %s already exists", name)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, backupFilesDir), 0700); err != nil {
+		return nil, fmt.Errorf("error creating backup directory: %w", err)
+	}
+	backup := &Backup{
+		Name:      name,
+		Lab:       lab,
+		Provider:  m.Provider.Name(),
+		Created:   time.Now(),
+		Snapshots: make([]*Snapshot, 0),
+		Files:     make([]string, 0),
+		Notes:     make([]string, 0),
+	}
+	err = m.takeSnapshots(ctx, backup, dir)
+	if err == nil {
+		err = backupFiles(backup, dir)
+	}
+	if err == nil {
+		err = saveBackup(backup, dir)
+	}
+	if err != nil {
+		if cleanupErr := m.deleteSnapshots(context.WithoutCancel(ctx), backup); cleanupErr != nil {
+			m.Logger.Warn("failed to delete snapshots", "backup", name, "error", cleanupErr)
+		}
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return backup, nil
+}
+
+// takeSnapshots snapshots the servers and volumes in parallel
+func (m *ManagerSvc) takeSnapshots(ctx context.Context, backup *Backup, dir string) error {
+	lab := backup.Lab
+	labName := lab.ObjectMeta.Name
+	snapshotOpts := func(name string) options.SnapshotOpts {
+		return options.SnapshotOpts{
+			Description: fmt.Sprintf("storctl backup %s of %s", backup.Name, name),
+			Labels:      map[string]string{"storctl_backup": backup.Name, "lab_name": labName},
+			Dir:         filepath.Join(dir, backupDisksDir),
+		}
+	}
+	var mu sync.Mutex
+	add := func(snapshot *Snapshot) {
+		mu.Lock()
+		defer mu.Unlock()
+		backup.Snapshots = append(backup.Snapshots, snapshot)
+	}
+
+	if snapshotter, ok := m.Provider.(provider.ServerSnapshotter); ok {
+		servers := lab.Spec.Servers
+		fmt.Printf("Taking snapshots of %d servers...\n", len(servers))
+		err := parallel.Run(ctx, len(servers), m.Concurrency, m.Limiter, func(i int) error {
+			name := resourceName(labName, servers[i].Name)
+			image, err := snapshotter.SnapshotServer(ctx, name, snapshotOpts(name))
+			if err != nil {
+				return err
+			}
+			add(&Snapshot{Kind: "Server", Name: servers[i].Name, ID: image})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to take server snapshots: %w", err)
+		}
+	} else {
+		backup.Notes = append(backup.Notes, fmt.Sprintf("%s servers can't be saved, the restored lab gets new servers from the spec images", backup.Provider))
+	}
+
+	if snapshotter, ok := m.Provider.(provider.VolumeSnapshotter); ok {
+		volumes := lab.Spec.Volumes
+		fmt.Printf("Copying %d volumes...\n", len(volumes))
+		err := parallel.Run(ctx, len(volumes), m.Concurrency, nil, func(i int) error {
+			name := resourceName(labName, volumes[i].Name)
+			path, err := snapshotter.SnapshotVolume(ctx, name, snapshotOpts(name))
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			add(&Snapshot{Kind: "Volume", Name: volumes[i].Name, ID: rel})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy volumes: %w", err)
+		}
+	} else {
+		backup.Notes = append(backup.Notes, fmt.Sprintf("%s volumes can't be saved, the restored lab gets empty volumes", backup.Provider))
+	}
+	sort.Slice(backup.Snapshots, func(i, j int) bool {
+		if backup.Snapshots[i].Kind != backup.Snapshots[j].Kind {
+			return backup.Snapshots[i].Kind > backup.Snapshots[j].Kind
+		}
+		return backup.Snapshots[i].Name < backup.Snapshots[j].Name
+	})
+	return nil
+}
+
+// backupFiles copies the lab inventory, kubeconfig, and admin keys that exist into the backup
+func backupFiles(backup *Backup, dir string) error {
+	labName := backup.Lab.ObjectMeta.Name
+	files := map[string]string{
+		"inventory.json": backup.Lab.Spec.Ansible.Inventory,
+		"admin":          serverchecker.AdminKeyPath(labName),
+		"admin.pub":      serverchecker.AdminKeyPath(labName) + ".pub",
+	}
+	if kubeconfig, err := KubeconfigPath(labName); err == nil {
+		files["kubeconfig"] = kubeconfig
+	}
+	for name, src := range files {
+		if src == "" || !filepath.IsAbs(src) {
+			continue
+		}
+		if err := copyFile(src, filepath.Join(dir, backupFilesDir, name)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("error copying %s: %w", src, err)
+		}
+		backup.Files = append(backup.Files, name)
+	}
+	sort.Strings(backup.Files)
+	return nil
+}
+
+// copyFile copies the file readable only by the user, as it may be a key
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func saveBackup(backup *Backup, dir string) error {
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling backup: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, backupFile), data, 0600); err != nil {
+		return fmt.Errorf("error saving backup: %w", err)
+	}
+	return nil
+}
+
+// LoadBackup reads the backup by its name
+func LoadBackup(name string) (*Backup, error) {
+	dir, err := BackupDir(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, backupFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("backup %s not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup %s: %w", name, err)
+	}
+	backup := &Backup{}
+	if err := json.Unmarshal(data, backup); err != nil {
+		return nil, fmt.Errorf("error parsing backup %s: %w", name, err)
+	}
+	return backup, nil
+}
+
+// ListBackups returns the backups, oldest first
+func ListBackups() ([]*Backup, error) {
+	dir, err := BackupDir("")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Backup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing backups: %w", err)
+	}
+	backups := make([]*Backup, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		backup, err := LoadBackup(entry.Name())
+		if err != nil {
+			// an incomplete backup
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Created.Before(backups[j].Created) })
+	return backups, nil
+}
+
+// DeleteBackup deletes the server snapshots of the backup and its directory
+func (m *ManagerSvc) DeleteBackup(ctx context.Context, name string) error {
+	backup, err := LoadBackup(name)
+	if err != nil {
+		return err
+	}
+	if backup.Provider != m.Provider.Name() {
+		return fmt.Errorf("backup %s was taken with provider %s, not %s", name, backup.Provider, m.Provider.Name())
+	}
+	if err := m.deleteSnapshots(ctx, backup); err != nil {
+		return err
+	}
+	dir, err := BackupDir(name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// deleteSnapshots deletes the server snapshots; the volume copies are in the backup directory
+func (m *ManagerSvc) deleteSnapshots(ctx context.Context, backup *Backup) error {
+	snapshotter, ok := m.Provider.(provider.ServerSnapshotter)
+	if !ok {
+		return nil
+	}
+	errs := make([]error, 0)
+	for _, snapshot := range backup.Snapshots {
+		if snapshot.Kind != "Server" {
+			continue
+		}
+		if err := snapshotter.DeleteServerSnapshot(ctx, snapshot.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RestoredLab returns the lab to create from the backup under the new name.
+// Its servers are created from the server snapshots.
+func RestoredLab(backup *Backup, labName string) (*types.Lab, error) {
+	if backup.Lab == nil {
+		return nil, fmt.Errorf("backup %s has no lab", backup.Name)
+	}
+	images := make(map[string]string)
+	for _, snapshot := range backup.Snapshots {
+		if snapshot.Kind == "Server" {
+			images[snapshot.Name] = snapshot.ID
+		}
+	}
+	source := backup.Lab
+	lab := &types.Lab{
+		TypeMeta:   source.TypeMeta,
+		ObjectMeta: types.ObjectMeta{Name: labName, Labels: make(map[string]string)},
+		Spec:       source.Spec,
+	}
+	lab.Spec.Provider = backup.Provider
+	lab.Spec.Ansible = types.AnsibleSpec{
+		ConfigFile: source.Spec.Ansible.ConfigFile,
+		Playbook:   source.Spec.Ansible.Playbook,
+		User:       source.Spec.Ansible.User,
+	}
+	lab.Spec.Servers = make([]*types.LabServerSpec, 0, len(source.Spec.Servers))
+	for _, serverSpec := range source.Spec.Servers {
+		restored := *serverSpec
+		if image, ok := images[serverSpec.Name]; ok {
+			restored.Image = image
+		}
+		lab.Spec.Servers = append(lab.Spec.Servers, &restored)
+	}
+	return lab, nil
+}
+
+// RestoreVolumes creates the volumes of the new lab from the volume copies in the backup,
+// before the lab is created, so the lab creation uses them.
+// If a volume can't be restored, the volumes restored so far are deleted.
+func RestoreVolumes(ctx context.Context, p provider.CloudProvider, backup *Backup, lab *types.Lab) error {
+	copies := make(map[string]string)
+	for _, snapshot := range backup.Snapshots {
+		if snapshot.Kind == "Volume" {
+			copies[snapshot.Name] = snapshot.ID
+		}
+	}
+	if len(copies) == 0 {
+		return nil
+	}
+	snapshotter, ok := p.(provider.VolumeSnapshotter)
+	if !ok {
+		return fmt.Errorf("provider %s can't restore volumes", p.Name())
+	}
+	dir, err := BackupDir(backup.Name)
+	if err != nil {
+		return err
+	}
+	restored := make([]string, 0, len(copies))
+	for _, volumeSpec := range lab.Spec.Volumes {
+		volumeCopy, ok := copies[volumeSpec.Name]
+		if !ok {
+			continue
+		}
+		opts := volumeCreateOpts(lab, volumeSpec)
+		fmt.Printf("Restoring volume %s...\n", opts.Name)
+		if _, err := snapshotter.RestoreVolume(ctx, filepath.Join(dir, volumeCopy), opts); err != nil {
+			errs := []error{fmt.Errorf("failed to restore volume %s: %w", opts.Name, err)}
+			for _, name := range restored {
+				if status := p.DeleteVolume(context.WithoutCancel(ctx), name, true); status.Error != nil {
+					errs = append(errs, fmt.Errorf("failed to delete restored volume %s: %w", name, status.Error))
+				}
+			}
+			return errors.Join(errs...)
+		}
+		restored = append(restored, opts.Name)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package lab
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/serverchecker"
+	"github.com/stretchr/testify/assert"
+)
+
+// snapshotProvider saves servers as images and volumes as files
+type snapshotProvider struct {
+	mock.MockProvider
+	mu          sync.Mutex
+	deleted     []string
+	failFrom    string // the server that can't be saved
+	failRestore string // the volume that can't be restored
+}
+
+func (p *snapshotProvider) SnapshotServer(ctx context.Context, name string, opts options.SnapshotOpts) (string, error) {
+	if name == p.failFrom {
+		return "", errors.New("snapshot quota exceeded")
+	}
+	return "image-" + name, nil
+}
+
+func (p *snapshotProvider) DeleteServerSnapshot(ctx context.Context, image string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deleted = append(p.deleted, image)
+	return nil
+}
+
+func (p *snapshotProvider) SnapshotVolume(ctx context.Context, name string, opts options.SnapshotOpts) (string, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(opts.Dir, name)
+	return path, os.WriteFile(path, []byte("data of "+name), 0644)
+}
+
+func (p *snapshotProvider) RestoreVolume(ctx context.Context, snapshot string, opts options.VolumeCreateOpts) (*types.Volume, error) {
+	if opts.Name == p.failRestore {
+		return nil, errors.New("disk is full")
+	}
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return testVolume(opts.Name+" from "+string(data), opts.Size), nil
+}
+
+func newSnapshotProvider() *snapshotProvider {
+	p := &snapshotProvider{}
+	p.NameFunc = func() string { return "lima" }
+	return p
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := newSnapshotProvider()
+	m := &ManagerSvc{Provider: p, Storage: newTestStorage(t), Logger: logger.Get(), Concurrency: 2}
+	lab := testLabSpec()
+	lab.Spec.Provider = "lima"
+	lab.Spec.Ansible = types.AnsibleSpec{Playbook: "site.yml", Inventory: filepath.Join(t.TempDir(), "test-inventory.json")}
+	assert.NoError(t, os.WriteFile(lab.Spec.Ansible.Inventory, []byte("{}"), 0644))
+	keyPath := serverchecker.AdminKeyPath("test")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(keyPath), 0700))
+	assert.NoError(t, os.WriteFile(keyPath, []byte("private key"), 0600))
+	assert.NoError(t, m.Storage.Save(lab))
+
+	backup, err := m.Backup(context.Background(), "test", BackupOpts{Name: "golden"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Snapshot{
+		{Kind: "Volume", Name: "volume-01", ID: "disks/test-volume-01"},
+		{Kind: "Server", Name: "cp", ID: "image-test-cp"},
+		{Kind: "Server", Name: "node-01", ID: "image-test-node-01"},
+	}, backup.Snapshots)
+	assert.Equal(t, []string{"admin", "inventory.json"}, backup.Files)
+	assert.Empty(t, backup.Notes)
+	dir, err := BackupDir("golden")
+	assert.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, "files", "admin"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, err = m.Backup(context.Background(), "test", BackupOpts{Name: "golden"})
+	assert.EqualError(t, err, "backup golden already exists")
+
+	backups, err := ListBackups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	assert.Equal(t, "golden", backups[0].Name)
+	assert.Equal(t, "test", backups[0].Lab.ObjectMeta.Name)
+
+	restored, err := RestoredLab(backups[0], "demo")
+	assert.NoError(t, err)
+	assert.Equal(t, "demo", restored.ObjectMeta.Name)
+	assert.Equal(t, "lima", restored.Spec.Provider)
+	assert.Equal(t, "image-test-cp", restored.Spec.Servers[0].Image)
+	assert.Equal(t, "image-test-node-01", restored.Spec.Servers[1].Image)
+	assert.Equal(t, types.AnsibleSpec{Playbook: "site.yml"}, restored.Spec.Ansible)
+	assert.Equal(t, "ubuntu-24.04", lab.Spec.Servers[0].Image, "the backed up lab is not changed")
+	assert.NoError(t, RestoreVolumes(context.Background(), p, backups[0], restored))
+
+	assert.NoError(t, m.DeleteBackup(context.Background(), "golden"))
+	sort.Strings(p.deleted)
+	assert.Equal(t, []string{"image-test-cp", "image-test-node-01"}, p.deleted)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBackupFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := newSnapshotProvider()
+	p.failFrom = "test-node-01"
+	m := &ManagerSvc{Provider: p, Storage: newTestStorage(t), Logger: logger.Get(), Concurrency: 1}
+	lab := testLabSpec()
+	lab.Spec.Provider = "lima"
+	assert.NoError(t, m.Storage.Save(lab))
+
+	_, err := m.Backup(context.Background(), "test", BackupOpts{Name: "golden"})
+	assert.ErrorContains(t, err, "failed to take server snapshots: snapshot quota exceeded")
+	assert.Equal(t, []string{"image-test-cp"}, p.deleted, "the snapshots taken are deleted")
+	dir, err := BackupDir("golden")
+	assert.NoError(t, err)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBackupNotes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	provider := &mock.MockProvider{NameFunc: func() string { return "aws" }}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	lab.Spec.Provider = "aws"
+	assert.NoError(t, m.Storage.Save(lab))
+
+	backup, err := m.Backup(context.Background(), "test", BackupOpts{})
+	assert.NoError(t, err)
+	assert.Regexp(t, `^test-\d{8}-\d{6}$`, backup.Name)
+	assert.Empty(t, backup.Snapshots)
+	assert.Equal(t, []string{
+		"aws servers can't be saved, the restored lab gets new servers from the spec images",
+		"aws volumes can't be saved, the restored lab gets empty volumes",
+	}, backup.Notes)
+	_, err = LoadBackup("missing")
+	assert.EqualError(t, err, "backup missing not found")
+}
+
+func TestBackupOtherProvider(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := &ManagerSvc{Provider: newSnapshotProvider(), Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	lab.Spec.Provider = "hetzner"
+	assert.NoError(t, m.Storage.Save(lab))
+
+	_, err := m.Backup(context.Background(), "test", BackupOpts{Name: "golden"})
+	assert.EqualError(t, err, "lab test runs on hetzner, not lima")
+	dir, err := BackupDir("golden")
+	assert.NoError(t, err)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRestoreVolumesFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := newSnapshotProvider()
+	p.failRestore = "demo-volume-02"
+	var deleted []string
+	p.DeleteVolumeFunc = func(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
+		deleted = append(deleted, name)
+		return &types.VolumeDeleteStatus{Deleted: true}
+	}
+	dir, err := BackupDir("golden")
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "disks"), 0755))
+	for _, name := range []string{"test-volume-01", "test-volume-02"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "disks", name), []byte("data of "+name), 0644))
+	}
+	lab := testLabSpec()
+	lab.Spec.Volumes = append(lab.Spec.Volumes, &types.LabVolumeSpec{Name: "volume-02", Server: "cp", Size: 100})
+	backup := &Backup{
+		Name: "golden",
+		Lab:  lab,
+		Snapshots: []*Snapshot{
+			{Kind: "Volume", Name: "volume-01", ID: "disks/test-volume-01"},
+			{Kind: "Volume", Name: "volume-02", ID: "disks/test-volume-02"},
+		},
+	}
+	restored, err := RestoredLab(backup, "demo")
+	assert.NoError(t, err)
+
+	err = RestoreVolumes(context.Background(), p, backup, restored)
+	assert.ErrorContains(t, err, "failed to restore volume demo-volume-02: disk is full")
+	assert.Equal(t, []string{"demo-volume-01"}, deleted, "the restored volumes are deleted")
+}
@@ -0,0 +1,443 @@
+package lab
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/output"
+	"github.com/pavelanni/storctl/internal/util/serverchecker"
+	"golang.org/x/crypto/scrypt"
+)
+
+// BundleVersion is the version of the export bundle layout
+const BundleVersion = 1
+
+// Files in the export bundle
+const (
+	bundleManifestFile  = "manifest.json"
+	bundleLabFile       = "lab.json" // the lab record
+	bundleTemplateFile  = "lab.yaml" // the lab spec as a template
+	bundleInventoryFile = "inventory.json"
+	bundleKubeconfig    = "kubeconfig"
+	bundleKeyFile       = "admin"
+	bundlePublicKeyFile = "admin.pub"
+	bundleEncryptedKey  = "admin.enc" // the admin key encrypted with the passphrase
+)
+
+// maxBundleFile limits the files read from a bundle
+const maxBundleFile = 16 * 1024 * 1024
+
+// Bundle is a lab exported to hand it to another user
+type Bundle struct {
+	Manifest BundleManifest
+	Lab      *types.Lab
+	Files    map[string][]byte // the bundle files by name
+}
+
+// BundleManifest describes the bundle
+type BundleManifest struct {
+	Version      int       `json:"version"`
+	Lab          string    `json:"lab"`
+	Provider     string    `json:"provider"`
+	Exported     time.Time `json:"exported"`
+	ExportedBy   string    `json:"exportedBy,omitempty"`
+	Files        []string  `json:"files"`
+	EncryptedKey bool      `json:"encryptedKey"`
+}
+
+// ExportOpts are the options for Export
+type ExportOpts struct {
+	Passphrase string // encrypts the admin key if set
+}
+
+// ImportOpts are the options for Import
+type ImportOpts struct {
+	Passphrase string // decrypts the admin key
+}
+
+// Export writes the lab record, template, inventory, kubeconfig, and admin keys
+// as a tar.gz bundle that Import registers on another machine
+func (m *ManagerSvc) Export(ctx context.Context, labName string, w io.Writer, opts ExportOpts) (*BundleManifest, error) {
+	lab, err := m.Storage.Get(labName)
+	if err != nil {
+		return nil, err
+	}
+	if lab.Spec.Provider != m.Provider.Name() {
+		return nil, fmt.Errorf("lab %s runs on %s, not %s, export it with --provider %s",
+			labName, lab.Spec.Provider, m.Provider.Name(), lab.Spec.Provider)
+	}
+	if m.Provider.Capabilities().SSHKeyPath != "" {
+		return nil, fmt.Errorf("%s labs run on this machine and can't be exported", lab.Spec.Provider)
+	}
+	files := make(map[string][]byte)
+	record, err := json.MarshalIndent(lab, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling lab: %w", err)
+	}
+	files[bundleLabFile] = record
+	template, err := labTemplate(lab)
+	if err != nil {
+		return nil, err
+	}
+	files[bundleTemplateFile] = template
+
+	kubeconfig, err := KubeconfigPath(labName)
+	if err != nil {
+		return nil, err
+	}
+	keyPath := serverchecker.AdminKeyPath(labName)
+	for name, path := range map[string]string{
+		bundleInventoryFile: lab.Spec.Ansible.Inventory,
+		bundleKubeconfig:    kubeconfig,
+		bundleKeyFile:       keyPath,
+		bundlePublicKeyFile: keyPath + ".pub",
+	} {
+		if !filepath.IsAbs(path) {
+			// the inventory isn't created yet
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		files[name] = data
+	}
+	if _, ok := files[bundleKeyFile]; !ok {
+		return nil, fmt.Errorf("lab %s admin key %s not found", labName, keyPath)
+	}
+	if opts.Passphrase != "" {
+		encrypted, err := encryptKey(files[bundleKeyFile], opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		delete(files, bundleKeyFile)
+		files[bundleEncryptedKey] = encrypted
+	}
+
+	manifest := &BundleManifest{
+		Version:      BundleVersion,
+		Lab:          labName,
+		Provider:     lab.Spec.Provider,
+		Exported:     time.Now(),
+		ExportedBy:   os.Getenv("USER"),
+		Files:        make([]string, 0, len(files)),
+		EncryptedKey: opts.Passphrase != "",
+	}
+	for name := range files {
+		manifest.Files = append(manifest.Files, name)
+	}
+	sort.Strings(manifest.Files)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling manifest: %w", err)
+	}
+	if err := writeBundle(w, manifest, data, files); err != nil {
+		return nil, fmt.Errorf("error writing bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// labTemplate returns the lab spec as a YAML template that creates the same lab
+func labTemplate(lab *types.Lab) ([]byte, error) {
+	template := &types.Lab{
+		TypeMeta:   lab.TypeMeta,
+		ObjectMeta: types.ObjectMeta{Name: lab.ObjectMeta.Name, Labels: lab.ObjectMeta.Labels},
+		Spec:       lab.Spec,
+	}
+	template.Spec.Ansible = types.AnsibleSpec{
+		ConfigFile: lab.Spec.Ansible.ConfigFile,
+		Playbook:   lab.Spec.Ansible.Playbook,
+		User:       lab.Spec.Ansible.User,
+	}
+	// the template uses the JSON field names, as the lab YAML files do
+	data, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling lab template: %w", err)
+	}
+	fields := make(map[string]any)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("error marshalling lab template: %w", err)
+	}
+	delete(fields, "status")
+	var buf bytes.Buffer
+	if err := output.YAML(fields, &buf); err != nil {
+		return nil, fmt.Errorf("error marshalling lab template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBundle writes the manifest first, then the files in name order
+func writeBundle(w io.Writer, manifest *BundleManifest, manifestData []byte, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte, mode int64) error {
+		header := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: manifest.Exported}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := write(bundleManifestFile, manifestData, 0644); err != nil {
+		return err
+	}
+	for _, name := range manifest.Files {
+		if err := write(name, files[name], 0600); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadBundle reads the bundle written by Export
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading bundle: %w", err)
+	}
+	defer gz.Close()
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || strings.Contains(header.Name, "/") {
+			return nil, fmt.Errorf("unexpected entry %s in bundle", header.Name)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxBundleFile+1))
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s from bundle: %w", header.Name, err)
+		}
+		if len(data) > maxBundleFile {
+			return nil, fmt.Errorf("file %s in bundle is too large", header.Name)
+		}
+		files[header.Name] = data
+	}
+
+	bundle := &Bundle{Files: files}
+	manifest, ok := files[bundleManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no %s, it isn't a storctl lab bundle", bundleManifestFile)
+	}
+	if err := json.Unmarshal(manifest, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("error parsing bundle manifest: %w", err)
+	}
+	if bundle.Manifest.Version > BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than %d, upgrade storctl", bundle.Manifest.Version, BundleVersion)
+	}
+	bundle.Lab = &types.Lab{}
+	if err := json.Unmarshal(files[bundleLabFile], bundle.Lab); err != nil {
+		return nil, fmt.Errorf("error parsing lab in bundle: %w", err)
+	}
+	if bundle.Lab.ObjectMeta.Name == "" || bundle.Lab.ObjectMeta.Name != bundle.Manifest.Lab {
+		return nil, fmt.Errorf("bundle lab %q doesn't match the manifest lab %q", bundle.Lab.ObjectMeta.Name, bundle.Manifest.Lab)
+	}
+	// the lab name is used in the paths of the keys, inventory, and kubeconfig Import writes
+	if !validLabName(bundle.Lab.ObjectMeta.Name) {
+		return nil, fmt.Errorf("invalid lab name %q in bundle, use only letters, digits, dashes, and underscores", bundle.Lab.ObjectMeta.Name)
+	}
+	return bundle, nil
+}
+
+// validLabName reports whether the name is a valid lab_name label value,
+// which also keeps the lab files in their directories
+func validLabName(name string) bool {
+	return name != "" && labelutil.SanitizeValue(name) == name
+}
+
+// Import registers the bundled lab in the lab storage and writes its admin keys,
+// inventory, and kubeconfig, so the lab can be used and deleted on this machine.
+// The inventory uses the admin key on this machine.
+func (m *ManagerSvc) Import(ctx context.Context, bundle *Bundle, opts ImportOpts) (*types.Lab, error) {
+	lab := bundle.Lab
+	labName := lab.ObjectMeta.Name
+	if lab.Spec.Provider != m.Provider.Name() {
+		return nil, fmt.Errorf("lab %s runs on %s, not %s", labName, lab.Spec.Provider, m.Provider.Name())
+	}
+	_, err := m.Storage.Get(labName)
+	if err == nil {
+		return nil, fmt.Errorf("lab %s already exists", labName)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("error checking lab %s: %w", labName, err)
+	}
+	keyPath := serverchecker.AdminKeyPath(labName)
+	if _, err := os.Stat(keyPath); err == nil {
+		return nil, fmt.Errorf("admin key %s already exists", keyPath)
+	}
+	kubeconfigPath, err := KubeconfigPath(labName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(kubeconfigPath); err == nil {
+		return nil, fmt.Errorf("kubeconfig %s already exists", kubeconfigPath)
+	}
+	key, err := bundleKey(bundle, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	inventoryPath := filepath.Join(homeDir, config.DefaultConfigDir, config.DefaultAnsibleDir, labName+"-inventory.json")
+
+	written := make([]string, 0)
+	write := func(path string, data []byte, perm os.FileMode) error {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("error creating directory for %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, data, perm); err != nil {
+			return fmt.Errorf("error writing %s: %w", path, err)
+		}
+		written = append(written, path)
+		return nil
+	}
+	err = write(keyPath, key, 0600)
+	if publicKey, ok := bundle.Files[bundlePublicKeyFile]; ok && err == nil {
+		err = write(keyPath+".pub", publicKey, 0644)
+	}
+	if inventory, ok := bundle.Files[bundleInventoryFile]; ok && err == nil {
+		inventory, err = inventoryWithKey(inventory, keyPath)
+		if err == nil {
+			err = write(inventoryPath, inventory, 0644)
+			lab.Spec.Ansible.Inventory = inventoryPath
+			lab.Spec.Ansible.InventoryFullPath = inventoryPath
+		}
+	}
+	if kubeconfig, ok := bundle.Files[bundleKubeconfig]; ok && err == nil {
+		err = write(kubeconfigPath, kubeconfig, 0600)
+	}
+	if err == nil {
+		// the paths and logs are on the machine the lab was exported from
+		lab.Spec.Ansible.PlaybookFullPath = ""
+		lab.Status.PlaybookRuns = nil
+		err = m.Storage.Save(lab)
+	}
+	if err != nil {
+		for _, path := range written {
+			os.Remove(path)
+		}
+		return nil, err
+	}
+	return lab, nil
+}
+
+// bundleKey returns the admin private key, decrypted with the passphrase if needed
+func bundleKey(bundle *Bundle, passphrase string) ([]byte, error) {
+	if !bundle.Manifest.EncryptedKey {
+		key, ok := bundle.Files[bundleKeyFile]
+		if !ok {
+			return nil, fmt.Errorf("bundle has no admin key")
+		}
+		return key, nil
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("the admin key in the bundle is encrypted, a passphrase is required")
+	}
+	return decryptKey(bundle.Files[bundleEncryptedKey], passphrase)
+}
+
+// inventoryWithKey points the inventory at the admin key on this machine
+func inventoryWithKey(data []byte, keyPath string) ([]byte, error) {
+	inventory := make(map[string]any)
+	if err := json.Unmarshal(data, &inventory); err != nil {
+		return nil, fmt.Errorf("error parsing inventory in bundle: %w", err)
+	}
+	all, _ := inventory["all"].(map[string]any)
+	if all == nil {
+		return nil, fmt.Errorf("inventory in bundle has no all group")
+	}
+	vars, _ := all["vars"].(map[string]any)
+	if vars == nil {
+		vars = make(map[string]any)
+		all["vars"] = vars
+	}
+	vars["ansible_ssh_private_key_file"] = keyPath
+	return json.MarshalIndent(inventory, "", "  ")
+}
+
+// Key encryption parameters: scrypt derives an AES-256-GCM key from the passphrase
+const (
+	keySaltSize = 16
+	scryptN     = 1 << 15
+	scryptR     = 8
+	scryptP     = 1
+)
+
+// encryptKey returns the salt, nonce, and the sealed key
+func encryptKey(key []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, keySaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("error generating salt: %w", err)
+	}
+	aead, err := keyCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+	sealed := append(salt, nonce...)
+	return aead.Seal(sealed, nonce, key, nil), nil
+}
+
+func decryptKey(data []byte, passphrase string) ([]byte, error) {
+	if len(data) < keySaltSize {
+		return nil, fmt.Errorf("encrypted admin key is too short")
+	}
+	aead, err := keyCipher(passphrase, data[:keySaltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[keySaltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted admin key is too short")
+	}
+	key, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase for the admin key")
+	}
+	return key, nil
+}
+
+func keyCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving key: %w", err)
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,144 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/serverchecker"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestExportImport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	provider := &mock.MockProvider{NameFunc: func() string { return "hetzner" }, CapabilitiesFunc: testCapabilities("hetzner")}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	lab.Spec.Provider = "hetzner"
+	lab.Spec.Ansible = types.AnsibleSpec{Playbook: "site.yml", Inventory: filepath.Join(t.TempDir(), "test-inventory.json")}
+	lab.Status.PlaybookRuns = []*types.PlaybookRun{{Playbook: "site.yml", LogPath: "/home/alice/.storctl/logs/test/site.log"}}
+	assert.NoError(t, os.WriteFile(lab.Spec.Ansible.Inventory,
+		[]byte(`{"all":{"vars":{"ansible_ssh_private_key_file":"/home/alice/.storctl/keys/test-admin"}}}`), 0644))
+	keyPath := serverchecker.AdminKeyPath("test")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(keyPath), 0700))
+	assert.NoError(t, os.WriteFile(keyPath, []byte("private key"), 0600))
+	assert.NoError(t, os.WriteFile(keyPath+".pub", []byte("public key"), 0644))
+	assert.NoError(t, m.Storage.Save(lab))
+
+	var buf bytes.Buffer
+	manifest, err := m.Export(context.Background(), "test", &buf, ExportOpts{Passphrase: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin.enc", "admin.pub", "inventory.json", "lab.json", "lab.yaml"}, manifest.Files)
+	assert.True(t, manifest.EncryptedKey)
+	assert.NotContains(t, buf.String(), "private key")
+
+	bundle, err := ReadBundle(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, "test", bundle.Lab.ObjectMeta.Name)
+	assert.Contains(t, string(bundle.Files["lab.yaml"]), "playbook: site.yml")
+	assert.NotContains(t, string(bundle.Files["lab.yaml"]), "status")
+
+	// the recipient has their own home and lab storage
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	recipient := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	_, err = recipient.Import(context.Background(), bundle, ImportOpts{})
+	assert.EqualError(t, err, "the admin key in the bundle is encrypted, a passphrase is required")
+	_, err = recipient.Import(context.Background(), bundle, ImportOpts{Passphrase: "wrong"})
+	assert.EqualError(t, err, "wrong passphrase for the admin key")
+
+	imported, err := recipient.Import(context.Background(), bundle, ImportOpts{Passphrase: "secret"})
+	assert.NoError(t, err)
+	newKeyPath := serverchecker.AdminKeyPath("test")
+	key, err := os.ReadFile(newKeyPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "private key", string(key))
+	inventoryPath := filepath.Join(home, ".storctl", "ansible", "test-inventory.json")
+	assert.Equal(t, inventoryPath, imported.Spec.Ansible.Inventory)
+	data, err := os.ReadFile(inventoryPath)
+	assert.NoError(t, err)
+	inventory := Inventory{}
+	assert.NoError(t, json.Unmarshal(data, &inventory))
+	assert.Equal(t, newKeyPath, inventory.All.Vars["ansible_ssh_private_key_file"])
+	stored, err := recipient.Storage.Get("test")
+	assert.NoError(t, err)
+	assert.Empty(t, stored.Status.PlaybookRuns)
+
+	_, err = recipient.Import(context.Background(), bundle, ImportOpts{Passphrase: "secret"})
+	assert.EqualError(t, err, "lab test already exists")
+}
+
+func TestReadBundleLabName(t *testing.T) {
+	for _, name := range []string{"../../.ssh/x", "a/b", "..", ".hidden"} {
+		data, err := json.Marshal(&types.Lab{ObjectMeta: types.ObjectMeta{Name: name}})
+		assert.NoError(t, err)
+		manifest := &BundleManifest{Version: BundleVersion, Lab: name, Files: []string{bundleLabFile}}
+		manifestData, err := json.Marshal(manifest)
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		assert.NoError(t, writeBundle(&buf, manifest, manifestData, map[string][]byte{bundleLabFile: data}))
+
+		_, err = ReadBundle(&buf)
+		assert.ErrorContains(t, err, fmt.Sprintf("invalid lab name %q in bundle", name))
+	}
+}
+
+func TestExportLocalLab(t *testing.T) {
+	provider := &mock.MockProvider{
+		NameFunc: func() string { return "lima" },
+		CapabilitiesFunc: func() types.ProviderCapabilities {
+			return types.ProviderCapabilities{SSHKeyPath: "/home/alice/.lima/_config/user"}
+		},
+	}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	lab.Spec.Provider = "lima"
+	assert.NoError(t, m.Storage.Save(lab))
+	_, err := m.Export(context.Background(), "test", &bytes.Buffer{}, ExportOpts{})
+	assert.EqualError(t, err, "lima labs run on this machine and can't be exported")
+
+	lab.Spec.Provider = "hetzner"
+	assert.NoError(t, m.Storage.Save(lab))
+	_, err = m.Export(context.Background(), "test", &bytes.Buffer{}, ExportOpts{})
+	assert.EqualError(t, err, "lab test runs on hetzner, not lima, export it with --provider hetzner")
+}
+
+func TestImportExisting(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	provider := &mock.MockProvider{NameFunc: func() string { return "hetzner" }, CapabilitiesFunc: testCapabilities("hetzner")}
+	storage := newTestStorage(t)
+	m := &ManagerSvc{Provider: provider, Storage: storage, Logger: logger.Get()}
+	lab := testLabSpec()
+	lab.Spec.Provider = "hetzner"
+	bundle := &Bundle{
+		Manifest: BundleManifest{Version: BundleVersion, Lab: "test", Provider: "hetzner"},
+		Lab:      lab,
+		Files:    map[string][]byte{bundleKeyFile: []byte("private key"), bundleKubeconfig: []byte("kubeconfig")},
+	}
+
+	kubeconfigPath, err := KubeconfigPath("test")
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(kubeconfigPath), 0700))
+	assert.NoError(t, os.WriteFile(kubeconfigPath, []byte("other kubeconfig"), 0600))
+	_, err = m.Import(context.Background(), bundle, ImportOpts{})
+	assert.EqualError(t, err, fmt.Sprintf("kubeconfig %s already exists", kubeconfigPath))
+	data, err := os.ReadFile(kubeconfigPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "other kubeconfig", string(data))
+	_, err = os.Stat(serverchecker.AdminKeyPath("test"))
+	assert.True(t, os.IsNotExist(err), "nothing is written")
+
+	assert.NoError(t, storage.(*BoltStorage).db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte("labs")).Put([]byte("test"), []byte(`{"metadata":`))
+	}))
+	_, err = m.Import(context.Background(), bundle, ImportOpts{})
+	assert.ErrorContains(t, err, "error checking lab test: failed to unmarshal lab")
+}
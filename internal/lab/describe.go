@@ -0,0 +1,326 @@
+package lab
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/parallel"
+)
+
+// maxDescribeEvents is the number of recent events in a lab description
+const maxDescribeEvents = 10
+
+// DescribeOpts are the options for Describe
+type DescribeOpts struct {
+	DNSDomain string // the domain of the lab DNS records; ignored for providers without public DNS
+}
+
+// Description is the full lab topology with the live health of its servers and volumes
+type Description struct {
+	Name         string               `json:"name" yaml:"name"`
+	Provider     string               `json:"provider" yaml:"provider"`
+	Location     string               `json:"location" yaml:"location"`
+	Owner        string               `json:"owner" yaml:"owner"`
+	State        string               `json:"state" yaml:"state"`
+	Created      time.Time            `json:"created" yaml:"created"`
+	DeleteAfter  time.Time            `json:"deleteAfter" yaml:"deleteAfter"`
+	TTLRemaining string               `json:"ttlRemaining" yaml:"ttlRemaining"`
+	Servers      []*ServerDescription `json:"servers" yaml:"servers"`
+	Volumes      []*VolumeDescription `json:"volumes" yaml:"volumes"`
+	DNSRecords   []*DNSRecord         `json:"dnsRecords,omitempty" yaml:"dnsRecords,omitempty"`
+	Inventory    string               `json:"inventory,omitempty" yaml:"inventory,omitempty"`
+	Kubeconfig   string               `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+	Playbook     *PlaybookRun         `json:"playbook,omitempty" yaml:"playbook,omitempty"`
+	Events       []*Event             `json:"events" yaml:"events"`
+	Error        string               `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ServerDescription is a lab server with its role, type, addresses, and attached volumes
+type ServerDescription struct {
+	Name    string   `json:"name" yaml:"name"`
+	Role    string   `json:"role" yaml:"role"`
+	Type    string   `json:"type" yaml:"type"`
+	Cores   int      `json:"cores" yaml:"cores"`
+	Memory  float32  `json:"memory" yaml:"memory"` // GB
+	Disk    int      `json:"disk" yaml:"disk"`     // GB
+	IP      string   `json:"ip" yaml:"ip"`
+	FQDN    string   `json:"fqdn" yaml:"fqdn"`
+	Volumes []string `json:"volumes" yaml:"volumes"`
+	Health  string   `json:"health" yaml:"health"` // the status reported by the provider now
+}
+
+// VolumeDescription is a lab volume and the server it's attached to
+type VolumeDescription struct {
+	Name      string `json:"name" yaml:"name"`
+	Server    string `json:"server" yaml:"server"`
+	Size      int    `json:"size" yaml:"size"` // GB
+	Format    string `json:"format" yaml:"format"`
+	Automount bool   `json:"automount" yaml:"automount"`
+	Health    string `json:"health" yaml:"health"`
+}
+
+// DNSRecord is a DNS record created for the lab
+type DNSRecord struct {
+	Name    string `json:"name" yaml:"name"`
+	Type    string `json:"type" yaml:"type"`
+	Content string `json:"content" yaml:"content"`
+}
+
+// PlaybookRun is the last run of the lab playbook
+type PlaybookRun struct {
+	Playbook string    `json:"playbook" yaml:"playbook"`
+	State    string    `json:"state" yaml:"state"` // empty if the playbook hasn't run
+	Started  time.Time `json:"started,omitempty" yaml:"started,omitempty"`
+	Finished time.Time `json:"finished,omitempty" yaml:"finished,omitempty"`
+	Error    string    `json:"error,omitempty" yaml:"error,omitempty"`
+	// LogPath and FailedTasks are from the last recorded run
+	LogPath     string   `json:"log,omitempty" yaml:"log,omitempty"`
+	FailedTasks []string `json:"failedTasks,omitempty" yaml:"failedTasks,omitempty"`
+}
+
+// Event is something that happened to the lab
+type Event struct {
+	Time    time.Time `json:"time" yaml:"time"`
+	Reason  string    `json:"reason" yaml:"reason"`
+	Message string    `json:"message" yaml:"message"`
+}
+
+// Describe returns the stored lab with the live status of its servers and volumes from the provider.
+// Resources the provider can't return are described with the error as their health.
+func (m *ManagerSvc) Describe(ctx context.Context, labName string, opts DescribeOpts) (*Description, error) {
+	lab, err := m.Get(ctx, labName)
+	if err != nil {
+		return nil, err
+	}
+	capabilities := m.Provider.Capabilities()
+	if !capabilities.NeedsPublicDNS {
+		opts.DNSDomain = ""
+	}
+	d := describeLab(lab, time.Now().UTC(), opts)
+
+	err = parallel.Run(ctx, len(d.Servers), m.Concurrency, m.Limiter, func(i int) error {
+		server, err := m.Provider.GetServer(ctx, d.Servers[i].Name)
+		d.Servers[i].Health = health(server, err)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = parallel.Run(ctx, len(d.Volumes), m.Concurrency, m.Limiter, func(i int) error {
+		volume, err := m.Provider.GetVolume(ctx, d.Volumes[i].Name)
+		status := ""
+		if volume != nil {
+			status = volume.Status.Status
+		}
+		d.Volumes[i].Health = healthStatus(volume != nil, status, err)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// KubeconfigPath returns where the lab playbook saves the cluster kubeconfig
+func KubeconfigPath(labName string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, config.DefaultConfigDir, config.DefaultKubeconfigDir, labName+"-kubeconfig"), nil
+}
+
+// describeLab describes the stored lab without asking the provider
+func describeLab(lab *types.Lab, now time.Time, opts DescribeOpts) *Description {
+	labName := lab.ObjectMeta.Name
+	d := &Description{
+		Name:        labName,
+		Provider:    lab.Spec.Provider,
+		Location:    lab.Spec.Location,
+		Owner:       lab.Status.Owner,
+		State:       lab.Status.State,
+		Created:     lab.Status.Created,
+		DeleteAfter: DeleteAfter(lab),
+		Servers:     make([]*ServerDescription, 0, len(lab.Status.Servers)),
+		Volumes:     make([]*VolumeDescription, 0, len(lab.Status.Volumes)),
+		Inventory:   lab.Spec.Ansible.InventoryFullPath,
+		Error:       lab.Status.Error,
+	}
+	if d.Owner == "" {
+		d.Owner = lab.ObjectMeta.Labels["owner"]
+	}
+	d.TTLRemaining = ttlRemaining(d.DeleteAfter, now)
+
+	roles := make(map[string]string)
+	for _, serverSpec := range lab.Spec.Servers {
+		roles[resourceName(labName, serverSpec.Name)] = serverSpec.Role
+	}
+	volumeServers := make(map[string]string)
+	for _, volumeSpec := range lab.Spec.Volumes {
+		volumeServers[resourceName(labName, volumeSpec.Name)] = resourceName(labName, volumeSpec.Server)
+	}
+
+	servers := make(map[string]*ServerDescription)
+	for _, server := range lab.Status.Servers {
+		s := &ServerDescription{
+			Name:    server.ObjectMeta.Name,
+			Role:    roles[server.ObjectMeta.Name],
+			Type:    server.Spec.ServerType,
+			Cores:   server.Status.Cores,
+			Memory:  server.Status.Memory,
+			Disk:    server.Status.Disk,
+			Volumes: make([]string, 0),
+		}
+		if publicNet := server.Status.PublicNet; publicNet != nil {
+			if publicNet.IPv4 != nil {
+				s.IP = publicNet.IPv4.IP
+			}
+			s.FQDN = publicNet.FQDN
+		}
+		servers[s.Name] = s
+		d.Servers = append(d.Servers, s)
+	}
+	for _, volume := range lab.Status.Volumes {
+		v := &VolumeDescription{
+			Name:      volume.ObjectMeta.Name,
+			Server:    volume.Spec.ServerName,
+			Size:      volume.Spec.Size,
+			Format:    volume.Spec.Format,
+			Automount: volume.Spec.Automount,
+		}
+		if v.Server == "" {
+			v.Server = volumeServers[v.Name]
+		}
+		if server, ok := servers[v.Server]; ok {
+			server.Volumes = append(server.Volumes, v.Name)
+		}
+		d.Volumes = append(d.Volumes, v)
+	}
+
+	d.DNSRecords = dnsRecords(labName, d.Servers, opts.DNSDomain)
+	// the playbook fetches the cluster kubeconfig from the control plane
+	if kubeconfig, err := KubeconfigPath(labName); err == nil {
+		if _, err := os.Stat(kubeconfig); err == nil {
+			d.Kubeconfig = kubeconfig
+		}
+	}
+	if lab.Spec.Ansible.Playbook != "" {
+		d.Playbook = &PlaybookRun{Playbook: lab.Spec.Ansible.Playbook}
+		if lab.Spec.Ansible.PlaybookFullPath != "" {
+			d.Playbook.Playbook = lab.Spec.Ansible.PlaybookFullPath
+		}
+		if phase := findPhase(lab, types.PhasePlaybook); phase != nil {
+			d.Playbook.State = phase.State
+			d.Playbook.Started = phase.Started
+			d.Playbook.Finished = phase.Finished
+			d.Playbook.Error = phase.Error
+		}
+		for i := len(lab.Status.PlaybookRuns) - 1; i >= 0; i-- {
+			if run := lab.Status.PlaybookRuns[i]; run.Playbook == d.Playbook.Playbook {
+				d.Playbook.LogPath = run.LogPath
+				d.Playbook.FailedTasks = run.FailedTasks
+				break
+			}
+		}
+	}
+	d.Events = labEvents(lab)
+	return d
+}
+
+// dnsRecords returns the A records <server>.<lab>.<domain> of the servers that got them
+// and the aistor record of the control plane
+func dnsRecords(labName string, servers []*ServerDescription, domain string) []*DNSRecord {
+	if domain == "" {
+		return nil
+	}
+	records := make([]*DNSRecord, 0)
+	for _, server := range servers {
+		shortName := strings.TrimPrefix(strings.ToLower(server.Name), strings.ToLower(labName)+"-")
+		if server.IP == "" || server.FQDN != dnsName(shortName, labName, domain) {
+			continue
+		}
+		records = append(records, &DNSRecord{Name: server.FQDN, Type: "A", Content: server.IP})
+	}
+	if len(records) > 0 && records[0].Content == servers[0].IP {
+		records = append(records, &DNSRecord{Name: dnsName("aistor", labName, domain), Type: "A", Content: servers[0].IP})
+	}
+	return records
+}
+
+// labEvents returns the most recent lab events, newest first
+func labEvents(lab *types.Lab) []*Event {
+	events := make([]*Event, 0)
+	if !lab.Status.Created.IsZero() {
+		events = append(events, &Event{Time: lab.Status.Created, Reason: "Created", Message: "lab created"})
+	}
+	for _, phase := range lab.Status.Phases {
+		if !phase.Started.IsZero() {
+			events = append(events, &Event{Time: phase.Started, Reason: "PhaseStarted", Message: "phase " + phase.Name + " started"})
+		}
+		if phase.Finished.IsZero() {
+			continue
+		}
+		switch phase.State {
+		case types.PhaseDone:
+			events = append(events, &Event{Time: phase.Finished, Reason: "PhaseDone", Message: "phase " + phase.Name + " done"})
+		case types.PhaseFailed:
+			events = append(events, &Event{Time: phase.Finished, Reason: "PhaseFailed", Message: "phase " + phase.Name + " failed: " + phase.Error})
+		}
+	}
+	for _, extension := range lab.Status.Extensions {
+		message := "extended to " + extension.DeleteAfter.Format(time.RFC3339)
+		if extension.By != "" {
+			message += " by " + extension.By
+		}
+		events = append(events, &Event{Time: extension.At, Reason: "Extended", Message: message})
+	}
+	// events at the same time stay in the order they happened
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	if len(events) > maxDescribeEvents {
+		events = events[:maxDescribeEvents]
+	}
+	return events
+}
+
+// ttlRemaining returns the time left until deleteAfter in minutes, "expired", or "N/A"
+func ttlRemaining(deleteAfter, now time.Time) string {
+	if deleteAfter.IsZero() {
+		return "N/A"
+	}
+	if !deleteAfter.After(now) {
+		return "expired"
+	}
+	return deleteAfter.Sub(now).Truncate(time.Minute).String()
+}
+
+// health returns the provider status of a server
+func health(server *types.Server, err error) string {
+	status := ""
+	if server != nil {
+		status = server.Status.Status
+	}
+	return healthStatus(server != nil, status, err)
+}
+
+// healthStatus returns the status reported by the provider or why there is none
+func healthStatus(found bool, status string, err error) string {
+	switch {
+	case err != nil:
+		return "error: " + err.Error()
+	case !found:
+		return "not found"
+	case status == "":
+		return "unknown"
+	}
+	return status
+}
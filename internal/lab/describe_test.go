@@ -0,0 +1,104 @@
+package lab
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	provider := &mock.MockProvider{
+		NameFunc:         func() string { return "hetzner" },
+		CapabilitiesFunc: testCapabilities("hetzner"),
+		GetServerFunc: func(ctx context.Context, name string) (*types.Server, error) {
+			if name == "test-node-01" {
+				return nil, errors.New("server not found")
+			}
+			server := testServer(name, "cx22")
+			server.Status.Status = "running"
+			return server, nil
+		},
+		GetVolumeFunc: func(ctx context.Context, name string) (*types.Volume, error) {
+			volume := testVolume(name, 100)
+			volume.Status.Status = "available"
+			return volume, nil
+		},
+	}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+
+	lab := testLabSpec()
+	lab.Spec.Provider = "hetzner"
+	lab.Spec.Servers[0].Role = "control-plane"
+	lab.Spec.Ansible = types.AnsibleSpec{User: "ansible", Playbook: "site.yml", InventoryFullPath: "/home/alice/.storctl/ansible/inventory.json"}
+	lab.Status.Owner = "alice"
+	lab.Status.Created = now.Add(-2 * time.Hour)
+	lab.Status.DeleteAfter = now.Add(3 * time.Hour)
+	for _, name := range []string{"cp", "node-01"} {
+		server := testServer("test-"+name, "cx22")
+		server.Status.PublicNet = &types.PublicNet{FQDN: name + ".test.example.com"}
+		server.Status.PublicNet.IPv4 = &struct {
+			IP string `json:"ip"`
+		}{IP: "10.0.0." + map[string]string{"cp": "1", "node-01": "2"}[name]}
+		lab.Status.Servers = append(lab.Status.Servers, server)
+	}
+	lab.Status.Volumes = []*types.Volume{testVolume("test-volume-01", 100)}
+	lab.Status.Phases = []*types.LabPhase{
+		{Name: types.PhaseServers, State: types.PhaseDone, Started: now.Add(-2 * time.Hour), Finished: now.Add(-110 * time.Minute)},
+		{Name: types.PhasePlaybook, State: types.PhaseFailed, Started: now.Add(-100 * time.Minute), Finished: now.Add(-90 * time.Minute), Error: "exit status 2"},
+	}
+	lab.Status.Extensions = []*types.LabExtension{{By: "bob", At: now.Add(-time.Hour), DeleteAfter: lab.Status.DeleteAfter}}
+	assert.NoError(t, m.Storage.Save(lab))
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	kubeconfig := filepath.Join(home, ".storctl", "kubeconfigs", "test-kubeconfig")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(kubeconfig), 0755))
+	assert.NoError(t, os.WriteFile(kubeconfig, []byte("apiVersion: v1\n"), 0600))
+
+	d, err := m.Describe(context.Background(), "test", DescribeOpts{DNSDomain: "example.com"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "alice", d.Owner)
+	assert.Regexp(t, `^2h5\dm0s$`, d.TTLRemaining)
+	if assert.Len(t, d.Servers, 2) {
+		assert.Equal(t, "control-plane", d.Servers[0].Role)
+		assert.Equal(t, "running", d.Servers[0].Health)
+		assert.Equal(t, []string{"test-volume-01"}, d.Servers[1].Volumes)
+		assert.Equal(t, "error: server not found", d.Servers[1].Health)
+	}
+	if assert.Len(t, d.Volumes, 1) {
+		assert.Equal(t, "test-node-01", d.Volumes[0].Server)
+		assert.Equal(t, "available", d.Volumes[0].Health)
+	}
+	assert.Equal(t, []*DNSRecord{
+		{Name: "cp.test.example.com", Type: "A", Content: "10.0.0.1"},
+		{Name: "node-01.test.example.com", Type: "A", Content: "10.0.0.2"},
+		{Name: "aistor.test.example.com", Type: "A", Content: "10.0.0.1"},
+	}, d.DNSRecords)
+	assert.Equal(t, "/home/alice/.storctl/ansible/inventory.json", d.Inventory)
+	assert.Equal(t, kubeconfig, d.Kubeconfig)
+	assert.Equal(t, &PlaybookRun{Playbook: "site.yml", State: types.PhaseFailed,
+		Started: now.Add(-100 * time.Minute), Finished: now.Add(-90 * time.Minute), Error: "exit status 2"}, d.Playbook)
+	if assert.Len(t, d.Events, 6) {
+		assert.Equal(t, "Extended", d.Events[0].Reason)
+		assert.Contains(t, d.Events[0].Message, "by bob")
+		assert.Equal(t, "PhaseFailed", d.Events[1].Reason)
+		assert.Equal(t, "Created", d.Events[5].Reason)
+	}
+}
+
+func TestTTLRemaining(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 30, 0, time.UTC)
+	assert.Equal(t, "N/A", ttlRemaining(time.Time{}, now))
+	assert.Equal(t, "expired", ttlRemaining(now.Add(-time.Minute), now))
+	assert.Equal(t, "1h29m0s", ttlRemaining(now.Add(90*time.Minute-time.Second), now))
+}
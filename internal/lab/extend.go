@@ -0,0 +1,167 @@
+package lab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// ExtendOpts are the options for extending a lab
+type ExtendOpts struct {
+	// TTL is added to the current delete_after, or to the current time if the lab has expired
+	TTL time.Duration
+	// MaxLifetime is the longest time a lab can live since its creation, zero means no limit
+	MaxLifetime time.Duration
+	// By is who extends the lab, kept in the extended_by label and in the lab extensions
+	By string
+}
+
+// Extend pushes the delete_after label of the lab servers, volumes, and SSH keys forward
+// and records the extension in the lab storage.
+// If a resource can't be updated, the lab isn't saved, so the extension can be retried.
+func (m *ManagerSvc) Extend(ctx context.Context, labName string, opts ExtendOpts) (*types.Lab, error) {
+	capabilities := m.Provider.Capabilities()
+	if !capabilities.SupportsTTLLabels || !capabilities.MutableLabels {
+		return nil, fmt.Errorf("provider %s can't change the TTL of existing resources", m.Provider.Name())
+	}
+	if opts.TTL <= 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+	lab, err := m.Get(ctx, labName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab: %w", err)
+	}
+
+	now := time.Now().UTC()
+	deleteAfter := DeleteAfter(lab)
+	if deleteAfter.Before(now) {
+		deleteAfter = now
+	}
+	// delete_after labels keep minutes only
+	deleteAfter = deleteAfter.Add(opts.TTL).Truncate(time.Minute)
+	if created := labCreated(lab); opts.MaxLifetime > 0 && !created.IsZero() {
+		if limit := created.Add(opts.MaxLifetime); deleteAfter.After(limit) {
+			return nil, fmt.Errorf("lab %s can't be extended to %s: the max lifetime is %s, it ends at %s",
+				labName, deleteAfter.Format(time.RFC3339), opts.MaxLifetime, limit.Format(time.RFC3339))
+		}
+	}
+
+	labels := map[string]string{"delete_after": timeutil.FormatDeleteAfter(deleteAfter)}
+	if by := labelutil.SanitizeValue(opts.By); by != "" {
+		labels["extended_by"] = by
+	}
+	if err := m.updateLabLabels(ctx, lab, labels); err != nil {
+		return nil, fmt.Errorf("failed to extend lab %s: %w", labName, err)
+	}
+
+	lab.ObjectMeta.Labels = labelutil.MergeLabels(lab.ObjectMeta.Labels, labels)
+	lab.Status.DeleteAfter = deleteAfter
+	for _, server := range lab.Status.Servers {
+		server.ObjectMeta.Labels = labelutil.MergeLabels(server.ObjectMeta.Labels, labels)
+		server.Status.DeleteAfter = deleteAfter
+	}
+	for _, volume := range lab.Status.Volumes {
+		volume.ObjectMeta.Labels = labelutil.MergeLabels(volume.ObjectMeta.Labels, labels)
+		volume.Status.DeleteAfter = deleteAfter
+	}
+	lab.Status.Extensions = append(lab.Status.Extensions, &types.LabExtension{
+		By:          opts.By,
+		At:          now,
+		DeleteAfter: deleteAfter,
+	})
+	if err := m.Storage.Save(lab); err != nil {
+		return nil, fmt.Errorf("failed to save lab: %w", err)
+	}
+	return lab, nil
+}
+
+// updateLabLabels merges the labels into the labels of the lab servers, volumes, and SSH keys.
+// Resources are found by the lab_name label, so the ones missing in the lab storage are updated too.
+// The SSH keys also get the lab_name and owner labels, so the reaper deletes a key with a delete_after
+// together with its lab instead of taking it for an orphan.
+// All resources are tried and the errors are joined.
+func (m *ManagerSvc) updateLabLabels(ctx context.Context, lab *types.Lab, labels map[string]string) error {
+	labName := lab.ObjectMeta.Name
+	selector := options.ListOpts{LabelSelector: "lab_name=" + labName}
+	servers, err := m.Provider.ListServers(ctx, options.ServerListOpts{ListOpts: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list servers: %w", err)
+	}
+	volumes, err := m.Provider.ListVolumes(ctx, options.VolumeListOpts{ListOpts: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list volumes: %w", err)
+	}
+	keys, err := m.Provider.ListSSHKeys(ctx, options.SSHKeyListOpts{ListOpts: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list SSH keys: %w", err)
+	}
+	// the lab admin key has no labels, it's found in the lab resources
+	keyNames := make([]string, 0, len(keys))
+	for _, key := range keys {
+		keyNames = append(keyNames, key.ObjectMeta.Name)
+	}
+	for _, resource := range lab.Status.Resources {
+		if resource.Kind == "SSHKey" && !contains(keyNames, resource.Name) {
+			keyNames = append(keyNames, resource.Name)
+		}
+	}
+
+	var errs []error
+	for _, server := range servers {
+		m.Logger.Info("updating server labels", "server", server.ObjectMeta.Name, "labels", labels)
+		if err := m.Provider.UpdateServerLabels(ctx, server.ObjectMeta.Name, labels); err != nil {
+			errs = append(errs, fmt.Errorf("server %s: %w", server.ObjectMeta.Name, err))
+		}
+	}
+	for _, volume := range volumes {
+		m.Logger.Info("updating volume labels", "volume", volume.ObjectMeta.Name, "labels", labels)
+		if err := m.Provider.UpdateVolumeLabels(ctx, volume.ObjectMeta.Name, labels); err != nil {
+			errs = append(errs, fmt.Errorf("volume %s: %w", volume.ObjectMeta.Name, err))
+		}
+	}
+	keyLabels := labelutil.MergeLabels(labels, map[string]string{"lab_name": labName})
+	if owner := lab.ObjectMeta.Labels["owner"]; owner != "" {
+		keyLabels["owner"] = owner
+	}
+	for _, keyName := range keyNames {
+		m.Logger.Info("updating ssh key labels", "key", keyName, "labels", keyLabels)
+		if err := m.Provider.UpdateSSHKeyLabels(ctx, keyName, keyLabels); err != nil {
+			errs = append(errs, fmt.Errorf("SSH key %s: %w", keyName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DeleteAfter returns the delete_after of the lab from its status or, for older labs, from its labels
+func DeleteAfter(lab *types.Lab) time.Time {
+	if !lab.Status.DeleteAfter.IsZero() {
+		return lab.Status.DeleteAfter
+	}
+	return timeutil.ParseDeleteAfter(lab.ObjectMeta.Labels["delete_after"])
+}
+
+// labCreated returns the earliest creation time of the lab and its servers
+func labCreated(lab *types.Lab) time.Time {
+	created := lab.Status.Created
+	for _, server := range lab.Status.Servers {
+		if created.IsZero() || (!server.Status.Created.IsZero() && server.Status.Created.Before(created)) {
+			created = server.Status.Created
+		}
+	}
+	return created
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
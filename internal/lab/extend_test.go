@@ -0,0 +1,129 @@
+package lab
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtend(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	tests := []struct {
+		name            string
+		deleteAfter     time.Time
+		capabilities    types.ProviderCapabilities
+		opts            ExtendOpts
+		updateErr       error
+		wantDeleteAfter time.Time
+		errContains     string
+	}{
+		{
+			name:            "extends from delete_after",
+			deleteAfter:     now.Add(time.Hour),
+			opts:            ExtendOpts{TTL: 4 * time.Hour, MaxLifetime: 24 * time.Hour, By: "alice"},
+			wantDeleteAfter: now.Add(5 * time.Hour),
+		},
+		{
+			name:            "expired lab extends from now",
+			deleteAfter:     now.Add(-time.Hour),
+			opts:            ExtendOpts{TTL: 4 * time.Hour, By: "alice"},
+			wantDeleteAfter: now.Add(4 * time.Hour),
+		},
+		{
+			name:        "beyond max lifetime",
+			deleteAfter: now.Add(time.Hour),
+			opts:        ExtendOpts{TTL: 48 * time.Hour, MaxLifetime: 24 * time.Hour, By: "alice"},
+			errContains: "the max lifetime is 24h0m0s",
+		},
+		{
+			name:         "labels can't be changed",
+			deleteAfter:  now.Add(time.Hour),
+			capabilities: types.ProviderCapabilities{SupportsLabels: true, SupportsTTLLabels: true},
+			opts:         ExtendOpts{TTL: 4 * time.Hour},
+			errContains:  "can't change the TTL",
+		},
+		{
+			name:        "update fails",
+			deleteAfter: now.Add(time.Hour),
+			opts:        ExtendOpts{TTL: 4 * time.Hour},
+			updateErr:   errors.New("rate limited"),
+			errContains: "volume test-volume-01: rate limited",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capabilities := tt.capabilities
+			if capabilities == (types.ProviderCapabilities{}) {
+				capabilities = testCapabilities("hetzner")()
+				capabilities.MutableLabels = true
+			}
+			updated := map[string]map[string]string{}
+			provider := &mock.MockProvider{
+				NameFunc:         func() string { return "hetzner" },
+				CapabilitiesFunc: func() types.ProviderCapabilities { return capabilities },
+				ListServersFunc: func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+					assert.Equal(t, "lab_name=test", opts.LabelSelector)
+					return []*types.Server{testServer("test-cp", "cx22")}, nil
+				},
+				ListVolumesFunc: func(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+					return []*types.Volume{testVolume("test-volume-01", 100)}, nil
+				},
+				UpdateServerLabelsFunc: func(ctx context.Context, name string, labels map[string]string) error {
+					updated["Server "+name] = labels
+					return nil
+				},
+				UpdateVolumeLabelsFunc: func(ctx context.Context, name string, labels map[string]string) error {
+					updated["Volume "+name] = labels
+					return tt.updateErr
+				},
+				UpdateSSHKeyLabelsFunc: func(ctx context.Context, name string, labels map[string]string) error {
+					updated["SSHKey "+name] = labels
+					return nil
+				},
+			}
+			m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+			lab := testLabSpec()
+			lab.ObjectMeta.Labels = map[string]string{"lab_name": "test", "delete_after": timeutil.FormatDeleteAfter(tt.deleteAfter)}
+			lab.Status.Created = now.Add(-2 * time.Hour)
+			lab.Status.DeleteAfter = tt.deleteAfter
+			lab.Status.Resources = []*types.LabResource{{Kind: "SSHKey", Name: "test-admin"}, {Kind: "Server", Name: "test-cp"}}
+			lab.Status.Servers = []*types.Server{testServer("test-cp", "cx22")}
+			assert.NoError(t, m.Storage.Save(lab))
+
+			extended, err := m.Extend(context.Background(), "test", tt.opts)
+			stored, getErr := m.Storage.Get("test")
+			assert.NoError(t, getErr)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				assert.Empty(t, stored.Status.Extensions)
+				assert.Equal(t, tt.deleteAfter, stored.Status.DeleteAfter)
+				return
+			}
+			assert.NoError(t, err)
+			want := map[string]string{"delete_after": timeutil.FormatDeleteAfter(tt.wantDeleteAfter), "extended_by": "alice"}
+			assert.Equal(t, map[string]map[string]string{
+				"Server test-cp":        want,
+				"Volume test-volume-01": want,
+				"SSHKey test-admin":     labelutil.MergeLabels(want, map[string]string{"lab_name": "test"}),
+			}, updated)
+			assert.Equal(t, tt.wantDeleteAfter, extended.Status.DeleteAfter)
+			assert.Equal(t, tt.wantDeleteAfter, stored.Status.DeleteAfter)
+			assert.Equal(t, want["delete_after"], stored.ObjectMeta.Labels["delete_after"])
+			assert.Equal(t, tt.wantDeleteAfter, stored.Status.Servers[0].Status.DeleteAfter)
+			if assert.Len(t, stored.Status.Extensions, 1) {
+				assert.Equal(t, "alice", stored.Status.Extensions[0].By)
+				assert.Equal(t, tt.wantDeleteAfter, stored.Status.Extensions[0].DeleteAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/kubeconfig"
+)
+
+// remoteKubeconfig is the kubeconfig K3s writes on the control plane
+const remoteKubeconfig = "/etc/rancher/k3s/k3s.yaml"
+
+// KubeconfigOpts are the options for Kubeconfig
+type KubeconfigOpts struct {
+	Refresh bool // fetch the kubeconfig even if it exists
+	UseIP   bool // point the server URL to the IP even if the server has a DNS name
+}
+
+// Kubeconfig returns the path of the lab kubeconfig. If it's missing,
+// it's fetched from the control plane over SSH. The server URL is set to
+// the public DNS name of the control plane or to its IP.
+func (m *ManagerSvc) Kubeconfig(ctx context.Context, labName string, opts KubeconfigOpts) (string, error) {
+	path, err := KubeconfigPath(labName)
+	if err != nil {
+		return "", err
+	}
+	lab, err := m.Get(ctx, labName)
+	if err != nil {
+		return "", err
+	}
+	nodes, err := m.labNodes(lab)
+	if err != nil {
+		return "", err
+	}
+	var controlPlane *Node
+	for _, node := range nodes {
+		if node.Role == RoleControlPlane {
+			controlPlane = node
+			break
+		}
+	}
+	if controlPlane == nil {
+		return "", fmt.Errorf("lab %s has no control plane node", labName)
+	}
+
+	var config *kubeconfig.Config
+	_, err = os.Stat(path)
+	fetch := opts.Refresh || errors.Is(err, os.ErrNotExist)
+	if fetch {
+		var stdout, stderr bytes.Buffer
+		if err := execOnNode(ctx, controlPlane, "sudo cat "+remoteKubeconfig, &stdout, &stderr); err != nil {
+			return "", fmt.Errorf("failed to fetch the kubeconfig from %s: %w: %s", controlPlane.Name, err, strings.TrimSpace(stderr.String()))
+		}
+		if config, err = kubeconfig.Parse(stdout.Bytes()); err != nil {
+			return "", err
+		}
+		if len(config.Clusters) == 0 {
+			return "", fmt.Errorf("the kubeconfig on %s has no clusters, is the lab installed?", controlPlane.Name)
+		}
+	} else if config, err = kubeconfig.Load(path); err != nil {
+		return "", err
+	}
+
+	changed, err := config.SetServerHost(m.serverHost(lab, controlPlane, opts.UseIP))
+	if err != nil {
+		return "", err
+	}
+	if changed || fetch {
+		if err := config.Save(path); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// serverHost returns the public address of the control plane for the Kubernetes API
+func (m *ManagerSvc) serverHost(lab *types.Lab, controlPlane *Node, useIP bool) string {
+	if useIP || !m.Provider.Capabilities().NeedsPublicDNS {
+		return controlPlane.Host
+	}
+	for _, server := range lab.Status.Servers {
+		if server.ObjectMeta.Name == controlPlane.Name && server.Status.PublicNet.FQDN != "" {
+			return server.Status.PublicNet.FQDN
+		}
+	}
+	return controlPlane.Host
+}
+
+// KubeContextName is the name of the lab context merged into the user kubeconfig
+func KubeContextName(labName string) string {
+	return "storctl-" + labName
+}
+
+// MergeKubeconfig merges the lab kubeconfig into the user kubeconfig at path as the named context
+func MergeKubeconfig(labKubeconfig, path, contextName string) error {
+	src, err := kubeconfig.Load(labKubeconfig)
+	if err != nil {
+		return err
+	}
+	dst, err := kubeconfig.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := dst.Merge(src, contextName); err != nil {
+		return fmt.Errorf("failed to merge %s: %w", labKubeconfig, err)
+	}
+	return dst.Save(path)
+}
+
+// RemoveKubeContext removes the named context from the user kubeconfig at path.
+// It returns false if the kubeconfig has no such context.
+func RemoveKubeContext(path, contextName string) (bool, error) {
+	config, err := kubeconfig.Load(path)
+	if err != nil {
+		return false, err
+	}
+	if !config.Remove(contextName) {
+		return false, nil
+	}
+	return true, config.Save(path)
+}
@@ -0,0 +1,99 @@
+package lab
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/kubeconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// k3sClient returns the K3s kubeconfig and counts the fetches
+type k3sClient struct {
+	fakeNodeClient
+	fetches *int
+}
+
+func (c *k3sClient) Run(cmd string, stdout, stderr io.Writer) error {
+	if cmd != "sudo cat "+remoteKubeconfig {
+		return fmt.Errorf("unexpected command %s", cmd)
+	}
+	*c.fetches++
+	fmt.Fprint(stdout, `apiVersion: v1
+kind: Config
+clusters:
+- name: default
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: default
+  context:
+    cluster: default
+    user: default
+current-context: default
+users:
+- name: default
+  user:
+    token: secret
+`)
+	return nil
+}
+
+func TestKubeconfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fetches := 0
+	original := newNodeClient
+	defer func() { newNodeClient = original }()
+	newNodeClient = func(node *Node) nodeClient { return &k3sClient{fakeNodeClient{node: node}, &fetches} }
+
+	provider := &mock.MockProvider{CapabilitiesFunc: testCapabilities("hetzner")}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	for i, name := range []string{"test-cp", "test-node-01"} {
+		server := testServer(name, "cx22")
+		server.Status.PublicNet = &types.PublicNet{FQDN: name + ".test.example.com", IPv4: &struct {
+			IP string `json:"ip"`
+		}{IP: fmt.Sprintf("10.0.0.%d", i+1)}}
+		lab.Status.Servers = append(lab.Status.Servers, server)
+	}
+	assert.NoError(t, m.Storage.Save(lab))
+
+	path, err := m.Kubeconfig(context.Background(), "test", KubeconfigOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+	config, err := kubeconfig.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://test-cp.test.example.com:6443", config.Clusters[0].Cluster["server"])
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the existing kubeconfig is only rewritten
+	_, err = m.Kubeconfig(context.Background(), "test", KubeconfigOpts{UseIP: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+	config, err = kubeconfig.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://10.0.0.1:6443", config.Clusters[0].Cluster["server"])
+
+	_, err = m.Kubeconfig(context.Background(), "test", KubeconfigOpts{Refresh: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+
+	// merge into the user kubeconfig and remove on lab deletion
+	userKubeconfig, err := kubeconfig.DefaultPath()
+	assert.NoError(t, err)
+	assert.NoError(t, MergeKubeconfig(path, userKubeconfig, KubeContextName("test")))
+	config, err = kubeconfig.Load(userKubeconfig)
+	assert.NoError(t, err)
+	assert.Equal(t, "storctl-test", config.Contexts[0].Name)
+	removed, err := RemoveKubeContext(userKubeconfig, "storctl-test")
+	assert.NoError(t, err)
+	assert.True(t, removed)
+}
@@ -1,15 +1,18 @@
 // Package lab contains the lab manager for the storctl tool.
 // It includes the functions to create, get, list, and delete labs.
 // It also includes the functions to sync labs from the provider and create an ansible inventory file.
-// Each lab is stored in the local storage and can be retrieved later.
+// Each lab is stored in the lab storage, a local bbolt file or a shared S3 bucket, and can be retrieved later.
 // The lab manager also includes the functions to create an ansible inventory file and run an ansible playbook.
 package lab
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pavelanni/storctl/internal/config"
@@ -18,94 +21,93 @@ import (
 	"github.com/pavelanni/storctl/internal/provider/options"
 	"github.com/pavelanni/storctl/internal/ssh"
 	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/parallel"
 	"github.com/pavelanni/storctl/internal/util/serverchecker"
-	"go.etcd.io/bbolt"
+	"golang.org/x/time/rate"
 )
 
 type Manager interface {
-	Create(lab *types.Lab) error
-	Get(labName string) (*types.Lab, error)
+	Create(ctx context.Context, lab *types.Lab, opts CreateOpts) error
+	Apply(ctx context.Context, lab *types.Lab) (*Plan, error)
+	Get(ctx context.Context, labName string) (*types.Lab, error)
 	List() ([]*types.Lab, error)
-	Delete(labName string, force bool) error
-	SyncLabs() error
+	Delete(ctx context.Context, labName string, force bool) error
+	Extend(ctx context.Context, labName string, opts ExtendOpts) (*types.Lab, error)
+	Describe(ctx context.Context, labName string, opts DescribeOpts) (*Description, error)
+	Scale(ctx context.Context, labName string, opts ScaleOpts) (*ScaleResult, error)
+	Kubeconfig(ctx context.Context, labName string, opts KubeconfigOpts) (string, error)
+	SyncLabs(ctx context.Context, opts SyncOpts) (*SyncReport, error)
 	CreateAnsibleInventoryFile(lab *types.Lab) error
-	RunAnsiblePlaybook(lab *types.Lab) error
+	RunAnsiblePlaybook(ctx context.Context, lab *types.Lab) error
 }
 
 type ManagerSvc struct {
-	Provider   provider.CloudProvider
-	SshManager *ssh.Manager
-	Storage    *Storage
-	Logger     *slog.Logger
-}
-
-type Storage struct {
-	db        *bbolt.DB
-	labBucket []byte
+	Provider    provider.CloudProvider
+	SshManager  *ssh.Manager
+	Storage     Storage
+	Logger      *slog.Logger
+	Concurrency int           // servers or volumes created in parallel
+	Limiter     *rate.Limiter // provider API rate limit, nil means no limit
 }
 
 var DefaultManager *ManagerSvc
 
 var _ Manager = (*ManagerSvc)(nil)
 
-func NewBboltDB(path string) (*bbolt.DB, error) {
-	db, err := bbolt.Open(path, 0600, nil)
+func NewManager(provider provider.CloudProvider, cfg *config.Config) (*ManagerSvc, error) {
+	storage, err := NewLabStorage(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open bbolt db: %w", err)
+		return nil, fmt.Errorf("failed to create lab storage: %w", err)
 	}
-	return db, nil
+	return NewManagerWithStorage(provider, cfg, storage), nil
 }
 
-func NewLabStorage(cfg *config.Config) (*Storage, error) {
-	db, err := NewBboltDB(cfg.Storage.Path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open bbolt from file %s: %w", cfg.Storage.Path, err)
-	}
-
-	// Create bucket if it doesn't exist
-	err = db.Update(func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(cfg.Storage.Bucket))
-		return err
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create bucket %s: %w", cfg.Storage.Bucket, err)
+// NewManagerWithStorage returns a lab manager that uses an open lab storage,
+// so the managers of several providers can share it
+func NewManagerWithStorage(provider provider.CloudProvider, cfg *config.Config, storage Storage) *ManagerSvc {
+	concurrency := cfg.ProviderConcurrency(provider.Name())
+	return &ManagerSvc{
+		Storage:     storage,
+		Provider:    provider,
+		SshManager:  ssh.NewManager(cfg),
+		Logger:      logger.Get(),
+		Concurrency: concurrency,
+		Limiter:     parallel.NewLimiter(cfg.ProviderRateLimit(provider.Name()), concurrency),
 	}
-
-	return &Storage{
-		db:        db,
-		labBucket: []byte(cfg.Storage.Bucket),
-	}, nil
 }
 
-func NewManager(provider provider.CloudProvider, cfg *config.Config) (*ManagerSvc, error) {
-	sshManager := ssh.NewManager(cfg)
-	storage, err := NewLabStorage(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create lab storage: %w", err)
-	}
-	return &ManagerSvc{
-		Storage:    storage,
-		Provider:   provider,
-		SshManager: sshManager,
-		Logger:     logger.Get(),
-	}, nil
+// CreateOpts are the options for creating a lab
+type CreateOpts struct {
+	// NoRollback keeps the resources created before a failure
+	// and saves the lab in the Failed state instead of deleting them
+	NoRollback bool
+	// Resume continues the creation of a Failed lab from its first unfinished phase.
+	// Resources created before aren't rolled back on failure.
+	Resume bool
 }
 
 // Create creates a new lab
 // It creates the lab in the cloud and stores the lab in the local storage
-// It creates servers, volumes, and ssh keys
-func (m *ManagerSvc) Create(lab *types.Lab) error {
-	switch lab.Spec.Provider {
-	case "lima":
-		err := m.createLabLima(lab)
-		if err != nil {
-			return fmt.Errorf("failed to create lab: %w", err)
+// It creates servers, volumes, and ssh keys in phases and saves the lab after each phase
+// If a phase that creates resources fails, the created resources are deleted in reverse order
+// If any other phase fails, the lab is left in the Failed state and can be resumed
+// Canceling ctx stops the creation and runs the same cleanup as a failure
+func (m *ManagerSvc) Create(ctx context.Context, lab *types.Lab, opts CreateOpts) error {
+	for _, phase := range m.createPhases(lab) {
+		err := m.RunPhase(lab, phase.name, func() error { return phase.run(ctx, lab) })
+		if err == nil {
+			continue
 		}
-	case "hetzner":
-		err := m.createLabHetzner(lab)
-		if err != nil {
-			return fmt.Errorf("failed to create lab: %w", err)
+		err = fmt.Errorf("failed to create lab: %w", err)
+		// roll back and save the lab even if the creation was canceled
+		cleanupCtx := context.WithoutCancel(ctx)
+		if phase.resources && !opts.Resume {
+			return m.handleCreateFailure(cleanupCtx, lab, err, opts)
+		}
+		if err := m.saveFailed(cleanupCtx, lab, err); err != nil {
+			m.Logger.Warn("failed to save lab", "lab", lab.ObjectMeta.Name, "error", err)
 		}
+		return fmt.Errorf("%w\nresume with 'storctl create lab %s --resume'", err, lab.ObjectMeta.Name)
 	}
 	m.Logger.Debug("created lab", "lab", lab)
 	m.Logger.Debug("lab servers:")
@@ -116,6 +118,7 @@ func (m *ManagerSvc) Create(lab *types.Lab) error {
 	for _, volume := range lab.Status.Volumes {
 		m.Logger.Debug("volume", "volume", volume)
 	}
+	setStatusFromLabels(lab)
 	err := m.Storage.Save(lab)
 	if err != nil {
 		return fmt.Errorf("failed to save lab: %w", err)
@@ -123,7 +126,9 @@ func (m *ManagerSvc) Create(lab *types.Lab) error {
 	return nil
 }
 
-func (m *ManagerSvc) Get(labName string) (*types.Lab, error) {
+// Get returns the stored lab or, if it isn't stored, the lab found on the provider and saves it.
+// Other storage errors are returned.
+func (m *ManagerSvc) Get(ctx context.Context, labName string) (*types.Lab, error) {
 	if m == nil {
 		return nil, fmt.Errorf("manager is nil")
 	}
@@ -134,135 +139,53 @@ func (m *ManagerSvc) Get(labName string) (*types.Lab, error) {
 	if err == nil {
 		return lab, nil
 	}
-	lab, err = m.syncLabFromProvider(labName)
+	// a record that can't be read isn't replaced with the lab found on the provider
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	lab, err = m.syncLabFromProvider(ctx, labName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sync lab from provider: %w", err)
 	}
 	return lab, nil
 }
 
-func (m *ManagerSvc) List() ([]*types.Lab, error) {
-	var labs []*types.Lab
-
-	err := m.Storage.db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(m.Storage.labBucket)
-		if b == nil {
-			return fmt.Errorf("labs bucket not found in database")
-		}
-
-		return b.ForEach(func(k, v []byte) error {
-			var lab types.Lab
-			if err := json.Unmarshal(v, &lab); err != nil {
-				return fmt.Errorf("failed to unmarshal lab: %w", err)
-			}
-			labs = append(labs, &lab)
-			return nil
-		})
-	})
-
-	return labs, err
-}
-
-func (m *ManagerSvc) SyncLabs() error {
-	labsMap := make(map[string]*types.Lab)
-	allServers, err := m.Provider.AllServers()
-	if err != nil {
-		return fmt.Errorf("failed to get all servers: %w", err)
+// Lookup returns the stored lab or, if it isn't stored, the lab found on the provider without saving it.
+// The error wraps ErrNotFound if the lab is neither stored nor has resources on the provider.
+func (m *ManagerSvc) Lookup(ctx context.Context, labName string) (*types.Lab, error) {
+	lab, err := m.Storage.Get(labName)
+	if err == nil {
+		return lab, nil
 	}
-	// collect unique lab names
-	for _, server := range allServers {
-		if server.Labels["lab_name"] != "" {
-			labsMap[server.Labels["lab_name"]] = &types.Lab{}
-		}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
 	}
-	for labName := range labsMap {
-		lab, err := m.getLabFromProvider(labName)
-		if err != nil {
-			return fmt.Errorf("failed to get lab from provider: %w", err)
-		}
-		labsMap[labName] = lab
+	lab, err = m.getLabFromProvider(ctx, labName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab from provider: %w", err)
 	}
-	return m.Storage.db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(m.Storage.labBucket)
-
-		// Clear existing data
-		if err := b.ForEach(func(k, v []byte) error {
-			return b.Delete(k)
-		}); err != nil {
-			return fmt.Errorf("failed to clear existing data: %w", err)
-		}
+	if len(lab.Status.Servers) == 0 && len(lab.Status.Volumes) == 0 {
+		return nil, fmt.Errorf("lab %s %w", labName, ErrNotFound)
+	}
+	return lab, nil
+}
 
-		// Store new data
-		for labName, lab := range labsMap {
-			data, err := json.Marshal(lab)
-			if err != nil {
-				return fmt.Errorf("failed to marshal lab: %w", err)
-			}
-			if err := b.Put([]byte(labName), data); err != nil {
-				return fmt.Errorf("failed to put lab: %w", err)
-			}
-		}
-		return nil
-	})
+func (m *ManagerSvc) List() ([]*types.Lab, error) {
+	return m.Storage.List()
 }
 
-func (m *ManagerSvc) Delete(labName string, force bool) error {
-	var err error
-	switch m.Provider.Name() {
-	case "lima":
-		err = m.deleteLabLima(labName, force)
-	case "hetzner":
-		err = m.deleteLabHetzner(labName, force)
-	}
-	if err != nil {
+func (m *ManagerSvc) Delete(ctx context.Context, labName string, force bool) error {
+	if err := m.deleteLab(ctx, labName, force); err != nil {
 		return fmt.Errorf("failed to delete lab: %w", err)
 	}
-	err = m.Storage.Delete(labName)
-	if err != nil {
+	if err := m.Storage.Delete(labName); err != nil {
 		return fmt.Errorf("failed to delete lab from storage: %w", err)
 	}
 	return nil
 }
 
-func (s *Storage) Get(labName string) (*types.Lab, error) {
-	var lab *types.Lab
-
-	err := s.db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(s.labBucket)
-		data := b.Get([]byte(labName))
-		if data == nil {
-			return fmt.Errorf("lab %s not found", labName)
-		}
-
-		lab = &types.Lab{}
-		if err := json.Unmarshal(data, lab); err != nil {
-			return fmt.Errorf("failed to unmarshal lab: %w", err)
-		}
-		return nil
-	})
-
-	return lab, err
-}
-
-func (s *Storage) Save(lab *types.Lab) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(s.labBucket)
-		data, err := json.Marshal(lab)
-		if err != nil {
-			return fmt.Errorf("failed to marshal lab: %w", err)
-		}
-		return b.Put([]byte(lab.Name), data)
-	})
-}
-
-func (s *Storage) Delete(labName string) error {
-	return s.db.Update(func(tx *bbolt.Tx) error {
-		return tx.Bucket(s.labBucket).Delete([]byte(labName))
-	})
-}
-
-func (m *ManagerSvc) syncLabFromProvider(labName string) (*types.Lab, error) {
-	lab, err := m.getLabFromProvider(labName)
+func (m *ManagerSvc) syncLabFromProvider(ctx context.Context, labName string) (*types.Lab, error) {
+	lab, err := m.getLabFromProvider(ctx, labName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lab from provider: %w", err)
 	}
@@ -272,7 +195,7 @@ func (m *ManagerSvc) syncLabFromProvider(labName string) (*types.Lab, error) {
 	return lab, nil
 }
 
-func (m *ManagerSvc) getLabFromProvider(labName string) (*types.Lab, error) {
+func (m *ManagerSvc) getLabFromProvider(ctx context.Context, labName string) (*types.Lab, error) {
 	lab := &types.Lab{
 		TypeMeta: types.TypeMeta{
 			APIVersion: "v1",
@@ -283,7 +206,7 @@ func (m *ManagerSvc) getLabFromProvider(labName string) (*types.Lab, error) {
 		},
 	}
 
-	servers, err := m.Provider.ListServers(options.ServerListOpts{
+	servers, err := m.Provider.ListServers(ctx, options.ServerListOpts{
 		ListOpts: options.ListOpts{
 			LabelSelector: "lab_name=" + labName,
 		},
@@ -291,7 +214,7 @@ func (m *ManagerSvc) getLabFromProvider(labName string) (*types.Lab, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to list servers: %w", err)
 	}
-	volumes, err := m.Provider.ListVolumes(options.VolumeListOpts{
+	volumes, err := m.Provider.ListVolumes(ctx, options.VolumeListOpts{
 		ListOpts: options.ListOpts{
 			LabelSelector: "lab_name=" + labName,
 		},
@@ -314,159 +237,198 @@ func (m *ManagerSvc) getLabFromProvider(labName string) (*types.Lab, error) {
 	return lab, nil
 }
 
-func (m *ManagerSvc) createLabLima(lab *types.Lab) error {
-	volumes := lab.Spec.Volumes
-	volumesStatus := make([]*types.Volume, len(volumes))
-	for i, volume := range volumes {
-		fmt.Printf("Creating volume %s of size %dGB...\n", strings.Join([]string{lab.ObjectMeta.Name, volume.Name}, "-"), volume.Size)
-		volume, err := m.Provider.CreateVolume(options.VolumeCreateOpts{
-			Name:       strings.Join([]string{lab.ObjectMeta.Name, volume.Name}, "-"),
-			Size:       volume.Size,
-			ServerName: volume.Server,
-			Automount:  volume.Automount,
-			Format:     volume.Format,
-			Labels:     lab.ObjectMeta.Labels,
-		})
+// createPhases returns the provider phases of lab creation in the order they run.
+// If the provider attaches disks when the VMs are created, volumes go first.
+// Otherwise servers get the lab admin key if the provider manages SSH keys,
+// and must pass the readiness check, if the provider needs it, before volumes are attached.
+func (m *ManagerSvc) createPhases(lab *types.Lab) []createPhase {
+	capabilities := m.Provider.Capabilities()
+	if !capabilities.VolumesAttachAfterBoot {
+		return []createPhase{
+			{name: types.PhaseVolumes, run: m.createVolumes, resources: true},
+			{name: types.PhaseServers, run: m.createServersWithDisks, resources: true},
+		}
+	}
+	phases := []createPhase{}
+	if capabilities.ManagesSSHKeys {
+		phases = append(phases, createPhase{name: types.PhaseKeys, run: m.createKeys, resources: true})
+	}
+	phases = append(phases, createPhase{name: types.PhaseServers, run: m.createServers, resources: true})
+	if capabilities.ReadinessCheck {
+		phases = append(phases, createPhase{name: types.PhaseReadiness, run: m.waitForServers})
+	}
+	return append(phases, createPhase{name: types.PhaseVolumes, run: m.createVolumes, resources: true})
+}
+
+// createVolumes creates the lab volumes that don't exist yet
+func (m *ManagerSvc) createVolumes(ctx context.Context, lab *types.Lab) error {
+	pending := make([]options.VolumeCreateOpts, 0, len(lab.Spec.Volumes))
+	for _, volumeSpec := range lab.Spec.Volumes {
+		opts := volumeCreateOpts(lab, volumeSpec)
+		if !isCreated(lab, "Volume", opts.Name) {
+			pending = append(pending, opts)
+		}
+	}
+	names := make([]string, len(pending))
+	for i, opts := range pending {
+		names[i] = opts.Name
+	}
+	fmt.Printf("Creating %d volumes: %s\n", len(pending), strings.Join(names, ", "))
+	volumes := make([]*types.Volume, len(pending))
+	created := make([]bool, len(pending))
+	err := m.createInParallel(ctx, "volume", names, func(i int) error {
+		volume, err := m.Provider.CreateVolume(ctx, pending[i])
 		if err != nil {
-			return fmt.Errorf("failed to create volume: %w", err)
+			return err
 		}
-		volumesStatus[i] = volume
+		volumes[i], created[i] = volume, true
 		m.Logger.Debug("created volume", "volume", volume)
+		return nil
+	})
+	for i := range pending {
+		if created[i] {
+			recordResource(lab, "Volume", pending[i].Name)
+			lab.Status.Volumes = append(lab.Status.Volumes, volumes[i])
+		}
 	}
-	lab.Status.Volumes = volumesStatus
-	m.Logger.Debug("created volumes:")
-	for _, volume := range volumesStatus {
-		m.Logger.Debug("volume", "volume", volume)
-	}
+	return err
+}
+
+// createServersWithDisks creates the lab VMs that don't exist yet with their volumes as additional disks
+func (m *ManagerSvc) createServersWithDisks(ctx context.Context, lab *types.Lab) error {
 	additionalDisks := make(map[string][]options.AdditionalDisk)
-	for _, volume := range volumes {
-		labServerName := strings.Join([]string{lab.ObjectMeta.Name, volume.Server}, "-")
+	for _, volume := range lab.Spec.Volumes {
+		labServerName := resourceName(lab.ObjectMeta.Name, volume.Server)
 		additionalDisks[labServerName] = append(additionalDisks[labServerName], options.AdditionalDisk{
-			Name:   strings.Join([]string{lab.ObjectMeta.Name, volume.Name}, "-"),
+			Name:   resourceName(lab.ObjectMeta.Name, volume.Name),
 			Format: false,
 		})
 	}
-	specServers := lab.Spec.Servers
-	serversStatus := make([]*types.Server, len(specServers))
-	for i, serverSpec := range specServers {
-		s := &types.Server{
-			TypeMeta: types.TypeMeta{
-				Kind:       "Server",
-				APIVersion: "v1",
-			},
-			ObjectMeta: types.ObjectMeta{
-				Name:   strings.Join([]string{lab.ObjectMeta.Name, serverSpec.Name}, "-"),
-				Labels: lab.ObjectMeta.Labels,
-			},
-			Spec: types.ServerSpec{
-				Location:   lab.Spec.Location,
-				Provider:   lab.Spec.Provider,
-				ServerType: serverSpec.ServerType,
-				Image:      serverSpec.Image,
-			},
-		}
-		serverAdditionalDisks, ok := additionalDisks[s.ObjectMeta.Name]
-		if !ok {
-			serverAdditionalDisks = []options.AdditionalDisk{}
+	return m.createServersWith(ctx, lab, func(opts *options.ServerCreateOpts) {
+		opts.AdditionalDisks = additionalDisks[opts.Name]
+		if opts.AdditionalDisks == nil {
+			opts.AdditionalDisks = []options.AdditionalDisk{}
 		}
-		fmt.Printf("Creating server %s with additional disks: %v\n", s.ObjectMeta.Name, serverAdditionalDisks)
-		server, err := m.Provider.CreateServer(options.ServerCreateOpts{
-			Name:            s.ObjectMeta.Name,
-			Type:            s.Spec.ServerType,
-			Image:           s.Spec.Image,
-			Location:        s.Spec.Location,
-			Provider:        s.Spec.Provider,
-			Labels:          s.ObjectMeta.Labels,
-			AdditionalDisks: serverAdditionalDisks,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create server: %w", err)
-		}
-		m.Logger.Debug("created server", "server", server)
-		serversStatus[i] = server
-	}
-	lab.Status.Servers = serversStatus
-	m.Logger.Debug("created servers:")
-	for _, server := range serversStatus {
-		m.Logger.Debug("server", "server", server)
-	}
-	return nil
+	})
 }
 
-func (m *ManagerSvc) createLabHetzner(lab *types.Lab) error {
-	labAdminKeyName := strings.Join([]string{lab.ObjectMeta.Name, "admin"}, "-")
-	sshKeys := make([]*types.SSHKey, 2) // 2 keys: default admin key and lab admin key
-	sshKeys[0] = &types.SSHKey{         // default admin key is already on the cloud
-		ObjectMeta: types.ObjectMeta{
-			Name: config.DefaultAdminKeyName,
-		},
+// createKeys creates the lab admin key locally and uploads it to the cloud
+func (m *ManagerSvc) createKeys(ctx context.Context, lab *types.Lab) error {
+	labAdminKeyName := resourceName(lab.ObjectMeta.Name, "admin")
+	var labAdminPublicKey string
+	var err error
+	if isCreated(lab, "LocalSSHKey", labAdminKeyName) {
+		labAdminPublicKey, err = m.SshManager.ReadLocalPublicKey(labAdminKeyName)
+	} else {
+		fmt.Printf("Creating lab admin key %s...\n", labAdminKeyName)
+		labAdminPublicKey, err = m.SshManager.CreateLocalKeyPair(labAdminKeyName)
+		if err == nil {
+			recordResource(lab, "LocalSSHKey", labAdminKeyName)
+		}
 	}
-	fmt.Printf("Creating lab admin key %s...\n", labAdminKeyName)
-	labAdminPublicKey, err := m.SshManager.CreateLocalKeyPair(labAdminKeyName)
 	if err != nil {
 		return fmt.Errorf("failed to create lab admin key: %w", err)
 	}
-	labAdminCloudKey, err := m.Provider.CreateSSHKey(options.SSHKeyCreateOpts{
+	if isCreated(lab, "SSHKey", labAdminKeyName) {
+		return nil
+	}
+	_, err = m.Provider.CreateSSHKey(ctx, options.SSHKeyCreateOpts{
 		Name:      labAdminKeyName,
 		PublicKey: labAdminPublicKey,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create lab admin cloud key: %w", err)
 	}
-	sshKeys[1] = labAdminCloudKey
+	recordResource(lab, "SSHKey", labAdminKeyName)
+	return nil
+}
 
-	ttl := lab.Spec.TTL
-	if ttl == "" {
-		ttl = config.DefaultTTL
+// createServers creates the lab cloud servers that don't exist yet
+func (m *ManagerSvc) createServers(ctx context.Context, lab *types.Lab) error {
+	sshKeys, userData, err := m.labSSHKeys(ctx, lab)
+	if err != nil {
+		return err
 	}
-	// Create servers
-	serversString := ""
+	return m.createServersWith(ctx, lab, func(opts *options.ServerCreateOpts) {
+		opts.SSHKeys = sshKeys
+		opts.UserData = userData
+	})
+}
+
+// createServersWith creates the lab servers that don't exist yet in parallel.
+// setOpts adds the provider-specific options.
+func (m *ManagerSvc) createServersWith(ctx context.Context, lab *types.Lab, setOpts func(opts *options.ServerCreateOpts)) error {
+	pending := make([]options.ServerCreateOpts, 0, len(lab.Spec.Servers))
 	for _, serverSpec := range lab.Spec.Servers {
-		serversString += serverSpec.Name + ", "
-	}
-	fmt.Printf("Creating %d servers: %s\n", len(lab.Spec.Servers), serversString)
-	specServers := lab.Spec.Servers
-	servers := make([]*types.Server, 0)
-	for _, serverSpec := range specServers {
-		s := &types.Server{
-			TypeMeta: types.TypeMeta{
-				Kind:       "Server",
-				APIVersion: "v1",
-			},
-			ObjectMeta: types.ObjectMeta{
-				Name:   strings.Join([]string{lab.ObjectMeta.Name, serverSpec.Name}, "-"),
-				Labels: lab.ObjectMeta.Labels,
-			},
-			Spec: types.ServerSpec{
-				Location:   lab.Spec.Location,
-				Provider:   lab.Spec.Provider,
-				ServerType: serverSpec.ServerType,
-				TTL:        ttl,
-				Image:      serverSpec.Image,
-			},
+		opts := serverCreateOpts(lab, serverSpec)
+		if isCreated(lab, "Server", opts.Name) {
+			continue
 		}
-		fmt.Printf("Creating server %s...\n", s.ObjectMeta.Name)
-		result, err := m.Provider.CreateServer(options.ServerCreateOpts{
-			Name:     s.ObjectMeta.Name,
-			Type:     s.Spec.ServerType,
-			Image:    s.Spec.Image,
-			Location: s.Spec.Location,
-			Provider: s.Spec.Provider,
-			SSHKeys:  sshKeys,
-			Labels:   s.ObjectMeta.Labels,
-			UserData: fmt.Sprintf(config.DefaultCloudInitUserData, labAdminPublicKey),
-		})
+		setOpts(&opts)
+		pending = append(pending, opts)
+	}
+	names := make([]string, len(pending))
+	for i, opts := range pending {
+		names[i] = opts.Name
+	}
+	fmt.Printf("Creating %d servers: %s\n", len(pending), strings.Join(names, ", "))
+	servers := make([]*types.Server, len(pending))
+	created := make([]bool, len(pending))
+	err := m.createInParallel(ctx, "server", names, func(i int) error {
+		server, err := m.Provider.CreateServer(ctx, pending[i])
 		if err != nil {
-			return fmt.Errorf("failed to create server: %w", err)
+			return err
+		}
+		servers[i], created[i] = server, true
+		m.Logger.Debug("created server", "server", server)
+		return nil
+	})
+	for i := range pending {
+		if created[i] {
+			recordResource(lab, "Server", pending[i].Name)
+			lab.Status.Servers = append(lab.Status.Servers, servers[i])
 		}
-		servers = append(servers, result)
 	}
+	sortServers(lab)
+	return err
+}
+
+// createInParallel runs create for each named resource with the manager's concurrency and rate limit.
+// It prints the progress of each resource and returns all errors joined.
+func (m *ManagerSvc) createInParallel(ctx context.Context, kind string, names []string, create func(i int) error) error {
+	var mu sync.Mutex
+	done := 0
+	return parallel.Run(ctx, len(names), m.Concurrency, m.Limiter, func(i int) error {
+		fmt.Printf("Creating %s %s...\n", kind, names[i])
+		if err := create(i); err != nil {
+			fmt.Printf("Failed to create %s %s: %v\n", kind, names[i], err)
+			return fmt.Errorf("failed to create %s %s: %w", kind, names[i], err)
+		}
+		mu.Lock()
+		done++
+		fmt.Printf("Created %s %s (%d/%d)\n", kind, names[i], done, len(names))
+		mu.Unlock()
+		return nil
+	})
+}
+
+// sortServers orders the lab servers as in the lab spec, so the control plane comes first
+func sortServers(lab *types.Lab) {
+	order := make(map[string]int)
+	for i, serverSpec := range lab.Spec.Servers {
+		order[resourceName(lab.ObjectMeta.Name, serverSpec.Name)] = i
+	}
+	sort.SliceStable(lab.Status.Servers, func(i, j int) bool {
+		return order[lab.Status.Servers[i].ObjectMeta.Name] < order[lab.Status.Servers[j].ObjectMeta.Name]
+	})
+}
 
-	// Wait for servers to be ready
+// waitForServers waits until cloud-init finishes on all lab servers
+func (m *ManagerSvc) waitForServers(ctx context.Context, lab *types.Lab) error {
 	fmt.Println("Waiting for servers to be ready...")
 	timeout := 30 * time.Minute
 	attempts := 20
-	results, err := serverchecker.CheckServers(servers, m.Logger, timeout, attempts)
+	results, err := serverchecker.CheckServers(ctx, lab.Status.Servers, m.Logger, timeout, attempts)
 	if err != nil {
 		return fmt.Errorf("failed to check servers: %w", err)
 	}
@@ -477,118 +439,71 @@ func (m *ManagerSvc) createLabHetzner(lab *types.Lab) error {
 		}
 	}
 	fmt.Println("Servers are ready")
-	// Create volumes
-	volumesString := ""
-	for _, volumeSpec := range lab.Spec.Volumes {
-		volumesString += volumeSpec.Name + ", "
-	}
-	fmt.Printf("Creating %d volumes: %s\n", len(lab.Spec.Volumes), volumesString)
-	volumes := lab.Spec.Volumes
-	for _, volumeSpec := range volumes {
-		if !volumeSpec.Automount { // if not specified, default to false
-			volumeSpec.Automount = config.DefaultVolumeAutomount
-		}
-		if volumeSpec.Format == "" { // if not specified, default to xfs
-			volumeSpec.Format = config.DefaultVolumeFormat
-		}
-		v := &types.Volume{
-			TypeMeta: types.TypeMeta{
-				Kind:       "Volume",
-				APIVersion: "v1",
-			},
-			ObjectMeta: types.ObjectMeta{
-				Name:   strings.Join([]string{lab.ObjectMeta.Name, volumeSpec.Name}, "-"),
-				Labels: lab.ObjectMeta.Labels,
-			},
-			Spec: types.VolumeSpec{
-				Size:       volumeSpec.Size,
-				ServerName: strings.Join([]string{lab.ObjectMeta.Name, volumeSpec.Server}, "-"),
-				Automount:  volumeSpec.Automount,
-				Format:     volumeSpec.Format,
-			},
-		}
-		fmt.Printf("Creating volume %s...\n", v.ObjectMeta.Name)
-		_, err := m.Provider.CreateVolume(options.VolumeCreateOpts{
-			Name:       v.ObjectMeta.Name,
-			Size:       v.Spec.Size,
-			ServerName: v.Spec.ServerName,
-			Automount:  v.Spec.Automount,
-			Format:     v.Spec.Format,
-			Labels:     v.ObjectMeta.Labels,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create volume: %w", err)
-		}
-	}
-
 	return nil
 }
 
-func (m *ManagerSvc) deleteLabLima(labName string, force bool) error {
-	lab, err := m.Get(labName)
+// deleteLab deletes the lab resources in the order the provider needs.
+// Volumes attached to running servers are detached and deleted first,
+// disks attached when the VMs were created are deleted after the VMs.
+func (m *ManagerSvc) deleteLab(ctx context.Context, labName string, force bool) error {
+	lab, err := m.Get(ctx, labName)
 	if err != nil {
 		return fmt.Errorf("failed to get lab: %w", err)
 	}
-	// in Lima, delete servers first
+	capabilities := m.Provider.Capabilities()
+	// Check if the lab is ready for deletion
+	if capabilities.SupportsTTLLabels && !lab.Status.DeleteAfter.Before(time.Now().UTC()) && !force {
+		return fmt.Errorf("lab %s is not ready for deletion", labName)
+	}
+	if capabilities.VolumesAttachAfterBoot {
+		if err := m.deleteVolumes(ctx, lab, force); err != nil {
+			return err
+		}
+	}
 	for _, server := range lab.Status.Servers {
 		// delete server's ssh keys
 		for _, sshKeyName := range server.Spec.SSHKeyNames {
 			m.Logger.Info("deleting ssh key", "key", sshKeyName)
-			status := m.Provider.DeleteSSHKey(sshKeyName, force)
+			status := m.Provider.DeleteSSHKey(ctx, sshKeyName, force)
 			if status.Error != nil {
 				return fmt.Errorf("failed to delete ssh key %s: %w", sshKeyName, status.Error)
 			}
 		}
 		m.Logger.Info("deleting server", "server", server.ObjectMeta.Name)
-		status := m.Provider.DeleteServer(server.ObjectMeta.Name, force)
+		status := m.Provider.DeleteServer(ctx, server.ObjectMeta.Name, force)
 		if status.Error != nil {
 			return fmt.Errorf("failed to delete server %s: %w", server.ObjectMeta.Name, status.Error)
 		}
 	}
-
-	// delete volumes after servers
-	for _, volume := range lab.Status.Volumes {
-		m.Logger.Info("deleting volume", "volume", volume.ObjectMeta.Name)
-		status := m.Provider.DeleteVolume(volume.ObjectMeta.Name, force)
+	if !capabilities.VolumesAttachAfterBoot {
+		if err := m.deleteVolumes(ctx, lab, force); err != nil {
+			return err
+		}
+	}
+	if !capabilities.ManagesSSHKeys {
+		return nil
+	}
+	// delete the lab SSH keys created with the lab
+	for _, resource := range lab.Status.Resources {
+		if resource.Kind != "SSHKey" {
+			continue
+		}
+		m.Logger.Info("deleting ssh key", "key", resource.Name)
+		status := m.Provider.DeleteSSHKey(ctx, resource.Name, force)
 		if status.Error != nil {
-			return fmt.Errorf("failed to delete volume %s: %w", volume.ObjectMeta.Name, status.Error)
+			return fmt.Errorf("failed to delete ssh key %s: %w", resource.Name, status.Error)
 		}
 	}
 	return nil
 }
 
-func (m *ManagerSvc) deleteLabHetzner(labName string, force bool) error {
-	lab, err := m.Get(labName)
-	if err != nil {
-		return fmt.Errorf("failed to get lab: %w", err)
-	}
-	// Check if the lab is ready for deletion
-	if !lab.Status.DeleteAfter.Before(time.Now().UTC()) && !force {
-		return fmt.Errorf("lab %s is not ready for deletion", labName)
-	}
-	// delete volumes first
+func (m *ManagerSvc) deleteVolumes(ctx context.Context, lab *types.Lab, force bool) error {
 	for _, volume := range lab.Status.Volumes {
 		m.Logger.Info("deleting volume", "volume", volume.ObjectMeta.Name)
-		status := m.Provider.DeleteVolume(volume.ObjectMeta.Name, force)
+		status := m.Provider.DeleteVolume(ctx, volume.ObjectMeta.Name, force)
 		if status.Error != nil {
 			return fmt.Errorf("failed to delete volume %s: %w", volume.ObjectMeta.Name, status.Error)
 		}
 	}
-	// delete servers
-	for _, server := range lab.Status.Servers {
-		// delete server's ssh keys
-		for _, sshKeyName := range server.Spec.SSHKeyNames {
-			m.Logger.Info("deleting ssh key", "key", sshKeyName)
-			status := m.Provider.DeleteSSHKey(sshKeyName, force)
-			if status.Error != nil {
-				return fmt.Errorf("failed to delete ssh key %s: %w", sshKeyName, status.Error)
-			}
-		}
-		m.Logger.Info("deleting server", "server", server.ObjectMeta.Name)
-		status := m.Provider.DeleteServer(server.ObjectMeta.Name, force)
-		if status.Error != nil {
-			return fmt.Errorf("failed to delete server %s: %w", server.ObjectMeta.Name, status.Error)
-		}
-	}
 	return nil
 }
@@ -0,0 +1,252 @@
+package lab
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// ansiblePlaybookCommand runs the playbooks; replaced in tests
+var ansiblePlaybookCommand = "ansible-playbook"
+
+const (
+	// maxPlaybookRuns is how many playbook runs the lab record keeps; the log files are kept
+	maxPlaybookRuns = 20
+	// logTimeFormat starts the log file names, so they sort by time
+	logTimeFormat = "20060102-150405"
+	// logTrailerPrefix starts the lines storctl adds to the log after the playbook output
+	logTrailerPrefix = "storctl: "
+	logFailedTask    = logTrailerPrefix + "failed task: "
+	logFinished      = logTrailerPrefix + "playbook finished"
+)
+
+// LogsDir returns the directory of the lab playbook logs
+func LogsDir(labName string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, config.DefaultConfigDir, config.DefaultLogsDir, labName), nil
+}
+
+// runAnsiblePlaybook runs the playbook, writing its output to the terminal and to a log file,
+// and records the run in the lab
+func (m *ManagerSvc) runAnsiblePlaybook(ctx context.Context, lab *types.Lab, playbookFile string, args []string) error {
+	logsDir, err := LogsDir(lab.ObjectMeta.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		return fmt.Errorf("error creating logs directory: %w", err)
+	}
+	playbook := strings.TrimSuffix(filepath.Base(playbookFile), filepath.Ext(playbookFile))
+	run := &types.PlaybookRun{Playbook: playbookFile, Started: time.Now()}
+	run.LogPath = filepath.Join(logsDir, run.Started.Format(logTimeFormat)+"-"+playbook+".log")
+	logFile, err := os.OpenFile(run.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating log file: %w", err)
+	}
+	defer logFile.Close()
+	lab.Status.PlaybookRuns = append(lab.Status.PlaybookRuns, run)
+	if len(lab.Status.PlaybookRuns) > maxPlaybookRuns {
+		lab.Status.PlaybookRuns = lab.Status.PlaybookRuns[len(lab.Status.PlaybookRuns)-maxPlaybookRuns:]
+	}
+	if err := m.Storage.Save(lab); err != nil {
+		return fmt.Errorf("error saving lab %s: %w", lab.ObjectMeta.Name, err)
+	}
+
+	failures := &taskFailures{}
+	cmd := exec.CommandContext(ctx, ansiblePlaybookCommand, args...)
+	cmd.Stdout = io.MultiWriter(os.Stdout, logFile, failures)
+	cmd.Stderr = io.MultiWriter(os.Stderr, logFile)
+	cmd.Env = append(os.Environ(), "ANSIBLE_STDOUT_CALLBACK=debug")
+	runErr := cmd.Run()
+	failures.Flush()
+
+	run.Finished = time.Now()
+	run.Duration = run.Finished.Sub(run.Started).Round(time.Second)
+	run.FailedTasks = failures.tasks
+	var exitErr *exec.ExitError
+	switch {
+	case runErr == nil:
+		run.ExitCode = 0
+	case errors.As(runErr, &exitErr):
+		run.ExitCode = exitErr.ExitCode()
+	default:
+		run.ExitCode = -1
+	}
+	for _, task := range run.FailedTasks {
+		fmt.Fprintf(logFile, "%s%s\n", logFailedTask, task)
+	}
+	fmt.Fprintf(logFile, "%s with exit code %d in %s\n", logFinished, run.ExitCode, run.Duration)
+	if err := m.Storage.Save(lab); err != nil {
+		return fmt.Errorf("error saving lab %s: %w", lab.ObjectMeta.Name, err)
+	}
+	if runErr != nil {
+		return fmt.Errorf("playbook %s failed: %w, see %s", playbook, runErr, run.LogPath)
+	}
+	return nil
+}
+
+// taskFailures collects the failed tasks from the Ansible output
+type taskFailures struct {
+	buf   bytes.Buffer
+	task  string
+	tasks []string
+}
+
+func (f *taskFailures) Write(data []byte) (int, error) {
+	f.buf.Write(data)
+	for {
+		line, err := f.buf.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			f.buf.Reset()
+			f.buf.WriteString(line)
+			return len(data), nil
+		}
+		f.scan(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Flush scans the incomplete last line
+func (f *taskFailures) Flush() {
+	if f.buf.Len() > 0 {
+		f.scan(f.buf.String())
+		f.buf.Reset()
+	}
+}
+
+func (f *taskFailures) scan(line string) {
+	switch {
+	case strings.HasPrefix(line, "TASK ["):
+		f.task = strings.TrimPrefix(line, "TASK [")
+		if i := strings.LastIndex(f.task, "]"); i >= 0 {
+			f.task = f.task[:i]
+		}
+	case strings.HasPrefix(line, "fatal: [") || strings.HasPrefix(line, "failed: ["):
+		host := line[strings.Index(line, "[")+1:]
+		if i := strings.Index(host, "]"); i >= 0 {
+			host = host[:i]
+		}
+		failed := fmt.Sprintf("%s (%s)", f.task, host)
+		if len(f.tasks) == 0 || f.tasks[len(f.tasks)-1] != failed {
+			f.tasks = append(f.tasks, failed)
+		}
+	case strings.HasPrefix(line, "...ignoring") && len(f.tasks) > 0:
+		// the failure of a task with ignore_errors
+		f.tasks = f.tasks[:len(f.tasks)-1]
+	}
+}
+
+// LogRun is a playbook run found in the lab logs directory
+type LogRun struct {
+	Number      int // 1 is the oldest run
+	Path        string
+	Playbook    string
+	Started     time.Time
+	Finished    bool
+	Summary     string // how the run finished
+	FailedTasks []string
+}
+
+// LogRuns returns the playbook runs of the lab from its log files, oldest first.
+// It doesn't need the lab record, so it works while a playbook is running.
+func LogRuns(labName string) ([]*LogRun, error) {
+	logsDir, err := LogsDir(labName)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(logsDir, "*.log"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing logs in %s: %w", logsDir, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no playbook logs for lab %s in %s", labName, logsDir)
+	}
+	sort.Strings(paths)
+	runs := make([]*LogRun, 0, len(paths))
+	for i, path := range paths {
+		run := &LogRun{Number: i + 1, Path: path}
+		name := strings.TrimSuffix(filepath.Base(path), ".log")
+		if len(name) > len(logTimeFormat) {
+			run.Started, _ = time.ParseInLocation(logTimeFormat, name[:len(logTimeFormat)], time.Local)
+			run.Playbook = strings.TrimPrefix(name[len(logTimeFormat):], "-")
+		}
+		if err := readTrailer(run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// readTrailer reads how the run finished from the lines storctl added to the log
+func readTrailer(run *LogRun) error {
+	file, err := os.Open(run.Path)
+	if err != nil {
+		return fmt.Errorf("error opening log %s: %w", run.Path, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, logFailedTask):
+			run.FailedTasks = append(run.FailedTasks, strings.TrimPrefix(line, logFailedTask))
+		case strings.HasPrefix(line, logFinished):
+			run.Finished = true
+			run.Summary = strings.TrimSpace(strings.TrimPrefix(line, logFinished))
+		}
+	}
+	return scanner.Err()
+}
+
+// FollowLog writes the log to out and then the lines added to it
+// until the playbook finishes or ctx is canceled
+func FollowLog(ctx context.Context, path string, out io.Writer, interval time.Duration) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening log %s: %w", path, err)
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		partial += line
+		if err == nil {
+			if _, err := io.WriteString(out, partial); err != nil {
+				return err
+			}
+			finished := strings.HasPrefix(partial, logFinished)
+			partial = ""
+			if finished {
+				return nil
+			}
+			continue
+		}
+		if err != io.EOF {
+			return fmt.Errorf("error reading log %s: %w", path, err)
+		}
+		select {
+		case <-ctx.Done():
+			_, err := io.WriteString(out, partial)
+			return err
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+const ansibleOutput = `PLAY [Install K3s Control Plane] ***
+
+TASK [Download K3s install script] ***
+ok: [cp.test.example.com]
+
+TASK [Discover the new drives] ***
+fatal: [cp.test.example.com]: FAILED! => {"rc": 1}
+...ignoring
+
+TASK [Install K3s agent] ***
+failed: [node-01.test.example.com] (item=a) => {"rc": 1}
+failed: [node-01.test.example.com] (item=b) => {"rc": 1}
+fatal: [node-02.test.example.com]: UNREACHABLE! => {}
+`
+
+func TestTaskFailures(t *testing.T) {
+	failures := &taskFailures{}
+	// write in pieces to check that lines are kept whole
+	for _, piece := range strings.SplitAfter(ansibleOutput, "FAILED") {
+		failures.Write([]byte(piece))
+	}
+	failures.Flush()
+	assert.Equal(t, []string{
+		"Install K3s agent (node-01.test.example.com)",
+		"Install K3s agent (node-02.test.example.com)",
+	}, failures.tasks)
+}
+
+func TestRunAnsiblePlaybookLogs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// the fake ansible-playbook prints the output and fails
+	script := filepath.Join(t.TempDir(), "ansible-playbook")
+	assert.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\ncat <<'EOF'\n"+ansibleOutput+"EOF\nexit 2\n"), 0755))
+	original := ansiblePlaybookCommand
+	defer func() { ansiblePlaybookCommand = original }()
+	ansiblePlaybookCommand = script
+
+	m := &ManagerSvc{Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	err := m.runAnsiblePlaybook(context.Background(), lab, "/playbooks/site.yml", nil)
+	assert.ErrorContains(t, err, "playbook site failed: exit status 2, see ")
+
+	stored, err := m.Storage.Get("test")
+	assert.NoError(t, err)
+	assert.Len(t, stored.Status.PlaybookRuns, 1)
+	run := stored.Status.PlaybookRuns[0]
+	assert.Equal(t, "/playbooks/site.yml", run.Playbook)
+	assert.Equal(t, 2, run.ExitCode)
+	assert.False(t, run.Finished.IsZero())
+	assert.Equal(t, []string{
+		"Install K3s agent (node-01.test.example.com)",
+		"Install K3s agent (node-02.test.example.com)",
+	}, run.FailedTasks)
+	logsDir, err := LogsDir("test")
+	assert.NoError(t, err)
+	assert.Equal(t, logsDir, filepath.Dir(run.LogPath))
+	assert.True(t, strings.HasSuffix(run.LogPath, "-site.log"))
+
+	runs, err := LogRuns("test")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, 1, runs[0].Number)
+	assert.Equal(t, "site", runs[0].Playbook)
+	assert.True(t, run.Started.Truncate(time.Second).Equal(runs[0].Started))
+	assert.True(t, runs[0].Finished)
+	assert.Equal(t, "with exit code 2 in 0s", runs[0].Summary)
+	assert.Equal(t, run.FailedTasks, runs[0].FailedTasks)
+
+	var out bytes.Buffer
+	assert.NoError(t, FollowLog(context.Background(), run.LogPath, &out, time.Millisecond))
+	assert.True(t, strings.HasPrefix(out.String(), ansibleOutput))
+	assert.True(t, strings.HasSuffix(out.String(), "storctl: playbook finished with exit code 2 in 0s\n"))
+}
+
+func TestFollowLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	assert.NoError(t, os.WriteFile(path, []byte("TASK [one] ***\nok: [cp]"), 0644))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		defer file.Close()
+		file.WriteString("\n" + logFinished + " with exit code 0 in 1s\n")
+	}()
+
+	var out bytes.Buffer
+	assert.NoError(t, FollowLog(context.Background(), path, &out, time.Millisecond))
+	assert.Equal(t, "TASK [one] ***\nok: [cp]\nstorctl: playbook finished with exit code 0 in 1s\n", out.String())
+
+	_, err := LogRuns("missing")
+	assert.ErrorContains(t, err, "no playbook logs for lab missing")
+}
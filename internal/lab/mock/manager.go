@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"context"
+
 	"github.com/pavelanni/storctl/internal/lab"
 	"github.com/pavelanni/storctl/internal/types"
 )
@@ -9,28 +11,53 @@ import (
 type Manager struct {
 	*lab.ManagerSvc  // Embed the ManagerSvc
 	ListFunc         func() ([]*types.Lab, error)
-	GetFunc          func(name string) (*types.Lab, error)
-	GetFromCloudFunc func(name string) (*types.Lab, error)
-	CreateFunc       func(lab *types.Lab) error
-	DeleteFunc       func(name string, force bool) error
+	GetFunc          func(ctx context.Context, name string) (*types.Lab, error)
+	GetFromCloudFunc func(ctx context.Context, name string) (*types.Lab, error)
+	CreateFunc       func(ctx context.Context, lab *types.Lab, opts lab.CreateOpts) error
+	ApplyFunc        func(ctx context.Context, lab *types.Lab) (*lab.Plan, error)
+	DeleteFunc       func(ctx context.Context, name string, force bool) error
+	ExtendFunc       func(ctx context.Context, name string, opts lab.ExtendOpts) (*types.Lab, error)
+	DescribeFunc     func(ctx context.Context, name string, opts lab.DescribeOpts) (*lab.Description, error)
+	ScaleFunc        func(ctx context.Context, name string, opts lab.ScaleOpts) (*lab.ScaleResult, error)
+	KubeconfigFunc   func(ctx context.Context, name string, opts lab.KubeconfigOpts) (string, error)
 }
 
 func (m *Manager) List() ([]*types.Lab, error) {
 	return m.ListFunc()
 }
 
-func (m *Manager) Get(name string) (*types.Lab, error) {
-	return m.GetFunc(name)
+func (m *Manager) Get(ctx context.Context, name string) (*types.Lab, error) {
+	return m.GetFunc(ctx, name)
+}
+
+func (m *Manager) Create(ctx context.Context, l *types.Lab, opts lab.CreateOpts) error {
+	return m.CreateFunc(ctx, l, opts)
+}
+
+func (m *Manager) Apply(ctx context.Context, l *types.Lab) (*lab.Plan, error) {
+	return m.ApplyFunc(ctx, l)
+}
+
+func (m *Manager) Delete(ctx context.Context, name string, force bool) error {
+	return m.DeleteFunc(ctx, name, force)
+}
+
+func (m *Manager) Extend(ctx context.Context, name string, opts lab.ExtendOpts) (*types.Lab, error) {
+	return m.ExtendFunc(ctx, name, opts)
+}
+
+func (m *Manager) Describe(ctx context.Context, name string, opts lab.DescribeOpts) (*lab.Description, error) {
+	return m.DescribeFunc(ctx, name, opts)
 }
 
-func (m *Manager) Create(lab *types.Lab) error {
-	return m.CreateFunc(lab)
+func (m *Manager) Scale(ctx context.Context, name string, opts lab.ScaleOpts) (*lab.ScaleResult, error) {
+	return m.ScaleFunc(ctx, name, opts)
 }
 
-func (m *Manager) Delete(name string, force bool) error {
-	return m.DeleteFunc(name, force)
+func (m *Manager) Kubeconfig(ctx context.Context, name string, opts lab.KubeconfigOpts) (string, error) {
+	return m.KubeconfigFunc(ctx, name, opts)
 }
 
-func (m *Manager) GetFromCloud(name string) (*types.Lab, error) {
-	return m.GetFromCloudFunc(name)
+func (m *Manager) GetFromCloud(ctx context.Context, name string) (*types.Lab, error) {
+	return m.GetFromCloudFunc(ctx, name)
 }
@@ -0,0 +1,188 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/serverchecker"
+)
+
+// Node is a lab server to log in to over SSH
+type Node struct {
+	Name    string // the server name
+	Role    string // RoleControlPlane or RoleNodes
+	Host    string // the server IP
+	User    string
+	KeyPath string
+}
+
+// nodeClient runs commands on a node and opens connections from it
+type nodeClient interface {
+	Connect() error
+	Close() error
+	Run(cmd string, stdout, stderr io.Writer) error
+	Dial(network, addr string) (net.Conn, error)
+}
+
+// newNodeClient returns the SSH client of the node; replaced in tests
+var newNodeClient = func(node *Node) nodeClient {
+	return serverchecker.NewSSHClient(node.addr(), node.User, node.KeyPath)
+}
+
+func (n *Node) addr() string {
+	return net.JoinHostPort(n.Host, "22")
+}
+
+// Nodes returns the lab servers with the user and key to log in with,
+// the same as in the Ansible inventory
+func (m *ManagerSvc) Nodes(ctx context.Context, labName string) ([]*Node, error) {
+	lab, err := m.Get(ctx, labName)
+	if err != nil {
+		return nil, err
+	}
+	return m.labNodes(lab)
+}
+
+func (m *ManagerSvc) labNodes(lab *types.Lab) ([]*Node, error) {
+	user, keyPath := m.sshLogin(lab.ObjectMeta.Name)
+	nodes := make([]*Node, 0, len(lab.Status.Servers))
+	for _, server := range lab.Status.Servers {
+		if server.Status.PublicNet == nil || server.Status.PublicNet.IPv4 == nil || server.Status.PublicNet.IPv4.IP == "" {
+			return nil, fmt.Errorf("server %s has no IP address", server.ObjectMeta.Name)
+		}
+		nodes = append(nodes, &Node{
+			Name:    server.ObjectMeta.Name,
+			Role:    serverRole(server),
+			Host:    server.Status.PublicNet.IPv4.IP,
+			User:    user,
+			KeyPath: keyPath,
+		})
+	}
+	return nodes, nil
+}
+
+// FindNode returns the node by its server name or by its name in the lab spec, like cp
+func FindNode(nodes []*Node, labName, name string) (*Node, error) {
+	for _, node := range nodes {
+		if node.Name == name || node.Name == resourceName(labName, name) {
+			return node, nil
+		}
+	}
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return nil, fmt.Errorf("lab %s has no node %s, its nodes are: %s", labName, name, strings.Join(names, ", "))
+}
+
+// Shell starts an interactive shell on the node
+func (n *Node) Shell(stdin *os.File, stdout, stderr io.Writer) error {
+	client := serverchecker.NewSSHClient(n.addr(), n.User, n.KeyPath)
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", n.Name, err)
+	}
+	defer client.Close()
+	return client.Shell(stdin, stdout, stderr)
+}
+
+// Exec runs the command on all nodes in parallel.
+// Each output line is prefixed with the node name. It returns the errors of all nodes joined.
+func Exec(ctx context.Context, nodes []*Node, command string, stdout, stderr io.Writer) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex // one line is written at a time
+		errs = make([]error, len(nodes))
+	)
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *Node) {
+			defer wg.Done()
+			prefix := "[" + node.Name + "] "
+			out := &prefixWriter{w: stdout, prefix: prefix, mu: &mu}
+			errOut := &prefixWriter{w: stderr, prefix: prefix, mu: &mu}
+			err := execOnNode(ctx, node, command, out, errOut)
+			out.Flush()
+			errOut.Flush()
+			if err != nil {
+				errs[i] = fmt.Errorf("node %s: %w", node.Name, err)
+			}
+		}(i, node)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// execOnNode runs the command on the node; canceling ctx closes the connection
+func execOnNode(ctx context.Context, node *Node, command string, stdout, stderr io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-done:
+		}
+	}()
+	defer client.Close()
+	err := client.Run(command, stdout, stderr)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// prefixWriter writes complete lines with the prefix to w
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	mu     *sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf.Write(data)
+	for {
+		line, err := p.buf.ReadBytes('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			p.buf.Reset()
+			p.buf.Write(line)
+			return len(data), nil
+		}
+		if err := p.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Flush writes the incomplete last line
+func (p *prefixWriter) Flush() error {
+	if p.buf.Len() == 0 {
+		return nil
+	}
+	line := append(p.buf.Bytes(), '\n')
+	p.buf.Reset()
+	return p.writeLine(line)
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := io.WriteString(p.w, p.prefix+string(line))
+	return err
+}
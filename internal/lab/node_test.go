@@ -0,0 +1,112 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNodeClient struct {
+	node *Node
+}
+
+func (c *fakeNodeClient) Connect() error {
+	if c.node.Host == "10.0.0.3" {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (c *fakeNodeClient) Close() error { return nil }
+
+func (c *fakeNodeClient) Dial(network, addr string) (net.Conn, error) {
+	return net.Dial(network, addr)
+}
+
+func (c *fakeNodeClient) Run(cmd string, stdout, stderr io.Writer) error {
+	// write the output in pieces to check that lines are kept whole
+	fmt.Fprintf(stdout, "%s on ", cmd)
+	fmt.Fprintf(stdout, "%s\nsecond line", c.node.Name)
+	if c.node.Role == RoleControlPlane {
+		fmt.Fprintln(stderr, "warning")
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+func TestNodes(t *testing.T) {
+	provider := &mock.MockProvider{
+		CapabilitiesFunc: func() types.ProviderCapabilities {
+			return types.ProviderCapabilities{SSHUser: "alice", SSHKeyPath: "/keys/lima"}
+		},
+	}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	for i, name := range []string{"test-cp", "test-node-01"} {
+		server := testServer(name, "cx22")
+		server.Status.PublicNet = &types.PublicNet{IPv4: &struct {
+			IP string `json:"ip"`
+		}{IP: fmt.Sprintf("10.0.0.%d", i+1)}}
+		lab.Status.Servers = append(lab.Status.Servers, server)
+	}
+	assert.NoError(t, m.Storage.Save(lab))
+
+	nodes, err := m.Nodes(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []*Node{
+		{Name: "test-cp", Role: RoleControlPlane, Host: "10.0.0.1", User: "alice", KeyPath: "/keys/lima"},
+		{Name: "test-node-01", Role: RoleNodes, Host: "10.0.0.2", User: "alice", KeyPath: "/keys/lima"},
+	}, nodes)
+
+	node, err := FindNode(nodes, "test", "node-01")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-node-01", node.Name)
+	node, err = FindNode(nodes, "test", "test-cp")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-cp", node.Name)
+	_, err = FindNode(nodes, "test", "node-02")
+	assert.EqualError(t, err, "lab test has no node node-02, its nodes are: test-cp, test-node-01")
+}
+
+func TestExec(t *testing.T) {
+	original := newNodeClient
+	defer func() { newNodeClient = original }()
+	newNodeClient = func(node *Node) nodeClient { return &fakeNodeClient{node: node} }
+
+	nodes := []*Node{
+		{Name: "test-cp", Role: RoleControlPlane, Host: "10.0.0.1"},
+		{Name: "test-node-01", Role: RoleNodes, Host: "10.0.0.2"},
+		{Name: "test-node-02", Role: RoleNodes, Host: "10.0.0.3"},
+	}
+	var stdout, stderr bytes.Buffer
+	err := Exec(context.Background(), nodes, "uptime", &stdout, &stderr)
+	assert.EqualError(t, err, "node test-cp: exit status 1\nnode test-node-02: connection refused")
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	sort.Strings(lines)
+	assert.Equal(t, []string{
+		"[test-cp] second line",
+		"[test-cp] uptime on test-cp",
+		"[test-node-01] second line",
+		"[test-node-01] uptime on test-node-01",
+	}, lines)
+	assert.Equal(t, "[test-cp] warning\n", stderr.String())
+}
+
+func TestExecCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Exec(ctx, []*Node{{Name: "test-cp"}}, "uptime", io.Discard, io.Discard)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,75 @@
+package lab
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// createPhase is a provider step of lab creation
+type createPhase struct {
+	name      string
+	run       func(ctx context.Context, lab *types.Lab) error
+	resources bool // the phase creates resources that are rolled back on failure
+}
+
+// RunPhase runs a lab creation phase unless it's already done and saves the lab after it.
+// A failed phase puts the lab in the Failed state so its creation can be resumed.
+func (m *ManagerSvc) RunPhase(lab *types.Lab, name string, run func() error) error {
+	phase := findPhase(lab, name)
+	if phase != nil && phase.State == types.PhaseDone {
+		fmt.Printf("Lab %s: Phase %s is already done, skipping.\n", lab.ObjectMeta.Name, name)
+		return nil
+	}
+	if phase == nil {
+		phase = &types.LabPhase{Name: name}
+		lab.Status.Phases = append(lab.Status.Phases, phase)
+	}
+	phase.State = types.PhaseRunning
+	phase.Started = time.Now().UTC()
+	phase.Finished = time.Time{}
+	phase.Error = ""
+
+	err := run()
+	phase.Finished = time.Now().UTC()
+	if err != nil {
+		phase.State = types.PhaseFailed
+		phase.Error = err.Error()
+		lab.Status.State = types.LabStateFailed
+		lab.Status.Error = err.Error()
+	} else {
+		phase.State = types.PhaseDone
+		if lab.Status.State == types.LabStateFailed {
+			lab.Status.State = ""
+			lab.Status.Error = ""
+		}
+	}
+	if saveErr := m.Storage.Save(lab); saveErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w (failed to save lab: %v)", err, saveErr)
+		}
+		return fmt.Errorf("failed to save lab: %w", saveErr)
+	}
+	return err
+}
+
+func findPhase(lab *types.Lab, name string) *types.LabPhase {
+	for _, phase := range lab.Status.Phases {
+		if phase.Name == name {
+			return phase
+		}
+	}
+	return nil
+}
+
+// isCreated returns true if the resource is in the lab journal
+func isCreated(lab *types.Lab, kind, name string) bool {
+	for _, resource := range lab.Status.Resources {
+		if resource.Kind == kind && resource.Name == name {
+			return true
+		}
+	}
+	return false
+}
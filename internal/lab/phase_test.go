@@ -0,0 +1,166 @@
+package lab
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPhase(t *testing.T) {
+	m := &ManagerSvc{Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+
+	err := m.RunPhase(lab, types.PhaseDNS, func() error { return errors.New("zone not found") })
+	assert.ErrorContains(t, err, "zone not found")
+	assert.Equal(t, types.LabStateFailed, lab.Status.State)
+	saved, err := m.Storage.Get("test")
+	assert.NoError(t, err)
+	assert.Equal(t, types.PhaseFailed, saved.Status.Phases[0].State)
+	assert.Equal(t, "zone not found", saved.Status.Phases[0].Error)
+
+	runs := 0
+	err = m.RunPhase(lab, types.PhaseDNS, func() error { runs++; return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Len(t, lab.Status.Phases, 1)
+	assert.Equal(t, types.PhaseDone, lab.Status.Phases[0].State)
+	assert.Empty(t, lab.Status.State)
+	assert.Empty(t, lab.Status.Error)
+
+	// a finished phase is skipped
+	err = m.RunPhase(lab, types.PhaseDNS, func() error { runs++; return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+}
+
+func TestCreateResume(t *testing.T) {
+	failNode := true
+	var created []string
+	provider := &mock.MockProvider{
+		NameFunc: func() string { return "lima" },
+		CreateVolumeFunc: func(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
+			created = append(created, opts.Name)
+			return testVolume(opts.Name, opts.Size), nil
+		},
+		CreateServerFunc: func(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+			if opts.Name == "test-node-01" && failNode {
+				return nil, errors.New("out of capacity")
+			}
+			created = append(created, opts.Name)
+			return testServer(opts.Name, opts.Type), nil
+		},
+	}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	lab.Spec.Provider = "lima"
+
+	err := m.Create(context.Background(), lab, CreateOpts{NoRollback: true})
+	assert.ErrorContains(t, err, "out of capacity")
+	assert.Equal(t, []string{"test-volume-01", "test-cp"}, created)
+
+	stored, err := m.Storage.Get("test")
+	assert.NoError(t, err)
+	assert.Equal(t, types.LabStateFailed, stored.Status.State)
+	assert.Equal(t, types.PhaseDone, stored.Status.Phases[0].State)
+	assert.Equal(t, types.PhaseFailed, stored.Status.Phases[1].State)
+
+	failNode = false
+	err = m.Create(context.Background(), stored, CreateOpts{Resume: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test-volume-01", "test-cp", "test-node-01"}, created)
+
+	stored, err = m.Storage.Get("test")
+	assert.NoError(t, err)
+	assert.Empty(t, stored.Status.State)
+	for _, phase := range stored.Status.Phases {
+		assert.Equal(t, types.PhaseDone, phase.State, phase.Name)
+	}
+}
+
+func TestCreateServersParallel(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	provider := &mock.MockProvider{
+		NameFunc: func() string { return "lima" },
+		CreateServerFunc: func(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if opts.Name == "test-node-02" || opts.Name == "test-node-03" {
+				return nil, errors.New("out of capacity")
+			}
+			return testServer(opts.Name, opts.Type), nil
+		},
+	}
+	m := &ManagerSvc{Provider: provider, Logger: logger.Get(), Concurrency: 5}
+	lab := testLabSpec()
+	lab.Spec.Volumes = nil
+	for _, name := range []string{"node-02", "node-03", "node-04"} {
+		lab.Spec.Servers = append(lab.Spec.Servers, &types.LabServerSpec{Name: name, ServerType: "cx22"})
+	}
+
+	err := m.createServersWithDisks(context.Background(), lab)
+	assert.ErrorContains(t, err, "failed to create server test-node-02")
+	assert.ErrorContains(t, err, "failed to create server test-node-03")
+	assert.Greater(t, maxRunning, 1)
+	assert.LessOrEqual(t, maxRunning, 5)
+
+	// successful servers are recorded in the lab spec order
+	names := make([]string, 0)
+	for _, server := range lab.Status.Servers {
+		names = append(names, server.ObjectMeta.Name)
+	}
+	assert.Equal(t, []string{"test-cp", "test-node-01", "test-node-04"}, names)
+	assert.Len(t, lab.Status.Resources, 3)
+}
+
+func TestCreatePhases(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities types.ProviderCapabilities
+		want         []string
+	}{
+		{
+			name:         "disks attached at boot",
+			capabilities: types.ProviderCapabilities{},
+			want:         []string{types.PhaseVolumes, types.PhaseServers},
+		},
+		{
+			name:         "cloud",
+			capabilities: testCapabilities("hetzner")(),
+			want:         []string{types.PhaseKeys, types.PhaseServers, types.PhaseReadiness, types.PhaseVolumes},
+		},
+		{
+			name:         "no readiness check",
+			capabilities: types.ProviderCapabilities{VolumesAttachAfterBoot: true, ManagesSSHKeys: true},
+			want:         []string{types.PhaseKeys, types.PhaseServers, types.PhaseVolumes},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mock.MockProvider{
+				CapabilitiesFunc: func() types.ProviderCapabilities { return tt.capabilities },
+			}
+			m := &ManagerSvc{Provider: provider, Logger: logger.Get()}
+			names := make([]string, 0)
+			for _, phase := range m.createPhases(testLabSpec()) {
+				names = append(names, phase.name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
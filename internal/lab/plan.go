@@ -0,0 +1,358 @@
+package lab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// ActionType is the kind of change a plan action makes to a lab resource
+type ActionType string
+
+const (
+	ActionCreate ActionType = "create"
+	ActionResize ActionType = "resize"
+	ActionDelete ActionType = "delete"
+)
+
+// Action is a single change to a lab resource
+type Action struct {
+	Type   ActionType `json:"type"`
+	Kind   string     `json:"kind"`
+	Name   string     `json:"name"`
+	Detail string     `json:"detail,omitempty"`
+
+	server *types.LabServerSpec
+	volume *types.LabVolumeSpec
+}
+
+// PlanOpts are the options for planning lab changes
+type PlanOpts struct {
+	// DNSDomain is the domain of the lab DNS records. DNS records are not planned if it's empty.
+	DNSDomain string
+}
+
+// Plan is the list of changes needed to bring a lab in line with its spec
+type Plan struct {
+	Lab     string   `json:"lab"`
+	Actions []Action `json:"actions"`
+}
+
+// Empty returns true if the plan has no changes
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// Filter returns the actions of the given type and kind
+func (p *Plan) Filter(actionType ActionType, kind string) []Action {
+	actions := make([]Action, 0)
+	for _, action := range p.Actions {
+		if action.Type == actionType && action.Kind == kind {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// PlanCreate returns the SSH keys, servers, volumes, and DNS records that Create would create for the lab.
+// It only reads from the provider and fails if the lab already exists.
+func (m *ManagerSvc) PlanCreate(ctx context.Context, lab *types.Lab, opts PlanOpts) (*Plan, error) {
+	labName := lab.ObjectMeta.Name
+	current, err := m.getLabFromProvider(ctx, labName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab from provider: %w", err)
+	}
+	if len(current.Status.Servers) > 0 || len(current.Status.Volumes) > 0 {
+		return nil, fmt.Errorf("lab %s already exists; use apply to change it", labName)
+	}
+	plan := &Plan{
+		Lab:     labName,
+		Actions: make([]Action, 0),
+	}
+
+	servers := make([]Action, 0, len(lab.Spec.Servers))
+	for _, serverSpec := range lab.Spec.Servers {
+		servers = append(servers, Action{
+			Type:   ActionCreate,
+			Kind:   "Server",
+			Name:   resourceName(labName, serverSpec.Name),
+			Detail: fmt.Sprintf("type %s, image %s", serverSpec.ServerType, serverSpec.Image),
+			server: serverSpec,
+		})
+	}
+	volumes := make([]Action, 0, len(lab.Spec.Volumes))
+	for _, volumeSpec := range lab.Spec.Volumes {
+		volumes = append(volumes, Action{
+			Type:   ActionCreate,
+			Kind:   "Volume",
+			Name:   resourceName(labName, volumeSpec.Name),
+			Detail: fmt.Sprintf("%dGB on %s", volumeSpec.Size, resourceName(labName, volumeSpec.Server)),
+			volume: volumeSpec,
+		})
+	}
+
+	// List the actions in the same order Create runs them
+	capabilities := m.Provider.Capabilities()
+	if !capabilities.VolumesAttachAfterBoot {
+		plan.Actions = append(plan.Actions, volumes...)
+		plan.Actions = append(plan.Actions, servers...)
+		return plan, nil
+	}
+	if capabilities.ManagesSSHKeys {
+		labAdminKeyName := resourceName(labName, "admin")
+		keyExists, err := m.Provider.CloudKeyExists(ctx, labAdminKeyName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check SSH key %s: %w", labAdminKeyName, err)
+		}
+		if keyExists {
+			return nil, fmt.Errorf("SSH key %s already exists", labAdminKeyName)
+		}
+		plan.Actions = append(plan.Actions, Action{
+			Type:   ActionCreate,
+			Kind:   "SSHKey",
+			Name:   labAdminKeyName,
+			Detail: "lab admin key",
+		})
+	}
+	plan.Actions = append(plan.Actions, servers...)
+	plan.Actions = append(plan.Actions, volumes...)
+	if opts.DNSDomain != "" && capabilities.NeedsPublicDNS && len(lab.Spec.Servers) > 0 {
+		serverNames := make([]string, 0, len(lab.Spec.Servers))
+		for _, serverSpec := range lab.Spec.Servers {
+			serverNames = append(serverNames, serverSpec.Name)
+		}
+		plan.Actions = append(plan.Actions, dnsActions(labName, serverNames, opts.DNSDomain)...)
+		// the aistor record points to the control plane, which is the first server
+		plan.Actions = append(plan.Actions, Action{
+			Type:   ActionCreate,
+			Kind:   "DNSRecord",
+			Name:   dnsName("aistor", labName, opts.DNSDomain),
+			Detail: "A record for " + resourceName(labName, lab.Spec.Servers[0].Name),
+		})
+	}
+	return plan, nil
+}
+
+// PlanDelete returns the servers, volumes, and SSH keys that Delete would remove for the lab.
+// It only reads from the provider and fails if the lab is not ready for deletion and force is false.
+func (m *ManagerSvc) PlanDelete(ctx context.Context, labName string, force bool) (*Plan, error) {
+	lab, err := m.Storage.Get(labName)
+	if err != nil {
+		lab, err = m.getLabFromProvider(ctx, labName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get lab from provider: %w", err)
+		}
+	}
+	if len(lab.Status.Servers) == 0 && len(lab.Status.Volumes) == 0 {
+		return nil, fmt.Errorf("lab %s not found", labName)
+	}
+	capabilities := m.Provider.Capabilities()
+	if capabilities.SupportsTTLLabels && !force && !lab.Status.DeleteAfter.Before(time.Now().UTC()) {
+		return nil, fmt.Errorf("lab %s is not ready for deletion until %s", labName,
+			lab.Status.DeleteAfter.Format(time.RFC3339))
+	}
+	plan := &Plan{
+		Lab:     labName,
+		Actions: make([]Action, 0),
+	}
+
+	servers := make([]Action, 0, len(lab.Status.Servers))
+	seenKeys := make(map[string]bool)
+	for _, server := range lab.Status.Servers {
+		for _, sshKeyName := range server.Spec.SSHKeyNames {
+			if seenKeys[sshKeyName] {
+				continue
+			}
+			seenKeys[sshKeyName] = true
+			servers = append(servers, Action{
+				Type: ActionDelete,
+				Kind: "SSHKey",
+				Name: sshKeyName,
+			})
+		}
+		servers = append(servers, Action{
+			Type:   ActionDelete,
+			Kind:   "Server",
+			Name:   server.ObjectMeta.Name,
+			Detail: server.Spec.ServerType,
+		})
+	}
+	for _, resource := range lab.Status.Resources {
+		if resource.Kind != "SSHKey" || seenKeys[resource.Name] || !capabilities.ManagesSSHKeys {
+			continue
+		}
+		seenKeys[resource.Name] = true
+		servers = append(servers, Action{
+			Type:   ActionDelete,
+			Kind:   "SSHKey",
+			Name:   resource.Name,
+			Detail: "lab admin key",
+		})
+	}
+	volumes := make([]Action, 0, len(lab.Status.Volumes))
+	for _, volume := range lab.Status.Volumes {
+		volumes = append(volumes, Action{
+			Type:   ActionDelete,
+			Kind:   "Volume",
+			Name:   volume.ObjectMeta.Name,
+			Detail: fmt.Sprintf("%dGB", volume.Spec.Size),
+		})
+	}
+
+	// List the actions in the same order Delete runs them
+	if !capabilities.VolumesAttachAfterBoot {
+		plan.Actions = append(plan.Actions, servers...)
+		plan.Actions = append(plan.Actions, volumes...)
+	} else {
+		plan.Actions = append(plan.Actions, volumes...)
+		plan.Actions = append(plan.Actions, servers...)
+	}
+	return plan, nil
+}
+
+// PlanApply compares the lab spec with the lab resources reported by the provider
+// and returns the servers and volumes that have to be created, resized, or deleted
+// and the DNS records for the new servers
+func (m *ManagerSvc) PlanApply(ctx context.Context, lab *types.Lab, opts PlanOpts) (*Plan, error) {
+	current, err := m.getLabFromProvider(ctx, lab.ObjectMeta.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab from provider: %w", err)
+	}
+	capabilities := m.Provider.Capabilities()
+	plan, err := planApply(lab, current, capabilities)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DNSDomain != "" && capabilities.NeedsPublicDNS {
+		serverNames := make([]string, 0)
+		for _, action := range plan.Filter(ActionCreate, "Server") {
+			serverNames = append(serverNames, action.server.Name)
+		}
+		plan.Actions = append(plan.Actions, dnsActions(lab.ObjectMeta.Name, serverNames, opts.DNSDomain)...)
+	}
+	return plan, nil
+}
+
+func planApply(desired, current *types.Lab, capabilities types.ProviderCapabilities) (*Plan, error) {
+	labName := desired.ObjectMeta.Name
+	plan := &Plan{
+		Lab:     labName,
+		Actions: make([]Action, 0),
+	}
+
+	currentServers := make(map[string]*types.Server)
+	for _, server := range current.Status.Servers {
+		currentServers[server.ObjectMeta.Name] = server
+	}
+	currentVolumes := make(map[string]*types.Volume)
+	for _, volume := range current.Status.Volumes {
+		currentVolumes[volume.ObjectMeta.Name] = volume
+	}
+
+	desiredServers := make(map[string]bool)
+	for _, serverSpec := range desired.Spec.Servers {
+		name := resourceName(labName, serverSpec.Name)
+		desiredServers[name] = true
+		server, ok := currentServers[name]
+		if !ok {
+			plan.Actions = append(plan.Actions, Action{
+				Type:   ActionCreate,
+				Kind:   "Server",
+				Name:   name,
+				Detail: fmt.Sprintf("type %s, image %s", serverSpec.ServerType, serverSpec.Image),
+				server: serverSpec,
+			})
+			continue
+		}
+		// Lima doesn't report server types, so we can only compare them if the provider does
+		if server.Spec.ServerType != "" && server.Spec.ServerType != serverSpec.ServerType {
+			return nil, fmt.Errorf("changing type of server %s from %s to %s is not supported; remove the server from the spec and add it under a new name",
+				name, server.Spec.ServerType, serverSpec.ServerType)
+		}
+	}
+
+	desiredVolumes := make(map[string]bool)
+	for _, volumeSpec := range desired.Spec.Volumes {
+		name := resourceName(labName, volumeSpec.Name)
+		serverName := resourceName(labName, volumeSpec.Server)
+		desiredVolumes[name] = true
+		volume, ok := currentVolumes[name]
+		if !ok {
+			// disks attached when the VM is created can't be added to an existing VM
+			if !capabilities.VolumesAttachAfterBoot {
+				if _, serverExists := currentServers[serverName]; serverExists {
+					return nil, fmt.Errorf("can't attach new volume %s to existing server %s", name, serverName)
+				}
+			}
+			plan.Actions = append(plan.Actions, Action{
+				Type:   ActionCreate,
+				Kind:   "Volume",
+				Name:   name,
+				Detail: fmt.Sprintf("%dGB on %s", volumeSpec.Size, serverName),
+				volume: volumeSpec,
+			})
+			continue
+		}
+		switch {
+		case volumeSpec.Size > volume.Spec.Size:
+			plan.Actions = append(plan.Actions, Action{
+				Type:   ActionResize,
+				Kind:   "Volume",
+				Name:   name,
+				Detail: fmt.Sprintf("%dGB -> %dGB", volume.Spec.Size, volumeSpec.Size),
+				volume: volumeSpec,
+			})
+		case volumeSpec.Size < volume.Spec.Size:
+			return nil, fmt.Errorf("volume %s can't be shrunk from %dGB to %dGB", name, volume.Spec.Size, volumeSpec.Size)
+		}
+	}
+
+	for _, volume := range current.Status.Volumes {
+		if !desiredVolumes[volume.ObjectMeta.Name] {
+			plan.Actions = append(plan.Actions, Action{
+				Type:   ActionDelete,
+				Kind:   "Volume",
+				Name:   volume.ObjectMeta.Name,
+				Detail: fmt.Sprintf("%dGB", volume.Spec.Size),
+			})
+		}
+	}
+	for _, server := range current.Status.Servers {
+		if !desiredServers[server.ObjectMeta.Name] {
+			plan.Actions = append(plan.Actions, Action{
+				Type:   ActionDelete,
+				Kind:   "Server",
+				Name:   server.ObjectMeta.Name,
+				Detail: server.Spec.ServerType,
+			})
+		}
+	}
+	return plan, nil
+}
+
+// dnsActions returns the A records <server>.<lab>.<domain> for the lab servers
+func dnsActions(labName string, serverNames []string, domain string) []Action {
+	actions := make([]Action, 0, len(serverNames))
+	for _, serverName := range serverNames {
+		actions = append(actions, Action{
+			Type:   ActionCreate,
+			Kind:   "DNSRecord",
+			Name:   dnsName(serverName, labName, domain),
+			Detail: "A record for " + resourceName(labName, serverName),
+		})
+	}
+	return actions
+}
+
+// dnsName returns the lowercase FQDN <name>.<lab>.<domain>
+func dnsName(name, labName, domain string) string {
+	return strings.ToLower(strings.Join([]string{name, labName, domain}, "."))
+}
+
+// resourceName returns the cloud name of a lab resource: <lab>-<name>
+func resourceName(labName, name string) string {
+	return strings.Join([]string{labName, name}, "-")
+}
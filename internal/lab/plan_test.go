@@ -0,0 +1,366 @@
+package lab
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStorage(t *testing.T) Storage {
+	storage, err := NewLabStorage(&config.Config{
+		Storage: config.StorageConfig{
+			Path:   filepath.Join(t.TempDir(), "labs.db"),
+			Bucket: "labs",
+		},
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+// testCapabilities returns the capabilities of the cloud and Lima providers for the mock provider
+func testCapabilities(providerName string) func() types.ProviderCapabilities {
+	return func() types.ProviderCapabilities {
+		if providerName == "lima" {
+			return types.ProviderCapabilities{}
+		}
+		return types.ProviderCapabilities{
+			VolumesAttachAfterBoot: true,
+			ReadinessCheck:         true,
+			NeedsPublicDNS:         true,
+			SupportsLabels:         true,
+			SupportsTTLLabels:      true,
+			ManagesSSHKeys:         true,
+		}
+	}
+}
+
+func testLabSpec() *types.Lab {
+	return &types.Lab{
+		ObjectMeta: types.ObjectMeta{
+			Name: "test",
+		},
+		Spec: types.LabSpec{
+			Servers: []*types.LabServerSpec{
+				{Name: "cp", ServerType: "cx22", Image: "ubuntu-24.04"},
+				{Name: "node-01", ServerType: "cx22", Image: "ubuntu-24.04"},
+			},
+			Volumes: []*types.LabVolumeSpec{
+				{Name: "volume-01", Server: "node-01", Size: 100},
+			},
+		},
+	}
+}
+
+func testServer(name, serverType string) *types.Server {
+	return &types.Server{
+		ObjectMeta: types.ObjectMeta{Name: name},
+		Spec:       types.ServerSpec{ServerType: serverType},
+	}
+}
+
+func testVolume(name string, size int) *types.Volume {
+	return &types.Volume{
+		ObjectMeta: types.ObjectMeta{Name: name},
+		Spec:       types.VolumeSpec{Size: size},
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    string
+		servers     []*types.Server
+		volumes     []*types.Volume
+		modify      func(lab *types.Lab)
+		want        []Action
+		errContains string
+	}{
+		{
+			name:     "up to date",
+			provider: "hetzner",
+			servers:  []*types.Server{testServer("test-cp", "cx22"), testServer("test-node-01", "cx22")},
+			volumes:  []*types.Volume{testVolume("test-volume-01", 100)},
+			want:     []Action{},
+		},
+		{
+			name:     "new lab",
+			provider: "hetzner",
+			want: []Action{
+				{Type: ActionCreate, Kind: "Server", Name: "test-cp"},
+				{Type: ActionCreate, Kind: "Server", Name: "test-node-01"},
+				{Type: ActionCreate, Kind: "Volume", Name: "test-volume-01"},
+			},
+		},
+		{
+			name:     "add server and volume, resize volume",
+			provider: "hetzner",
+			servers:  []*types.Server{testServer("test-cp", "cx22"), testServer("test-node-01", "cx22")},
+			volumes:  []*types.Volume{testVolume("test-volume-01", 50)},
+			modify: func(lab *types.Lab) {
+				lab.Spec.Servers = append(lab.Spec.Servers, &types.LabServerSpec{Name: "node-02", ServerType: "cx22"})
+				lab.Spec.Volumes = append(lab.Spec.Volumes, &types.LabVolumeSpec{Name: "volume-02", Server: "node-02", Size: 100})
+			},
+			want: []Action{
+				{Type: ActionCreate, Kind: "Server", Name: "test-node-02"},
+				{Type: ActionResize, Kind: "Volume", Name: "test-volume-01"},
+				{Type: ActionCreate, Kind: "Volume", Name: "test-volume-02"},
+			},
+		},
+		{
+			name:     "remove server and volume",
+			provider: "hetzner",
+			servers:  []*types.Server{testServer("test-cp", "cx22"), testServer("test-node-01", "cx22"), testServer("test-node-02", "cx22")},
+			volumes:  []*types.Volume{testVolume("test-volume-01", 100), testVolume("test-volume-02", 100)},
+			want: []Action{
+				{Type: ActionDelete, Kind: "Volume", Name: "test-volume-02"},
+				{Type: ActionDelete, Kind: "Server", Name: "test-node-02"},
+			},
+		},
+		{
+			name:        "shrink volume",
+			provider:    "hetzner",
+			servers:     []*types.Server{testServer("test-cp", "cx22"), testServer("test-node-01", "cx22")},
+			volumes:     []*types.Volume{testVolume("test-volume-01", 200)},
+			errContains: "can't be shrunk",
+		},
+		{
+			name:        "change server type",
+			provider:    "hetzner",
+			servers:     []*types.Server{testServer("test-cp", "cx32"), testServer("test-node-01", "cx22")},
+			volumes:     []*types.Volume{testVolume("test-volume-01", 100)},
+			errContains: "changing type of server test-cp",
+		},
+		{
+			name:     "new volume on existing Lima server",
+			provider: "lima",
+			servers:  []*types.Server{testServer("test-cp", ""), testServer("test-node-01", "")},
+			volumes:  []*types.Volume{testVolume("test-volume-01", 100)},
+			modify: func(lab *types.Lab) {
+				lab.Spec.Volumes = append(lab.Spec.Volumes, &types.LabVolumeSpec{Name: "volume-02", Server: "node-01", Size: 100})
+			},
+			errContains: "can't attach new volume",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mock.MockProvider{
+				NameFunc:         func() string { return tt.provider },
+				CapabilitiesFunc: testCapabilities(tt.provider),
+				ListServersFunc: func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+					assert.Equal(t, "lab_name=test", opts.LabelSelector)
+					return tt.servers, nil
+				},
+				ListVolumesFunc: func(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+					return tt.volumes, nil
+				},
+			}
+			m := &ManagerSvc{Provider: provider, Logger: logger.Get()}
+			lab := testLabSpec()
+			if tt.modify != nil {
+				tt.modify(lab)
+			}
+
+			plan, err := m.PlanApply(context.Background(), lab, PlanOpts{})
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			got := make([]Action, 0, len(plan.Actions))
+			for _, action := range plan.Actions {
+				got = append(got, Action{Type: action.Type, Kind: action.Kind, Name: action.Name})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    string
+		opts        PlanOpts
+		servers     []*types.Server
+		keyExists   bool
+		want        []Action
+		errContains string
+	}{
+		{
+			name:     "hetzner with DNS",
+			provider: "hetzner",
+			opts:     PlanOpts{DNSDomain: "example.com"},
+			want: []Action{
+				{Type: ActionCreate, Kind: "SSHKey", Name: "test-admin"},
+				{Type: ActionCreate, Kind: "Server", Name: "test-cp"},
+				{Type: ActionCreate, Kind: "Server", Name: "test-node-01"},
+				{Type: ActionCreate, Kind: "Volume", Name: "test-volume-01"},
+				{Type: ActionCreate, Kind: "DNSRecord", Name: "cp.test.example.com"},
+				{Type: ActionCreate, Kind: "DNSRecord", Name: "node-01.test.example.com"},
+				{Type: ActionCreate, Kind: "DNSRecord", Name: "aistor.test.example.com"},
+			},
+		},
+		{
+			name:     "hetzner without DNS",
+			provider: "hetzner",
+			want: []Action{
+				{Type: ActionCreate, Kind: "SSHKey", Name: "test-admin"},
+				{Type: ActionCreate, Kind: "Server", Name: "test-cp"},
+				{Type: ActionCreate, Kind: "Server", Name: "test-node-01"},
+				{Type: ActionCreate, Kind: "Volume", Name: "test-volume-01"},
+			},
+		},
+		{
+			name:     "lima creates volumes first",
+			provider: "lima",
+			want: []Action{
+				{Type: ActionCreate, Kind: "Volume", Name: "test-volume-01"},
+				{Type: ActionCreate, Kind: "Server", Name: "test-cp"},
+				{Type: ActionCreate, Kind: "Server", Name: "test-node-01"},
+			},
+		},
+		{
+			name:        "lab already exists",
+			provider:    "hetzner",
+			servers:     []*types.Server{testServer("test-cp", "cx22")},
+			errContains: "already exists",
+		},
+		{
+			name:        "lab admin key already exists",
+			provider:    "hetzner",
+			keyExists:   true,
+			errContains: "SSH key test-admin already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mock.MockProvider{
+				NameFunc:         func() string { return tt.provider },
+				CapabilitiesFunc: testCapabilities(tt.provider),
+				ListServersFunc: func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+					return tt.servers, nil
+				},
+				CloudKeyExistsFunc: func(ctx context.Context, name string) (bool, error) {
+					assert.Equal(t, "test-admin", name)
+					return tt.keyExists, nil
+				},
+			}
+			m := &ManagerSvc{Provider: provider, Logger: logger.Get()}
+			lab := testLabSpec()
+			lab.Spec.Provider = tt.provider
+
+			plan, err := m.PlanCreate(context.Background(), lab, tt.opts)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			got := make([]Action, 0, len(plan.Actions))
+			for _, action := range plan.Actions {
+				got = append(got, Action{Type: action.Type, Kind: action.Kind, Name: action.Name})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanDelete(t *testing.T) {
+	expired := time.Now().UTC().Add(-time.Hour)
+	notExpired := time.Now().UTC().Add(time.Hour)
+	servers := func(deleteAfter time.Time) []*types.Server {
+		cp := testServer("test-cp", "cx22")
+		cp.Spec.SSHKeyNames = []string{"test-admin"}
+		cp.Status.DeleteAfter = deleteAfter
+		node := testServer("test-node-01", "cx22")
+		node.Spec.SSHKeyNames = []string{"test-admin"}
+		return []*types.Server{cp, node}
+	}
+
+	tests := []struct {
+		name        string
+		provider    string
+		servers     []*types.Server
+		force       bool
+		want        []Action
+		errContains string
+	}{
+		{
+			name:     "hetzner deletes volumes first",
+			provider: "hetzner",
+			servers:  servers(expired),
+			want: []Action{
+				{Type: ActionDelete, Kind: "Volume", Name: "test-volume-01"},
+				{Type: ActionDelete, Kind: "SSHKey", Name: "test-admin"},
+				{Type: ActionDelete, Kind: "Server", Name: "test-cp"},
+				{Type: ActionDelete, Kind: "Server", Name: "test-node-01"},
+			},
+		},
+		{
+			name:     "lima deletes servers first",
+			provider: "lima",
+			servers:  servers(notExpired),
+			want: []Action{
+				{Type: ActionDelete, Kind: "SSHKey", Name: "test-admin"},
+				{Type: ActionDelete, Kind: "Server", Name: "test-cp"},
+				{Type: ActionDelete, Kind: "Server", Name: "test-node-01"},
+				{Type: ActionDelete, Kind: "Volume", Name: "test-volume-01"},
+			},
+		},
+		{
+			name:        "not ready for deletion",
+			provider:    "hetzner",
+			servers:     servers(notExpired),
+			errContains: "not ready for deletion",
+		},
+		{
+			name:     "not ready for deletion with force",
+			provider: "hetzner",
+			servers:  servers(notExpired),
+			force:    true,
+			want: []Action{
+				{Type: ActionDelete, Kind: "Volume", Name: "test-volume-01"},
+				{Type: ActionDelete, Kind: "SSHKey", Name: "test-admin"},
+				{Type: ActionDelete, Kind: "Server", Name: "test-cp"},
+				{Type: ActionDelete, Kind: "Server", Name: "test-node-01"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mock.MockProvider{
+				NameFunc:         func() string { return tt.provider },
+				CapabilitiesFunc: testCapabilities(tt.provider),
+				ListServersFunc: func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+					return tt.servers, nil
+				},
+				ListVolumesFunc: func(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+					return []*types.Volume{testVolume("test-volume-01", 100)}, nil
+				},
+			}
+			m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+
+			plan, err := m.PlanDelete(context.Background(), "test", tt.force)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			got := make([]Action, 0, len(plan.Actions))
+			for _, action := range plan.Actions {
+				got = append(got, Action{Type: action.Type, Kind: action.Kind, Name: action.Name})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
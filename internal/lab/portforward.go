@@ -0,0 +1,200 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// AIStor ports: the aistor service behind the ingress from aistor-ingress.yaml.j2,
+// and the Object API and Console UI NodePorts the README sets for the first object store in Inbound Traffic
+const (
+	aistorNamespace         = "aistor"
+	aistorService           = "aistor"
+	aistorConsolePort       = 8444
+	aistorObjectAPIPort     = 30001
+	aistorObjectStoreUIPort = 31001
+)
+
+const (
+	// forwardListenAddress is where the forwarded ports listen, so they are reachable only locally
+	forwardListenAddress = "127.0.0.1"
+	// defaultForwardRemoteHost is the node itself
+	defaultForwardRemoteHost = "127.0.0.1"
+)
+
+// Forward is a local port forwarded through a node to a remote address
+type Forward struct {
+	LocalPort  int
+	RemoteHost string // as seen from the node
+	RemotePort int
+	Name       string // what the port is for, optional
+}
+
+func (f Forward) remoteAddr() string {
+	return net.JoinHostPort(f.RemoteHost, strconv.Itoa(f.RemotePort))
+}
+
+func (f Forward) String() string {
+	s := fmt.Sprintf("%s:%d -> %s", forwardListenAddress, f.LocalPort, f.remoteAddr())
+	if f.Name != "" {
+		s += " (" + f.Name + ")"
+	}
+	return s
+}
+
+// ParseForward parses local:remote or local:host:remote like ssh -L.
+// Without a host the remote port is on the node itself.
+func ParseForward(spec string) (Forward, error) {
+	parts := strings.Split(spec, ":")
+	forward := Forward{RemoteHost: defaultForwardRemoteHost}
+	var local, remote string
+	switch len(parts) {
+	case 2:
+		local, remote = parts[0], parts[1]
+	case 3:
+		local, forward.RemoteHost, remote = parts[0], parts[1], parts[2]
+	default:
+		return Forward{}, fmt.Errorf("invalid port forward %s, use local:remote or local:host:remote", spec)
+	}
+	var err error
+	if forward.LocalPort, err = parsePort(local); err != nil {
+		return Forward{}, fmt.Errorf("invalid local port in %s: %w", spec, err)
+	}
+	if forward.RemotePort, err = parsePort(remote); err != nil {
+		return Forward{}, fmt.Errorf("invalid remote port in %s: %w", spec, err)
+	}
+	if forward.RemoteHost == "" {
+		return Forward{}, fmt.Errorf("invalid port forward %s, the host is empty", spec)
+	}
+	return forward, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port %d is out of range", port)
+	}
+	return port, nil
+}
+
+// AIStorForwards returns the forwards of the AIStor console and the object store S3 API and console.
+// The address of the aistor service is read with kubectl on the node.
+func AIStorForwards(ctx context.Context, node *Node) ([]Forward, error) {
+	var stdout, stderr bytes.Buffer
+	command := fmt.Sprintf("kubectl get service %s -n %s -o jsonpath='{.spec.clusterIP}'", aistorService, aistorNamespace)
+	if err := execOnNode(ctx, node, command, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("failed to get the address of the %s service: %w: %s", aistorService, err, strings.TrimSpace(stderr.String()))
+	}
+	serviceIP := strings.TrimSpace(stdout.String())
+	if net.ParseIP(serviceIP) == nil {
+		return nil, fmt.Errorf("the %s service has no cluster IP: %q", aistorService, serviceIP)
+	}
+	return []Forward{
+		{LocalPort: aistorConsolePort, RemoteHost: serviceIP, RemotePort: aistorConsolePort, Name: "AIStor console"},
+		{LocalPort: aistorObjectAPIPort, RemoteHost: node.Host, RemotePort: aistorObjectAPIPort, Name: "object store S3 API"},
+		{LocalPort: aistorObjectStoreUIPort, RemoteHost: node.Host, RemotePort: aistorObjectStoreUIPort, Name: "object store console"},
+	}, nil
+}
+
+// PortForward listens on the local ports and forwards the connections through the node
+// until ctx is canceled. It prints each forward to out once it's listening.
+func PortForward(ctx context.Context, node *Node, forwards []Forward, out io.Writer) error {
+	if len(forwards) == 0 {
+		return fmt.Errorf("no ports to forward")
+	}
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", node.Name, err)
+	}
+	defer client.Close()
+
+	listeners := make([]net.Listener, 0, len(forwards))
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+	for _, forward := range forwards {
+		listener, err := net.Listen("tcp", net.JoinHostPort(forwardListenAddress, strconv.Itoa(forward.LocalPort)))
+		if err != nil {
+			return fmt.Errorf("failed to listen on port %d: %w", forward.LocalPort, err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(forwards))
+	for i, forward := range forwards {
+		fmt.Fprintf(out, "Forwarding from %s\n", forward)
+		wg.Add(1)
+		go func(listener net.Listener, forward Forward) {
+			defer wg.Done()
+			errs <- serveForward(listener, client, forward, out)
+		}(listeners[i], forward)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+		// a listener failed, stop the others
+	}
+	for _, listener := range listeners {
+		listener.Close()
+	}
+	// closing the client ends the open connections
+	client.Close()
+	wg.Wait()
+	return err
+}
+
+// serveForward accepts the connections until the listener is closed
+func serveForward(listener net.Listener, client nodeClient, forward Forward, out io.Writer) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept on port %d: %w", forward.LocalPort, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer local.Close()
+			remote, err := client.Dial("tcp", forward.remoteAddr())
+			if err != nil {
+				fmt.Fprintf(out, "Failed to connect to %s: %v\n", forward.remoteAddr(), err)
+				return
+			}
+			defer remote.Close()
+			pipe(local, remote)
+		}()
+	}
+}
+
+// pipe copies data both ways until one side closes
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
@@ -0,0 +1,145 @@
+package lab
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseForward(t *testing.T) {
+	tests := []struct {
+		spec        string
+		want        Forward
+		errContains string
+	}{
+		{spec: "8444:8444", want: Forward{LocalPort: 8444, RemoteHost: "127.0.0.1", RemotePort: 8444}},
+		{spec: "9000:10.43.0.10:80", want: Forward{LocalPort: 9000, RemoteHost: "10.43.0.10", RemotePort: 80}},
+		{spec: "8444", errContains: "use local:remote or local:host:remote"},
+		{spec: "http:80", errContains: "invalid local port in http:80"},
+		{spec: "80:70000", errContains: "port 70000 is out of range"},
+		{spec: "80::80", errContains: "the host is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseForward(tt.spec)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// kubectlClient returns the cluster IP of the aistor service
+type kubectlClient struct {
+	fakeNodeClient
+}
+
+func (c *kubectlClient) Run(cmd string, stdout, stderr io.Writer) error {
+	if !strings.HasPrefix(cmd, "kubectl get service aistor -n aistor") {
+		return fmt.Errorf("unexpected command %s", cmd)
+	}
+	fmt.Fprint(stdout, "10.43.182.85")
+	return nil
+}
+
+func TestAIStorForwards(t *testing.T) {
+	original := newNodeClient
+	defer func() { newNodeClient = original }()
+	newNodeClient = func(node *Node) nodeClient { return &kubectlClient{fakeNodeClient{node: node}} }
+
+	forwards, err := AIStorForwards(context.Background(), &Node{Name: "test-cp", Host: "10.0.0.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Forward{
+		{LocalPort: 8444, RemoteHost: "10.43.182.85", RemotePort: 8444, Name: "AIStor console"},
+		{LocalPort: 30001, RemoteHost: "10.0.0.1", RemotePort: 30001, Name: "object store S3 API"},
+		{LocalPort: 31001, RemoteHost: "10.0.0.1", RemotePort: 31001, Name: "object store console"},
+	}, forwards)
+}
+
+// chanWriter sends each write to the channel
+type chanWriter struct {
+	ch chan string
+}
+
+func (b *chanWriter) Write(p []byte) (int, error) {
+	b.ch <- string(p)
+	return len(p), nil
+}
+
+func TestPortForward(t *testing.T) {
+	original := newNodeClient
+	defer func() { newNodeClient = original }()
+	newNodeClient = func(node *Node) nodeClient { return &fakeNodeClient{node: node} }
+
+	// the remote service echoes the lines it gets
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	remotePort := echo.Addr().(*net.TCPAddr).Port
+
+	// find a free local port
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	localPort := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &chanWriter{ch: make(chan string, 10)}
+	result := make(chan error)
+	forward := Forward{LocalPort: localPort, RemoteHost: "127.0.0.1", RemotePort: remotePort}
+	go func() {
+		result <- PortForward(ctx, &Node{Name: "test-cp", Host: "10.0.0.1"}, []Forward{forward}, out)
+	}()
+	assert.Equal(t, fmt.Sprintf("Forwarding from 127.0.0.1:%d -> 127.0.0.1:%d\n", localPort, remotePort), <-out.ch)
+
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", localPort), 5*time.Second)
+	if assert.NoError(t, err) {
+		fmt.Fprintln(conn, "ping")
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "ping\n", line)
+		conn.Close()
+	}
+
+	cancel()
+	assert.NoError(t, <-result)
+	_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", localPort))
+	assert.Error(t, err, "the local port is closed")
+}
+
+func TestPortForwardPortInUse(t *testing.T) {
+	original := newNodeClient
+	defer func() { newNodeClient = original }()
+	newNodeClient = func(node *Node) nodeClient { return &fakeNodeClient{node: node} }
+
+	used, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer used.Close()
+	port := used.Addr().(*net.TCPAddr).Port
+
+	err = PortForward(context.Background(), &Node{Name: "test-cp"}, []Forward{{LocalPort: port, RemoteHost: "127.0.0.1", RemotePort: 80}}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, fmt.Sprintf("failed to listen on port %d", port))
+}
@@ -0,0 +1,104 @@
+package lab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// recordResource adds a created resource to the lab journal
+func recordResource(lab *types.Lab, kind, name string) {
+	lab.Status.Resources = append(lab.Status.Resources, &types.LabResource{
+		Kind: kind,
+		Name: name,
+	})
+}
+
+// handleCreateFailure cleans up after a failed lab creation.
+// Unless rollback is disabled, it deletes the created resources in reverse order.
+// If rollback is disabled or fails, the lab is saved in the Failed state so it can be resumed or deleted later.
+func (m *ManagerSvc) handleCreateFailure(ctx context.Context, lab *types.Lab, createErr error, opts CreateOpts) error {
+	labName := lab.ObjectMeta.Name
+	if !opts.NoRollback {
+		fmt.Printf("Lab %s: Creation failed, rolling back %d resources...\n", labName, len(lab.Status.Resources))
+		rollbackErr := m.rollback(ctx, lab)
+		if rollbackErr == nil {
+			fmt.Printf("Lab %s: Rolled back.\n", labName)
+			// the lab was saved after each phase
+			if err := m.Storage.Delete(labName); err != nil {
+				return errors.Join(createErr, fmt.Errorf("failed to delete lab from storage: %w", err))
+			}
+			return createErr
+		}
+		createErr = errors.Join(createErr, fmt.Errorf("failed to roll back: %w", rollbackErr))
+	}
+	if err := m.saveFailed(ctx, lab, createErr); err != nil {
+		return errors.Join(createErr, err)
+	}
+	return fmt.Errorf("%w\nlab %s is saved in the %s state; resume it with 'storctl create lab %s --resume' or delete it with 'storctl delete lab %s --force'",
+		createErr, labName, types.LabStateFailed, labName, labName)
+}
+
+// rollback deletes the resources in the lab journal in reverse order.
+// Resources that can't be deleted stay in the journal.
+func (m *ManagerSvc) rollback(ctx context.Context, lab *types.Lab) error {
+	var errs []error
+	remaining := make([]*types.LabResource, 0)
+	for i := len(lab.Status.Resources) - 1; i >= 0; i-- {
+		resource := lab.Status.Resources[i]
+		fmt.Printf("Deleting %s %s...\n", resource.Kind, resource.Name)
+		if err := m.deleteResource(ctx, resource); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", resource.Kind, resource.Name, err))
+			remaining = append([]*types.LabResource{resource}, remaining...)
+		}
+	}
+	lab.Status.Resources = remaining
+	return errors.Join(errs...)
+}
+
+func (m *ManagerSvc) deleteResource(ctx context.Context, resource *types.LabResource) error {
+	switch resource.Kind {
+	case "Volume":
+		return m.Provider.DeleteVolume(ctx, resource.Name, true).Error
+	case "Server":
+		return m.Provider.DeleteServer(ctx, resource.Name, true).Error
+	case "SSHKey":
+		return m.Provider.DeleteSSHKey(ctx, resource.Name, true).Error
+	case "LocalSSHKey":
+		return m.SshManager.DeleteLocalKeyPair(resource.Name)
+	}
+	return fmt.Errorf("unknown resource kind %s", resource.Kind)
+}
+
+// saveFailed saves the partially created lab in the Failed state.
+// The servers and volumes are taken from the provider so Delete can find them.
+func (m *ManagerSvc) saveFailed(ctx context.Context, lab *types.Lab, createErr error) error {
+	lab.Status.State = types.LabStateFailed
+	lab.Status.Error = createErr.Error()
+	setStatusFromLabels(lab)
+	current, err := m.getLabFromProvider(ctx, lab.ObjectMeta.Name)
+	if err != nil {
+		m.Logger.Warn("failed to get lab from provider", "lab", lab.ObjectMeta.Name, "error", err)
+	} else {
+		lab.Status.Servers = current.Status.Servers
+		lab.Status.Volumes = current.Status.Volumes
+	}
+	if err := m.Storage.Save(lab); err != nil {
+		return fmt.Errorf("failed to save lab: %w", err)
+	}
+	return nil
+}
+
+// setStatusFromLabels sets the lab owner and delete_after from its labels
+// and the creation time if it's not set yet
+func setStatusFromLabels(lab *types.Lab) {
+	lab.Status.Owner = lab.ObjectMeta.Labels["owner"]
+	if lab.Status.Created.IsZero() {
+		lab.Status.Created = time.Now().UTC()
+	}
+	lab.Status.DeleteAfter = timeutil.ParseDeleteAfter(lab.ObjectMeta.Labels["delete_after"])
+}
@@ -0,0 +1,135 @@
+package lab
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRollback(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          CreateOpts
+		deleteErr     error
+		wantDeleted   []string
+		wantSaved     bool
+		wantResources []*types.LabResource
+	}{
+		{
+			name:          "rollback in reverse order",
+			wantDeleted:   []string{"Server test-cp", "Volume test-volume-01"},
+			wantSaved:     false,
+			wantResources: []*types.LabResource{},
+		},
+		{
+			name:        "no rollback",
+			opts:        CreateOpts{NoRollback: true},
+			wantDeleted: nil,
+			wantSaved:   true,
+			wantResources: []*types.LabResource{
+				{Kind: "Volume", Name: "test-volume-01"},
+				{Kind: "Server", Name: "test-cp"},
+			},
+		},
+		{
+			name:        "failed rollback",
+			deleteErr:   errors.New("server is locked"),
+			wantDeleted: []string{"Server test-cp", "Volume test-volume-01"},
+			wantSaved:   true,
+			wantResources: []*types.LabResource{
+				{Kind: "Server", Name: "test-cp"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []string
+			provider := &mock.MockProvider{
+				NameFunc: func() string { return "lima" },
+				CreateVolumeFunc: func(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
+					return testVolume(opts.Name, opts.Size), nil
+				},
+				CreateServerFunc: func(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+					if opts.Name == "test-node-01" {
+						return nil, errors.New("out of capacity")
+					}
+					return testServer(opts.Name, opts.Type), nil
+				},
+				DeleteServerFunc: func(ctx context.Context, name string, force bool) *types.ServerDeleteStatus {
+					assert.True(t, force)
+					deleted = append(deleted, "Server "+name)
+					if tt.deleteErr != nil {
+						return &types.ServerDeleteStatus{Error: tt.deleteErr}
+					}
+					return &types.ServerDeleteStatus{Deleted: true}
+				},
+				DeleteVolumeFunc: func(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
+					assert.True(t, force)
+					deleted = append(deleted, "Volume "+name)
+					return &types.VolumeDeleteStatus{Deleted: true}
+				},
+			}
+			m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+			lab := testLabSpec()
+			lab.Spec.Provider = "lima"
+			lab.ObjectMeta.Labels = map[string]string{"lab_name": "test"}
+
+			err := m.Create(context.Background(), lab, tt.opts)
+			assert.ErrorContains(t, err, "out of capacity")
+			assert.Equal(t, tt.wantDeleted, deleted)
+			assert.Equal(t, tt.wantResources, lab.Status.Resources)
+
+			saved, err := m.Storage.Get("test")
+			if !tt.wantSaved {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, types.LabStateFailed, saved.Status.State)
+			assert.Contains(t, saved.Status.Error, "out of capacity")
+			assert.Equal(t, tt.wantResources, saved.Status.Resources)
+		})
+	}
+}
+
+func TestCreateCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var deleted []string
+	provider := &mock.MockProvider{
+		NameFunc: func() string { return "lima" },
+		CreateVolumeFunc: func(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
+			return testVolume(opts.Name, opts.Size), nil
+		},
+		CreateServerFunc: func(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+			// Ctrl-C while the first server is created
+			cancel()
+			return nil, ctx.Err()
+		},
+		DeleteVolumeFunc: func(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
+			if err := ctx.Err(); err != nil {
+				return &types.VolumeDeleteStatus{Error: err}
+			}
+			deleted = append(deleted, "Volume "+name)
+			return &types.VolumeDeleteStatus{Deleted: true}
+		},
+	}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	lab := testLabSpec()
+	lab.Spec.Provider = "lima"
+
+	err := m.Create(ctx, lab, CreateOpts{})
+	assert.ErrorIs(t, err, context.Canceled)
+	// the created volume is rolled back even though the context is canceled
+	assert.Equal(t, []string{"Volume test-volume-01"}, deleted)
+	assert.Empty(t, lab.Status.Resources)
+	_, err = m.Storage.Get("test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,258 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// ScaleOpts are the options for Scale
+type ScaleOpts struct {
+	Nodes         int  // worker nodes
+	DrivesPerNode int  // volumes on each worker node; 0 keeps the current number
+	NoDrain       bool // delete the nodes without draining them, e.g. if the lab has no cluster
+}
+
+// ScaleResult is what Scale changed
+type ScaleResult struct {
+	Lab  *types.Lab // the scaled lab
+	Plan *Plan
+	// JoinServers are the new servers to join to the cluster
+	JoinServers []string
+	// DriveServers are the servers kept in the cluster that got new drives
+	DriveServers []string
+}
+
+// Scale adds or removes worker nodes and their drives following the lab naming scheme:
+// <lab>-node-NN servers and <lab>-volume-NN volumes.
+// Removed nodes are drained and deleted from the cluster in reverse order first.
+// The new servers are checked for readiness the same way as in Apply.
+func (m *ManagerSvc) Scale(ctx context.Context, labName string, opts ScaleOpts) (*ScaleResult, error) {
+	current, err := m.Get(ctx, labName)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := scaleSpec(current, opts)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := m.PlanApply(ctx, desired, PlanOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan changes: %w", err)
+	}
+	result := &ScaleResult{Lab: desired, Plan: plan, JoinServers: make([]string, 0), DriveServers: make([]string, 0)}
+	if plan.Empty() {
+		return result, nil
+	}
+
+	deleted := plan.Filter(ActionDelete, "Server")
+	if len(deleted) > 0 && !opts.NoDrain {
+		if err := m.drainNodes(ctx, current, deleted); err != nil {
+			return result, err
+		}
+	}
+	if err := m.executePlan(ctx, desired, plan); err != nil {
+		return result, fmt.Errorf("failed to apply changes: %w", err)
+	}
+	if err := m.refreshLab(ctx, desired); err != nil {
+		return result, err
+	}
+
+	result.JoinServers, result.DriveServers = scaledServers(labName, plan, desired.Status.Servers)
+	return result, nil
+}
+
+// scaledServers returns the new servers and the existing servers with new drives in the order of the servers.
+// The new servers get their drives when they join, so they aren't in drives.
+func scaledServers(labName string, plan *Plan, servers []*types.Server) (join, drives []string) {
+	created := make(map[string]bool)
+	for _, action := range plan.Filter(ActionCreate, "Server") {
+		created[action.Name] = true
+	}
+	newDrives := make(map[string]bool)
+	for _, action := range plan.Filter(ActionCreate, "Volume") {
+		newDrives[resourceName(labName, action.volume.Server)] = true
+	}
+	join = make([]string, 0)
+	drives = make([]string, 0)
+	for _, server := range servers {
+		switch name := server.ObjectMeta.Name; {
+		case created[name]:
+			join = append(join, name)
+		case newDrives[name]:
+			drives = append(drives, name)
+		}
+	}
+	return join, drives
+}
+
+// drainNodes drains the servers and deletes them from the cluster, the last one first
+func (m *ManagerSvc) drainNodes(ctx context.Context, lab *types.Lab, actions []Action) error {
+	nodes, err := m.labNodes(lab)
+	if err != nil {
+		return err
+	}
+	var controlPlane *Node
+	for _, node := range nodes {
+		if node.Role == RoleControlPlane {
+			controlPlane = node
+			break
+		}
+	}
+	if controlPlane == nil {
+		return fmt.Errorf("lab %s has no control plane node to drain the nodes from", lab.ObjectMeta.Name)
+	}
+	servers := make(map[string]*types.Server)
+	for _, server := range lab.Status.Servers {
+		servers[server.ObjectMeta.Name] = server
+	}
+	for i := len(actions) - 1; i >= 0; i-- {
+		server, ok := servers[actions[i].Name]
+		if !ok {
+			continue
+		}
+		nodeName := InventoryName(server)
+		fmt.Printf("Draining node %s...\n", nodeName)
+		command := fmt.Sprintf("kubectl drain %s --ignore-daemonsets --delete-emptydir-data --force --timeout=300s && kubectl delete node %s",
+			nodeName, nodeName)
+		var stdout, stderr bytes.Buffer
+		if err := execOnNode(ctx, controlPlane, command, &stdout, &stderr); err != nil {
+			return fmt.Errorf("failed to drain node %s: %w: %s", nodeName, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	return nil
+}
+
+// InventoryName returns the name of the server in the Ansible inventory and in the cluster
+func InventoryName(server *types.Server) string {
+	if server.Status.PublicNet != nil && server.Status.PublicNet.FQDN != "" {
+		return server.Status.PublicNet.FQDN
+	}
+	return server.ObjectMeta.Name
+}
+
+// scaleSpec returns a copy of the lab with the worker nodes and their volumes scaled.
+// Nodes are removed from the end and their volumes with them;
+// drives can't be removed from the nodes that are kept.
+func scaleSpec(lab *types.Lab, opts ScaleOpts) (*types.Lab, error) {
+	if opts.Nodes < 1 {
+		return nil, fmt.Errorf("the lab needs at least one worker node")
+	}
+	if opts.DrivesPerNode < 0 {
+		return nil, fmt.Errorf("the number of drives per node can't be negative")
+	}
+	workers := make([]*types.LabServerSpec, 0)
+	var controlPlane *types.LabServerSpec
+	for _, serverSpec := range lab.Spec.Servers {
+		if roleOf(serverSpec.Name) == RoleControlPlane {
+			if controlPlane == nil {
+				controlPlane = serverSpec
+			}
+			continue
+		}
+		workers = append(workers, serverSpec)
+	}
+	if controlPlane == nil {
+		return nil, fmt.Errorf("lab %s has no control plane server", lab.ObjectMeta.Name)
+	}
+
+	drives := make(map[string][]*types.LabVolumeSpec)
+	for _, volumeSpec := range lab.Spec.Volumes {
+		drives[volumeSpec.Server] = append(drives[volumeSpec.Server], volumeSpec)
+	}
+	drivesPerNode := opts.DrivesPerNode
+	if drivesPerNode == 0 {
+		for _, worker := range workers {
+			drivesPerNode = max(drivesPerNode, len(drives[worker.Name]))
+		}
+	}
+
+	desired := *lab
+	desired.Spec.Servers = make([]*types.LabServerSpec, 0, len(lab.Spec.Servers))
+	desired.Spec.Volumes = make([]*types.LabVolumeSpec, 0, len(lab.Spec.Volumes))
+	kept := make(map[string]bool)
+	for _, serverSpec := range lab.Spec.Servers {
+		if roleOf(serverSpec.Name) == RoleNodes {
+			if len(kept) == opts.Nodes {
+				continue
+			}
+			kept[serverSpec.Name] = true
+		}
+		desired.Spec.Servers = append(desired.Spec.Servers, serverSpec)
+	}
+	for _, volumeSpec := range lab.Spec.Volumes {
+		if roleOf(volumeSpec.Server) == RoleControlPlane || kept[volumeSpec.Server] {
+			desired.Spec.Volumes = append(desired.Spec.Volumes, volumeSpec)
+		}
+	}
+
+	serverTemplate := controlPlane
+	if len(workers) > 0 {
+		serverTemplate = workers[len(workers)-1]
+	}
+	nextNode := nextIndex(lab.Spec.Servers, func(s *types.LabServerSpec) string { return s.Name }, "node-")
+	for len(kept) < opts.Nodes {
+		serverSpec := &types.LabServerSpec{
+			Name:       fmt.Sprintf("node-%02d", nextNode),
+			Role:       serverTemplate.Role,
+			ServerType: serverTemplate.ServerType,
+			Image:      serverTemplate.Image,
+		}
+		if serverTemplate == controlPlane {
+			serverSpec.Role = "worker"
+		}
+		nextNode++
+		kept[serverSpec.Name] = true
+		desired.Spec.Servers = append(desired.Spec.Servers, serverSpec)
+	}
+
+	volumeTemplate := &types.LabVolumeSpec{
+		Size:      config.DefaultVolumeSize,
+		Format:    config.DefaultVolumeFormat,
+		Automount: config.DefaultVolumeAutomount,
+	}
+	if len(lab.Spec.Volumes) > 0 {
+		volumeTemplate = lab.Spec.Volumes[len(lab.Spec.Volumes)-1]
+	}
+	nextVolume := nextIndex(lab.Spec.Volumes, func(v *types.LabVolumeSpec) string { return v.Name }, "volume-")
+	for _, serverSpec := range desired.Spec.Servers {
+		if roleOf(serverSpec.Name) != RoleNodes {
+			continue
+		}
+		count := len(drives[serverSpec.Name])
+		if count > drivesPerNode {
+			return nil, fmt.Errorf("node %s has %d drives; drives can't be removed from the nodes that are kept", serverSpec.Name, count)
+		}
+		for ; count < drivesPerNode; count++ {
+			desired.Spec.Volumes = append(desired.Spec.Volumes, &types.LabVolumeSpec{
+				Name:      fmt.Sprintf("volume-%02d", nextVolume),
+				Server:    serverSpec.Name,
+				Size:      volumeTemplate.Size,
+				Format:    volumeTemplate.Format,
+				Automount: volumeTemplate.Automount,
+			})
+			nextVolume++
+		}
+	}
+	return &desired, nil
+}
+
+// nextIndex returns the number after the highest <prefix>NN name
+func nextIndex[T any](items []T, name func(T) string, prefix string) int {
+	next := 1
+	for _, item := range items {
+		suffix, ok := strings.CutPrefix(name(item), prefix)
+		if !ok {
+			continue
+		}
+		if index, err := strconv.Atoi(suffix); err == nil {
+			next = max(next, index+1)
+		}
+	}
+	return next
+}
@@ -0,0 +1,126 @@
+package lab
+
+import (
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleSpec(t *testing.T) {
+	node := func(name string) *types.LabServerSpec {
+		return &types.LabServerSpec{Name: name, ServerType: "cx22", Image: "ubuntu-24.04"}
+	}
+	volume := func(name, server string) *types.LabVolumeSpec {
+		return &types.LabVolumeSpec{Name: name, Server: server, Size: 100}
+	}
+	twoNodes := func() *types.Lab {
+		lab := testLabSpec()
+		lab.Spec.Servers = append(lab.Spec.Servers, node("node-02"))
+		lab.Spec.Volumes = []*types.LabVolumeSpec{
+			volume("volume-01", "node-01"),
+			volume("volume-02", "node-01"),
+			volume("volume-03", "node-02"),
+			volume("volume-04", "node-02"),
+		}
+		return lab
+	}
+
+	tests := []struct {
+		name        string
+		lab         *types.Lab
+		opts        ScaleOpts
+		wantServers []string
+		wantVolumes []*types.LabVolumeSpec
+		errContains string
+	}{
+		{
+			name:        "add nodes with the same number of drives",
+			lab:         twoNodes(),
+			opts:        ScaleOpts{Nodes: 3},
+			wantServers: []string{"cp", "node-01", "node-02", "node-03"},
+			wantVolumes: []*types.LabVolumeSpec{
+				volume("volume-01", "node-01"),
+				volume("volume-02", "node-01"),
+				volume("volume-03", "node-02"),
+				volume("volume-04", "node-02"),
+				volume("volume-05", "node-03"),
+				volume("volume-06", "node-03"),
+			},
+		},
+		{
+			name:        "add drives to all nodes",
+			lab:         testLabSpec(),
+			opts:        ScaleOpts{Nodes: 2, DrivesPerNode: 2},
+			wantServers: []string{"cp", "node-01", "node-02"},
+			wantVolumes: []*types.LabVolumeSpec{
+				volume("volume-01", "node-01"),
+				volume("volume-02", "node-01"),
+				volume("volume-03", "node-02"),
+				volume("volume-04", "node-02"),
+			},
+		},
+		{
+			name:        "remove the last node with its drives",
+			lab:         twoNodes(),
+			opts:        ScaleOpts{Nodes: 1},
+			wantServers: []string{"cp", "node-01"},
+			wantVolumes: []*types.LabVolumeSpec{
+				volume("volume-01", "node-01"),
+				volume("volume-02", "node-01"),
+			},
+		},
+		{
+			name:        "no change",
+			lab:         twoNodes(),
+			opts:        ScaleOpts{Nodes: 2, DrivesPerNode: 2},
+			wantServers: []string{"cp", "node-01", "node-02"},
+			wantVolumes: twoNodes().Spec.Volumes,
+		},
+		{
+			name:        "remove drives",
+			lab:         twoNodes(),
+			opts:        ScaleOpts{Nodes: 2, DrivesPerNode: 1},
+			errContains: "node node-01 has 2 drives; drives can't be removed from the nodes that are kept",
+		},
+		{
+			name:        "no nodes",
+			lab:         twoNodes(),
+			opts:        ScaleOpts{Nodes: 0},
+			errContains: "the lab needs at least one worker node",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := len(tt.lab.Spec.Servers)
+			got, err := scaleSpec(tt.lab, tt.opts)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			names := make([]string, 0, len(got.Spec.Servers))
+			for _, serverSpec := range got.Spec.Servers {
+				names = append(names, serverSpec.Name)
+				assert.Equal(t, "cx22", serverSpec.ServerType)
+				assert.Equal(t, "ubuntu-24.04", serverSpec.Image)
+			}
+			assert.Equal(t, tt.wantServers, names)
+			assert.Equal(t, tt.wantVolumes, got.Spec.Volumes)
+			assert.Len(t, tt.lab.Spec.Servers, original, "the lab is not changed")
+		})
+	}
+}
+
+func TestScaledServers(t *testing.T) {
+	plan := &Plan{Lab: "test", Actions: []Action{
+		{Type: ActionCreate, Kind: "Volume", Name: "test-volume-02", volume: &types.LabVolumeSpec{Name: "volume-02", Server: "node-01"}},
+		{Type: ActionCreate, Kind: "Server", Name: "test-node-02"},
+		{Type: ActionCreate, Kind: "Volume", Name: "test-volume-03", volume: &types.LabVolumeSpec{Name: "volume-03", Server: "node-02"}},
+	}}
+	servers := []*types.Server{testServer("test-cp", "cx22"), testServer("test-node-01", "cx22"), testServer("test-node-02", "cx22")}
+
+	join, drives := scaledServers("test", plan, servers)
+	assert.Equal(t, []string{"test-node-02"}, join, "only the new servers join the cluster")
+	assert.Equal(t, []string{"test-node-01"}, drives, "the existing servers with new drives only get the drives set up")
+}
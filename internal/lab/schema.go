@@ -0,0 +1,312 @@
+package lab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/pavelanni/storctl/internal/types"
+	"go.etcd.io/bbolt"
+)
+
+// SchemaVersion is the version of the lab records this storctl writes.
+// Add a migration to migrations when the records change in a way old records have to be upgraded.
+const SchemaVersion = 1
+
+const (
+	// metaBucket keeps the storage metadata next to the labs bucket
+	metaBucket = "storctl_meta"
+	// schemaVersionKey is the schema version of the lab records; no key means version 0
+	schemaVersionKey = "schema_version"
+)
+
+// Migration upgrades a lab record to its version from the previous one.
+// Migrations have to be safe to run again on a record already migrated.
+type Migration struct {
+	Version     int
+	Description string
+	// Migrate changes the decoded JSON record and returns true if it changed
+	Migrate func(record map[string]any) (bool, error)
+}
+
+// migrations are applied in order to the records older than their version
+var migrations = []Migration{
+	{Version: 1, Description: "set the lab provider from the lab servers", Migrate: migrateLabProvider},
+}
+
+// migrateLabProvider sets spec.provider of the labs recorded before it was set, so sync finds them
+func migrateLabProvider(record map[string]any) (bool, error) {
+	spec, _ := record["spec"].(map[string]any)
+	if spec == nil {
+		return false, fmt.Errorf("record has no spec")
+	}
+	if provider, _ := spec["provider"].(string); provider != "" {
+		return false, nil
+	}
+	status, _ := record["status"].(map[string]any)
+	servers, _ := status["servers"].([]any)
+	for _, server := range servers {
+		serverSpec, _ := server.(map[string]any)["spec"].(map[string]any)
+		if provider, _ := serverSpec["provider"].(string); provider != "" {
+			spec["provider"] = provider
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MigrateOpts are the options for Migrate
+type MigrateOpts struct {
+	DryRun bool
+	// All runs all the migrations, not only the ones after the stored version,
+	// to repair the records saved by an older storctl after the storage was migrated
+	All bool
+}
+
+// MigrationResult is what Migrate did
+type MigrationResult struct {
+	From     int
+	To       int
+	Applied  []string // the migrations applied
+	Migrated []string // the labs changed
+	Skipped  []string // the labs that can't be migrated, with the reason
+	Backup   string   // the copy of the database before the migration
+}
+
+// schemaVersion returns the stored schema version
+func schemaVersion(tx *bbolt.Tx) (int, error) {
+	b := tx.Bucket([]byte(metaBucket))
+	if b == nil {
+		return 0, nil
+	}
+	value := b.Get([]byte(schemaVersionKey))
+	if value == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q: %w", value, err)
+	}
+	return version, nil
+}
+
+func setSchemaVersion(tx *bbolt.Tx, version int) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+	if err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", metaBucket, err)
+	}
+	return b.Put([]byte(schemaVersionKey), []byte(strconv.Itoa(version)))
+}
+
+// SchemaVersion returns the schema version of the stored lab records
+func (s *BoltStorage) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		version, err = schemaVersion(tx)
+		return err
+	})
+	return version, err
+}
+
+// Migrate upgrades the lab records to SchemaVersion in one transaction.
+// The database is copied next to it first if any record changes.
+// A record a migration fails on is skipped and reported in the result, and the schema version
+// stays as it is until all the records are migrated, so one bad record doesn't block the others.
+func (s *BoltStorage) Migrate(opts MigrateOpts) (*MigrationResult, error) {
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > SchemaVersion {
+		return nil, fmt.Errorf("lab storage schema version %d is newer than %d, upgrade storctl", current, SchemaVersion)
+	}
+	from := current
+	if opts.All {
+		from = 0
+	}
+	result := &MigrationResult{From: from, To: SchemaVersion, Applied: make([]string, 0), Migrated: make([]string, 0), Skipped: make([]string, 0)}
+	pending := make([]Migration, 0)
+	for _, migration := range migrations {
+		if migration.Version > from {
+			pending = append(pending, migration)
+			result.Applied = append(result.Applied, fmt.Sprintf("%d: %s", migration.Version, migration.Description))
+		}
+	}
+
+	updates := make(map[string][]byte)
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.labBucket).ForEach(func(k, v []byte) error {
+			data, changed, err := migrateRecord(v, pending)
+			if err != nil {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", k, err))
+				return nil
+			}
+			if changed {
+				updates[string(k)] = data
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name := range updates {
+		result.Migrated = append(result.Migrated, name)
+	}
+	sort.Strings(result.Migrated)
+	sort.Strings(result.Skipped)
+	if opts.DryRun || (len(updates) == 0 && (current == SchemaVersion || len(result.Skipped) > 0)) {
+		return result, nil
+	}
+
+	if len(updates) > 0 {
+		result.Backup = fmt.Sprintf("%s.v%d.bak", s.db.Path(), current)
+		if err := s.copyTo(result.Backup); err != nil {
+			return nil, fmt.Errorf("failed to back up lab storage: %w", err)
+		}
+	}
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.labBucket)
+		for name, data := range updates {
+			if err := b.Put([]byte(name), data); err != nil {
+				return err
+			}
+		}
+		if len(result.Skipped) > 0 {
+			return nil
+		}
+		return setSchemaVersion(tx, SchemaVersion)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save migrated labs: %w", err)
+	}
+	return result, nil
+}
+
+// migrateRecord applies the migrations to the JSON record
+func migrateRecord(data []byte, pending []Migration) ([]byte, bool, error) {
+	if len(pending) == 0 {
+		return data, false, nil
+	}
+	record := make(map[string]any)
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, false, fmt.Errorf("invalid JSON: %w", err)
+	}
+	changed := false
+	for _, migration := range pending {
+		migrated, err := migration.Migrate(record)
+		if err != nil {
+			return nil, false, fmt.Errorf("migration %d: %w", migration.Version, err)
+		}
+		changed = changed || migrated
+	}
+	if !changed {
+		return data, false, nil
+	}
+	data, err := json.Marshal(record)
+	return data, true, err
+}
+
+// copyTo writes a consistent copy of the database
+func (s *BoltStorage) copyTo(path string) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := tx.WriteTo(f); err != nil {
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// CheckResult is what Check found in the lab storage
+type CheckResult struct {
+	Version  int
+	Labs     int
+	Problems []CheckProblem
+}
+
+// CheckProblem is a lab record that can't be used as is
+type CheckProblem struct {
+	Lab     string
+	Problem string
+}
+
+// Check decodes every lab record strictly and reports the records that don't match the lab schema
+func (s *BoltStorage) Check() (*CheckResult, error) {
+	result := &CheckResult{Problems: make([]CheckProblem, 0)}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		version, err := schemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		result.Version = version
+		return tx.Bucket(s.labBucket).ForEach(func(k, v []byte) error {
+			result.Labs++
+			name := string(k)
+			problem := func(format string, args ...any) {
+				result.Problems = append(result.Problems, CheckProblem{Lab: name, Problem: fmt.Sprintf(format, args...)})
+			}
+			decoder := json.NewDecoder(bytes.NewReader(v))
+			decoder.DisallowUnknownFields()
+			lab := &types.Lab{}
+			if err := decoder.Decode(lab); err != nil {
+				problem("doesn't match the lab schema: %v", err)
+				return nil
+			}
+			if lab.ObjectMeta.Name != name {
+				problem("is stored as %s but named %q", name, lab.ObjectMeta.Name)
+			}
+			if lab.Spec.Provider == "" {
+				problem("has no provider")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Dump writes the schema version and the raw lab records as JSON.
+// Records that aren't valid JSON are written as strings.
+func (s *BoltStorage) Dump(w io.Writer) error {
+	dump := struct {
+		SchemaVersion int                        `json:"schemaVersion"`
+		Labs          map[string]json.RawMessage `json:"labs"`
+	}{Labs: make(map[string]json.RawMessage)}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		version, err := schemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		dump.SchemaVersion = version
+		return tx.Bucket(s.labBucket).ForEach(func(k, v []byte) error {
+			if json.Valid(v) {
+				dump.Labs[string(k)] = append(json.RawMessage(nil), v...)
+				return nil
+			}
+			quoted, err := json.Marshal(string(v))
+			if err != nil {
+				return err
+			}
+			dump.Labs[string(k)] = quoted
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dump)
+}
@@ -0,0 +1,169 @@
+package lab
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+// writeLegacyDB writes the lab records like storctl did before the schema version
+func writeLegacyDB(t *testing.T, path string, records map[string]string) {
+	db, err := bbolt.Open(path, 0600, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("labs"))
+		if err != nil {
+			return err
+		}
+		for name, record := range records {
+			if err := b.Put([]byte(name), []byte(record)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	assert.NoError(t, db.Close())
+}
+
+func TestSchemaMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labs.db")
+	cfg := &config.Config{Storage: config.StorageConfig{Path: path, Bucket: "labs"}}
+	writeLegacyDB(t, path, map[string]string{
+		"old": `{"metadata":{"name":"old"},"spec":{},"status":{"servers":[{"metadata":{"name":"old-cp"},"spec":{"provider":"hetzner"}}]}}`,
+		"new": `{"metadata":{"name":"new"},"spec":{"provider":"lima"},"status":{}}`,
+	})
+
+	storage, err := OpenBoltStorage(cfg)
+	assert.NoError(t, err)
+	version, err := storage.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+	check, err := storage.Check()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, check.Labs)
+	assert.Equal(t, []CheckProblem{{Lab: "old", Problem: "has no provider"}}, check.Problems)
+
+	result, err := storage.Migrate(MigrateOpts{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old"}, result.Migrated)
+	assert.Empty(t, result.Backup)
+	version, _ = storage.SchemaVersion()
+	assert.Equal(t, 0, version, "a dry run doesn't change the storage")
+	assert.NoError(t, storage.Close())
+
+	// opening the storage migrates it
+	storage, err = NewBoltStorage(cfg)
+	assert.NoError(t, err)
+	version, err = storage.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+	lab, err := storage.Get("old")
+	assert.NoError(t, err)
+	assert.Equal(t, "hetzner", lab.Spec.Provider)
+	assert.FileExists(t, path+".v0.bak")
+	check, err = storage.Check()
+	assert.NoError(t, err)
+	assert.Empty(t, check.Problems)
+
+	result, err = storage.Migrate(MigrateOpts{All: true})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Migrated, "the migrations don't change migrated records")
+	assert.NoError(t, storage.Close())
+
+	// the backup is the storage before the migration
+	backup, err := OpenBoltStorage(&config.Config{Storage: config.StorageConfig{Path: path + ".v0.bak", Bucket: "labs"}})
+	assert.NoError(t, err)
+	version, _ = backup.SchemaVersion()
+	assert.Equal(t, 0, version)
+	assert.NoError(t, backup.Close())
+}
+
+func TestSchemaNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labs.db")
+	cfg := &config.Config{Storage: config.StorageConfig{Path: path, Bucket: "labs"}}
+	storage, err := OpenBoltStorage(cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, storage.db.Update(func(tx *bbolt.Tx) error { return setSchemaVersion(tx, SchemaVersion+1) }))
+	assert.NoError(t, storage.Close())
+
+	_, err = NewBoltStorage(cfg)
+	assert.ErrorContains(t, err, "upgrade storctl")
+}
+
+func TestSchemaMigrationSkipsBadRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labs.db")
+	cfg := &config.Config{Storage: config.StorageConfig{Path: path, Bucket: "labs"}}
+	writeLegacyDB(t, path, map[string]string{
+		"nospec": `{"metadata":{"name":"nospec"}}`,
+		"old":    `{"metadata":{"name":"old"},"spec":{},"status":{"servers":[{"metadata":{"name":"old-cp"},"spec":{"provider":"hetzner"}}]}}`,
+	})
+
+	// a record without spec doesn't keep the storage from opening
+	storage, err := NewBoltStorage(cfg)
+	assert.NoError(t, err)
+	lab, err := storage.Get("old")
+	assert.NoError(t, err)
+	assert.Equal(t, "hetzner", lab.Spec.Provider, "the other records are migrated")
+	version, _ := storage.SchemaVersion()
+	assert.Equal(t, 0, version)
+	result, err := storage.Migrate(MigrateOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nospec: migration 1: record has no spec"}, result.Skipped)
+	assert.Empty(t, result.Migrated)
+
+	// once the record is deleted, the storage is at the current version
+	assert.NoError(t, storage.Delete("nospec"))
+	_, err = storage.Migrate(MigrateOpts{})
+	assert.NoError(t, err)
+	version, _ = storage.SchemaVersion()
+	assert.Equal(t, SchemaVersion, version)
+	assert.NoError(t, storage.Close())
+}
+
+func TestSchemaCheckAndDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labs.db")
+	writeLegacyDB(t, path, map[string]string{
+		"broken":  `{"metadata":`,
+		"renamed": `{"metadata":{"name":"other"},"spec":{"provider":"lima"}}`,
+		"unknown": `{"metadata":{"name":"unknown"},"spec":{"provider":"lima","color":"red"}}`,
+	})
+	storage, err := OpenBoltStorage(&config.Config{Storage: config.StorageConfig{Path: path, Bucket: "labs"}})
+	assert.NoError(t, err)
+	defer storage.Close()
+
+	check, err := storage.Check()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, check.Labs)
+	problems := make(map[string]string)
+	for _, problem := range check.Problems {
+		problems[problem.Lab] = problem.Problem
+	}
+	assert.Contains(t, problems["broken"], "doesn't match the lab schema")
+	assert.Equal(t, `is stored as renamed but named "other"`, problems["renamed"])
+	assert.Contains(t, problems["unknown"], `unknown field "color"`)
+
+	result, err := storage.Migrate(MigrateOpts{})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Skipped, 1) {
+		assert.Contains(t, result.Skipped[0], "broken: invalid JSON")
+	}
+	version, _ := storage.SchemaVersion()
+	assert.Equal(t, 0, version, "the schema version isn't set while a record isn't migrated")
+
+	var out bytes.Buffer
+	assert.NoError(t, storage.Dump(&out))
+	var dump struct {
+		SchemaVersion int                        `json:"schemaVersion"`
+		Labs          map[string]json.RawMessage `json:"labs"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &dump))
+	assert.Equal(t, 0, dump.SchemaVersion)
+	assert.Len(t, dump.Labs, 3)
+	assert.JSONEq(t, `"{\"metadata\":"`, string(dump.Labs["broken"]))
+	assert.JSONEq(t, `{"metadata":{"name":"other"},"spec":{"provider":"lima"}}`, string(dump.Labs["renamed"]))
+}
@@ -0,0 +1,244 @@
+package lab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/types"
+	"go.etcd.io/bbolt"
+)
+
+// Storage keeps the lab records
+type Storage interface {
+	// Get returns the lab or an error if it isn't stored
+	Get(labName string) (*types.Lab, error)
+	// Save stores the lab; ErrConflict means someone else changed the lab since it was read
+	Save(lab *types.Lab) error
+	Delete(labName string) error
+	List() ([]*types.Lab, error)
+	Close() error
+}
+
+// ErrNotFound is wrapped in the error of Get when the lab isn't stored
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned by the shared storages when the lab was changed by someone else
+var ErrConflict = errors.New("lab was changed by someone else, run the command again")
+
+// NewLabStorage opens the storage backend selected in the config
+func NewLabStorage(cfg *config.Config) (Storage, error) {
+	switch cfg.Storage.Backend {
+	case "", config.StorageBackendBbolt:
+		return NewBoltStorage(cfg)
+	case config.StorageBackendS3:
+		return NewS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %s, use %s or %s", cfg.Storage.Backend, config.StorageBackendBbolt, config.StorageBackendS3)
+	}
+}
+
+// BoltStorage keeps the labs in a local bbolt file
+type BoltStorage struct {
+	db        *bbolt.DB
+	labBucket []byte
+}
+
+var _ Storage = (*BoltStorage)(nil)
+
+// boltLockTimeout is how long a call of the per-call storage waits for another storctl holding the bbolt file
+const boltLockTimeout = time.Minute
+
+func NewBboltDB(path string) (*bbolt.DB, error) {
+	return newBboltDB(path, 0)
+}
+
+// newBboltDB opens the bbolt file, waiting for the file lock up to timeout; 0 means no timeout
+func newBboltDB(path string, timeout time.Duration) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bbolt db: %w", err)
+	}
+	return db, nil
+}
+
+// NewBoltStorage opens the lab storage and migrates the lab records to SchemaVersion.
+// The records that can't be migrated are logged and left for storctl db to inspect.
+func NewBoltStorage(cfg *config.Config) (*BoltStorage, error) {
+	storage, err := OpenBoltStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	result, err := storage.Migrate(MigrateOpts{})
+	if err != nil {
+		storage.Close()
+		return nil, fmt.Errorf("failed to migrate lab storage %s: %w", cfg.Storage.Path, err)
+	}
+	if len(result.Skipped) > 0 {
+		logger.Get().Warn("lab records not migrated, run storctl db check", "labs", result.Skipped)
+	}
+	return storage, nil
+}
+
+// OpenBoltStorage opens the lab storage as is, to check or migrate it
+func OpenBoltStorage(cfg *config.Config) (*BoltStorage, error) {
+	return openBoltStorage(cfg, 0)
+}
+
+func openBoltStorage(cfg *config.Config, timeout time.Duration) (*BoltStorage, error) {
+	db, err := newBboltDB(cfg.Storage.Path, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bbolt from file %s: %w", cfg.Storage.Path, err)
+	}
+
+	// Create bucket if it doesn't exist
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(cfg.Storage.Bucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bucket %s: %w", cfg.Storage.Bucket, err)
+	}
+
+	return &BoltStorage{
+		db:        db,
+		labBucket: []byte(cfg.Storage.Bucket),
+	}, nil
+}
+
+func (s *BoltStorage) Get(labName string) (*types.Lab, error) {
+	var lab *types.Lab
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.labBucket)
+		data := b.Get([]byte(labName))
+		if data == nil {
+			return fmt.Errorf("lab %s %w", labName, ErrNotFound)
+		}
+
+		lab = &types.Lab{}
+		if err := json.Unmarshal(data, lab); err != nil {
+			return fmt.Errorf("failed to unmarshal lab: %w", err)
+		}
+		return nil
+	})
+
+	return lab, err
+}
+
+func (s *BoltStorage) Save(lab *types.Lab) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.labBucket)
+		data, err := json.Marshal(lab)
+		if err != nil {
+			return fmt.Errorf("failed to marshal lab: %w", err)
+		}
+		return b.Put([]byte(lab.Name), data)
+	})
+}
+
+func (s *BoltStorage) Delete(labName string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(s.labBucket).Delete([]byte(labName))
+	})
+}
+
+func (s *BoltStorage) List() ([]*types.Lab, error) {
+	var labs []*types.Lab
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.labBucket)
+		if b == nil {
+			return fmt.Errorf("labs bucket not found in database")
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var lab types.Lab
+			if err := json.Unmarshal(v, &lab); err != nil {
+				return fmt.Errorf("failed to unmarshal lab: %w", err)
+			}
+			labs = append(labs, &lab)
+			return nil
+		})
+	})
+
+	return labs, err
+}
+
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
+
+// NewDaemonStorage opens the storage for the commands that run until interrupted, like reaper and notify.
+// The bbolt file is opened for each call and closed after it, so the other storctl commands
+// aren't locked out while the daemon waits for the next check.
+func NewDaemonStorage(cfg *config.Config) (Storage, error) {
+	if cfg.Storage.Backend != "" && cfg.Storage.Backend != config.StorageBackendBbolt {
+		return NewLabStorage(cfg)
+	}
+	// migrate the records once, and fail now if the file can't be opened
+	storage, err := NewBoltStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close lab storage: %w", err)
+	}
+	return &boltPerCallStorage{cfg: cfg}, nil
+}
+
+// boltPerCallStorage opens the bbolt file for each call
+type boltPerCallStorage struct {
+	cfg *config.Config
+}
+
+var _ Storage = (*boltPerCallStorage)(nil)
+
+// with runs f with the bbolt storage open
+func (s *boltPerCallStorage) with(f func(storage *BoltStorage) error) error {
+	storage, err := openBoltStorage(s.cfg, boltLockTimeout)
+	if err != nil {
+		return err
+	}
+	err = f(storage)
+	if closeErr := storage.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close lab storage: %w", closeErr)
+	}
+	return err
+}
+
+func (s *boltPerCallStorage) Get(labName string) (*types.Lab, error) {
+	var lab *types.Lab
+	err := s.with(func(storage *BoltStorage) error {
+		var err error
+		lab, err = storage.Get(labName)
+		return err
+	})
+	return lab, err
+}
+
+func (s *boltPerCallStorage) Save(lab *types.Lab) error {
+	return s.with(func(storage *BoltStorage) error { return storage.Save(lab) })
+}
+
+func (s *boltPerCallStorage) Delete(labName string) error {
+	return s.with(func(storage *BoltStorage) error { return storage.Delete(labName) })
+}
+
+func (s *boltPerCallStorage) List() ([]*types.Lab, error) {
+	var labs []*types.Lab
+	err := s.with(func(storage *BoltStorage) error {
+		var err error
+		labs, err = storage.List()
+		return err
+	})
+	return labs, err
+}
+
+func (s *boltPerCallStorage) Close() error {
+	return nil
+}
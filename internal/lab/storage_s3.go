@@ -0,0 +1,288 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// s3Timeout limits each storage request, as the Storage methods have no context
+const s3Timeout = 30 * time.Second
+
+// s3API is the part of the S3 client the storage uses
+type s3API interface {
+	s3.ListObjectsV2APIClient
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// S3Storage keeps each lab as a JSON object in an S3-compatible bucket, so a team shares the labs.
+// Writes are conditional on the ETag of the object last read, so a lab changed by someone else
+// in the meantime isn't overwritten, and a new lab doesn't replace one created at the same time.
+type S3Storage struct {
+	client s3API
+	bucket string
+	prefix string
+
+	mu    sync.Mutex
+	etags map[string]string // the ETags of the lab objects read or written, by lab name
+}
+
+var _ Storage = (*S3Storage)(nil)
+
+// NewS3Storage connects to the bucket in the storage config.
+// The credentials in the config take precedence over the AWS environment and shared config.
+func NewS3Storage(cfg *config.Config) (*S3Storage, error) {
+	storageConfig := cfg.Storage
+	if storageConfig.Bucket == "" {
+		return nil, fmt.Errorf("storage bucket is not set in the config file")
+	}
+	region := storageConfig.Region
+	if region == "" {
+		region = config.DefaultS3StorageRegion
+	}
+	opts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(region)}
+	if profile := storageConfig.Credentials["profile"]; profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(profile))
+	}
+	if accessKeyID := storageConfig.Credentials["access_key_id"]; accessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			accessKeyID,
+			storageConfig.Credentials["secret_access_key"],
+			storageConfig.Credentials["session_token"])))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading S3 config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if storageConfig.Endpoint != "" {
+			// AIStor and MinIO serve the buckets in the path, not in the host name
+			o.BaseEndpoint = awssdk.String(storageConfig.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: awssdk.String(storageConfig.Bucket)}); err != nil {
+		return nil, fmt.Errorf("failed to access storage bucket %s: %w", storageConfig.Bucket, err)
+	}
+	return newS3Storage(client, storageConfig.Bucket, storageConfig.Prefix), nil
+}
+
+func newS3Storage(client s3API, bucket, prefix string) *S3Storage {
+	if prefix == "" {
+		prefix = config.DefaultS3StoragePrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &S3Storage{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+		etags:  make(map[string]string),
+	}
+}
+
+func (s *S3Storage) key(labName string) string {
+	return s.prefix + labName + ".json"
+}
+
+func (s *S3Storage) setETag(labName string, etag *string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if etag == nil {
+		delete(s.etags, labName)
+		return
+	}
+	s.etags[labName] = *etag
+}
+
+func (s *S3Storage) etag(labName string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	etag, ok := s.etags[labName]
+	return etag, ok
+}
+
+func (s *S3Storage) Get(labName string) (*types.Lab, error) {
+	lab, err := s.get(labName)
+	if err == nil && lab == nil {
+		return nil, fmt.Errorf("lab %s %w", labName, ErrNotFound)
+	}
+	return lab, err
+}
+
+// get returns nil without an error if the lab object doesn't exist
+func (s *S3Storage) get(labName string) (*types.Lab, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: awssdk.String(s.bucket),
+		Key:    awssdk.String(s.key(labName)),
+	})
+	if isNotFound(err) {
+		s.setETag(labName, nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab %s: %w", labName, err)
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lab %s: %w", labName, err)
+	}
+	lab := &types.Lab{}
+	if err := json.Unmarshal(data, lab); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lab: %w", err)
+	}
+	s.setETag(labName, out.ETag)
+	lab.ResourceVersion = awssdk.ToString(out.ETag)
+	return lab, nil
+}
+
+// Save replaces the lab object read before only if it wasn't changed since,
+// and creates a new lab object only if there is none.
+// The ResourceVersion the lab was read as takes precedence over the ETag this storage read last,
+// so a lab read before someone else changed it isn't saved over their change.
+func (s *S3Storage) Save(lab *types.Lab) error {
+	data, err := json.Marshal(lab)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lab: %w", err)
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      awssdk.String(s.bucket),
+		Key:         awssdk.String(s.key(lab.Name)),
+		Body:        bytes.NewReader(data),
+		ContentType: awssdk.String("application/json"),
+	}
+	if lab.ResourceVersion != "" {
+		input.IfMatch = awssdk.String(lab.ResourceVersion)
+	} else if etag, ok := s.etag(lab.Name); ok {
+		input.IfMatch = awssdk.String(etag)
+	} else {
+		input.IfNoneMatch = awssdk.String("*")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	out, err := s.client.PutObject(ctx, input)
+	if isConflict(err) {
+		return fmt.Errorf("failed to save lab %s: %w", lab.Name, ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save lab %s: %w", lab.Name, err)
+	}
+	s.setETag(lab.Name, out.ETag)
+	lab.ResourceVersion = awssdk.ToString(out.ETag)
+	return nil
+}
+
+// Delete deletes the lab object if it wasn't changed since it was read
+func (s *S3Storage) Delete(labName string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: awssdk.String(s.bucket),
+		Key:    awssdk.String(s.key(labName)),
+	}
+	if etag, ok := s.etag(labName); ok {
+		input.IfMatch = awssdk.String(etag)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	_, err := s.client.DeleteObject(ctx, input)
+	if isConflict(err) {
+		return fmt.Errorf("failed to delete lab %s: %w", labName, ErrConflict)
+	}
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete lab %s: %w", labName, err)
+	}
+	s.setETag(labName, nil)
+	return nil
+}
+
+// List returns the labs in the bucket by name
+func (s *S3Storage) List() ([]*types.Lab, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	names := make([]string, 0)
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: awssdk.String(s.bucket),
+		Prefix: awssdk.String(s.prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list labs: %w", err)
+		}
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(awssdk.ToString(object.Key), s.prefix)
+			if strings.Contains(name, "/") || !strings.HasSuffix(name, ".json") {
+				continue
+			}
+			names = append(names, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(names)
+	labs := make([]*types.Lab, 0, len(names))
+	for _, name := range names {
+		lab, err := s.get(name)
+		if err != nil {
+			return nil, err
+		}
+		if lab == nil {
+			// deleted since it was listed
+			continue
+		}
+		labs = append(labs, lab)
+	}
+	return labs, nil
+}
+
+func (s *S3Storage) Close() error {
+	return nil
+}
+
+// isNotFound returns true if the object doesn't exist
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	var noSuchKey *s3types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NoSuchKey" || apiErr.ErrorCode() == "NotFound")
+}
+
+// isConflict returns true if a conditional write failed because the object was changed
+func isConflict(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,335 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+// fakeS3 is an in-memory bucket that checks the conditional writes like S3 does
+type fakeS3 struct {
+	mu      sync.Mutex
+	version int
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data []byte
+	etag string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string]fakeObject)}
+}
+
+var errPreconditionFailed = &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+
+func (f *fakeS3) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[*params.Key]
+	if !ok {
+		return nil, &s3types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(object.data)), ETag: awssdk.String(object.etag)}, nil
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, exists := f.objects[*params.Key]
+	if params.IfNoneMatch != nil && exists {
+		return nil, errPreconditionFailed
+	}
+	if params.IfMatch != nil && (!exists || object.etag != *params.IfMatch) {
+		return nil, errPreconditionFailed
+	}
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.version++
+	etag := fmt.Sprintf("%q", fmt.Sprint(f.version))
+	f.objects[*params.Key] = fakeObject{data: data, etag: etag}
+	return &s3.PutObjectOutput{ETag: awssdk.String(etag)}, nil
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, exists := f.objects[*params.Key]
+	if params.IfMatch != nil && exists && object.etag != *params.IfMatch {
+		return nil, errPreconditionFailed
+	}
+	delete(f.objects, *params.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0)
+	for key := range f.objects {
+		if strings.HasPrefix(key, *params.Prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	out := &s3.ListObjectsV2Output{}
+	for _, key := range keys {
+		out.Contents = append(out.Contents, s3types.Object{Key: awssdk.String(key)})
+	}
+	return out, nil
+}
+
+func TestStorageBackends(t *testing.T) {
+	backends := map[string]func(t *testing.T) Storage{
+		"bbolt": newTestStorage,
+		"bbolt per call": func(t *testing.T) Storage {
+			storage, err := NewDaemonStorage(&config.Config{Storage: config.StorageConfig{Path: filepath.Join(t.TempDir(), "labs.db"), Bucket: "labs"}})
+			assert.NoError(t, err)
+			return storage
+		},
+		"s3": func(t *testing.T) Storage { return newS3Storage(newFakeS3(), "labs", "") },
+	}
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			storage := newStorage(t)
+			_, err := storage.Get("test")
+			assert.EqualError(t, err, "lab test not found")
+
+			lab := testLabSpec()
+			lab.Status.State = "Creating"
+			assert.NoError(t, storage.Save(lab))
+			lab.Status.State = "Running"
+			assert.NoError(t, storage.Save(lab))
+			other := testLabSpec()
+			other.ObjectMeta.Name = "other"
+			assert.NoError(t, storage.Save(other))
+
+			stored, err := storage.Get("test")
+			assert.NoError(t, err)
+			assert.Equal(t, "Running", stored.Status.State)
+			labs, err := storage.List()
+			assert.NoError(t, err)
+			names := make([]string, 0, len(labs))
+			for _, lab := range labs {
+				names = append(names, lab.ObjectMeta.Name)
+			}
+			sort.Strings(names)
+			assert.Equal(t, []string{"other", "test"}, names)
+
+			assert.NoError(t, storage.Delete("test"))
+			_, err = storage.Get("test")
+			assert.EqualError(t, err, "lab test not found")
+			assert.NoError(t, storage.Close())
+		})
+	}
+}
+
+func TestDaemonStorageDoesNotLock(t *testing.T) {
+	cfg := &config.Config{Storage: config.StorageConfig{Path: filepath.Join(t.TempDir(), "labs.db"), Bucket: "labs"}}
+	daemon, err := NewDaemonStorage(cfg)
+	assert.NoError(t, err)
+	defer daemon.Close()
+	assert.NoError(t, daemon.Save(testLabSpec()))
+
+	// another command opens the storage while the daemon runs
+	storage, err := openBoltStorage(cfg, time.Second)
+	assert.NoError(t, err)
+	lab, err := storage.Get("test")
+	assert.NoError(t, err)
+	lab.Status.State = "Running"
+	assert.NoError(t, storage.Save(lab))
+
+	// and the daemon waits for it only up to the lock timeout
+	done := make(chan error, 1)
+	go func() {
+		_, err := daemon.Get("test")
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, storage.Close())
+	assert.NoError(t, <-done)
+	lab, err = daemon.Get("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "Running", lab.Status.State)
+}
+
+func TestS3StorageConflicts(t *testing.T) {
+	bucket := newFakeS3()
+	alice := newS3Storage(bucket, "labs", "team")
+	bob := newS3Storage(bucket, "labs", "team")
+
+	assert.NoError(t, alice.Save(testLabSpec()))
+	_, ok := bucket.objects["team/test.json"]
+	assert.True(t, ok, "the lab object is under the prefix")
+	err := bob.Save(testLabSpec())
+	assert.ErrorIs(t, err, ErrConflict, "a new lab doesn't replace one created by someone else")
+
+	aliceLab, err := alice.Get("test")
+	assert.NoError(t, err)
+	bobLab, err := bob.Get("test")
+	assert.NoError(t, err)
+	bobLab.Status.State = "Running"
+	assert.NoError(t, bob.Save(bobLab))
+	aliceLab.Status.State = "Failed"
+	err = alice.Save(aliceLab)
+	assert.ErrorIs(t, err, ErrConflict, "a lab changed by someone else isn't overwritten")
+	assert.ErrorIs(t, alice.Delete("test"), ErrConflict)
+
+	aliceLab, err = alice.Get("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "Running", aliceLab.Status.State)
+	assert.NoError(t, alice.Delete("test"))
+	labs, err := bob.List()
+	assert.NoError(t, err)
+	assert.Empty(t, labs)
+	assert.ErrorIs(t, bob.Save(bobLab), ErrConflict, "a lab deleted by someone else isn't saved again")
+	_, err = bob.Get("test")
+	assert.EqualError(t, err, "lab test not found")
+	assert.NoError(t, bob.Save(&types.Lab{ObjectMeta: types.ObjectMeta{Name: "test"}}), "a new lab is created after the lab is read as deleted")
+}
+
+func TestS3StorageSaveThroughAnotherClient(t *testing.T) {
+	bucket := newFakeS3()
+	assert.NoError(t, newS3Storage(bucket, "labs", "").Save(testLabSpec()))
+
+	l, err := newS3Storage(bucket, "labs", "").Get("test")
+	assert.NoError(t, err)
+	l.Status.State = "Failed"
+	assert.NoError(t, newS3Storage(bucket, "labs", "").Save(l), "a lab read through one client is saved through another")
+	l.Status.State = "Running"
+	assert.NoError(t, newS3Storage(bucket, "labs", "").Save(l), "the lab is saved again at the version it was saved as")
+
+	stale, err := newS3Storage(bucket, "labs", "").Get("test")
+	assert.NoError(t, err)
+	l.Status.State = "Deleting"
+	assert.NoError(t, newS3Storage(bucket, "labs", "").Save(l))
+	assert.ErrorIs(t, newS3Storage(bucket, "labs", "").Save(stale), ErrConflict, "a lab changed since it was read isn't overwritten")
+
+	saved, err := newS3Storage(bucket, "labs", "").Get("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "Deleting", saved.Status.State)
+}
+
+func TestS3StorageSaveAtReadVersion(t *testing.T) {
+	bucket := newFakeS3()
+	alice := newS3Storage(bucket, "labs", "")
+	assert.NoError(t, alice.Save(testLabSpec()))
+
+	stale, err := alice.Get("test")
+	assert.NoError(t, err)
+	bob, err := newS3Storage(bucket, "labs", "").Get("test")
+	assert.NoError(t, err)
+	bob.Status.State = "Running"
+	assert.NoError(t, newS3Storage(bucket, "labs", "").Save(bob))
+
+	// alice reads the lab again, so her cached ETag is newer than the version of the stale lab
+	_, err = alice.Get("test")
+	assert.NoError(t, err)
+	stale.Status.State = "Failed"
+	assert.ErrorIs(t, alice.Save(stale), ErrConflict, "a lab is saved at the version it was read as, not at the cached one")
+
+	saved, err := alice.Get("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "Running", saved.Status.State)
+}
+
+// brokenStorage fails to read the labs, like an unreachable bucket
+type brokenStorage struct {
+	Storage
+}
+
+func (s brokenStorage) Get(labName string) (*types.Lab, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestLookup(t *testing.T) {
+	servers := []*types.Server{testServer("found-cp", "cx22")}
+	provider := &mock.MockProvider{
+		NameFunc:         func() string { return "hetzner" },
+		CapabilitiesFunc: testCapabilities("hetzner"),
+		ListServersFunc: func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+			if opts.LabelSelector == "lab_name=found" {
+				return servers, nil
+			}
+			return []*types.Server{}, nil
+		},
+		ListVolumesFunc: func(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+			return []*types.Volume{}, nil
+		},
+	}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+	assert.NoError(t, m.Storage.Save(testLabSpec()))
+
+	lab, err := m.Lookup(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", lab.ObjectMeta.Name)
+	lab, err = m.Lookup(context.Background(), "found")
+	assert.NoError(t, err)
+	assert.Len(t, lab.Status.Servers, 1)
+	_, err = m.Storage.Get("found")
+	assert.ErrorIs(t, err, ErrNotFound, "a lab found on the provider isn't saved")
+	_, err = m.Lookup(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	m.Storage = brokenStorage{m.Storage}
+	_, err = m.Lookup(context.Background(), "test")
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestGetKeepsUnreadableRecords(t *testing.T) {
+	servers := []*types.Server{testServer("test-cp", "cx22")}
+	provider := &mock.MockProvider{
+		NameFunc:         func() string { return "hetzner" },
+		CapabilitiesFunc: testCapabilities("hetzner"),
+		ListServersFunc: func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+			return servers, nil
+		},
+		ListVolumesFunc: func(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+			return []*types.Volume{}, nil
+		},
+	}
+	storage := newTestStorage(t)
+	m := &ManagerSvc{Provider: provider, Storage: storage, Logger: logger.Get()}
+	db := storage.(*BoltStorage).db
+	assert.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte("labs")).Put([]byte("test"), []byte(`{"metadata":`))
+	}))
+
+	_, err := m.Get(context.Background(), "test")
+	assert.ErrorContains(t, err, "failed to unmarshal lab")
+	assert.NoError(t, db.View(func(tx *bbolt.Tx) error {
+		assert.Equal(t, `{"metadata":`, string(tx.Bucket([]byte("labs")).Get([]byte("test"))), "the record isn't replaced with the provider lab")
+		return nil
+	}))
+
+	lab, err := m.Get(context.Background(), "found")
+	assert.NoError(t, err)
+	assert.Len(t, lab.Status.Servers, 1, "a lab that isn't stored is taken from the provider")
+}
@@ -0,0 +1,244 @@
+package lab
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+)
+
+// Sync actions
+const (
+	SyncDiscovered = "Discovered" // a new lab found on the provider
+	SyncUpdated    = "Updated"    // the provider state of a stored lab changed
+	SyncGone       = "Gone"       // a stored lab not found on the provider
+	SyncSkipped    = "Skipped"    // a lab being created
+)
+
+// staleCreation is how long after its start a running creation phase is taken as interrupted,
+// e.g. by a crash, so sync reconciles the lab again
+const staleCreation = 2 * time.Hour
+
+// SyncOpts are the options for SyncLabs
+type SyncOpts struct {
+	DryRun bool // only report the changes
+}
+
+// SyncReport is what sync changed in the lab storage for a provider
+type SyncReport struct {
+	Provider  string
+	Changes   []*SyncChange // by lab name
+	Unchanged int
+}
+
+// SyncChange is a lab changed by sync
+type SyncChange struct {
+	Lab     string
+	Action  string
+	Changes []string // what changed, like "servers: +lab-node-03"
+}
+
+// SyncLabs merges the provider state of the labs into the stored labs.
+// Servers, volumes, owner, and delete_after come from the provider, the rest of the lab record is kept.
+// Labs found on the provider and not stored are added as Discovered,
+// and stored labs of the provider whose resources are gone are marked as Gone.
+func (m *ManagerSvc) SyncLabs(ctx context.Context, opts SyncOpts) (*SyncReport, error) {
+	providerName := m.Provider.Name()
+	// labs are found by the lab_name label of their servers
+	if !m.Provider.Capabilities().SupportsLabels {
+		return nil, fmt.Errorf("provider %s doesn't support labels, labs can't be synced", providerName)
+	}
+	allServers, err := m.Provider.AllServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all servers: %w", err)
+	}
+	stored, err := m.Storage.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labs: %w", err)
+	}
+	storedLabs := make(map[string]*types.Lab)
+	for _, lab := range stored {
+		storedLabs[lab.ObjectMeta.Name] = lab
+	}
+	// the labs with servers, and the stored labs of the provider that may have only volumes left
+	labNames := make(map[string]bool)
+	for _, server := range allServers {
+		if labName := server.Labels["lab_name"]; labName != "" {
+			labNames[labName] = true
+		}
+	}
+	for name, lab := range storedLabs {
+		if lab.Spec.Provider == providerName && lab.Status.State != types.LabStateGone {
+			labNames[name] = true
+		}
+	}
+	names := make([]string, 0, len(labNames))
+	for name := range labNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := &SyncReport{Provider: providerName, Changes: make([]*SyncChange, 0)}
+	now := time.Now().UTC()
+	for _, name := range names {
+		current, err := m.getLabFromProvider(ctx, name)
+		if err != nil {
+			return report, fmt.Errorf("failed to get lab from provider: %w", err)
+		}
+		found := len(current.Status.Servers) > 0 || len(current.Status.Volumes) > 0
+		lab, ok := storedLabs[name]
+		var change *SyncChange
+		switch {
+		case !ok && !found:
+			continue
+		case !ok:
+			current.Spec.Provider = providerName
+			current.Status.State = types.LabStateDiscovered
+			lab = current
+			change = &SyncChange{Lab: name, Action: SyncDiscovered, Changes: []string{
+				fmt.Sprintf("%d servers, %d volumes", len(current.Status.Servers), len(current.Status.Volumes)),
+			}}
+		case lab.Spec.Provider != "" && lab.Spec.Provider != providerName:
+			m.Logger.Warn("lab found on another provider", "lab", name, "stored", lab.Spec.Provider, "provider", providerName)
+			continue
+		case creating(lab, now):
+			report.Changes = append(report.Changes, &SyncChange{Lab: name, Action: SyncSkipped, Changes: []string{"creation in progress"}})
+			continue
+		case !found:
+			change = &SyncChange{Lab: name, Action: SyncGone, Changes: []string{fmt.Sprintf("not found on %s", providerName)}}
+			lab.Status.State = types.LabStateGone
+			lab.Status.Servers = nil
+			lab.Status.Volumes = nil
+		default:
+			changes := mergeProviderState(lab, current)
+			if len(changes) == 0 {
+				report.Unchanged++
+				continue
+			}
+			change = &SyncChange{Lab: name, Action: SyncUpdated, Changes: changes}
+		}
+		report.Changes = append(report.Changes, change)
+		if opts.DryRun {
+			continue
+		}
+		if err := m.Storage.Save(lab); err != nil {
+			return report, fmt.Errorf("failed to save lab %s: %w", name, err)
+		}
+	}
+	return report, nil
+}
+
+// creating returns true if the lab creation is running, so its resources may not exist yet.
+// A phase left running for longer than staleCreation was interrupted and doesn't count.
+func creating(lab *types.Lab, now time.Time) bool {
+	if lab.Status.State == types.LabStateFailed {
+		return false
+	}
+	for _, phase := range lab.Status.Phases {
+		if phase.State == types.PhaseRunning && now.Sub(phase.Started) < staleCreation {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeProviderState updates the stored lab from the lab found on the provider
+// and returns what changed
+func mergeProviderState(lab, current *types.Lab) []string {
+	changes := make([]string, 0)
+	if diff := nameDiff(serverNames(lab.Status.Servers), serverNames(current.Status.Servers)); diff != "" {
+		changes = append(changes, "servers: "+diff)
+	}
+	if diff := nameDiff(volumeNames(lab.Status.Volumes), volumeNames(current.Status.Volumes)); diff != "" {
+		changes = append(changes, "volumes: "+diff)
+	}
+	lab.Status.Servers = current.Status.Servers
+	lab.Status.Volumes = current.Status.Volumes
+
+	// a Failed or Discovered lab keeps its state until it's created or deleted with storctl
+	if state := current.Status.State; lab.Status.State != types.LabStateFailed && lab.Status.State != types.LabStateDiscovered &&
+		state != "" && state != lab.Status.State {
+		changes = append(changes, fmt.Sprintf("state: %s -> %s", orNone(lab.Status.State), state))
+		lab.Status.State = state
+	}
+	if owner := current.Status.Owner; owner != "" && owner != lab.Status.Owner {
+		changes = append(changes, fmt.Sprintf("owner: %s -> %s", orNone(lab.Status.Owner), owner))
+		lab.Status.Owner = owner
+	}
+	if deleteAfter := current.Status.DeleteAfter; !deleteAfter.IsZero() && !deleteAfter.Equal(lab.Status.DeleteAfter) {
+		changes = append(changes, fmt.Sprintf("delete after: %s -> %s", formatSyncTime(lab.Status.DeleteAfter), formatSyncTime(deleteAfter)))
+		lab.Status.DeleteAfter = deleteAfter
+	}
+	if lab.Status.Created.IsZero() {
+		lab.Status.Created = current.Status.Created
+	}
+	if lab.Spec.Location == "" {
+		lab.Spec.Location = current.Spec.Location
+	}
+	if lab.Spec.Provider == "" {
+		lab.Spec.Provider = current.Spec.Provider
+	}
+	if len(current.ObjectMeta.Labels) > 0 {
+		lab.ObjectMeta.Labels = labelutil.MergeLabels(lab.ObjectMeta.Labels, current.ObjectMeta.Labels)
+	}
+	return changes
+}
+
+func serverNames(servers []*types.Server) []string {
+	names := make([]string, 0, len(servers))
+	for _, server := range servers {
+		names = append(names, server.ObjectMeta.Name)
+	}
+	return names
+}
+
+func volumeNames(volumes []*types.Volume) []string {
+	names := make([]string, 0, len(volumes))
+	for _, volume := range volumes {
+		names = append(names, volume.ObjectMeta.Name)
+	}
+	return names
+}
+
+// nameDiff returns the added and removed names, like "+a, -b", or "" if they are the same
+func nameDiff(before, after []string) string {
+	inBefore := make(map[string]bool)
+	for _, name := range before {
+		inBefore[name] = true
+	}
+	inAfter := make(map[string]bool)
+	for _, name := range after {
+		inAfter[name] = true
+	}
+	diff := make([]string, 0)
+	for _, name := range after {
+		if !inBefore[name] {
+			diff = append(diff, "+"+name)
+		}
+	}
+	for _, name := range before {
+		if !inAfter[name] {
+			diff = append(diff, "-"+name)
+		}
+	}
+	sort.Strings(diff)
+	return strings.Join(diff, ", ")
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+func formatSyncTime(t time.Time) string {
+	if t.IsZero() {
+		return "none"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
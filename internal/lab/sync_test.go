@@ -0,0 +1,120 @@
+package lab
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncLabs(t *testing.T) {
+	deleteAfter := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	labServer := func(labName, name string) *types.Server {
+		server := testServer(name, "cx22")
+		server.ObjectMeta.Labels = map[string]string{"lab_name": labName, "owner": "bob"}
+		server.Status.Status = "running"
+		server.Status.Owner = "bob"
+		server.Status.DeleteAfter = deleteAfter
+		return server
+	}
+	servers := []*types.Server{
+		labServer("test", "test-cp"), labServer("test", "test-node-01"), labServer("test", "test-node-02"),
+		labServer("found", "found-cp"), labServer("same", "same-cp"),
+	}
+	provider := &mock.MockProvider{
+		NameFunc:         func() string { return "hetzner" },
+		CapabilitiesFunc: testCapabilities("hetzner"),
+		AllServersFunc:   func(ctx context.Context) ([]*types.Server, error) { return servers, nil },
+		ListServersFunc: func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+			found := make([]*types.Server, 0)
+			for _, server := range servers {
+				if "lab_name="+server.Labels["lab_name"] == opts.LabelSelector {
+					found = append(found, server)
+				}
+			}
+			return found, nil
+		},
+		ListVolumesFunc: func(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+			return []*types.Volume{}, nil
+		},
+	}
+	m := &ManagerSvc{Provider: provider, Storage: newTestStorage(t), Logger: logger.Get()}
+
+	stored := func(name, provider string, servers ...string) *types.Lab {
+		lab := testLabSpec()
+		lab.ObjectMeta.Name = name
+		lab.Spec.Provider = provider
+		lab.Spec.Ansible = types.AnsibleSpec{Playbook: "site.yml", Inventory: "/home/alice/.storctl/ansible/" + name + "-inventory.json"}
+		lab.Status.State = "running"
+		lab.Status.Owner = "bob"
+		lab.Status.DeleteAfter = deleteAfter
+		for _, name := range servers {
+			lab.Status.Servers = append(lab.Status.Servers, labServer(lab.ObjectMeta.Name, name))
+		}
+		assert.NoError(t, m.Storage.Save(lab))
+		return lab
+	}
+	test := stored("test", "hetzner", "test-cp", "test-node-01")
+	test.Status.DeleteAfter = deleteAfter.Add(-time.Hour)
+	test.Status.PlaybookRuns = []*types.PlaybookRun{{Playbook: "site.yml"}}
+	assert.NoError(t, m.Storage.Save(test))
+	stored("same", "hetzner", "same-cp")
+	stored("gone", "hetzner", "gone-cp")
+	stored("local", "lima", "local-cp")
+	creatingLab := stored("new", "hetzner")
+	creatingLab.Status.Phases = []*types.LabPhase{{Name: types.PhaseServers, State: types.PhaseRunning, Started: time.Now().UTC()}}
+	assert.NoError(t, m.Storage.Save(creatingLab))
+	// storctl was killed while creating the lab, so the phase stays running
+	crashed := stored("crashed", "hetzner", "crashed-cp")
+	crashed.Status.Phases = []*types.LabPhase{{Name: types.PhaseServers, State: types.PhaseRunning, Started: time.Now().UTC().Add(-staleCreation)}}
+	assert.NoError(t, m.Storage.Save(crashed))
+
+	report, err := m.SyncLabs(context.Background(), SyncOpts{DryRun: true})
+	assert.NoError(t, err)
+	summary := make([]string, 0)
+	for _, change := range report.Changes {
+		summary = append(summary, change.Lab+" "+change.Action+" "+strings.Join(change.Changes, "; "))
+	}
+	assert.Equal(t, []string{
+		"crashed Gone not found on hetzner",
+		"found Discovered 1 servers, 0 volumes",
+		"gone Gone not found on hetzner",
+		"new Skipped creation in progress",
+		"test Updated servers: +test-node-02; delete after: 2025-01-02T23:00:00Z -> 2025-01-03T00:00:00Z",
+	}, summary)
+	assert.Equal(t, 1, report.Unchanged)
+	gone, err := m.Storage.Get("gone")
+	assert.NoError(t, err)
+	assert.Equal(t, "running", gone.Status.State, "dry run doesn't change the labs")
+
+	_, err = m.SyncLabs(context.Background(), SyncOpts{})
+	assert.NoError(t, err)
+	test, err = m.Storage.Get("test")
+	assert.NoError(t, err)
+	assert.Len(t, test.Status.Servers, 3)
+	assert.Equal(t, deleteAfter, test.Status.DeleteAfter)
+	assert.Equal(t, "/home/alice/.storctl/ansible/test-inventory.json", test.Spec.Ansible.Inventory, "the local fields are kept")
+	assert.Len(t, test.Status.PlaybookRuns, 1)
+	found, err := m.Storage.Get("found")
+	assert.NoError(t, err)
+	assert.Equal(t, types.LabStateDiscovered, found.Status.State)
+	assert.Equal(t, "hetzner", found.Spec.Provider)
+	gone, err = m.Storage.Get("gone")
+	assert.NoError(t, err)
+	assert.Equal(t, types.LabStateGone, gone.Status.State)
+	assert.Empty(t, gone.Status.Servers)
+	local, err := m.Storage.Get("local")
+	assert.NoError(t, err)
+	assert.Equal(t, "running", local.Status.State, "the labs of other providers aren't changed")
+
+	report, err = m.SyncLabs(context.Background(), SyncOpts{})
+	assert.NoError(t, err)
+	assert.Len(t, report.Changes, 1, "only the lab being created is reported again")
+	assert.Equal(t, 3, report.Unchanged)
+}
@@ -0,0 +1,144 @@
+// Package notifier tells the lab owners that their labs are about to expire.
+// It scans the labs of the configured owner in the lab storage and sends a notification to every sink
+// once for each deletion time, so a lab extended later is notified again.
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+)
+
+// Notification is sent to the sinks for a lab that expires soon
+type Notification struct {
+	Lab         string    `json:"lab"`
+	Provider    string    `json:"provider"`
+	Owner       string    `json:"owner"`
+	Email       string    `json:"email"`
+	DeleteAfter time.Time `json:"deleteAfter"`
+}
+
+// Subject is a one-line summary of the notification
+func (n Notification) Subject() string {
+	return fmt.Sprintf("Lab %s will be deleted after %s", n.Lab, n.DeleteAfter.UTC().Format("2006-01-02 15:04 MST"))
+}
+
+// Text is the notification message
+func (n Notification) Text() string {
+	return fmt.Sprintf("%s.\nIf you still need it, run 'storctl extend lab %s --ttl 4h'.", n.Subject(), n.Lab)
+}
+
+// Sink delivers notifications
+type Sink interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Notifier finds the labs of the owner that expire within the window and notifies the owner
+type Notifier struct {
+	Manager *lab.ManagerSvc
+	Sinks   []Sink
+	Window  time.Duration
+	Owner   string // the owner label of the labs to notify about, sanitized like the labels
+	Email   string // where the owner gets the email notifications
+	Logger  *slog.Logger
+	now     func() time.Time
+}
+
+// New returns a notifier for the labs of the owner in the lab manager.
+// The labs of the other owners in a shared storage are left to their own notifiers.
+func New(manager *lab.ManagerSvc, sinks []Sink, window time.Duration, owner, email string) *Notifier {
+	return &Notifier{
+		Manager: manager,
+		Sinks:   sinks,
+		Window:  window,
+		Owner:   labelutil.SanitizeValue(owner),
+		Email:   email,
+		Logger:  logger.Get(),
+		now:     func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run checks the labs every interval until ctx is canceled.
+// Errors are logged and don't stop the notifier.
+func (n *Notifier) Run(ctx context.Context, interval time.Duration) error {
+	n.Logger.Info("notifier started", "interval", interval, "window", n.Window)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := n.Check(ctx); err != nil {
+			n.Logger.Error("notifier pass failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			n.Logger.Info("notifier stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check notifies the owners of the labs that expire within the window and weren't notified yet.
+// A lab is marked as notified when at least one sink delivered the notification.
+// It returns the sent notifications and the errors of all sinks joined.
+func (n *Notifier) Check(ctx context.Context) ([]Notification, error) {
+	labs, err := n.Manager.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labs: %w", err)
+	}
+	now := n.now()
+	sent := make([]Notification, 0)
+	var errs []error
+	for _, l := range labs {
+		if labOwner(l) != n.Owner {
+			continue
+		}
+		deleteAfter := lab.DeleteAfter(l)
+		if deleteAfter.IsZero() || !deleteAfter.After(now) || deleteAfter.Sub(now) > n.Window {
+			continue
+		}
+		if l.Status.ExpiryNotified.Equal(deleteAfter) {
+			continue
+		}
+		notification := Notification{
+			Lab:         l.ObjectMeta.Name,
+			Provider:    l.Spec.Provider,
+			Owner:       labOwner(l),
+			Email:       n.Email,
+			DeleteAfter: deleteAfter,
+		}
+		delivered := false
+		for _, sink := range n.Sinks {
+			if err := sink.Notify(ctx, notification); err != nil {
+				errs = append(errs, fmt.Errorf("failed to notify about lab %s with %s: %w", notification.Lab, sink.Name(), err))
+				continue
+			}
+			delivered = true
+		}
+		if !delivered {
+			continue
+		}
+		fmt.Printf("Notified the owner of lab %s, it will be deleted after %s\n", notification.Lab, deleteAfter.Format(time.RFC3339))
+		sent = append(sent, notification)
+		l.Status.ExpiryNotified = deleteAfter
+		if err := n.Manager.Storage.Save(l); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save lab %s: %w", notification.Lab, err))
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// labOwner returns the owner label of the lab or, for labs found on the provider, the owner of its servers
+func labOwner(l *types.Lab) string {
+	if owner := l.ObjectMeta.Labels["owner"]; owner != "" {
+		return owner
+	}
+	return l.Status.Owner
+}
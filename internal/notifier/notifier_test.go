@@ -0,0 +1,241 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeSMTP is an SMTP server that keeps the messages it receives
+type fakeSMTP struct {
+	net.Listener
+	mu       sync.Mutex
+	messages []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{Listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 fake")
+		case command == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTP) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.messages...)
+}
+
+type failingSink struct{}
+
+func (failingSink) Name() string { return "failing" }
+
+func (failingSink) Notify(ctx context.Context, n Notification) error {
+	return errors.New("unreachable")
+}
+
+func newTestManager(t *testing.T) *lab.ManagerSvc {
+	// the storage notify uses, opened for each call
+	storage, err := lab.NewDaemonStorage(&config.Config{Storage: config.StorageConfig{Path: filepath.Join(t.TempDir(), "labs.db"), Bucket: "labs"}})
+	assert.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	m := &lab.ManagerSvc{Storage: storage, Logger: logger.Get()}
+	for name, deleteAfter := range map[string]time.Time{
+		"soon":    testNow.Add(30 * time.Minute),
+		"later":   testNow.Add(5 * time.Hour),
+		"expired": testNow.Add(-time.Minute),
+	} {
+		l := &types.Lab{ObjectMeta: types.ObjectMeta{Name: name}, Spec: types.LabSpec{Provider: "hetzner"}}
+		l.Status.Owner = "alice"
+		l.Status.DeleteAfter = deleteAfter
+		assert.NoError(t, storage.Save(l))
+	}
+	// created before the delete_after was kept in the status
+	old := &types.Lab{ObjectMeta: types.ObjectMeta{Name: "old", Labels: map[string]string{"delete_after": "2025-06-01-13-00", "owner": "alice"}}}
+	assert.NoError(t, storage.Save(old))
+	// a lab of another owner in the shared storage
+	other := &types.Lab{ObjectMeta: types.ObjectMeta{Name: "bobs", Labels: map[string]string{"owner": "bobexamplecom"}}}
+	other.Status.DeleteAfter = testNow.Add(30 * time.Minute)
+	assert.NoError(t, storage.Save(other))
+	return m
+}
+
+func TestCheck(t *testing.T) {
+	smtpServer := newFakeSMTP(t)
+	var mu sync.Mutex
+	var webhook []Notification
+	var slack []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/webhook":
+			var n Notification
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+			webhook = append(webhook, n)
+		case "/slack":
+			var body map[string]string
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			slack = append(slack, body)
+		}
+	}))
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(smtpServer.Addr().String())
+	cfg := &config.Config{Email: "alice@example.com", Notify: config.NotifyConfig{
+		SMTP:            config.SMTPConfig{Host: host},
+		WebhookURL:      server.URL + "/webhook",
+		SlackWebhookURL: server.URL + "/slack",
+	}}
+	sinks := NewSinks(cfg)
+	if assert.Len(t, sinks, 3) {
+		assert.Equal(t, "alice@example.com", sinks[0].(*SMTPSink).From)
+		sinks[0].(*SMTPSink).Addr = net.JoinHostPort(host, port)
+	}
+
+	n := New(newTestManager(t), sinks, time.Hour+time.Minute, "alice", cfg.Email)
+	n.now = func() time.Time { return testNow }
+	sent, err := n.Check(context.Background())
+	assert.NoError(t, err)
+	names := make([]string, 0)
+	for _, notification := range sent {
+		names = append(names, notification.Lab)
+	}
+	assert.ElementsMatch(t, []string{"soon", "old"}, names)
+
+	messages := smtpServer.received()
+	if assert.Len(t, messages, 2) {
+		assert.Contains(t, strings.Join(messages, ""), "To: alice@example.com")
+		assert.Contains(t, strings.Join(messages, ""), "Subject: Lab soon will be deleted after 2025-06-01 12:30 UTC")
+	}
+	mu.Lock()
+	assert.Len(t, webhook, 2)
+	assert.Len(t, slack, 2)
+	assert.Contains(t, slack[0]["text"], "storctl extend lab")
+	mu.Unlock()
+
+	// each deletion time is notified once
+	sent, err = n.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, sent)
+
+	// an extended lab is notified again
+	soon, err := n.Manager.Storage.Get("soon")
+	assert.NoError(t, err)
+	soon.Status.DeleteAfter = testNow.Add(time.Hour)
+	assert.NoError(t, n.Manager.Storage.Save(soon))
+	sent, err = n.Check(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, sent, 1)
+}
+
+func TestCheckSinkErrors(t *testing.T) {
+	n := New(newTestManager(t), []Sink{failingSink{}}, time.Hour, "alice", "alice@example.com")
+	n.now = func() time.Time { return testNow }
+	sent, err := n.Check(context.Background())
+	assert.ErrorContains(t, err, "failed to notify about lab soon with failing: unreachable")
+	assert.Empty(t, sent)
+
+	// undelivered notifications are retried
+	stored, err := n.Manager.Storage.Get("soon")
+	assert.NoError(t, err)
+	assert.True(t, stored.Status.ExpiryNotified.IsZero())
+}
+
+func TestCheckOwner(t *testing.T) {
+	var sent []Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification Notification
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		sent = append(sent, notification)
+	}))
+	defer server.Close()
+
+	n := New(newTestManager(t), []Sink{&WebhookSink{URL: server.URL}}, time.Hour, "bob@example.com", "bob@example.com")
+	n.now = func() time.Time { return testNow }
+
+	_, err := n.Check(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 1, "only the labs of the owner are notified") {
+		assert.Equal(t, "bobs", sent[0].Lab)
+		assert.Equal(t, "bob@example.com", sent[0].Email)
+	}
+}
+
+func TestWebhookErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	}))
+	defer server.Close()
+	err := (&WebhookSink{URL: server.URL}).Notify(context.Background(), Notification{Lab: "soon"})
+	assert.ErrorContains(t, err, "webhook returned 404 Not Found: no such hook")
+}
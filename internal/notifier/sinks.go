@@ -0,0 +1,135 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+)
+
+// SMTPSink emails the notifications
+type SMTPSink struct {
+	Addr string // host:port of the SMTP server
+	From string
+	Auth smtp.Auth // nil if the server needs no authentication
+}
+
+// WebhookSink posts the notifications as JSON
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// SlackSink posts the notifications to a Slack-compatible incoming webhook
+type SlackSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewSinks returns the sinks configured in the notify section
+func NewSinks(cfg *config.Config) []Sink {
+	sinks := make([]Sink, 0)
+	notifyConfig := cfg.Notify
+	if smtpConfig := notifyConfig.SMTP; smtpConfig.Host != "" {
+		port := smtpConfig.Port
+		if port == 0 {
+			port = config.DefaultSMTPPort
+		}
+		sink := &SMTPSink{
+			Addr: net.JoinHostPort(smtpConfig.Host, strconv.Itoa(port)),
+			From: smtpConfig.From,
+		}
+		if sink.From == "" {
+			sink.From = cfg.Email
+		}
+		if smtpConfig.Username != "" {
+			sink.Auth = smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)
+		}
+		sinks = append(sinks, sink)
+	}
+	if notifyConfig.WebhookURL != "" {
+		sinks = append(sinks, &WebhookSink{URL: notifyConfig.WebhookURL, Client: &http.Client{Timeout: 30 * time.Second}})
+	}
+	if notifyConfig.SlackWebhookURL != "" {
+		sinks = append(sinks, &SlackSink{URL: notifyConfig.SlackWebhookURL, Client: &http.Client{Timeout: 30 * time.Second}})
+	}
+	return sinks
+}
+
+func (s *SMTPSink) Name() string {
+	return "smtp"
+}
+
+// Notify sends the notification to its email address.
+// net/smtp has no context, so a canceled ctx only stops the notifications that haven't started.
+func (s *SMTPSink) Notify(ctx context.Context, n Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if n.Email == "" {
+		return fmt.Errorf("no email address for lab %s", n.Lab)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", n.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(n.Text(), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+	if err := smtp.SendMail(s.Addr, s.Auth, s.From, []string{n.Email}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.Client, s.URL, n)
+}
+
+func (s *SlackSink) Name() string {
+	return "slack"
+}
+
+func (s *SlackSink) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.Client, s.URL, map[string]string{"text": n.Text()})
+}
+
+// postJSON posts the body as JSON and expects a 2xx response
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error marshalling notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
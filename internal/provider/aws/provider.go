@@ -0,0 +1,233 @@
+// Package aws contains the AWS implementation of the provider interface for the storctl tool.
+// Servers are EC2 instances, volumes are EBS volumes, and SSH keys are EC2 key pairs.
+// Labels are kept as EC2 tags, so the label selectors and the delete_after checks work like on Hetzner.
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+)
+
+// nameTag is the EC2 tag with the resource name
+const nameTag = "Name"
+
+// waiterDelay is the minimum delay between the state checks of new instances and volumes
+var waiterDelay = 5 * time.Second
+
+// defaultServerTypes maps the Hetzner server types used in the lab templates to similar instance types.
+// The server_types in the provider config override them; other types are used as instance types as is.
+var defaultServerTypes = map[string]string{
+	"cx22":  "t3.medium",
+	"cx32":  "c6i.xlarge",
+	"cx42":  "c6i.2xlarge",
+	"cpx21": "t3.medium",
+	"cpx31": "c6i.xlarge",
+	"cpx41": "c6i.2xlarge",
+}
+
+// ec2API is the part of the EC2 client the provider uses
+type ec2API interface {
+	ec2.DescribeInstancesAPIClient
+	ec2.DescribeVolumesAPIClient
+	RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error)
+	TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
+	DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error)
+	DescribeImages(ctx context.Context, params *ec2.DescribeImagesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error)
+	CreateVolume(ctx context.Context, params *ec2.CreateVolumeInput, optFns ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error)
+	AttachVolume(ctx context.Context, params *ec2.AttachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.AttachVolumeOutput, error)
+	DetachVolume(ctx context.Context, params *ec2.DetachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DetachVolumeOutput, error)
+	DeleteVolume(ctx context.Context, params *ec2.DeleteVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error)
+	ModifyVolume(ctx context.Context, params *ec2.ModifyVolumeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyVolumeOutput, error)
+	ImportKeyPair(ctx context.Context, params *ec2.ImportKeyPairInput, optFns ...func(*ec2.Options)) (*ec2.ImportKeyPairOutput, error)
+	DescribeKeyPairs(ctx context.Context, params *ec2.DescribeKeyPairsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeKeyPairsOutput, error)
+	DeleteKeyPair(ctx context.Context, params *ec2.DeleteKeyPairInput, optFns ...func(*ec2.Options)) (*ec2.DeleteKeyPairOutput, error)
+	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	CreateSecurityGroup(ctx context.Context, params *ec2.CreateSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.CreateSecurityGroupOutput, error)
+	AuthorizeSecurityGroupIngress(ctx context.Context, params *ec2.AuthorizeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.AuthorizeSecurityGroupIngressOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+}
+
+type AWSProvider struct {
+	Client        ec2API
+	config        *config.Config
+	logger        *slog.Logger
+	region        string
+	subnet        string // subnet ID for the instances, the default VPC subnet if empty
+	securityGroup string // security group ID for the instances, storctl creates one if empty
+	serverTypes   map[string]string
+
+	// mu serializes the security group creation and the volume attachments,
+	// as servers and volumes are created in parallel
+	mu      sync.Mutex
+	devices map[string]map[string]bool // devices reserved for the attachments by instance ID
+}
+
+func New(cfg *config.Config) (*AWSProvider, error) {
+	providerConfig := getProviderConfig(cfg, "aws")
+	if providerConfig == nil {
+		return nil, fmt.Errorf("provider config not found for aws")
+	}
+	region := providerConfig.Location
+	if region == "" {
+		region = config.DefaultAWSRegion
+	}
+
+	logger := logger.Get()
+	logger.Info("Initializing AWS provider")
+	logger.Debug("Using configuration",
+		"region", region,
+		"subnet", providerConfig.Subnet,
+		"security_group", providerConfig.SecurityGroup,
+		"credentials_present", providerConfig.Credentials["access_key_id"] != "")
+
+	// the credentials in the config take precedence over the AWS environment and shared config
+	opts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(region)}
+	if profile := providerConfig.Credentials["profile"]; profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(profile))
+	}
+	if accessKeyID := providerConfig.Credentials["access_key_id"]; accessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			accessKeyID,
+			providerConfig.Credentials["secret_access_key"],
+			providerConfig.Credentials["session_token"])))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading AWS config: %w", err)
+	}
+
+	serverTypes := labelutil.MergeLabels(defaultServerTypes, providerConfig.ServerTypes)
+	return &AWSProvider{
+		Client:        ec2.NewFromConfig(awsCfg),
+		config:        cfg,
+		logger:        logger,
+		region:        region,
+		subnet:        providerConfig.Subnet,
+		securityGroup: providerConfig.SecurityGroup,
+		serverTypes:   serverTypes,
+		devices:       make(map[string]map[string]bool),
+	}, nil
+}
+
+func (p *AWSProvider) Name() string {
+	return "aws"
+}
+
+// Capabilities of EC2: volumes are attached to running instances that get public DNS names
+func (p *AWSProvider) Capabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{
+		VolumesAttachAfterBoot: true,
+		ReadinessCheck:         true,
+		NeedsPublicDNS:         true,
+		SupportsLabels:         true,
+		SupportsTTLLabels:      true,
+		MutableLabels:          true,
+		ManagesSSHKeys:         true,
+		SSHUser:                config.DefaultAdminUser,
+	}
+}
+
+// instanceType returns the EC2 instance type for the lab server type
+func (p *AWSProvider) instanceType(serverType string) string {
+	if instanceType, ok := p.serverTypes[serverType]; ok {
+		return instanceType
+	}
+	return serverType
+}
+
+// isNotFound reports whether the EC2 error means the resource doesn't exist
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && strings.HasSuffix(apiErr.ErrorCode(), ".NotFound")
+}
+
+// tagSpecification converts the labels and the name to the tags of a new resource
+func tagSpecification(resourceType ec2types.ResourceType, name string, labels map[string]string) ec2types.TagSpecification {
+	tags := append([]ec2types.Tag{{Key: awssdk.String(nameTag), Value: awssdk.String(name)}}, labelsToTags(labels)...)
+	return ec2types.TagSpecification{ResourceType: resourceType, Tags: tags}
+}
+
+// labelsToTags converts the labels to tags sorted by key
+func labelsToTags(labels map[string]string) []ec2types.Tag {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tags := make([]ec2types.Tag, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, ec2types.Tag{Key: awssdk.String(key), Value: awssdk.String(labels[key])})
+	}
+	return tags
+}
+
+// updateTags adds the labels to the tags of the resources, replacing the tags with the same keys
+func (p *AWSProvider) updateTags(ctx context.Context, labels map[string]string, resourceIDs ...string) error {
+	_, err := p.Client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: resourceIDs,
+		Tags:      labelsToTags(labels),
+	})
+	return err
+}
+
+// tagsToLabels converts the EC2 tags to labels, leaving out the name and the AWS tags
+func tagsToLabels(tags []ec2types.Tag) map[string]string {
+	labels := make(map[string]string)
+	for _, tag := range tags {
+		key := awssdk.ToString(tag.Key)
+		if key == nameTag || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		labels[key] = awssdk.ToString(tag.Value)
+	}
+	return labels
+}
+
+func tagValue(tags []ec2types.Tag, key string) string {
+	for _, tag := range tags {
+		if awssdk.ToString(tag.Key) == key {
+			return awssdk.ToString(tag.Value)
+		}
+	}
+	return ""
+}
+
+// selectorFilters converts the label selector terms to tag filters
+func selectorFilters(selector string) []ec2types.Filter {
+	filters := []ec2types.Filter{}
+	for _, term := range labelutil.SelectorTerms(selector) {
+		if key, value, ok := strings.Cut(term, "="); ok {
+			filters = append(filters, ec2types.Filter{Name: awssdk.String("tag:" + key), Values: []string{value}})
+		} else {
+			filters = append(filters, ec2types.Filter{Name: awssdk.String("tag-key"), Values: []string{term}})
+		}
+	}
+	return filters
+}
+
+func getProviderConfig(cfg *config.Config, providerName string) *config.ProviderConfig {
+	for _, provider := range cfg.Providers {
+		if provider.Name == providerName {
+			return &provider
+		}
+	}
+	return nil
+}
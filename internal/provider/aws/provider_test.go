@@ -0,0 +1,539 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// created is the creation time of all the fake resources
+var created = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// fakeEC2 is an in-memory EC2 serving the API calls the provider makes.
+// New instances are running and new volumes are available right away.
+type fakeEC2 struct {
+	mu             sync.Mutex
+	nextID         int
+	instances      map[string]*ec2types.Instance
+	volumes        map[string]*ec2types.Volume
+	keyPairs       map[string]*ec2types.KeyPairInfo
+	securityGroups []ec2types.SecurityGroup
+	runInput       *ec2.RunInstancesInput
+	ingress        *ec2.AuthorizeSecurityGroupIngressInput
+}
+
+var fakeInstanceTypes = map[string]ec2types.InstanceTypeInfo{
+	"t3.medium": instanceTypeInfo("t3.medium", 2, 4096, ec2types.ArchitectureTypeX8664),
+	"m6i.large": instanceTypeInfo("m6i.large", 2, 8192, ec2types.ArchitectureTypeX8664),
+	"m7g.large": instanceTypeInfo("m7g.large", 2, 8192, ec2types.ArchitectureTypeArm64),
+}
+
+var fakeImages = []ec2types.Image{
+	{ImageId: awssdk.String("ami-old"), Name: awssdk.String("ubuntu/images/hvm-ssd-gp3/ubuntu-noble-24.04-amd64-server-20240101"), CreationDate: awssdk.String("2024-01-01T00:00:00.000Z"), RootDeviceName: awssdk.String("/dev/sda1")},
+	{ImageId: awssdk.String("ami-amd64"), Name: awssdk.String("ubuntu/images/hvm-ssd-gp3/ubuntu-noble-24.04-amd64-server-20250101"), CreationDate: awssdk.String("2025-01-01T00:00:00.000Z"), RootDeviceName: awssdk.String("/dev/sda1")},
+	{ImageId: awssdk.String("ami-arm64"), Name: awssdk.String("ubuntu/images/hvm-ssd-gp3/ubuntu-noble-24.04-arm64-server-20250101"), CreationDate: awssdk.String("2025-01-01T00:00:00.000Z"), RootDeviceName: awssdk.String("/dev/sda1")},
+}
+
+func instanceTypeInfo(name string, cpus int32, memory int64, arch ec2types.ArchitectureType) ec2types.InstanceTypeInfo {
+	return ec2types.InstanceTypeInfo{
+		InstanceType:  ec2types.InstanceType(name),
+		VCpuInfo:      &ec2types.VCpuInfo{DefaultVCpus: awssdk.Int32(cpus)},
+		MemoryInfo:    &ec2types.MemoryInfo{SizeInMiB: awssdk.Int64(memory)},
+		ProcessorInfo: &ec2types.ProcessorInfo{SupportedArchitectures: []ec2types.ArchitectureType{arch}},
+	}
+}
+
+func newTestProvider(t *testing.T) (*AWSProvider, *fakeEC2) {
+	t.Setenv("HOME", t.TempDir())
+	waiterDelay = time.Millisecond
+	fake := &fakeEC2{
+		instances: map[string]*ec2types.Instance{},
+		volumes:   map[string]*ec2types.Volume{},
+		keyPairs:  map[string]*ec2types.KeyPairInfo{},
+	}
+	return &AWSProvider{
+		Client:      fake,
+		config:      &config.Config{},
+		logger:      logger.Get(),
+		region:      "us-east-1",
+		serverTypes: map[string]string{"cx22": "t3.medium"},
+		devices:     make(map[string]map[string]bool),
+	}, fake
+}
+
+func notFound(code string) error {
+	return &smithy.GenericAPIError{Code: code, Message: "not found"}
+}
+
+func (f *fakeEC2) id(prefix string) string {
+	f.nextID++
+	return fmt.Sprintf("%s-%d", prefix, f.nextID)
+}
+
+// addInstance adds a running instance with a root volume
+func (f *fakeEC2) addInstance(name, instanceType, zone string, labels map[string]string) *ec2types.Instance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	spec := tagSpecification(ec2types.ResourceTypeInstance, name, labels)
+	return f.launch(ec2types.InstanceType(instanceType), "ami-amd64", zone, spec.Tags, spec.Tags)
+}
+
+func (f *fakeEC2) launch(instanceType ec2types.InstanceType, imageID, zone string, tags, volumeTags []ec2types.Tag) *ec2types.Instance {
+	rootID := f.id("vol")
+	instanceID := f.id("i")
+	f.volumes[rootID] = &ec2types.Volume{
+		VolumeId:         awssdk.String(rootID),
+		Size:             awssdk.Int32(rootVolumeSize),
+		SnapshotId:       awssdk.String("snap-1"),
+		State:            ec2types.VolumeStateInUse,
+		AvailabilityZone: awssdk.String(zone),
+		CreateTime:       awssdk.Time(created),
+		Tags:             volumeTags,
+		Attachments:      []ec2types.VolumeAttachment{{InstanceId: awssdk.String(instanceID), Device: awssdk.String("/dev/sda1")}},
+	}
+	instance := &ec2types.Instance{
+		InstanceId:       awssdk.String(instanceID),
+		InstanceType:     instanceType,
+		ImageId:          awssdk.String(imageID),
+		State:            &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+		Placement:        &ec2types.Placement{AvailabilityZone: awssdk.String(zone)},
+		PublicIpAddress:  awssdk.String("203.0.113.5"),
+		PrivateIpAddress: awssdk.String("10.0.0.5"),
+		LaunchTime:       awssdk.Time(created),
+		RootDeviceName:   awssdk.String("/dev/sda1"),
+		Tags:             tags,
+		BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{{
+			DeviceName: awssdk.String("/dev/sda1"),
+			Ebs:        &ec2types.EbsInstanceBlockDevice{VolumeId: awssdk.String(rootID)},
+		}},
+	}
+	f.instances[instanceID] = instance
+	return instance
+}
+
+// addVolume adds an available volume
+func (f *fakeEC2) addVolume(name string, size int32, labels map[string]string) *ec2types.Volume {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id("vol")
+	volume := &ec2types.Volume{
+		VolumeId:         awssdk.String(id),
+		Size:             awssdk.Int32(size),
+		State:            ec2types.VolumeStateAvailable,
+		AvailabilityZone: awssdk.String("us-east-1a"),
+		CreateTime:       awssdk.Time(created),
+		Tags:             tagSpecification(ec2types.ResourceTypeVolume, name, labels).Tags,
+	}
+	f.volumes[id] = volume
+	return volume
+}
+
+// matchFilters checks the tag filters and the other filters with the attributes of the resource
+func matchFilters(filters []ec2types.Filter, tags []ec2types.Tag, attributes map[string]string) bool {
+	for _, filter := range filters {
+		name := awssdk.ToString(filter.Name)
+		var value string
+		var ok bool
+		switch {
+		case strings.HasPrefix(name, "tag:"):
+			key := strings.TrimPrefix(name, "tag:")
+			for _, tag := range tags {
+				if awssdk.ToString(tag.Key) == key {
+					value, ok = awssdk.ToString(tag.Value), true
+				}
+			}
+		case name == "tag-key":
+			for _, tag := range tags {
+				if awssdk.ToString(tag.Key) == filter.Values[0] {
+					value, ok = filter.Values[0], true
+				}
+			}
+		default:
+			value, ok = attributes[name]
+		}
+		if !ok {
+			return false
+		}
+		matched := false
+		for _, want := range filter.Values {
+			if want == value {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeEC2) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	output := &ec2.DescribeInstancesOutput{}
+	for _, id := range params.InstanceIds {
+		if _, ok := f.instances[id]; !ok {
+			return nil, notFound("InvalidInstanceID.NotFound")
+		}
+	}
+	for id, instance := range f.instances {
+		if len(params.InstanceIds) > 0 && !contains(params.InstanceIds, id) {
+			continue
+		}
+		if matchFilters(params.Filters, instance.Tags, map[string]string{"instance-state-name": string(instance.State.Name)}) {
+			output.Reservations = append(output.Reservations, ec2types.Reservation{Instances: []ec2types.Instance{*instance}})
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	output := &ec2.DescribeVolumesOutput{}
+	for _, id := range params.VolumeIds {
+		if _, ok := f.volumes[id]; !ok {
+			return nil, notFound("InvalidVolume.NotFound")
+		}
+	}
+	for id, volume := range f.volumes {
+		if len(params.VolumeIds) > 0 && !contains(params.VolumeIds, id) {
+			continue
+		}
+		if matchFilters(params.Filters, volume.Tags, nil) {
+			output.Volumes = append(output.Volumes, *volume)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.runInput = params
+	zone := "us-east-1a"
+	if params.Placement != nil {
+		zone = awssdk.ToString(params.Placement.AvailabilityZone)
+	}
+	var tags, volumeTags []ec2types.Tag
+	for _, spec := range params.TagSpecifications {
+		switch spec.ResourceType {
+		case ec2types.ResourceTypeInstance:
+			tags = spec.Tags
+		case ec2types.ResourceTypeVolume:
+			volumeTags = spec.Tags
+		}
+	}
+	instance := f.launch(params.InstanceType, awssdk.ToString(params.ImageId), zone, tags, volumeTags)
+	return &ec2.RunInstancesOutput{Instances: []ec2types.Instance{*instance}}, nil
+}
+
+func (f *fakeEC2) TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range params.InstanceIds {
+		instance, ok := f.instances[id]
+		if !ok {
+			return nil, notFound("InvalidInstanceID.NotFound")
+		}
+		for _, mapping := range instance.BlockDeviceMappings {
+			delete(f.volumes, awssdk.ToString(mapping.Ebs.VolumeId))
+		}
+		instance.State = &ec2types.InstanceState{Name: ec2types.InstanceStateNameTerminated}
+	}
+	return &ec2.TerminateInstancesOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	output := &ec2.DescribeInstanceTypesOutput{}
+	for _, instanceType := range params.InstanceTypes {
+		info, ok := fakeInstanceTypes[string(instanceType)]
+		if !ok {
+			return nil, &smithy.GenericAPIError{Code: "InvalidInstanceType", Message: "invalid instance type"}
+		}
+		output.InstanceTypes = append(output.InstanceTypes, info)
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) DescribeImages(ctx context.Context, params *ec2.DescribeImagesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	output := &ec2.DescribeImagesOutput{}
+	for _, image := range fakeImages {
+		if len(params.ImageIds) > 0 && contains(params.ImageIds, awssdk.ToString(image.ImageId)) {
+			output.Images = append(output.Images, image)
+		}
+		for _, filter := range params.Filters {
+			if awssdk.ToString(filter.Name) != "name" {
+				continue
+			}
+			// the filter has the version and the architecture between wildcards
+			parts := strings.Split(filter.Values[0], "*")
+			if strings.Contains(awssdk.ToString(image.Name), parts[2]) {
+				output.Images = append(output.Images, image)
+			}
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) CreateVolume(ctx context.Context, params *ec2.CreateVolumeInput, optFns ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id("vol")
+	f.volumes[id] = &ec2types.Volume{
+		VolumeId:         awssdk.String(id),
+		Size:             params.Size,
+		State:            ec2types.VolumeStateAvailable,
+		AvailabilityZone: params.AvailabilityZone,
+		CreateTime:       awssdk.Time(created),
+		Tags:             params.TagSpecifications[0].Tags,
+	}
+	return &ec2.CreateVolumeOutput{VolumeId: awssdk.String(id)}, nil
+}
+
+func (f *fakeEC2) AttachVolume(ctx context.Context, params *ec2.AttachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.AttachVolumeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	volume, ok := f.volumes[awssdk.ToString(params.VolumeId)]
+	if !ok {
+		return nil, notFound("InvalidVolume.NotFound")
+	}
+	instance, ok := f.instances[awssdk.ToString(params.InstanceId)]
+	if !ok {
+		return nil, notFound("InvalidInstanceID.NotFound")
+	}
+	for _, mapping := range instance.BlockDeviceMappings {
+		if awssdk.ToString(mapping.DeviceName) == awssdk.ToString(params.Device) {
+			return nil, &smithy.GenericAPIError{Code: "InvalidParameterValue", Message: "device in use"}
+		}
+	}
+	volume.State = ec2types.VolumeStateInUse
+	volume.Attachments = []ec2types.VolumeAttachment{{InstanceId: params.InstanceId, Device: params.Device}}
+	instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, ec2types.InstanceBlockDeviceMapping{
+		DeviceName: params.Device,
+		Ebs:        &ec2types.EbsInstanceBlockDevice{VolumeId: params.VolumeId},
+	})
+	return &ec2.AttachVolumeOutput{}, nil
+}
+
+func (f *fakeEC2) DetachVolume(ctx context.Context, params *ec2.DetachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DetachVolumeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	volume, ok := f.volumes[awssdk.ToString(params.VolumeId)]
+	if !ok {
+		return nil, notFound("InvalidVolume.NotFound")
+	}
+	for _, attachment := range volume.Attachments {
+		instance := f.instances[awssdk.ToString(attachment.InstanceId)]
+		mappings := []ec2types.InstanceBlockDeviceMapping{}
+		for _, mapping := range instance.BlockDeviceMappings {
+			if awssdk.ToString(mapping.Ebs.VolumeId) != awssdk.ToString(params.VolumeId) {
+				mappings = append(mappings, mapping)
+			}
+		}
+		instance.BlockDeviceMappings = mappings
+	}
+	volume.State = ec2types.VolumeStateAvailable
+	volume.Attachments = nil
+	return &ec2.DetachVolumeOutput{}, nil
+}
+
+func (f *fakeEC2) DeleteVolume(ctx context.Context, params *ec2.DeleteVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	volume, ok := f.volumes[awssdk.ToString(params.VolumeId)]
+	if !ok {
+		return nil, notFound("InvalidVolume.NotFound")
+	}
+	if volume.State != ec2types.VolumeStateAvailable {
+		return nil, &smithy.GenericAPIError{Code: "VolumeInUse", Message: "volume in use"}
+	}
+	delete(f.volumes, awssdk.ToString(params.VolumeId))
+	return &ec2.DeleteVolumeOutput{}, nil
+}
+
+func (f *fakeEC2) ModifyVolume(ctx context.Context, params *ec2.ModifyVolumeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyVolumeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	volume, ok := f.volumes[awssdk.ToString(params.VolumeId)]
+	if !ok {
+		return nil, notFound("InvalidVolume.NotFound")
+	}
+	volume.Size = params.Size
+	return &ec2.ModifyVolumeOutput{}, nil
+}
+
+func (f *fakeEC2) ImportKeyPair(ctx context.Context, params *ec2.ImportKeyPairInput, optFns ...func(*ec2.Options)) (*ec2.ImportKeyPairOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := awssdk.ToString(params.KeyName)
+	if _, ok := f.keyPairs[name]; ok {
+		return nil, &smithy.GenericAPIError{Code: "InvalidKeyPair.Duplicate", Message: "key pair exists"}
+	}
+	f.keyPairs[name] = &ec2types.KeyPairInfo{
+		KeyPairId:  awssdk.String(f.id("key")),
+		KeyName:    params.KeyName,
+		PublicKey:  awssdk.String(string(params.PublicKeyMaterial)),
+		CreateTime: awssdk.Time(created),
+		Tags:       params.TagSpecifications[0].Tags,
+	}
+	return &ec2.ImportKeyPairOutput{KeyName: params.KeyName}, nil
+}
+
+func (f *fakeEC2) DescribeKeyPairs(ctx context.Context, params *ec2.DescribeKeyPairsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeKeyPairsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	output := &ec2.DescribeKeyPairsOutput{}
+	for _, name := range params.KeyNames {
+		if _, ok := f.keyPairs[name]; !ok {
+			return nil, notFound("InvalidKeyPair.NotFound")
+		}
+	}
+	for name, keyPair := range f.keyPairs {
+		if len(params.KeyNames) > 0 && !contains(params.KeyNames, name) {
+			continue
+		}
+		if matchFilters(params.Filters, keyPair.Tags, nil) {
+			output.KeyPairs = append(output.KeyPairs, *keyPair)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) DeleteKeyPair(ctx context.Context, params *ec2.DeleteKeyPairInput, optFns ...func(*ec2.Options)) (*ec2.DeleteKeyPairOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.keyPairs, awssdk.ToString(params.KeyName))
+	return &ec2.DeleteKeyPairOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	return &ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{{SubnetId: awssdk.String(params.SubnetIds[0]), VpcId: awssdk.String("vpc-1")}}}, nil
+}
+
+func (f *fakeEC2) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	output := &ec2.DescribeSecurityGroupsOutput{}
+	for _, group := range f.securityGroups {
+		if matchFilters(params.Filters, nil, map[string]string{"group-name": awssdk.ToString(group.GroupName), "vpc-id": awssdk.ToString(group.VpcId)}) {
+			output.SecurityGroups = append(output.SecurityGroups, group)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEC2) CreateSecurityGroup(ctx context.Context, params *ec2.CreateSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.CreateSecurityGroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id("sg")
+	f.securityGroups = append(f.securityGroups, ec2types.SecurityGroup{GroupId: awssdk.String(id), GroupName: params.GroupName, VpcId: params.VpcId})
+	return &ec2.CreateSecurityGroupOutput{GroupId: awssdk.String(id)}, nil
+}
+
+func (f *fakeEC2) AuthorizeSecurityGroupIngress(ctx context.Context, params *ec2.AuthorizeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ingress = params
+	return &ec2.AuthorizeSecurityGroupIngressOutput{}, nil
+}
+
+func (f *fakeEC2) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range params.Resources {
+		switch {
+		case f.instances[id] != nil:
+			f.instances[id].Tags = mergeTags(f.instances[id].Tags, params.Tags)
+		case f.volumes[id] != nil:
+			f.volumes[id].Tags = mergeTags(f.volumes[id].Tags, params.Tags)
+		default:
+			found := false
+			for _, keyPair := range f.keyPairs {
+				if awssdk.ToString(keyPair.KeyPairId) == id {
+					keyPair.Tags = mergeTags(keyPair.Tags, params.Tags)
+					found = true
+				}
+			}
+			if !found {
+				return nil, notFound("InvalidID.NotFound")
+			}
+		}
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+// mergeTags replaces the tags with the same keys and appends the others
+func mergeTags(tags, updates []ec2types.Tag) []ec2types.Tag {
+	merged := append([]ec2types.Tag{}, tags...)
+	for _, update := range updates {
+		replaced := false
+		for i, tag := range merged {
+			if awssdk.ToString(tag.Key) == awssdk.ToString(update.Key) {
+				merged[i] = update
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, update)
+		}
+	}
+	return merged
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&config.Config{})
+	assert.ErrorContains(t, err, "provider config not found")
+
+	p, err := New(&config.Config{Providers: []config.ProviderConfig{{
+		Name:        "aws",
+		Credentials: map[string]string{"access_key_id": "AKIA", "secret_access_key": "secret"},
+		ServerTypes: map[string]string{"cx22": "m6i.large", "large": "m6i.2xlarge"},
+	}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "aws", p.Name())
+	assert.Equal(t, config.DefaultAWSRegion, p.region)
+	assert.Equal(t, "m6i.large", p.instanceType("cx22"))
+	assert.Equal(t, "m6i.2xlarge", p.instanceType("large"))
+	assert.Equal(t, "c6i.xlarge", p.instanceType("cx32"))
+	assert.Equal(t, "m7g.large", p.instanceType("m7g.large"))
+}
+
+func TestTags(t *testing.T) {
+	spec := tagSpecification(ec2types.ResourceTypeInstance, "test-cp", map[string]string{"owner": "me", "lab_name": "test"})
+	assert.Equal(t, "test-cp", tagValue(spec.Tags, nameTag))
+	assert.Equal(t, "lab_name", awssdk.ToString(spec.Tags[1].Key))
+	tags := append(spec.Tags, ec2types.Tag{Key: awssdk.String("aws:cloudformation:stack-name"), Value: awssdk.String("x")})
+	assert.Equal(t, map[string]string{"owner": "me", "lab_name": "test"}, tagsToLabels(tags))
+
+	filters := selectorFilters("lab_name=test,owner")
+	if assert.Len(t, filters, 2) {
+		assert.Equal(t, "tag:lab_name", awssdk.ToString(filters[0].Name))
+		assert.Equal(t, []string{"test"}, filters[0].Values)
+		assert.Equal(t, "tag-key", awssdk.ToString(filters[1].Name))
+		assert.Equal(t, []string{"owner"}, filters[1].Values)
+	}
+
+	assert.True(t, isNotFound(notFound("InvalidKeyPair.NotFound")))
+	assert.False(t, isNotFound(&smithy.GenericAPIError{Code: "UnauthorizedOperation"}))
+}
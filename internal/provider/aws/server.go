@@ -0,0 +1,566 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+const (
+	// canonicalOwner is the AWS account with the official Ubuntu images
+	canonicalOwner = "099720109477"
+	// rootVolumeSize is the root disk size of the instances in GiB, like the Hetzner servers
+	rootVolumeSize = 40
+	// securityGroupName is the security group storctl creates when none is configured
+	securityGroupName = "storctl"
+)
+
+// securityGroupPorts are open to the world in the storctl security group: SSH, HTTP(S) for the ingress, and Kubernetes API
+var securityGroupPorts = []int32{22, 80, 443, 6443}
+
+// activeStates are the instance states of the servers that exist
+var activeStates = []string{"pending", "running", "stopping", "stopped"}
+
+func (p *AWSProvider) CreateServer(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+	defer cancel()
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if opts.Type == "" {
+		return nil, fmt.Errorf("type is required")
+	}
+	if opts.Image == "" {
+		return nil, fmt.Errorf("image is required")
+	}
+	existing, err := p.findInstance(ctx, opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting server: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("server %s already exists", opts.Name)
+	}
+	instanceType := p.instanceType(opts.Type)
+	typeInfo, err := p.describeInstanceTypes(ctx, []string{instanceType})
+	if err != nil {
+		return nil, fmt.Errorf("invalid server type %s: %w", opts.Type, err)
+	}
+	image, err := p.findImage(ctx, opts.Image, imageArch(typeInfo[instanceType]))
+	if err != nil {
+		return nil, err
+	}
+	// EC2 takes a single key pair, the other keys come with the cloud-init user data
+	keyName := ""
+	for _, sshKey := range opts.SSHKeys {
+		exists, err := p.CloudKeyExists(ctx, sshKey.ObjectMeta.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error getting SSH key: %w", err)
+		}
+		if exists {
+			keyName = sshKey.ObjectMeta.Name
+			break
+		}
+	}
+	if keyName == "" && opts.UserData == "" {
+		return nil, fmt.Errorf("no SSH keys provided")
+	}
+	securityGroup, err := p.ensureSecurityGroup(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &ec2.RunInstancesInput{
+		ImageId:      image.ImageId,
+		InstanceType: ec2types.InstanceType(instanceType),
+		MinCount:     awssdk.Int32(1),
+		MaxCount:     awssdk.Int32(1),
+		BlockDeviceMappings: []ec2types.BlockDeviceMapping{{
+			DeviceName: image.RootDeviceName,
+			Ebs: &ec2types.EbsBlockDevice{
+				VolumeSize:          awssdk.Int32(rootVolumeSize),
+				VolumeType:          ec2types.VolumeTypeGp3,
+				DeleteOnTermination: awssdk.Bool(true),
+			},
+		}},
+		TagSpecifications: []ec2types.TagSpecification{
+			tagSpecification(ec2types.ResourceTypeInstance, opts.Name, opts.Labels),
+			tagSpecification(ec2types.ResourceTypeVolume, opts.Name, opts.Labels),
+		},
+	}
+	if keyName != "" {
+		input.KeyName = awssdk.String(keyName)
+	}
+	if opts.UserData != "" {
+		input.UserData = awssdk.String(base64.StdEncoding.EncodeToString([]byte(opts.UserData)))
+	}
+	// the location is either the region or an availability zone in it
+	if opts.Location != "" && opts.Location != p.region {
+		input.Placement = &ec2types.Placement{AvailabilityZone: awssdk.String(opts.Location)}
+	}
+	if p.subnet != "" {
+		input.NetworkInterfaces = []ec2types.InstanceNetworkInterfaceSpecification{{
+			DeviceIndex:              awssdk.Int32(0),
+			SubnetId:                 awssdk.String(p.subnet),
+			Groups:                   []string{securityGroup},
+			AssociatePublicIpAddress: awssdk.Bool(true),
+		}}
+	} else {
+		input.SecurityGroupIds = []string{securityGroup}
+	}
+	p.logger.Debug("creating server",
+		"name", opts.Name,
+		"type", instanceType,
+		"image", awssdk.ToString(image.ImageId),
+		"location", opts.Location,
+		"key_name", keyName)
+	output, err := p.Client.RunInstances(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error creating server: %w", err)
+	}
+	instanceID := awssdk.ToString(output.Instances[0].InstanceId)
+	waiter := ec2.NewInstanceRunningWaiter(p.Client, func(o *ec2.InstanceRunningWaiterOptions) {
+		o.MinDelay = waiterDelay
+	})
+	described, err := waiter.WaitForOutput(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}}, 10*time.Minute)
+	if err != nil {
+		// don't leave a failed instance behind, even if the creation was canceled
+		if _, termErr := p.Client.TerminateInstances(context.WithoutCancel(ctx), &ec2.TerminateInstancesInput{InstanceIds: []string{instanceID}}); termErr != nil {
+			p.logger.Warn("failed to terminate server", "server", opts.Name, "error", termErr)
+		}
+		return nil, fmt.Errorf("error waiting for server %s: %w", opts.Name, err)
+	}
+	p.logger.Debug("successfully created server",
+		"name", opts.Name,
+		"id", instanceID)
+
+	servers, err := p.mapServers(ctx, instancesOf(described))
+	if err != nil {
+		return nil, err
+	}
+	return servers[0], nil
+}
+
+func (p *AWSProvider) GetServer(ctx context.Context, serverName string) (*types.Server, error) {
+	instance, err := p.findInstance(ctx, serverName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting server: %w", err)
+	}
+	if instance == nil {
+		return nil, nil
+	}
+	servers, err := p.mapServers(ctx, []ec2types.Instance{*instance})
+	if err != nil {
+		return nil, err
+	}
+	return servers[0], nil
+}
+
+func (p *AWSProvider) ListServers(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+	filters := append(selectorFilters(opts.LabelSelector), ec2types.Filter{Name: awssdk.String("instance-state-name"), Values: activeStates})
+	instances, err := p.describeInstances(ctx, &ec2.DescribeInstancesInput{Filters: filters})
+	if err != nil {
+		return nil, fmt.Errorf("error listing servers: %w", err)
+	}
+	return p.mapServers(ctx, instances)
+}
+
+func (p *AWSProvider) AllServers(ctx context.Context) ([]*types.Server, error) {
+	return p.ListServers(ctx, options.ServerListOpts{})
+}
+
+func (p *AWSProvider) DeleteServer(ctx context.Context, serverName string, force bool) *types.ServerDeleteStatus {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if serverName == "" {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("empty server name provided"),
+		}
+	}
+	instance, err := p.findInstance(ctx, serverName)
+	if err != nil {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("error getting server: %w", err),
+		}
+	}
+	if instance == nil {
+		p.logger.Debug("Server not found, skipping",
+			"server", serverName)
+		return &types.ServerDeleteStatus{
+			Deleted: true,
+		}
+	}
+	deleteAfter := timeutil.ParseDeleteAfter(tagValue(instance.Tags, "delete_after"))
+	if !force && time.Now().UTC().Before(deleteAfter) {
+		p.logger.Warn("server not ready for deletion",
+			"server", serverName,
+			"delete_after", deleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.ServerDeleteStatus{
+			DeleteAfter: deleteAfter,
+		}
+	}
+	// keep the volumes: only the root volume goes away with the instance
+	for _, volumeID := range dataVolumeIDs(instance) {
+		_, err := p.Client.DetachVolume(ctx, &ec2.DetachVolumeInput{VolumeId: awssdk.String(volumeID)})
+		if err != nil && !isNotFound(err) {
+			return &types.ServerDeleteStatus{
+				Error: fmt.Errorf("error detaching volume: %w", err),
+			}
+		}
+	}
+	_, err = p.Client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{InstanceIds: []string{awssdk.ToString(instance.InstanceId)}})
+	if err != nil && !isNotFound(err) {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("error deleting server: %w", err),
+		}
+	}
+	p.releaseDevices(awssdk.ToString(instance.InstanceId))
+	return &types.ServerDeleteStatus{
+		Deleted: true,
+	}
+}
+
+func (p *AWSProvider) ServerToCreateOpts(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error) {
+	sshKeys, err := p.KeyNamesToSSHKeys(ctx, server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
+		Labels: server.ObjectMeta.Labels,
+	})
+	if err != nil {
+		return options.ServerCreateOpts{}, fmt.Errorf("error converting SSH keys: %w", err)
+	}
+	cloudInitUserData := fmt.Sprintf(config.DefaultCloudInitUserData, sshKeys[0].Spec.PublicKey)
+	return options.ServerCreateOpts{
+		Name:     server.ObjectMeta.Name,
+		Type:     server.Spec.ServerType,
+		Image:    server.Spec.Image,
+		Location: server.Spec.Location,
+		Provider: "aws",
+		SSHKeys:  sshKeys,
+		Labels:   server.ObjectMeta.Labels,
+		UserData: cloudInitUserData,
+	}, nil
+}
+
+// UpdateServerLabels adds the labels to the tags of the instance and its root volume
+func (p *AWSProvider) UpdateServerLabels(ctx context.Context, serverName string, labels map[string]string) error {
+	instance, err := p.findInstance(ctx, serverName)
+	if err != nil {
+		return fmt.Errorf("error getting server: %w", err)
+	}
+	if instance == nil {
+		return fmt.Errorf("server not found: %s", serverName)
+	}
+	resourceIDs := []string{awssdk.ToString(instance.InstanceId)}
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs != nil && awssdk.ToString(mapping.DeviceName) == awssdk.ToString(instance.RootDeviceName) {
+			resourceIDs = append(resourceIDs, awssdk.ToString(mapping.Ebs.VolumeId))
+		}
+	}
+	p.logger.Debug("updating server tags",
+		"server", serverName,
+		"labels", labels)
+	if err := p.updateTags(ctx, labels, resourceIDs...); err != nil {
+		return fmt.Errorf("error updating server tags: %w", err)
+	}
+	return nil
+}
+
+// findInstance returns the instance with the name or nil if it doesn't exist
+func (p *AWSProvider) findInstance(ctx context.Context, name string) (*ec2types.Instance, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty server name provided")
+	}
+	instances, err := p.describeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: awssdk.String("tag:" + nameTag), Values: []string{name}},
+			{Name: awssdk.String("instance-state-name"), Values: activeStates},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, nil
+	}
+	return &instances[0], nil
+}
+
+// describeInstances returns all pages of the instances sorted by name
+func (p *AWSProvider) describeInstances(ctx context.Context, input *ec2.DescribeInstancesInput) ([]ec2types.Instance, error) {
+	instances := []ec2types.Instance{}
+	paginator := ec2.NewDescribeInstancesPaginator(p.Client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instancesOf(output)...)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return tagValue(instances[i].Tags, nameTag) < tagValue(instances[j].Tags, nameTag)
+	})
+	return instances, nil
+}
+
+func instancesOf(output *ec2.DescribeInstancesOutput) []ec2types.Instance {
+	instances := []ec2types.Instance{}
+	for _, reservation := range output.Reservations {
+		instances = append(instances, reservation.Instances...)
+	}
+	return instances
+}
+
+// dataVolumeIDs returns the IDs of the volumes attached to the instance besides the root volume
+func dataVolumeIDs(instance *ec2types.Instance) []string {
+	ids := []string{}
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil || awssdk.ToString(mapping.DeviceName) == awssdk.ToString(instance.RootDeviceName) {
+			continue
+		}
+		ids = append(ids, awssdk.ToString(mapping.Ebs.VolumeId))
+	}
+	return ids
+}
+
+func (p *AWSProvider) describeInstanceTypes(ctx context.Context, instanceTypes []string) (map[string]ec2types.InstanceTypeInfo, error) {
+	input := &ec2.DescribeInstanceTypesInput{}
+	seen := map[string]bool{}
+	for _, instanceType := range instanceTypes {
+		if !seen[instanceType] {
+			seen[instanceType] = true
+			input.InstanceTypes = append(input.InstanceTypes, ec2types.InstanceType(instanceType))
+		}
+	}
+	output, err := p.Client.DescribeInstanceTypes(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[string]ec2types.InstanceTypeInfo)
+	for _, info := range output.InstanceTypes {
+		infos[string(info.InstanceType)] = info
+	}
+	return infos, nil
+}
+
+// imageArch returns the architecture of the Ubuntu images for the instance type
+func imageArch(info ec2types.InstanceTypeInfo) string {
+	if info.ProcessorInfo != nil {
+		for _, arch := range info.ProcessorInfo.SupportedArchitectures {
+			if arch == ec2types.ArchitectureTypeArm64 {
+				return "arm64"
+			}
+		}
+	}
+	return "amd64"
+}
+
+// findImage returns the AMI with the ID, or the latest official Ubuntu AMI for images like ubuntu-24.04
+func (p *AWSProvider) findImage(ctx context.Context, image, arch string) (*ec2types.Image, error) {
+	input := &ec2.DescribeImagesInput{}
+	if strings.HasPrefix(image, "ami-") {
+		input.ImageIds = []string{image}
+	} else {
+		version, ok := strings.CutPrefix(image, "ubuntu-")
+		if !ok {
+			return nil, fmt.Errorf("unsupported image: %s", image)
+		}
+		input.Owners = []string{canonicalOwner}
+		input.Filters = []ec2types.Filter{
+			{Name: awssdk.String("name"), Values: []string{fmt.Sprintf("ubuntu/images/*/ubuntu-*-%s-%s-server-*", version, arch)}},
+			{Name: awssdk.String("state"), Values: []string{"available"}},
+		}
+	}
+	output, err := p.Client.DescribeImages(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error getting image %s: %w", image, err)
+	}
+	if len(output.Images) == 0 {
+		return nil, fmt.Errorf("image %s not found", image)
+	}
+	images := output.Images
+	sort.Slice(images, func(i, j int) bool {
+		return awssdk.ToString(images[i].CreationDate) > awssdk.ToString(images[j].CreationDate)
+	})
+	return &images[0], nil
+}
+
+// ensureSecurityGroup returns the configured security group or the storctl one, creating it if needed
+func (p *AWSProvider) ensureSecurityGroup(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.securityGroup != "" {
+		return p.securityGroup, nil
+	}
+	filters := []ec2types.Filter{{Name: awssdk.String("group-name"), Values: []string{securityGroupName}}}
+	var vpcID *string
+	if p.subnet != "" {
+		subnets, err := p.Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{p.subnet}})
+		if err != nil {
+			return "", fmt.Errorf("error getting subnet %s: %w", p.subnet, err)
+		}
+		if len(subnets.Subnets) == 0 {
+			return "", fmt.Errorf("subnet %s not found", p.subnet)
+		}
+		vpcID = subnets.Subnets[0].VpcId
+		filters = append(filters, ec2types.Filter{Name: awssdk.String("vpc-id"), Values: []string{awssdk.ToString(vpcID)}})
+	}
+	groups, err := p.Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{Filters: filters})
+	if err != nil {
+		return "", fmt.Errorf("error getting security group: %w", err)
+	}
+	if len(groups.SecurityGroups) > 0 {
+		p.securityGroup = awssdk.ToString(groups.SecurityGroups[0].GroupId)
+		return p.securityGroup, nil
+	}
+
+	p.logger.Info("creating security group", "name", securityGroupName)
+	group, err := p.Client.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:   awssdk.String(securityGroupName),
+		Description: awssdk.String("storctl lab servers"),
+		VpcId:       vpcID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating security group: %w", err)
+	}
+	groupID := awssdk.ToString(group.GroupId)
+	permissions := []ec2types.IpPermission{{
+		// the lab servers talk to each other on any port
+		IpProtocol:       awssdk.String("-1"),
+		UserIdGroupPairs: []ec2types.UserIdGroupPair{{GroupId: awssdk.String(groupID)}},
+	}}
+	for _, port := range securityGroupPorts {
+		permissions = append(permissions, ec2types.IpPermission{
+			IpProtocol: awssdk.String("tcp"),
+			FromPort:   awssdk.Int32(port),
+			ToPort:     awssdk.Int32(port),
+			IpRanges:   []ec2types.IpRange{{CidrIp: awssdk.String("0.0.0.0/0")}},
+		})
+	}
+	_, err = p.Client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       awssdk.String(groupID),
+		IpPermissions: permissions,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error opening ports in security group: %w", err)
+	}
+	p.securityGroup = groupID
+	return groupID, nil
+}
+
+// mapServers converts EC2 instances to our generic Server type
+func (p *AWSProvider) mapServers(ctx context.Context, instances []ec2types.Instance) ([]*types.Server, error) {
+	servers := make([]*types.Server, 0, len(instances))
+	if len(instances) == 0 {
+		return servers, nil
+	}
+	instanceTypes := make([]string, 0, len(instances))
+	volumeIDs := []string{}
+	for _, instance := range instances {
+		instanceTypes = append(instanceTypes, string(instance.InstanceType))
+		for _, mapping := range instance.BlockDeviceMappings {
+			if mapping.Ebs != nil {
+				volumeIDs = append(volumeIDs, awssdk.ToString(mapping.Ebs.VolumeId))
+			}
+		}
+	}
+	typeInfo, err := p.describeInstanceTypes(ctx, instanceTypes)
+	if err != nil {
+		return nil, fmt.Errorf("error getting server types: %w", err)
+	}
+	volumes := map[string]ec2types.Volume{}
+	if len(volumeIDs) > 0 {
+		described, err := p.describeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: volumeIDs})
+		if err != nil {
+			return nil, fmt.Errorf("error getting volumes: %w", err)
+		}
+		for _, volume := range described {
+			volumes[awssdk.ToString(volume.VolumeId)] = volume
+		}
+	}
+
+	for _, instance := range instances {
+		name := tagValue(instance.Tags, nameTag)
+		labels := tagsToLabels(instance.Tags)
+		info := typeInfo[string(instance.InstanceType)]
+		disk := 0
+		serverVolumes := []*types.Volume{}
+		for _, mapping := range instance.BlockDeviceMappings {
+			if mapping.Ebs == nil {
+				continue
+			}
+			volume, ok := volumes[awssdk.ToString(mapping.Ebs.VolumeId)]
+			if !ok {
+				continue
+			}
+			if awssdk.ToString(mapping.DeviceName) == awssdk.ToString(instance.RootDeviceName) {
+				disk = int(awssdk.ToInt32(volume.Size))
+				continue
+			}
+			serverVolumes = append(serverVolumes, mapVolume(volume, name))
+		}
+		cores, memory := 0, float32(0)
+		if info.VCpuInfo != nil {
+			cores = int(awssdk.ToInt32(info.VCpuInfo.DefaultVCpus))
+		}
+		if info.MemoryInfo != nil {
+			memory = float32(awssdk.ToInt64(info.MemoryInfo.SizeInMiB)) / 1024
+		}
+		ip := awssdk.ToString(instance.PublicIpAddress)
+		if ip == "" {
+			ip = awssdk.ToString(instance.PrivateIpAddress)
+		}
+		location := ""
+		if instance.Placement != nil {
+			location = awssdk.ToString(instance.Placement.AvailabilityZone)
+		}
+		status := ""
+		if instance.State != nil {
+			status = string(instance.State.Name)
+		}
+		servers = append(servers, &types.Server{
+			TypeMeta: types.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Server",
+			},
+			ObjectMeta: types.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+			Spec: types.ServerSpec{
+				ServerType: string(instance.InstanceType),
+				Location:   location,
+				Provider:   "aws",
+				Image:      awssdk.ToString(instance.ImageId),
+				Labels:     labels,
+				Volumes:    serverVolumes,
+				TTL:        labels["ttl"],
+			},
+			Status: types.ServerStatus{
+				Status:      status,
+				Owner:       labels["owner"],
+				Cores:       cores,
+				Memory:      memory,
+				Disk:        disk,
+				Created:     awssdk.ToTime(instance.LaunchTime),
+				DeleteAfter: timeutil.ParseDeleteAfter(labels["delete_after"]),
+				PublicNet: &types.PublicNet{
+					IPv4: &struct {
+						IP string `json:"ip"`
+					}{IP: ip},
+				},
+			},
+		})
+	}
+	return servers, nil
+}
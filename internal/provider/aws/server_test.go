@@ -0,0 +1,170 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateServer(t *testing.T) {
+	sshKeys := []*types.SSHKey{
+		{ObjectMeta: types.ObjectMeta{Name: "admin"}},
+		{ObjectMeta: types.ObjectMeta{Name: "test-admin"}},
+	}
+
+	t.Run("invalid server type", func(t *testing.T) {
+		p, _ := newTestProvider(t)
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "cx99", Image: "ubuntu-24.04", UserData: "#cloud-config"})
+		assert.ErrorContains(t, err, "invalid server type")
+		assert.Nil(t, server)
+	})
+
+	t.Run("no SSH keys", func(t *testing.T) {
+		p, _ := newTestProvider(t)
+		_, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "cx22", Image: "ubuntu-24.04", SSHKeys: sshKeys})
+		assert.ErrorContains(t, err, "no SSH keys provided")
+	})
+
+	t.Run("creates instance with labels as tags", func(t *testing.T) {
+		p, fake := newTestProvider(t)
+		fake.keyPairs["test-admin"] = &ec2types.KeyPairInfo{KeyName: awssdk.String("test-admin")}
+
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{
+			Name:     "test-cp",
+			Type:     "cx22",
+			Image:    "ubuntu-24.04",
+			Location: "us-east-1b",
+			SSHKeys:  sshKeys,
+			Labels:   map[string]string{"lab_name": "test", "owner": "me", "delete_after": "2099-01-01-00-00"},
+			UserData: "#cloud-config",
+		})
+		assert.NoError(t, err)
+
+		input := fake.runInput
+		assert.Equal(t, "ami-amd64", awssdk.ToString(input.ImageId))
+		assert.Equal(t, ec2types.InstanceType("t3.medium"), input.InstanceType)
+		assert.Equal(t, "test-admin", awssdk.ToString(input.KeyName))
+		assert.Equal(t, "us-east-1b", awssdk.ToString(input.Placement.AvailabilityZone))
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("#cloud-config")), awssdk.ToString(input.UserData))
+		assert.Equal(t, []string{"sg-1"}, input.SecurityGroupIds)
+		assert.Equal(t, "me", tagValue(input.TagSpecifications[0].Tags, "owner"))
+		// the storctl security group is created once with the lab ports open
+		assert.Len(t, fake.securityGroups, 1)
+		assert.Len(t, fake.ingress.IpPermissions, len(securityGroupPorts)+1)
+
+		if assert.NotNil(t, server) {
+			assert.Equal(t, "test-cp", server.ObjectMeta.Name)
+			assert.Equal(t, "running", server.Status.Status)
+			assert.Equal(t, "t3.medium", server.Spec.ServerType)
+			assert.Equal(t, "us-east-1b", server.Spec.Location)
+			assert.Equal(t, "aws", server.Spec.Provider)
+			assert.Equal(t, 2, server.Status.Cores)
+			assert.Equal(t, float32(4), server.Status.Memory)
+			assert.Equal(t, rootVolumeSize, server.Status.Disk)
+			assert.Empty(t, server.Spec.Volumes)
+			assert.Equal(t, "me", server.Status.Owner)
+			assert.Equal(t, "test", server.ObjectMeta.Labels["lab_name"])
+			assert.Equal(t, 2099, server.Status.DeleteAfter.Year())
+			assert.Equal(t, "203.0.113.5", server.Status.PublicNet.IPv4.IP)
+		}
+
+		_, err = p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "cx22", Image: "ubuntu-24.04", UserData: "#cloud-config"})
+		assert.ErrorContains(t, err, "already exists")
+
+		_, err = p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-node-1", Type: "m7g.large", Image: "ubuntu-24.04", UserData: "#cloud-config"})
+		assert.NoError(t, err)
+		assert.Equal(t, "ami-arm64", awssdk.ToString(fake.runInput.ImageId))
+		assert.Nil(t, fake.runInput.Placement)
+		assert.Len(t, fake.securityGroups, 1)
+	})
+
+	t.Run("uses subnet and security group from config", func(t *testing.T) {
+		p, fake := newTestProvider(t)
+		p.subnet = "subnet-1"
+		p.securityGroup = "sg-lab"
+
+		_, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "m6i.large", Image: "ami-old", UserData: "#cloud-config"})
+		assert.NoError(t, err)
+		assert.Empty(t, fake.securityGroups)
+		assert.Equal(t, "ami-old", awssdk.ToString(fake.runInput.ImageId))
+		if assert.Len(t, fake.runInput.NetworkInterfaces, 1) {
+			assert.Equal(t, "subnet-1", awssdk.ToString(fake.runInput.NetworkInterfaces[0].SubnetId))
+			assert.Equal(t, []string{"sg-lab"}, fake.runInput.NetworkInterfaces[0].Groups)
+		}
+	})
+}
+
+func TestListServers(t *testing.T) {
+	p, fake := newTestProvider(t)
+	fake.addInstance("b", "t3.medium", "us-east-1a", map[string]string{"lab_name": "test"})
+	fake.addInstance("a", "t3.medium", "us-east-1a", map[string]string{"lab_name": "test"})
+	fake.addInstance("c", "t3.medium", "us-east-1a", map[string]string{"lab_name": "other"})
+
+	servers, err := p.ListServers(context.Background(), options.ServerListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	if assert.Len(t, servers, 2) {
+		assert.Equal(t, "a", servers[0].ObjectMeta.Name)
+		assert.Equal(t, "b", servers[1].ObjectMeta.Name)
+	}
+
+	servers, err = p.AllServers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, servers, 3)
+
+	server, err := p.GetServer(context.Background(), "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, server)
+}
+
+func TestDeleteServer(t *testing.T) {
+	p, fake := newTestProvider(t)
+	instance := fake.addInstance("test-cp", "t3.medium", "us-east-1a", map[string]string{"delete_after": "2099-01-01-00-00"})
+	_, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-cp-volume-1", Size: 10, ServerName: "test-cp"})
+	assert.NoError(t, err)
+
+	status := p.DeleteServer(context.Background(), "test-cp", false)
+	assert.False(t, status.Deleted)
+	assert.Equal(t, 2099, status.DeleteAfter.Year())
+
+	status = p.DeleteServer(context.Background(), "test-cp", true)
+	assert.True(t, status.Deleted)
+	assert.NoError(t, status.Error)
+	assert.Equal(t, ec2types.InstanceStateNameTerminated, fake.instances[awssdk.ToString(instance.InstanceId)].State.Name)
+
+	// the data volume is detached and kept
+	volume, err := p.GetVolume(context.Background(), "test-cp-volume-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, volume) {
+		assert.Equal(t, "available", volume.Status.Status)
+		assert.Empty(t, volume.Spec.ServerName)
+	}
+
+	status = p.DeleteServer(context.Background(), "test-cp", false)
+	assert.True(t, status.Deleted)
+}
+
+func TestUpdateServerLabels(t *testing.T) {
+	p, fake := newTestProvider(t)
+	instance := fake.addInstance("test-cp", "t3.medium", "us-east-1a", map[string]string{"lab_name": "test", "delete_after": "2025-01-01-00-00"})
+
+	err := p.UpdateServerLabels(context.Background(), "test-cp", map[string]string{"delete_after": "2099-01-01-00-00", "extended_by": "me"})
+	assert.NoError(t, err)
+	server, err := p.GetServer(context.Background(), "test-cp")
+	assert.NoError(t, err)
+	if assert.NotNil(t, server) {
+		assert.Equal(t, map[string]string{"lab_name": "test", "delete_after": "2099-01-01-00-00", "extended_by": "me"}, server.ObjectMeta.Labels)
+		assert.Equal(t, 2099, server.Status.DeleteAfter.Year())
+	}
+	// the root volume keeps the labels of the instance
+	rootVolume := fake.volumes[awssdk.ToString(instance.BlockDeviceMappings[0].Ebs.VolumeId)]
+	assert.Equal(t, "2099-01-01-00-00", tagValue(rootVolume.Tags, "delete_after"))
+
+	err = p.UpdateServerLabels(context.Background(), "missing", map[string]string{"delete_after": "2099-01-01-00-00"})
+	assert.ErrorContains(t, err, "server not found")
+}
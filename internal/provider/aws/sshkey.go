@@ -0,0 +1,231 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/ssh"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+func (p *AWSProvider) CreateSSHKey(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
+	p.logger.Debug("creating SSH key",
+		"name", opts.Name,
+		"public_key", opts.PublicKey)
+	_, err := p.Client.ImportKeyPair(ctx, &ec2.ImportKeyPairInput{
+		KeyName:           awssdk.String(opts.Name),
+		PublicKeyMaterial: []byte(opts.PublicKey),
+		TagSpecifications: []ec2types.TagSpecification{tagSpecification(ec2types.ResourceTypeKeyPair, opts.Name, opts.Labels)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating SSH key: %w", err)
+	}
+	keyPair, err := p.getKeyPair(ctx, opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting SSH key: %w", err)
+	}
+	if keyPair == nil {
+		return nil, fmt.Errorf("SSH key not found")
+	}
+	return mapSSHKey(*keyPair), nil
+}
+
+func (p *AWSProvider) GetSSHKey(ctx context.Context, name string) (*types.SSHKey, error) {
+	p.logger.Debug("getting SSH key",
+		"key", name)
+	keyPair, err := p.getKeyPair(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting SSH key: %w", err)
+	}
+	if keyPair == nil {
+		p.logger.Debug("SSH key not found",
+			"key", name)
+		return nil, fmt.Errorf("SSH key not found")
+	}
+	return mapSSHKey(*keyPair), nil
+}
+
+func (p *AWSProvider) ListSSHKeys(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
+	output, err := p.Client.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+		Filters:          selectorFilters(opts.LabelSelector),
+		IncludePublicKey: awssdk.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing SSH keys: %w", err)
+	}
+	sshKeys := make([]*types.SSHKey, 0, len(output.KeyPairs))
+	for _, keyPair := range output.KeyPairs {
+		sshKeys = append(sshKeys, mapSSHKey(keyPair))
+	}
+	return sshKeys, nil
+}
+
+func (p *AWSProvider) AllSSHKeys(ctx context.Context) ([]*types.SSHKey, error) {
+	return p.ListSSHKeys(ctx, options.SSHKeyListOpts{})
+}
+
+func (p *AWSProvider) DeleteSSHKey(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus {
+	if name == "" {
+		return &types.SSHKeyDeleteStatus{
+			Error: fmt.Errorf("empty SSH key name provided"),
+		}
+	}
+	keyPair, err := p.getKeyPair(ctx, name)
+	if err != nil {
+		return &types.SSHKeyDeleteStatus{
+			Error: fmt.Errorf("error getting SSH key: %w", err),
+		}
+	}
+	if keyPair == nil {
+		p.logger.Debug("SSH key not found, skipping",
+			"key", name)
+		return &types.SSHKeyDeleteStatus{
+			Deleted: true,
+		}
+	}
+	deleteAfter := timeutil.ParseDeleteAfter(tagValue(keyPair.Tags, "delete_after"))
+	if !force && time.Now().UTC().Before(deleteAfter) {
+		p.logger.Warn("SSH key not ready for deletion",
+			"key", name,
+			"delete_after", deleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.SSHKeyDeleteStatus{
+			DeleteAfter: deleteAfter,
+		}
+	}
+	p.logger.Debug("deleting cloud SSH key",
+		"key", name)
+	_, err = p.Client.DeleteKeyPair(ctx, &ec2.DeleteKeyPairInput{KeyName: awssdk.String(name)})
+	if err != nil && !isNotFound(err) {
+		p.logger.Error("failed to delete cloud SSH key",
+			"key", name)
+		return &types.SSHKeyDeleteStatus{
+			Error: fmt.Errorf("error deleting SSH key: %w", err),
+		}
+	}
+	return &types.SSHKeyDeleteStatus{
+		Deleted: true,
+	}
+}
+
+func (p *AWSProvider) CloudKeyExists(ctx context.Context, name string) (bool, error) {
+	keyPair, err := p.getKeyPair(ctx, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to check SSH key existence: %w", err)
+	}
+	return keyPair != nil, nil
+}
+
+// KeyNamesToSSHKeys converts a list of SSH key names to a list of SSH keys
+// It will upload local SSH keys to the cloud if they don't exist
+// It adds the default admin key to the list
+func (p *AWSProvider) KeyNamesToSSHKeys(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
+	sshManager := ssh.NewManager(p.config)
+	sshKeys := make([]*types.SSHKey, 0)
+	adminKey, err := p.GetSSHKey(ctx, config.DefaultAdminKeyName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin key: %w", err)
+	}
+	sshKeys = append(sshKeys, adminKey)
+
+	for _, keyName := range keyNames {
+		cloudKeyExists, err := p.CloudKeyExists(ctx, keyName)
+		if err != nil {
+			return nil, fmt.Errorf("error checking if SSH key exists: %w", err)
+		}
+		if cloudKeyExists {
+			sshKey, err := p.GetSSHKey(ctx, keyName)
+			if err != nil {
+				return nil, err
+			}
+			sshKeys = append(sshKeys, sshKey)
+			continue
+		}
+		// check if the key exists locally
+		localKeyExists, err := sshManager.LocalKeyExists(keyName)
+		if err != nil {
+			return nil, err
+		}
+		if !localKeyExists {
+			fmt.Printf("SSH key %s not found locally, skipping it\n", keyName)
+			continue
+		}
+		pubKey, err := sshManager.ReadLocalPublicKey(keyName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local public key: %w", err)
+		}
+		opts.Name = keyName
+		opts.PublicKey = pubKey
+		newKey, err := p.CreateSSHKey(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SSH key: %w", err)
+		}
+		sshKeys = append(sshKeys, newKey)
+	}
+	return sshKeys, nil
+}
+
+// UpdateSSHKeyLabels adds the labels to the tags of the key pair
+func (p *AWSProvider) UpdateSSHKeyLabels(ctx context.Context, name string, labels map[string]string) error {
+	keyPair, err := p.getKeyPair(ctx, name)
+	if err != nil {
+		return fmt.Errorf("error getting SSH key: %w", err)
+	}
+	if keyPair == nil {
+		return fmt.Errorf("SSH key not found: %s", name)
+	}
+	p.logger.Debug("updating SSH key tags",
+		"key", name,
+		"labels", labels)
+	if err := p.updateTags(ctx, labels, awssdk.ToString(keyPair.KeyPairId)); err != nil {
+		return fmt.Errorf("error updating SSH key tags: %w", err)
+	}
+	return nil
+}
+
+// getKeyPair returns the key pair or nil if it doesn't exist
+func (p *AWSProvider) getKeyPair(ctx context.Context, name string) (*ec2types.KeyPairInfo, error) {
+	output, err := p.Client.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+		KeyNames:         []string{name},
+		IncludePublicKey: awssdk.Bool(true),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(output.KeyPairs) == 0 {
+		return nil, nil
+	}
+	return &output.KeyPairs[0], nil
+}
+
+func mapSSHKey(k ec2types.KeyPairInfo) *types.SSHKey {
+	labels := tagsToLabels(k.Tags)
+	return &types.SSHKey{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "SSHKey",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name:   awssdk.ToString(k.KeyName),
+			Labels: labels,
+		},
+		Spec: types.SSHKeySpec{
+			PublicKey: awssdk.ToString(k.PublicKey),
+			Labels:    labels,
+		},
+		Status: types.SSHKeyStatus{
+			Owner:       labels["owner"],
+			Created:     awssdk.ToTime(k.CreateTime),
+			DeleteAfter: timeutil.ParseDeleteAfter(labels["delete_after"]),
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHKeys(t *testing.T) {
+	p, fake := newTestProvider(t)
+
+	_, err := p.GetSSHKey(context.Background(), "test-admin")
+	assert.ErrorContains(t, err, "SSH key not found")
+	exists, err := p.CloudKeyExists(context.Background(), "test-admin")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	key, err := p.CreateSSHKey(context.Background(), options.SSHKeyCreateOpts{
+		Name:      "test-admin",
+		PublicKey: "ssh-ed25519 AAAA test-admin",
+		Labels:    map[string]string{"lab_name": "test", "delete_after": "2099-01-01-00-00"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "test-admin", key.ObjectMeta.Name)
+	assert.Equal(t, 2025, key.Status.Created.Year())
+
+	key, err = p.GetSSHKey(context.Background(), "test-admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh-ed25519 AAAA test-admin", key.Spec.PublicKey)
+	assert.Equal(t, "test", key.Spec.Labels["lab_name"])
+
+	fake.keyPairs["other"] = &ec2types.KeyPairInfo{KeyName: awssdk.String("other")}
+	keys, err := p.AllSSHKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	keys, err = p.ListSSHKeys(context.Background(), options.SSHKeyListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	status := p.DeleteSSHKey(context.Background(), "test-admin", false)
+	assert.False(t, status.Deleted)
+	assert.Equal(t, 2099, status.DeleteAfter.Year())
+	status = p.DeleteSSHKey(context.Background(), "test-admin", true)
+	assert.True(t, status.Deleted)
+	assert.NotContains(t, fake.keyPairs, "test-admin")
+	status = p.DeleteSSHKey(context.Background(), "test-admin", false)
+	assert.True(t, status.Deleted)
+}
+
+func TestKeyNamesToSSHKeys(t *testing.T) {
+	p, fake := newTestProvider(t)
+
+	_, err := p.KeyNamesToSSHKeys(context.Background(), []string{"user"}, options.SSHKeyCreateOpts{})
+	assert.ErrorContains(t, err, "failed to get admin key")
+
+	fake.keyPairs[config.DefaultAdminKeyName] = &ec2types.KeyPairInfo{KeyName: awssdk.String(config.DefaultAdminKeyName), PublicKey: awssdk.String("ssh-ed25519 AAAA admin")}
+	fake.keyPairs["user"] = &ec2types.KeyPairInfo{KeyName: awssdk.String("user"), PublicKey: awssdk.String("ssh-ed25519 AAAA user")}
+	keys, err := p.KeyNamesToSSHKeys(context.Background(), []string{"user", "missing"}, options.SSHKeyCreateOpts{})
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, config.DefaultAdminKeyName, keys[0].ObjectMeta.Name)
+		assert.Equal(t, "user", keys[1].ObjectMeta.Name)
+	}
+}
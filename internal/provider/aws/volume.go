@@ -0,0 +1,434 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// deviceLetters are the suffixes of the /dev/sd[f-p] devices recommended for EBS volumes
+const deviceLetters = "fghijklmnop"
+
+func (p *AWSProvider) CreateVolume(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("volume name is required")
+	}
+	if opts.Size == 0 {
+		return nil, fmt.Errorf("volume size is required")
+	}
+	var instance *ec2types.Instance
+	zone := opts.Location
+	if opts.ServerName == "" {
+		if zone == "" || zone == p.region {
+			return nil, fmt.Errorf("availability zone is required when server name is empty")
+		}
+		p.logger.Debug("server name is empty, using location instead",
+			"location", opts.Location)
+	} else {
+		var err error
+		instance, err = p.findInstance(ctx, opts.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("error getting server: %w", err)
+		}
+		if instance == nil {
+			return nil, fmt.Errorf("server not found: %s", opts.ServerName)
+		}
+		// EBS volumes can only be attached in the zone of the instance
+		zone = awssdk.ToString(instance.Placement.AvailabilityZone)
+	}
+	existing, err := p.findVolume(ctx, opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("volume %s already exists", opts.Name)
+	}
+	p.logger.Debug("creating volume",
+		"name", opts.Name,
+		"size", opts.Size,
+		"zone", zone,
+		"server", opts.ServerName)
+	output, err := p.Client.CreateVolume(ctx, &ec2.CreateVolumeInput{
+		AvailabilityZone:  awssdk.String(zone),
+		Size:              awssdk.Int32(int32(opts.Size)),
+		VolumeType:        ec2types.VolumeTypeGp3,
+		TagSpecifications: []ec2types.TagSpecification{tagSpecification(ec2types.ResourceTypeVolume, opts.Name, opts.Labels)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating volume: %w", err)
+	}
+	volumeID := awssdk.ToString(output.VolumeId)
+	availableWaiter := ec2.NewVolumeAvailableWaiter(p.Client, func(o *ec2.VolumeAvailableWaiterOptions) {
+		o.MinDelay = waiterDelay
+	})
+	described, err := availableWaiter.WaitForOutput(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}}, 5*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for volume %s: %w", opts.Name, err)
+	}
+	volume := described.Volumes[0]
+	serverName := ""
+	if instance != nil {
+		if volume, err = p.attachVolume(ctx, volumeID, instance); err != nil {
+			return nil, err
+		}
+		serverName = opts.ServerName
+	}
+	p.logger.Debug("successfully created volume",
+		"name", opts.Name,
+		"id", volumeID)
+	return mapVolume(volume, serverName), nil
+}
+
+func (p *AWSProvider) GetVolume(ctx context.Context, volumeName string) (*types.Volume, error) {
+	volume, err := p.findVolume(ctx, volumeName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume: %w", err)
+	}
+	if volume == nil {
+		return nil, nil
+	}
+	volumes, err := p.mapVolumesWithServers(ctx, []ec2types.Volume{*volume})
+	if err != nil {
+		return nil, err
+	}
+	return volumes[0], nil
+}
+
+func (p *AWSProvider) ListVolumes(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+	volumes, err := p.describeVolumes(ctx, &ec2.DescribeVolumesInput{Filters: selectorFilters(opts.LabelSelector)})
+	if err != nil {
+		return nil, fmt.Errorf("error listing volumes: %w", err)
+	}
+	// root volumes are listed with their servers
+	dataVolumes := []ec2types.Volume{}
+	for _, volume := range volumes {
+		if tagValue(volume.Tags, nameTag) != "" && !isRootVolume(volume) {
+			dataVolumes = append(dataVolumes, volume)
+		}
+	}
+	return p.mapVolumesWithServers(ctx, dataVolumes)
+}
+
+func (p *AWSProvider) AllVolumes(ctx context.Context) ([]*types.Volume, error) {
+	return p.ListVolumes(ctx, options.VolumeListOpts{})
+}
+
+func (p *AWSProvider) DeleteVolume(ctx context.Context, volumeName string, force bool) *types.VolumeDeleteStatus {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	if volumeName == "" {
+		return &types.VolumeDeleteStatus{
+			Error: fmt.Errorf("empty volume name provided"),
+		}
+	}
+	volume, err := p.findVolume(ctx, volumeName)
+	if err != nil {
+		return &types.VolumeDeleteStatus{
+			Error: fmt.Errorf("error getting volume: %w", err),
+		}
+	}
+	if volume == nil {
+		p.logger.Debug("Volume not found, skipping",
+			"volume", volumeName)
+		return &types.VolumeDeleteStatus{
+			Deleted: true,
+		}
+	}
+	deleteAfter := timeutil.ParseDeleteAfter(tagValue(volume.Tags, "delete_after"))
+	if !force && time.Now().UTC().Before(deleteAfter) {
+		p.logger.Warn("volume not ready for deletion",
+			"volume", volumeName,
+			"delete_after", deleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.VolumeDeleteStatus{
+			DeleteAfter: deleteAfter,
+		}
+	}
+	volumeID := awssdk.ToString(volume.VolumeId)
+	if volume.State != ec2types.VolumeStateAvailable {
+		for _, attachment := range volume.Attachments {
+			p.logger.Debug("volume is attached to server, detaching",
+				"volume", volumeName,
+				"server", awssdk.ToString(attachment.InstanceId))
+			_, err := p.Client.DetachVolume(ctx, &ec2.DetachVolumeInput{VolumeId: volume.VolumeId})
+			if err != nil && !isNotFound(err) {
+				return &types.VolumeDeleteStatus{
+					Error: fmt.Errorf("error detaching volume: %w", err),
+				}
+			}
+			p.releaseDevice(awssdk.ToString(attachment.InstanceId), awssdk.ToString(attachment.Device))
+		}
+		waiter := ec2.NewVolumeAvailableWaiter(p.Client, func(o *ec2.VolumeAvailableWaiterOptions) {
+			o.MinDelay = waiterDelay
+		})
+		if err := waiter.Wait(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}}, 5*time.Minute); err != nil {
+			return &types.VolumeDeleteStatus{
+				Error: fmt.Errorf("error waiting for volume %s to detach: %w", volumeName, err),
+			}
+		}
+	}
+	_, err = p.Client.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: volume.VolumeId})
+	if err != nil && !isNotFound(err) {
+		return &types.VolumeDeleteStatus{
+			Error: fmt.Errorf("error deleting volume: %w", err),
+		}
+	}
+	return &types.VolumeDeleteStatus{
+		Deleted: true,
+	}
+}
+
+// ResizeVolume grows a volume to the new size in GB.
+// EBS volumes can't be shrunk, so a smaller size returns an error.
+func (p *AWSProvider) ResizeVolume(ctx context.Context, volumeName string, size int) (*types.Volume, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	volume, err := p.findVolume(ctx, volumeName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume: %w", err)
+	}
+	if volume == nil {
+		return nil, fmt.Errorf("volume not found: %s", volumeName)
+	}
+	current := int(awssdk.ToInt32(volume.Size))
+	if size < current {
+		return nil, fmt.Errorf("volume %s can't be shrunk from %dGB to %dGB", volumeName, current, size)
+	}
+	if size > current {
+		p.logger.Debug("resizing volume",
+			"volume", volumeName,
+			"from", current,
+			"to", size)
+		_, err = p.Client.ModifyVolume(ctx, &ec2.ModifyVolumeInput{
+			VolumeId: volume.VolumeId,
+			Size:     awssdk.Int32(int32(size)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error resizing volume: %w", err)
+		}
+		// the new size shows up when the modification starts optimizing
+		if volume, err = p.waitForVolumeSize(ctx, awssdk.ToString(volume.VolumeId), size); err != nil {
+			return nil, err
+		}
+	}
+	volumes, err := p.mapVolumesWithServers(ctx, []ec2types.Volume{*volume})
+	if err != nil {
+		return nil, err
+	}
+	return volumes[0], nil
+}
+
+// UpdateVolumeLabels adds the labels to the tags of the volume
+func (p *AWSProvider) UpdateVolumeLabels(ctx context.Context, volumeName string, labels map[string]string) error {
+	volume, err := p.findVolume(ctx, volumeName)
+	if err != nil {
+		return fmt.Errorf("error getting volume: %w", err)
+	}
+	if volume == nil {
+		return fmt.Errorf("volume not found: %s", volumeName)
+	}
+	p.logger.Debug("updating volume tags",
+		"volume", volumeName,
+		"labels", labels)
+	if err := p.updateTags(ctx, labels, awssdk.ToString(volume.VolumeId)); err != nil {
+		return fmt.Errorf("error updating volume tags: %w", err)
+	}
+	return nil
+}
+
+func (p *AWSProvider) waitForVolumeSize(ctx context.Context, volumeID string, size int) (*ec2types.Volume, error) {
+	ticker := time.NewTicker(waiterDelay)
+	defer ticker.Stop()
+	for {
+		volumes, err := p.describeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}})
+		if err != nil {
+			return nil, fmt.Errorf("error getting volume %s: %w", volumeID, err)
+		}
+		if len(volumes) == 0 {
+			return nil, fmt.Errorf("volume not found: %s", volumeID)
+		}
+		if int(awssdk.ToInt32(volumes[0].Size)) == size {
+			return &volumes[0], nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for volume %s to grow to %dGB", volumeID, size)
+		case <-ticker.C:
+		}
+	}
+}
+
+// attachVolume attaches the volume to the first free device of the instance and waits until it's in use
+func (p *AWSProvider) attachVolume(ctx context.Context, volumeID string, instance *ec2types.Instance) (ec2types.Volume, error) {
+	instanceID := awssdk.ToString(instance.InstanceId)
+	device, err := p.reserveDevice(instance)
+	if err != nil {
+		return ec2types.Volume{}, err
+	}
+	p.logger.Debug("attaching volume",
+		"volume", volumeID,
+		"server", instanceID,
+		"device", device)
+	_, err = p.Client.AttachVolume(ctx, &ec2.AttachVolumeInput{
+		VolumeId:   awssdk.String(volumeID),
+		InstanceId: awssdk.String(instanceID),
+		Device:     awssdk.String(device),
+	})
+	if err != nil {
+		p.releaseDevice(instanceID, device)
+		return ec2types.Volume{}, fmt.Errorf("error attaching volume: %w", err)
+	}
+	waiter := ec2.NewVolumeInUseWaiter(p.Client, func(o *ec2.VolumeInUseWaiterOptions) {
+		o.MinDelay = waiterDelay
+	})
+	described, err := waiter.WaitForOutput(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}}, 5*time.Minute)
+	if err != nil {
+		return ec2types.Volume{}, fmt.Errorf("error waiting for volume %s to attach: %w", volumeID, err)
+	}
+	return described.Volumes[0], nil
+}
+
+// reserveDevice picks a device that is neither mapped on the instance nor being attached by another goroutine
+func (p *AWSProvider) reserveDevice(instance *ec2types.Instance) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	instanceID := awssdk.ToString(instance.InstanceId)
+	if p.devices[instanceID] == nil {
+		p.devices[instanceID] = make(map[string]bool)
+	}
+	reserved := p.devices[instanceID]
+	for _, mapping := range instance.BlockDeviceMappings {
+		reserved[awssdk.ToString(mapping.DeviceName)] = true
+	}
+	for _, letter := range deviceLetters {
+		device := "/dev/sd" + string(letter)
+		// the instance may report the xvd name for the same device
+		if !reserved[device] && !reserved["/dev/xvd"+string(letter)] {
+			reserved[device] = true
+			return device, nil
+		}
+	}
+	return "", fmt.Errorf("no free devices left on server %s", tagValue(instance.Tags, nameTag))
+}
+
+func (p *AWSProvider) releaseDevice(instanceID, device string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.devices[instanceID], device)
+}
+
+func (p *AWSProvider) releaseDevices(instanceID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.devices, instanceID)
+}
+
+// findVolume returns the volume with the name or nil if it doesn't exist
+func (p *AWSProvider) findVolume(ctx context.Context, name string) (*ec2types.Volume, error) {
+	volumes, err := p.describeVolumes(ctx, &ec2.DescribeVolumesInput{
+		Filters: []ec2types.Filter{{Name: awssdk.String("tag:" + nameTag), Values: []string{name}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, volume := range volumes {
+		if !isRootVolume(volume) {
+			return &volume, nil
+		}
+	}
+	return nil, nil
+}
+
+// describeVolumes returns all pages of the volumes sorted by name
+func (p *AWSProvider) describeVolumes(ctx context.Context, input *ec2.DescribeVolumesInput) ([]ec2types.Volume, error) {
+	volumes := []ec2types.Volume{}
+	paginator := ec2.NewDescribeVolumesPaginator(p.Client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, output.Volumes...)
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return tagValue(volumes[i].Tags, nameTag) < tagValue(volumes[j].Tags, nameTag)
+	})
+	return volumes, nil
+}
+
+// isRootVolume reports whether the volume was created with an instance.
+// Root volumes get the server name and labels from the instance tag specification,
+// so they are told apart by the snapshot they were created from.
+func isRootVolume(volume ec2types.Volume) bool {
+	return strings.HasPrefix(awssdk.ToString(volume.SnapshotId), "snap-")
+}
+
+// mapVolumesWithServers converts EBS volumes looking up the names of the servers they're attached to
+func (p *AWSProvider) mapVolumesWithServers(ctx context.Context, volumes []ec2types.Volume) ([]*types.Volume, error) {
+	instanceIDs := []string{}
+	for _, volume := range volumes {
+		for _, attachment := range volume.Attachments {
+			instanceIDs = append(instanceIDs, awssdk.ToString(attachment.InstanceId))
+		}
+	}
+	serverNames := map[string]string{}
+	if len(instanceIDs) > 0 {
+		instances, err := p.describeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: instanceIDs})
+		if err != nil {
+			return nil, fmt.Errorf("error getting servers: %w", err)
+		}
+		for _, instance := range instances {
+			serverNames[awssdk.ToString(instance.InstanceId)] = tagValue(instance.Tags, nameTag)
+		}
+	}
+	result := make([]*types.Volume, 0, len(volumes))
+	for _, volume := range volumes {
+		serverName := ""
+		if len(volume.Attachments) > 0 {
+			serverName = serverNames[awssdk.ToString(volume.Attachments[0].InstanceId)]
+		}
+		result = append(result, mapVolume(volume, serverName))
+	}
+	return result, nil
+}
+
+// mapVolume converts an EBS volume to our generic Volume type
+func mapVolume(v ec2types.Volume, serverName string) *types.Volume {
+	labels := tagsToLabels(v.Tags)
+	return &types.Volume{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Volume",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name:   tagValue(v.Tags, nameTag),
+			Labels: labels,
+		},
+		Spec: types.VolumeSpec{
+			Size:       int(awssdk.ToInt32(v.Size)),
+			ServerName: serverName,
+			Location:   awssdk.ToString(v.AvailabilityZone),
+			Provider:   "aws",
+			Labels:     labels,
+		},
+		Status: types.VolumeStatus{
+			Status:      string(v.State),
+			Owner:       labels["owner"],
+			Created:     awssdk.ToTime(v.CreateTime),
+			DeleteAfter: timeutil.ParseDeleteAfter(labels["delete_after"]),
+		},
+	}
+}
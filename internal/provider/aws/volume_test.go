@@ -0,0 +1,150 @@
+package aws
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateVolume(t *testing.T) {
+	t.Run("server not found", func(t *testing.T) {
+		p, _ := newTestProvider(t)
+		_, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-cp-volume-1", Size: 10, ServerName: "test-cp"})
+		assert.ErrorContains(t, err, "server not found")
+	})
+
+	t.Run("zone required without server", func(t *testing.T) {
+		p, _ := newTestProvider(t)
+		_, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-volume", Size: 10, Location: "us-east-1"})
+		assert.ErrorContains(t, err, "availability zone is required")
+
+		volume, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-volume", Size: 10, Location: "us-east-1c"})
+		assert.NoError(t, err)
+		assert.Equal(t, "available", volume.Status.Status)
+		assert.Equal(t, "us-east-1c", volume.Spec.Location)
+	})
+
+	t.Run("attaches to server", func(t *testing.T) {
+		p, fake := newTestProvider(t)
+		fake.addInstance("test-cp", "t3.medium", "us-east-1b", nil)
+
+		volume, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{
+			Name:       "test-cp-volume-1",
+			Size:       10,
+			ServerName: "test-cp",
+			Labels:     map[string]string{"lab_name": "test"},
+		})
+		assert.NoError(t, err)
+		if assert.NotNil(t, volume) {
+			assert.Equal(t, "in-use", volume.Status.Status)
+			assert.Equal(t, "test-cp", volume.Spec.ServerName)
+			assert.Equal(t, "us-east-1b", volume.Spec.Location)
+			assert.Equal(t, "aws", volume.Spec.Provider)
+			assert.Equal(t, "test", volume.ObjectMeta.Labels["lab_name"])
+			assert.Equal(t, 2025, volume.Status.Created.Year())
+		}
+
+		volume, err = p.GetVolume(context.Background(), "test-cp-volume-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "test-cp", volume.Spec.ServerName)
+
+		server, err := p.GetServer(context.Background(), "test-cp")
+		assert.NoError(t, err)
+		if assert.Len(t, server.Spec.Volumes, 1) {
+			assert.Equal(t, "test-cp-volume-1", server.Spec.Volumes[0].ObjectMeta.Name)
+		}
+
+		_, err = p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-cp-volume-1", Size: 10, ServerName: "test-cp"})
+		assert.ErrorContains(t, err, "already exists")
+	})
+
+	t.Run("attaches in parallel to free devices", func(t *testing.T) {
+		p, fake := newTestProvider(t)
+		fake.addInstance("test-cp", "t3.medium", "us-east-1a", nil)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 4)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-cp-volume-" + string(rune('1'+i)), Size: 10, ServerName: "test-cp"})
+			}()
+		}
+		wg.Wait()
+		for _, err := range errs {
+			assert.NoError(t, err)
+		}
+		devices := map[string]bool{}
+		for _, volume := range fake.volumes {
+			for _, attachment := range volume.Attachments {
+				devices[awssdk.ToString(attachment.Device)] = true
+			}
+		}
+		// the root device and four data devices
+		assert.Len(t, devices, 5)
+	})
+}
+
+func TestListVolumes(t *testing.T) {
+	p, fake := newTestProvider(t)
+	fake.addInstance("test-cp", "t3.medium", "us-east-1a", map[string]string{"lab_name": "test"})
+	fake.addVolume("b", 10, map[string]string{"lab_name": "test"})
+	fake.addVolume("a", 10, map[string]string{"lab_name": "test"})
+	fake.addVolume("c", 10, map[string]string{"lab_name": "other"})
+
+	// the root volume of the server isn't listed
+	volumes, err := p.ListVolumes(context.Background(), options.VolumeListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	if assert.Len(t, volumes, 2) {
+		assert.Equal(t, "a", volumes[0].ObjectMeta.Name)
+		assert.Equal(t, "b", volumes[1].ObjectMeta.Name)
+	}
+
+	volume, err := p.GetVolume(context.Background(), "test-cp")
+	assert.NoError(t, err)
+	assert.Nil(t, volume)
+}
+
+func TestResizeVolume(t *testing.T) {
+	p, fake := newTestProvider(t)
+	fake.addVolume("test-cp-volume-1", 10, nil)
+
+	_, err := p.ResizeVolume(context.Background(), "test-cp-volume-1", 5)
+	assert.ErrorContains(t, err, "can't be shrunk")
+
+	volume, err := p.ResizeVolume(context.Background(), "test-cp-volume-1", 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, volume.Spec.Size)
+
+	_, err = p.ResizeVolume(context.Background(), "missing", 20)
+	assert.ErrorContains(t, err, "volume not found")
+}
+
+func TestDeleteVolume(t *testing.T) {
+	p, fake := newTestProvider(t)
+	fake.addInstance("test-cp", "t3.medium", "us-east-1a", nil)
+	_, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{
+		Name:       "test-cp-volume-1",
+		Size:       10,
+		ServerName: "test-cp",
+		Labels:     map[string]string{"delete_after": "2099-01-01-00-00"},
+	})
+	assert.NoError(t, err)
+
+	status := p.DeleteVolume(context.Background(), "test-cp-volume-1", false)
+	assert.False(t, status.Deleted)
+	assert.Len(t, fake.volumes, 2)
+
+	status = p.DeleteVolume(context.Background(), "test-cp-volume-1", true)
+	assert.True(t, status.Deleted)
+	assert.NoError(t, status.Error)
+	assert.Len(t, fake.volumes, 1)
+
+	status = p.DeleteVolume(context.Background(), "test-cp-volume-1", true)
+	assert.True(t, status.Deleted)
+}
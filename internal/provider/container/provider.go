@@ -0,0 +1,164 @@
+// Package container contains the Docker and Podman implementation of the provider interface for the storctl tool.
+// Servers are privileged systemd containers, volumes are sparse files attached to them as loop devices,
+// and SSH keys are kept locally and injected into the containers as authorized keys.
+// It needs a rootful Docker or Podman on Linux, so it can run labs on CI runners without VMs.
+package container
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/keystore"
+	"github.com/pavelanni/storctl/internal/provider/volumestore"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+const (
+	// managedLabel marks the containers created by storctl
+	managedLabel = "storctl.managed"
+	// serverTypeLabel keeps the server type of the container
+	serverTypeLabel = "storctl.server_type"
+	// imageLabel keeps the lab image the container was built from
+	imageLabel = "storctl.image"
+	// networkName is the bridge network shared by the lab containers
+	networkName = "storctl"
+	// volumesMountPath is where the volume files are mounted in the containers
+	volumesMountPath = "/var/lib/storctl/volumes"
+)
+
+// runFunc runs the container runtime CLI with the given arguments and stdin and returns its output
+type runFunc func(ctx context.Context, stdin string, args ...string) ([]byte, error)
+
+type ContainerProvider struct {
+	config  *config.Config
+	logger  *slog.Logger
+	runtime string // docker or podman
+	dir     string // directory with the volume files
+	keys    *keystore.Store
+	volumes *volumestore.Store
+	run     runFunc
+}
+
+func New(cfg *config.Config) (*ContainerProvider, error) {
+	providerConfig := getProviderConfig(cfg, "container")
+	if providerConfig == nil {
+		providerConfig = &config.ProviderConfig{Name: "container"}
+	}
+
+	runtime, err := findRuntime(providerConfig.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+
+	logger := logger.Get()
+	logger.Info("Initializing container provider")
+	logger.Debug("Using configuration",
+		"runtime", runtime,
+		"location", providerConfig.Location)
+
+	dir := filepath.Join(homeDir, config.DefaultConfigDir, config.DefaultContainerDir)
+	return &ContainerProvider{
+		config:  cfg,
+		logger:  logger,
+		runtime: runtime,
+		dir:     dir,
+		keys:    keystore.New(filepath.Join(dir, "keys")),
+		volumes: volumestore.New(filepath.Join(dir, "volumes")),
+		run:     runtimeRunner(runtime),
+	}, nil
+}
+
+func (p *ContainerProvider) Name() string {
+	return "container"
+}
+
+// Capabilities of containers: they are ready when they are created and have no public DNS names
+func (p *ContainerProvider) Capabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{
+		VolumesAttachAfterBoot: true,
+		SupportsLabels:         true,
+		SupportsTTLLabels:      true,
+		ManagesSSHKeys:         true,
+		SSHUser:                config.DefaultAdminUser,
+	}
+}
+
+// Runtime returns the container runtime CLI used by the provider
+func (p *ContainerProvider) Runtime() string {
+	return p.runtime
+}
+
+// findRuntime returns the configured container runtime or the first one found in PATH
+func findRuntime(configured string) (string, error) {
+	if configured != "" {
+		if configured != "docker" && configured != "podman" {
+			return "", fmt.Errorf("unsupported container runtime: %s", configured)
+		}
+		if _, err := exec.LookPath(configured); err != nil {
+			return "", fmt.Errorf("container runtime %s not found in PATH", configured)
+		}
+		return configured, nil
+	}
+	for _, runtime := range []string{config.DefaultContainerRuntime, "podman"} {
+		if _, err := exec.LookPath(runtime); err == nil {
+			return runtime, nil
+		}
+	}
+	return "", fmt.Errorf("neither docker nor podman found in PATH")
+}
+
+// runtimeRunner returns a runFunc that executes the runtime CLI
+func runtimeRunner(runtime string) runFunc {
+	return func(ctx context.Context, stdin string, args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, runtime, args...)
+		if stdin != "" {
+			cmd.Stdin = strings.NewReader(stdin)
+		}
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return stdout.Bytes(), fmt.Errorf("timeout running %s %s: %w", runtime, args[0], err)
+			}
+			return stdout.Bytes(), fmt.Errorf("%s %s: %w, output: %s", runtime, args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.Bytes(), nil
+	}
+}
+
+// isNotFound reports whether the runtime error means the object doesn't exist.
+// Docker and Podman word it differently.
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"no such", "not found", "unable to find", "failed to find"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func getProviderConfig(cfg *config.Config, providerName string) *config.ProviderConfig {
+	for _, provider := range cfg.Providers {
+		if provider.Name == providerName {
+			return &provider
+		}
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/ssh"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// serverTypes maps the lab server types to container resource limits
+var serverTypes = map[string]ConfigServer{
+	"cx22":  {CPUs: 2, Memory: "4g"},
+	"cx32":  {CPUs: 4, Memory: "8g"},
+	"cx42":  {CPUs: 8, Memory: "16g"},
+	"cpx21": {CPUs: 2, Memory: "4g"},
+	"cpx31": {CPUs: 4, Memory: "8g"},
+	"cpx41": {CPUs: 8, Memory: "16g"},
+}
+
+// containerfile builds a systemd image with sshd and the admin user from an Ubuntu base image
+const containerfile = `FROM ubuntu:%s
+ENV container=docker
+RUN apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y --no-install-recommends \
+    systemd systemd-sysv dbus openssh-server sudo python3 iproute2 util-linux xfsprogs ca-certificates curl \
+    && apt-get clean && rm -rf /var/lib/apt/lists/*
+RUN useradd --create-home --shell /bin/bash --groups sudo %[2]s \
+    && echo '%[2]s ALL=(ALL) NOPASSWD:ALL' > /etc/sudoers.d/%[2]s \
+    && systemctl enable ssh
+STOPSIGNAL SIGRTMIN+3
+CMD ["/sbin/init"]
+`
+
+func (p *ContainerProvider) CreateServer(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if opts.Type == "" {
+		return nil, fmt.Errorf("type is required")
+	}
+	if opts.Image == "" {
+		return nil, fmt.Errorf("image is required")
+	}
+	serverType, ok := serverTypes[opts.Type]
+	if !ok {
+		return nil, fmt.Errorf("invalid server type: %s", opts.Type)
+	}
+	checkServer, err := p.GetServer(ctx, opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("checking server: %w", err)
+	}
+	if checkServer != nil {
+		return nil, fmt.Errorf("server %s already exists", opts.Name)
+	}
+	image, err := p.ensureImage(ctx, opts.Image)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.ensureNetwork(ctx); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(p.volumes.Dir(), 0755); err != nil {
+		return nil, fmt.Errorf("error creating volumes directory: %w", err)
+	}
+
+	args := []string{"run", "--detach",
+		"--name", opts.Name,
+		"--hostname", opts.Name,
+		"--privileged",
+		"--cgroupns=private",
+		"--tmpfs", "/run",
+		"--tmpfs", "/run/lock",
+		"--network", networkName,
+		"--cpus", strconv.Itoa(serverType.CPUs),
+		"--memory", serverType.Memory,
+		"--volume", p.volumes.Dir() + ":" + volumesMountPath,
+		"--volume", "/lib/modules:/lib/modules:ro",
+		"--label", managedLabel + "=true",
+		"--label", serverTypeLabel + "=" + opts.Type,
+		"--label", imageLabel + "=" + opts.Image,
+	}
+	for _, key := range sortedKeys(opts.Labels) {
+		args = append(args, "--label", key+"="+opts.Labels[key])
+	}
+	args = append(args, image)
+
+	fmt.Printf("Creating container %s...\n", opts.Name)
+	if _, err := p.run(ctx, "", args...); err != nil {
+		return nil, fmt.Errorf("error creating container: %w", err)
+	}
+	if err := p.setupServer(ctx, opts); err != nil {
+		// don't leave a half-configured container behind, even if the creation was canceled
+		if _, rmErr := p.run(context.WithoutCancel(ctx), "", "rm", "--force", opts.Name); rmErr != nil {
+			p.logger.Warn("failed to remove container", "server", opts.Name, "error", rmErr)
+		}
+		return nil, err
+	}
+	fmt.Printf("Successfully created container %s\n", opts.Name)
+
+	return p.GetServer(ctx, opts.Name)
+}
+
+func (p *ContainerProvider) GetServer(ctx context.Context, name string) (*types.Server, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	containers, err := p.inspect(ctx, name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("inspecting server: %w", err)
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	return p.mapServer(containers[0])
+}
+
+func (p *ContainerProvider) ListServers(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+	args := []string{"ps", "--all", "--quiet", "--filter", "label=" + managedLabel + "=true"}
+	for _, selector := range labelutil.SelectorTerms(opts.ListOpts.LabelSelector) {
+		args = append(args, "--filter", "label="+selector)
+	}
+	output, err := p.run(ctx, "", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing servers: %w", err)
+	}
+	servers := []*types.Server{}
+	ids := strings.Fields(string(output))
+	if len(ids) == 0 {
+		return servers, nil
+	}
+	containers, err := p.inspect(ctx, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting servers: %w", err)
+	}
+	for _, container := range containers {
+		server, err := p.mapServer(container)
+		if err != nil {
+			return nil, fmt.Errorf("error mapping server: %w", err)
+		}
+		servers = append(servers, server)
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].ObjectMeta.Name < servers[j].ObjectMeta.Name
+	})
+	return servers, nil
+}
+
+func (p *ContainerProvider) AllServers(ctx context.Context) ([]*types.Server, error) {
+	servers, err := p.ListServers(ctx, options.ServerListOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing servers: %w", err)
+	}
+	return servers, nil
+}
+
+func (p *ContainerProvider) DeleteServer(ctx context.Context, name string, force bool) *types.ServerDeleteStatus {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if name == "" {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("name is required"),
+		}
+	}
+	server, err := p.GetServer(ctx, name)
+	if err != nil {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("error getting server: %w", err),
+		}
+	}
+	if server == nil {
+		p.logger.Debug("server not found, skipping", "server", name)
+		return &types.ServerDeleteStatus{Deleted: true}
+	}
+	if !force && time.Now().UTC().Before(server.Status.DeleteAfter) {
+		p.logger.Warn("server not ready for deletion",
+			"server", name,
+			"delete_after", server.Status.DeleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.ServerDeleteStatus{
+			DeleteAfter: server.Status.DeleteAfter,
+		}
+	}
+	// loop devices belong to the host, so release them before the container goes away
+	if err := p.detachServerVolumes(ctx, name); err != nil {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("error detaching volumes: %w", err),
+		}
+	}
+	if _, err := p.run(ctx, "", "rm", "--force", "--volumes", name); err != nil && !isNotFound(err) {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("deleting server: %w", err),
+		}
+	}
+	return &types.ServerDeleteStatus{
+		Deleted: true,
+	}
+}
+
+func (p *ContainerProvider) ServerToCreateOpts(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error) {
+	sshKeys, err := p.KeyNamesToSSHKeys(ctx, server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
+		Labels: server.ObjectMeta.Labels,
+	})
+	if err != nil {
+		return options.ServerCreateOpts{}, fmt.Errorf("error converting SSH keys: %w", err)
+	}
+	return options.ServerCreateOpts{
+		Name:     server.ObjectMeta.Name,
+		Type:     server.Spec.ServerType,
+		Image:    server.Spec.Image,
+		Location: server.Spec.Location,
+		Provider: "container",
+		SSHKeys:  sshKeys,
+		Labels:   server.ObjectMeta.Labels,
+	}, nil
+}
+
+// UpdateServerLabels returns an error, as the labels of a container can't be changed after it's created
+func (p *ContainerProvider) UpdateServerLabels(ctx context.Context, name string, labels map[string]string) error {
+	return fmt.Errorf("the labels of container %s can't be changed", name)
+}
+
+// setupServer waits for systemd in the new container and installs the SSH keys for the admin user
+func (p *ContainerProvider) setupServer(ctx context.Context, opts options.ServerCreateOpts) error {
+	if err := p.waitForSystemd(ctx, opts.Name); err != nil {
+		return err
+	}
+	authorizedKeys := p.authorizedKeys(opts.SSHKeys)
+	if authorizedKeys == "" {
+		p.logger.Warn("no SSH keys to install", "server", opts.Name)
+		return nil
+	}
+	sshDir := filepath.Join("/home", config.DefaultAdminUser, ".ssh")
+	script := fmt.Sprintf("install -d -m 700 -o %[1]s -g %[1]s %[2]s && cat > %[2]s/authorized_keys && chown %[1]s:%[1]s %[2]s/authorized_keys && chmod 600 %[2]s/authorized_keys",
+		config.DefaultAdminUser, sshDir)
+	if _, err := p.run(ctx, authorizedKeys, "exec", "--interactive", opts.Name, "sh", "-c", script); err != nil {
+		return fmt.Errorf("error installing SSH keys: %w", err)
+	}
+	return nil
+}
+
+// waitForSystemd waits until systemd in the container finished booting.
+// A degraded system is fine, as some units can't start in a container.
+func (p *ContainerProvider) waitForSystemd(ctx context.Context, name string) error {
+	output, err := p.run(ctx, "", "exec", name, "systemctl", "is-system-running", "--wait")
+	state := strings.TrimSpace(string(output))
+	if state == "running" || state == "degraded" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error waiting for systemd in %s: %w", name, err)
+	}
+	return fmt.Errorf("systemd in %s is %s", name, state)
+}
+
+// authorizedKeys returns the authorized_keys content for the keys
+func (p *ContainerProvider) authorizedKeys(sshKeys []*types.SSHKey) string {
+	publicKeys, missing := p.keys.PublicKeys(ssh.NewManager(p.config), sshKeys)
+	for _, name := range missing {
+		p.logger.Warn("SSH key not found, skipping it", "key", name)
+	}
+	if len(publicKeys) == 0 {
+		return ""
+	}
+	return strings.Join(publicKeys, "\n") + "\n"
+}
+
+// ensureImage builds the container image for the lab image if it doesn't exist and returns its tag
+func (p *ContainerProvider) ensureImage(ctx context.Context, image string) (string, error) {
+	version, ok := strings.CutPrefix(image, "ubuntu-")
+	if !ok {
+		return "", fmt.Errorf("unsupported image: %s", image)
+	}
+	tag := "storctl-ubuntu:" + version
+	if _, err := p.run(ctx, "", "image", "inspect", tag); err == nil {
+		return tag, nil
+	} else if !isNotFound(err) {
+		return "", fmt.Errorf("error checking image %s: %w", tag, err)
+	}
+
+	buildDir, err := os.MkdirTemp("", "storctl-image-")
+	if err != nil {
+		return "", fmt.Errorf("error creating build directory: %w", err)
+	}
+	defer os.RemoveAll(buildDir)
+	content := fmt.Sprintf(containerfile, version, config.DefaultAdminUser)
+	if err := os.WriteFile(filepath.Join(buildDir, "Containerfile"), []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("error writing Containerfile: %w", err)
+	}
+	fmt.Printf("Building image %s...\n", tag)
+	if _, err := p.run(ctx, "", "build", "--tag", tag, "--file", filepath.Join(buildDir, "Containerfile"), buildDir); err != nil {
+		return "", fmt.Errorf("error building image %s: %w", tag, err)
+	}
+	return tag, nil
+}
+
+// ensureNetwork creates the lab network if it doesn't exist
+func (p *ContainerProvider) ensureNetwork(ctx context.Context) error {
+	if _, err := p.run(ctx, "", "network", "inspect", networkName); err == nil {
+		return nil
+	} else if !isNotFound(err) {
+		return fmt.Errorf("error checking network %s: %w", networkName, err)
+	}
+	if _, err := p.run(ctx, "", "network", "create", networkName); err != nil {
+		return fmt.Errorf("error creating network %s: %w", networkName, err)
+	}
+	return nil
+}
+
+// inspect returns the inspect output for the containers
+func (p *ContainerProvider) inspect(ctx context.Context, names ...string) ([]Inspect, error) {
+	output, err := p.run(ctx, "", append([]string{"inspect", "--type", "container"}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+	containers := []Inspect{}
+	if err := json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("unmarshalling inspect output: %w", err)
+	}
+	return containers, nil
+}
+
+func (p *ContainerProvider) mapServer(c Inspect) (*types.Server, error) {
+	name := strings.TrimPrefix(c.Name, "/") // Docker prefixes the names with a slash
+	labels := make(map[string]string)
+	for key, value := range c.Config.Labels {
+		if !strings.HasPrefix(key, "storctl.") {
+			labels[key] = value
+		}
+	}
+	ip := ""
+	if network, ok := c.NetworkSettings.Networks[networkName]; ok {
+		ip = network.IPAddress
+	} else {
+		for _, network := range c.NetworkSettings.Networks {
+			if network.IPAddress != "" {
+				ip = network.IPAddress
+				break
+			}
+		}
+	}
+	volumes, err := p.serverVolumes(name)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Server{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Server",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: types.ServerSpec{
+			ServerType: c.Config.Labels[serverTypeLabel],
+			Image:      c.Config.Labels[imageLabel],
+			Location:   config.DefaultLocalLocation,
+			Provider:   "container",
+			Labels:     labels,
+			Volumes:    volumes,
+			TTL:        labels["ttl"],
+		},
+		Status: types.ServerStatus{
+			Status:      c.State.Status,
+			Owner:       labels["owner"],
+			Cores:       int(c.HostConfig.NanoCpus / 1e9),
+			Memory:      float32(c.HostConfig.Memory) / 1024 / 1024 / 1024,
+			Created:     c.Created,
+			DeleteAfter: timeutil.ParseDeleteAfter(labels["delete_after"]),
+			PublicNet: &types.PublicNet{
+				FQDN: name,
+				IPv4: &struct {
+					IP string `json:"ip"`
+				}{
+					IP: ip,
+				},
+			},
+		},
+	}, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
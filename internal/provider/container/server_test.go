@@ -0,0 +1,227 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/keystore"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/provider/volumestore"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRuntime records the runtime commands and answers them with respond
+type fakeRuntime struct {
+	calls   []string
+	stdin   []string
+	respond func(args []string) (string, error)
+}
+
+func (f *fakeRuntime) run(ctx context.Context, stdin string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(args, " "))
+	f.stdin = append(f.stdin, stdin)
+	output, err := f.respond(args)
+	return []byte(output), err
+}
+
+func (f *fakeRuntime) called(prefix string) bool {
+	for _, call := range f.calls {
+		if strings.HasPrefix(call, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestProvider(t *testing.T, respond func(args []string) (string, error)) (*ContainerProvider, *fakeRuntime) {
+	t.Setenv("HOME", t.TempDir())
+	fake := &fakeRuntime{respond: respond}
+	dir := t.TempDir()
+	p := &ContainerProvider{
+		config:  &config.Config{},
+		logger:  logger.Get(),
+		runtime: "docker",
+		dir:     dir,
+		keys:    keystore.New(filepath.Join(dir, "keys")),
+		volumes: volumestore.New(filepath.Join(dir, "volumes")),
+		run:     fake.run,
+	}
+	if err := os.MkdirAll(p.volumes.Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return p, fake
+}
+
+const testInspect = `[{
+  "Id": "abc123",
+  "Name": "/test-cp",
+  "Created": "2025-01-02T03:04:05Z",
+  "State": {"Status": "running", "Running": true},
+  "Config": {
+    "Hostname": "test-cp",
+    "Image": "storctl-ubuntu:24.04",
+    "Labels": {"storctl.managed": "true", "storctl.server_type": "cx22", "storctl.image": "ubuntu-24.04", "lab_name": "test", "owner": "me", "delete_after": "2099-01-01-00-00"}
+  },
+  "HostConfig": {"NanoCpus": 2000000000, "Memory": 4294967296},
+  "NetworkSettings": {"Networks": {"storctl": {"IPAddress": "10.89.0.5"}}}
+}]`
+
+var errNoSuchContainer = errors.New("docker inspect: exit status 1, output: Error: No such container: test-cp")
+
+func TestCreateServer(t *testing.T) {
+	t.Run("invalid server type", func(t *testing.T) {
+		p, fake := newTestProvider(t, func(args []string) (string, error) { return "", nil })
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "invalid-type", Image: "ubuntu-24.04"})
+		assert.ErrorContains(t, err, "invalid server type")
+		assert.Nil(t, server)
+		assert.Empty(t, fake.calls)
+	})
+
+	t.Run("unsupported image", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(args []string) (string, error) {
+			if args[0] == "inspect" {
+				return "", errNoSuchContainer
+			}
+			return "", nil
+		})
+		_, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "cx22", Image: "debian-12"})
+		assert.ErrorContains(t, err, "unsupported image")
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(args []string) (string, error) { return testInspect, nil })
+		_, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "cx22", Image: "ubuntu-24.04"})
+		assert.ErrorContains(t, err, "already exists")
+	})
+
+	t.Run("valid server creation", func(t *testing.T) {
+		created := false
+		p, fake := newTestProvider(t, func(args []string) (string, error) {
+			switch {
+			case args[0] == "inspect" && !created:
+				return "", errNoSuchContainer
+			case args[0] == "inspect":
+				return testInspect, nil
+			case args[0] == "image":
+				return "", errors.New("Error: No such image: storctl-ubuntu:24.04")
+			case args[0] == "network" && args[1] == "inspect":
+				return "", errors.New("Error: network storctl not found")
+			case args[0] == "run":
+				created = true
+			case args[0] == "exec" && args[2] == "systemctl":
+				return "degraded\n", errors.New("exit status 1")
+			}
+			return "", nil
+		})
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{
+			Name:   "test-cp",
+			Type:   "cx22",
+			Image:  "ubuntu-24.04",
+			Labels: map[string]string{"lab_name": "test"},
+			SSHKeys: []*types.SSHKey{
+				{ObjectMeta: types.ObjectMeta{Name: "test-admin"}, Spec: types.SSHKeySpec{PublicKey: "ssh-ed25519 AAAA test-admin\n"}},
+				{ObjectMeta: types.ObjectMeta{Name: "missing"}},
+			},
+		})
+		assert.NoError(t, err)
+		assert.True(t, fake.called("build --tag storctl-ubuntu:24.04"))
+		assert.True(t, fake.called("network create storctl"))
+		assert.True(t, fake.called("run --detach --name test-cp --hostname test-cp --privileged"))
+		for _, call := range fake.calls {
+			if strings.HasPrefix(call, "run ") {
+				assert.Contains(t, call, "--cpus 2 --memory 4g")
+				assert.Contains(t, call, "--label lab_name=test")
+				assert.True(t, strings.HasSuffix(call, " storctl-ubuntu:24.04"))
+			}
+		}
+		assert.Contains(t, fake.stdin, "ssh-ed25519 AAAA test-admin\n")
+
+		assert.Equal(t, "test-cp", server.ObjectMeta.Name)
+		assert.Equal(t, map[string]string{"lab_name": "test", "owner": "me", "delete_after": "2099-01-01-00-00"}, server.ObjectMeta.Labels)
+		assert.Equal(t, "cx22", server.Spec.ServerType)
+		assert.Equal(t, "ubuntu-24.04", server.Spec.Image)
+		assert.Equal(t, "container", server.Spec.Provider)
+		assert.Equal(t, "running", server.Status.Status)
+		assert.Equal(t, 2, server.Status.Cores)
+		assert.Equal(t, float32(4), server.Status.Memory)
+		assert.Equal(t, "10.89.0.5", server.Status.PublicNet.IPv4.IP)
+		assert.Equal(t, "test-cp", server.Status.PublicNet.FQDN)
+	})
+
+	t.Run("failed setup removes the container", func(t *testing.T) {
+		p, fake := newTestProvider(t, func(args []string) (string, error) {
+			switch {
+			case args[0] == "inspect":
+				return "", errNoSuchContainer
+			case args[0] == "exec":
+				return "starting\n", errors.New("exit status 1")
+			}
+			return "", nil
+		})
+		_, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "cx22", Image: "ubuntu-24.04"})
+		assert.ErrorContains(t, err, "error waiting for systemd")
+		assert.True(t, fake.called("rm --force test-cp"))
+	})
+}
+
+func TestListServers(t *testing.T) {
+	p, fake := newTestProvider(t, func(args []string) (string, error) {
+		if args[0] == "ps" {
+			return "abc123\n", nil
+		}
+		return testInspect, nil
+	})
+	servers, err := p.ListServers(context.Background(), options.ServerListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	assert.Len(t, servers, 1)
+	assert.Equal(t, "test-cp", servers[0].ObjectMeta.Name)
+	assert.Equal(t, []string{
+		"ps --all --quiet --filter label=storctl.managed=true --filter label=lab_name=test",
+		"inspect --type container abc123",
+	}, fake.calls)
+}
+
+func TestDeleteServer(t *testing.T) {
+	t.Run("not expired", func(t *testing.T) {
+		p, fake := newTestProvider(t, func(args []string) (string, error) { return testInspect, nil })
+		status := p.DeleteServer(context.Background(), "test-cp", false)
+		assert.NoError(t, status.Error)
+		assert.False(t, status.Deleted)
+		assert.False(t, status.DeleteAfter.IsZero())
+		assert.False(t, fake.called("rm"))
+	})
+
+	t.Run("force detaches volumes", func(t *testing.T) {
+		p, fake := newTestProvider(t, func(args []string) (string, error) {
+			if args[0] == "inspect" {
+				return testInspect, nil
+			}
+			return "", nil
+		})
+		assert.NoError(t, resizeFile(p.volumeFile("test-volume-01"), 1))
+		assert.NoError(t, p.volumes.Write(&volumestore.Meta{Name: "test-volume-01", Size: 1, Server: "test-cp", Device: "/dev/loop7"}))
+
+		status := p.DeleteServer(context.Background(), "test-cp", true)
+		assert.NoError(t, status.Error)
+		assert.True(t, status.Deleted)
+		assert.True(t, fake.called("exec test-cp losetup --detach /dev/loop7"))
+		assert.True(t, fake.called("rm --force --volumes test-cp"))
+		meta, err := p.volumes.Read("test-volume-01")
+		assert.NoError(t, err)
+		assert.Empty(t, meta.Device)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(args []string) (string, error) { return "", errNoSuchContainer })
+		status := p.DeleteServer(context.Background(), "test-cp", true)
+		assert.NoError(t, status.Error)
+		assert.True(t, status.Deleted)
+	})
+}
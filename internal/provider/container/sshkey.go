@@ -0,0 +1,64 @@
+package container
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/ssh"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// Containers have no cloud key store, so the public keys are kept in the local key store
+// and installed into the containers when they are created.
+
+func (p *ContainerProvider) CreateSSHKey(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
+	return p.keys.Create(opts)
+}
+
+func (p *ContainerProvider) GetSSHKey(ctx context.Context, name string) (*types.SSHKey, error) {
+	key, err := p.keys.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("SSH key %s not found", name)
+	}
+	return key, nil
+}
+
+func (p *ContainerProvider) ListSSHKeys(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
+	return p.keys.List(opts.LabelSelector)
+}
+
+func (p *ContainerProvider) AllSSHKeys(ctx context.Context) ([]*types.SSHKey, error) {
+	return p.keys.List("")
+}
+
+func (p *ContainerProvider) DeleteSSHKey(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus {
+	status := p.keys.Delete(name, force)
+	if !status.Deleted && status.Error == nil {
+		p.logger.Warn("key not ready for deletion",
+			"key", name,
+			"delete_after", status.DeleteAfter.Format("2006-01-02 15:04:05"))
+	}
+	return status
+}
+
+func (p *ContainerProvider) CloudKeyExists(ctx context.Context, name string) (bool, error) {
+	key, err := p.keys.Get(name)
+	if err != nil {
+		return false, err
+	}
+	return key != nil, nil
+}
+
+// KeyNamesToSSHKeys converts a list of SSH key names to a list of SSH keys.
+// Local SSH keys are added to the key store if they aren't there.
+func (p *ContainerProvider) KeyNamesToSSHKeys(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
+	return p.keys.KeyNamesToSSHKeys(ssh.NewManager(p.config), keyNames, opts)
+}
+
+func (p *ContainerProvider) UpdateSSHKeyLabels(ctx context.Context, name string, labels map[string]string) error {
+	return p.keys.UpdateLabels(name, labels)
+}
@@ -0,0 +1,76 @@
+package container
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHKeys(t *testing.T) {
+	p, _ := newTestProvider(t, func(args []string) (string, error) { return "", nil })
+
+	key, err := p.CreateSSHKey(context.Background(), options.SSHKeyCreateOpts{
+		Name:      "test-admin",
+		PublicKey: "ssh-ed25519 AAAA test-admin\n",
+		Labels:    map[string]string{"lab_name": "test", "delete_after": "2099-01-01-00-00"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh-ed25519 AAAA test-admin", key.Spec.PublicKey)
+
+	_, err = p.CreateSSHKey(context.Background(), options.SSHKeyCreateOpts{Name: "test-admin", PublicKey: "ssh-ed25519 BBBB"})
+	assert.ErrorContains(t, err, "already exists")
+
+	key, err = p.GetSSHKey(context.Background(), "test-admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-admin", key.ObjectMeta.Name)
+	assert.False(t, key.Status.DeleteAfter.IsZero())
+
+	_, err = p.GetSSHKey(context.Background(), "missing")
+	assert.ErrorContains(t, err, "not found")
+
+	exists, err := p.CloudKeyExists(context.Background(), "test-admin")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	keys, err := p.ListSSHKeys(context.Background(), options.SSHKeyListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=other"}})
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	keys, err = p.AllSSHKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	status := p.DeleteSSHKey(context.Background(), "test-admin", false)
+	assert.False(t, status.Deleted)
+	status = p.DeleteSSHKey(context.Background(), "test-admin", true)
+	assert.NoError(t, status.Error)
+	assert.True(t, status.Deleted)
+	exists, err = p.CloudKeyExists(context.Background(), "test-admin")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestKeyNamesToSSHKeys(t *testing.T) {
+	p, _ := newTestProvider(t, func(args []string) (string, error) { return "", nil })
+	keysDir := filepath.Join(os.Getenv("HOME"), config.DefaultConfigDir, config.DefaultKeysDir)
+	assert.NoError(t, os.MkdirAll(keysDir, 0700))
+	for _, name := range []string{config.DefaultAdminKeyName, "user-key"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(keysDir, name), []byte("private"), 0600))
+		assert.NoError(t, os.WriteFile(filepath.Join(keysDir, name+".pub"), []byte("ssh-ed25519 AAAA "+name), 0644))
+	}
+
+	keys, err := p.KeyNamesToSSHKeys(context.Background(), []string{"user-key", "missing"}, options.SSHKeyCreateOpts{})
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, config.DefaultAdminKeyName, keys[0].ObjectMeta.Name)
+	assert.Equal(t, "ssh-ed25519 AAAA user-key", keys[1].Spec.PublicKey)
+
+	// the local keys are in the key store now
+	exists, err := p.CloudKeyExists(context.Background(), "user-key")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
@@ -0,0 +1,34 @@
+package container
+
+import "time"
+
+// ConfigServer is the resources given to a container of a server type
+type ConfigServer struct {
+	CPUs   int
+	Memory string
+}
+
+// Inspect is the part of the docker/podman inspect output we use
+type Inspect struct {
+	ID      string    `json:"Id"`
+	Name    string    `json:"Name"`
+	Created time.Time `json:"Created"`
+	State   struct {
+		Status  string `json:"Status"`
+		Running bool   `json:"Running"`
+	} `json:"State"`
+	Config struct {
+		Hostname string            `json:"Hostname"`
+		Image    string            `json:"Image"`
+		Labels   map[string]string `json:"Labels"`
+	} `json:"Config"`
+	HostConfig struct {
+		NanoCpus int64 `json:"NanoCpus"`
+		Memory   int64 `json:"Memory"`
+	} `json:"HostConfig"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
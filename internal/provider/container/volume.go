@@ -0,0 +1,263 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/provider/volumestore"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// CreateVolume creates a sparse volume file.
+// If the server exists, the file is attached to it as a loop device.
+func (p *ContainerProvider) CreateVolume(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("volume name is required")
+	}
+	if opts.Size == 0 {
+		return nil, fmt.Errorf("volume size is required")
+	}
+	existing, err := p.volumes.Read(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("volume %s already exists", opts.Name)
+	}
+	if err := os.MkdirAll(p.volumes.Dir(), 0755); err != nil {
+		return nil, fmt.Errorf("error creating volumes directory: %w", err)
+	}
+	if err := resizeFile(p.volumeFile(opts.Name), opts.Size); err != nil {
+		return nil, fmt.Errorf("error creating volume file: %w", err)
+	}
+	meta := &volumestore.Meta{
+		Name:    opts.Name,
+		Size:    opts.Size,
+		Format:  opts.Format,
+		Labels:  opts.Labels,
+		Created: time.Now().UTC(),
+	}
+	if opts.ServerName != "" {
+		server, err := p.GetServer(ctx, opts.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("error getting server %s: %w", opts.ServerName, err)
+		}
+		if server != nil {
+			device, err := p.attachVolume(ctx, opts.ServerName, opts.Name)
+			if err != nil {
+				os.Remove(p.volumeFile(opts.Name))
+				return nil, err
+			}
+			meta.Server, meta.Device = opts.ServerName, device
+		}
+	}
+	if err := p.volumes.Write(meta); err != nil {
+		return nil, err
+	}
+	return p.mapVolume(meta), nil
+}
+
+func (p *ContainerProvider) GetVolume(ctx context.Context, name string) (*types.Volume, error) {
+	meta, err := p.volumes.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.mapVolume(meta), nil
+}
+
+func (p *ContainerProvider) ListVolumes(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+	metas, err := p.volumes.List()
+	if err != nil {
+		return nil, err
+	}
+	volumes := []*types.Volume{}
+	for _, meta := range metas {
+		if labelutil.MatchSelector(meta.Labels, opts.ListOpts.LabelSelector) {
+			volumes = append(volumes, p.mapVolume(meta))
+		}
+	}
+	return volumes, nil
+}
+
+func (p *ContainerProvider) AllVolumes(ctx context.Context) ([]*types.Volume, error) {
+	return p.ListVolumes(ctx, options.VolumeListOpts{})
+}
+
+func (p *ContainerProvider) DeleteVolume(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	meta, err := p.volumes.Read(name)
+	if err != nil {
+		return &types.VolumeDeleteStatus{Error: err}
+	}
+	if meta == nil {
+		p.logger.Debug("volume not found, skipping", "volume", name)
+		return &types.VolumeDeleteStatus{Deleted: true}
+	}
+	deleteAfter := timeutil.ParseDeleteAfter(meta.Labels["delete_after"])
+	if !force && time.Now().UTC().Before(deleteAfter) {
+		p.logger.Warn("volume not ready for deletion",
+			"volume", name,
+			"delete_after", deleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.VolumeDeleteStatus{DeleteAfter: deleteAfter}
+	}
+	if err := p.detachVolume(ctx, meta); err != nil {
+		return &types.VolumeDeleteStatus{Error: err}
+	}
+	if err := os.Remove(p.volumeFile(name)); err != nil && !os.IsNotExist(err) {
+		return &types.VolumeDeleteStatus{Error: fmt.Errorf("error deleting volume file: %w", err)}
+	}
+	if err := p.volumes.Delete(name); err != nil {
+		return &types.VolumeDeleteStatus{Error: err}
+	}
+	return &types.VolumeDeleteStatus{Deleted: true}
+}
+
+// ResizeVolume grows the volume file to the new size in GiB and refreshes the loop device capacity
+func (p *ContainerProvider) ResizeVolume(ctx context.Context, name string, size int) (*types.Volume, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	meta, err := p.volumes.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("volume %s not found", name)
+	}
+	if size < meta.Size {
+		return nil, fmt.Errorf("volume %s can't shrink from %dGB to %dGB", name, meta.Size, size)
+	}
+	if err := resizeFile(p.volumeFile(name), size); err != nil {
+		return nil, fmt.Errorf("error resizing volume file: %w", err)
+	}
+	if meta.Device != "" {
+		if _, err := p.run(ctx, "", "exec", meta.Server, "losetup", "--set-capacity", meta.Device); err != nil {
+			return nil, fmt.Errorf("error refreshing loop device %s: %w", meta.Device, err)
+		}
+	}
+	meta.Size = size
+	if err := p.volumes.Write(meta); err != nil {
+		return nil, err
+	}
+	return p.mapVolume(meta), nil
+}
+
+// UpdateVolumeLabels merges the labels into the volume metadata
+func (p *ContainerProvider) UpdateVolumeLabels(ctx context.Context, name string, labels map[string]string) error {
+	return p.volumes.UpdateLabels(name, labels)
+}
+
+// attachVolume attaches the volume file to a free loop device in the server and returns the device
+func (p *ContainerProvider) attachVolume(ctx context.Context, serverName, name string) (string, error) {
+	file := volumesMountPath + "/" + filepath.Base(p.volumeFile(name))
+	output, err := p.run(ctx, "", "exec", serverName, "losetup", "--find", "--show", file)
+	if err != nil {
+		return "", fmt.Errorf("error attaching volume %s to %s: %w", name, serverName, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// detachVolume releases the loop device of the volume.
+// The device is gone already if the server doesn't exist.
+func (p *ContainerProvider) detachVolume(ctx context.Context, meta *volumestore.Meta) error {
+	if meta.Device == "" {
+		return nil
+	}
+	if _, err := p.run(ctx, "", "exec", meta.Server, "losetup", "--detach", meta.Device); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error detaching volume %s from %s: %w", meta.Name, meta.Server, err)
+	}
+	meta.Server, meta.Device = "", ""
+	return p.volumes.Write(meta)
+}
+
+// detachServerVolumes releases the loop devices of all volumes attached to the server
+func (p *ContainerProvider) detachServerVolumes(ctx context.Context, serverName string) error {
+	metas, err := p.volumes.List()
+	if err != nil {
+		return err
+	}
+	for _, meta := range metas {
+		if meta.Server == serverName {
+			if err := p.detachVolume(ctx, meta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// serverVolumes returns the volumes attached to the server
+func (p *ContainerProvider) serverVolumes(serverName string) ([]*types.Volume, error) {
+	metas, err := p.volumes.List()
+	if err != nil {
+		return nil, err
+	}
+	volumes := []*types.Volume{}
+	for _, meta := range metas {
+		if meta.Server == serverName {
+			volumes = append(volumes, p.mapVolume(meta))
+		}
+	}
+	return volumes, nil
+}
+
+func (p *ContainerProvider) volumeFile(name string) string {
+	return filepath.Join(p.volumes.Dir(), name+".img")
+}
+
+// resizeFile creates or grows a sparse file to the size in GiB
+func resizeFile(path string, size int) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Truncate(int64(size) * 1024 * 1024 * 1024)
+}
+
+func (p *ContainerProvider) mapVolume(meta *volumestore.Meta) *types.Volume {
+	if meta == nil {
+		return nil
+	}
+	status := "available"
+	if meta.Device != "" {
+		status = "attached"
+	}
+	return &types.Volume{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Volume",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name:   meta.Name,
+			Labels: meta.Labels,
+		},
+		Spec: types.VolumeSpec{
+			ServerName: meta.Server,
+			Location:   config.DefaultLocalLocation,
+			Provider:   "container",
+			Size:       meta.Size,
+			Format:     meta.Format,
+			Labels:     meta.Labels,
+		},
+		Status: types.VolumeStatus{
+			Status:      status,
+			Owner:       meta.Labels["owner"],
+			Created:     meta.Created,
+			DeleteAfter: timeutil.ParseDeleteAfter(meta.Labels["delete_after"]),
+		},
+	}
+}
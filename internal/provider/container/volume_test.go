@@ -0,0 +1,127 @@
+package container
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/provider/volumestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateVolume(t *testing.T) {
+	t.Run("attached to the server", func(t *testing.T) {
+		p, fake := newTestProvider(t, func(args []string) (string, error) {
+			if args[0] == "inspect" {
+				return testInspect, nil
+			}
+			return "/dev/loop7\n", nil
+		})
+		volume, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{
+			Name:       "test-volume-01",
+			Size:       10,
+			ServerName: "test-cp",
+			Labels:     map[string]string{"lab_name": "test"},
+		})
+		assert.NoError(t, err)
+		assert.True(t, fake.called("exec test-cp losetup --find --show /var/lib/storctl/volumes/test-volume-01.img"))
+		assert.Equal(t, "test-cp", volume.Spec.ServerName)
+		assert.Equal(t, 10, volume.Spec.Size)
+		assert.Equal(t, "attached", volume.Status.Status)
+
+		info, err := os.Stat(p.volumeFile("test-volume-01"))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10)*1024*1024*1024, info.Size())
+
+		_, err = p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-volume-01", Size: 10})
+		assert.ErrorContains(t, err, "already exists")
+	})
+
+	t.Run("without server", func(t *testing.T) {
+		p, fake := newTestProvider(t, func(args []string) (string, error) { return "", errNoSuchContainer })
+		volume, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-volume-01", Size: 1, ServerName: "test-cp"})
+		assert.NoError(t, err)
+		assert.Empty(t, volume.Spec.ServerName)
+		assert.Equal(t, "available", volume.Status.Status)
+		assert.False(t, fake.called("exec"))
+	})
+
+	t.Run("size is required", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(args []string) (string, error) { return "", nil })
+		_, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-volume-01"})
+		assert.ErrorContains(t, err, "volume size is required")
+	})
+}
+
+func TestListVolumes(t *testing.T) {
+	p, _ := newTestProvider(t, func(args []string) (string, error) { return "", errNoSuchContainer })
+	for _, opts := range []options.VolumeCreateOpts{
+		{Name: "test-volume-01", Size: 1, Labels: map[string]string{"lab_name": "test"}},
+		{Name: "other-volume-01", Size: 1, Labels: map[string]string{"lab_name": "other"}},
+	} {
+		_, err := p.CreateVolume(context.Background(), opts)
+		assert.NoError(t, err)
+	}
+
+	volumes, err := p.ListVolumes(context.Background(), options.VolumeListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	assert.Len(t, volumes, 1)
+	assert.Equal(t, "test-volume-01", volumes[0].ObjectMeta.Name)
+
+	volumes, err = p.AllVolumes(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, volumes, 2)
+
+	volume, err := p.GetVolume(context.Background(), "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, volume)
+}
+
+func TestResizeVolume(t *testing.T) {
+	p, fake := newTestProvider(t, func(args []string) (string, error) { return "", nil })
+	assert.NoError(t, resizeFile(p.volumeFile("test-volume-01"), 1))
+	assert.NoError(t, p.volumes.Write(&volumestore.Meta{Name: "test-volume-01", Size: 1, Server: "test-cp", Device: "/dev/loop7"}))
+
+	volume, err := p.ResizeVolume(context.Background(), "test-volume-01", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, volume.Spec.Size)
+	assert.True(t, fake.called("exec test-cp losetup --set-capacity /dev/loop7"))
+	info, err := os.Stat(p.volumeFile("test-volume-01"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2)*1024*1024*1024, info.Size())
+
+	_, err = p.ResizeVolume(context.Background(), "test-volume-01", 1)
+	assert.ErrorContains(t, err, "can't shrink")
+	_, err = p.ResizeVolume(context.Background(), "missing", 1)
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestDeleteVolume(t *testing.T) {
+	p, fake := newTestProvider(t, func(args []string) (string, error) { return "", nil })
+	assert.NoError(t, resizeFile(p.volumeFile("test-volume-01"), 1))
+	assert.NoError(t, p.volumes.Write(&volumestore.Meta{
+		Name:   "test-volume-01",
+		Size:   1,
+		Server: "test-cp",
+		Device: "/dev/loop7",
+		Labels: map[string]string{"delete_after": "2099-01-01-00-00"},
+	}))
+
+	status := p.DeleteVolume(context.Background(), "test-volume-01", false)
+	assert.False(t, status.Deleted)
+	assert.False(t, status.DeleteAfter.IsZero())
+
+	status = p.DeleteVolume(context.Background(), "test-volume-01", true)
+	assert.NoError(t, status.Error)
+	assert.True(t, status.Deleted)
+	assert.True(t, fake.called("exec test-cp losetup --detach /dev/loop7"))
+	_, err := os.Stat(p.volumeFile("test-volume-01"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(p.volumes.File("test-volume-01"))
+	assert.True(t, os.IsNotExist(err))
+
+	// deleting a missing volume is a no-op
+	status = p.DeleteVolume(context.Background(), "test-volume-01", true)
+	assert.True(t, status.Deleted)
+}
@@ -6,8 +6,12 @@ import (
 	"fmt"
 
 	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/aws"
+	"github.com/pavelanni/storctl/internal/provider/container"
 	"github.com/pavelanni/storctl/internal/provider/hetzner"
+	"github.com/pavelanni/storctl/internal/provider/libvirt"
 	"github.com/pavelanni/storctl/internal/provider/lima"
+	"github.com/pavelanni/storctl/internal/provider/openstack"
 )
 
 // NewProvider creates a new cloud provider based on the configuration
@@ -17,6 +21,14 @@ func NewProvider(cfg config.Config, providerName string) (CloudProvider, error)
 		return hetzner.New(&cfg)
 	case "lima":
 		return lima.New(&cfg)
+	case "container":
+		return container.New(&cfg)
+	case "libvirt":
+		return libvirt.New(&cfg)
+	case "openstack":
+		return openstack.New(&cfg)
+	case "aws":
+		return aws.New(&cfg)
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", providerName)
 	}
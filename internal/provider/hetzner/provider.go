@@ -10,6 +10,7 @@ import (
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 	"github.com/pavelanni/storctl/internal/config"
 	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/types"
 )
 
 type HetznerProvider struct {
@@ -50,6 +51,20 @@ func (p *HetznerProvider) Name() string {
 	return "hetzner"
 }
 
+// Capabilities of Hetzner Cloud: volumes are attached to running servers that get public DNS names
+func (p *HetznerProvider) Capabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{
+		VolumesAttachAfterBoot: true,
+		ReadinessCheck:         true,
+		NeedsPublicDNS:         true,
+		SupportsLabels:         true,
+		SupportsTTLLabels:      true,
+		MutableLabels:          true,
+		ManagesSSHKeys:         true,
+		SSHUser:                config.DefaultAdminUser,
+	}
+}
+
 func getProviderConfig(cfg *config.Config, providerName string) *config.ProviderConfig {
 	for _, provider := range cfg.Providers {
 		if provider.Name == providerName {
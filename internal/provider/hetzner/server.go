@@ -9,14 +9,15 @@ import (
 	"github.com/pavelanni/storctl/internal/config"
 	"github.com/pavelanni/storctl/internal/provider/options"
 	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
 	"github.com/pavelanni/storctl/internal/util/timeutil"
 )
 
-func (p *HetznerProvider) CreateServer(opts options.ServerCreateOpts) (*types.Server, error) {
+func (p *HetznerProvider) CreateServer(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
 	serverOpts := hcloud.ServerCreateOpts{
 		Name:       opts.Name,
 		ServerType: &hcloud.ServerType{Name: opts.Type},
-		Image:      &hcloud.Image{Name: opts.Image},
+		Image:      imageByNameOrID(opts.Image),
 		Location:   &hcloud.Location{Name: opts.Location},
 		Labels:     opts.Labels,
 		UserData:   opts.UserData,
@@ -27,7 +28,7 @@ func (p *HetznerProvider) CreateServer(opts options.ServerCreateOpts) (*types.Se
 	}
 	hCloudSSHKeys := make([]*hcloud.SSHKey, 0)
 	for _, sshKey := range opts.SSHKeys {
-		hCloudKey, _, err := p.Client.SSHKey.Get(context.Background(), sshKey.ObjectMeta.Name)
+		hCloudKey, _, err := p.Client.SSHKey.Get(ctx, sshKey.ObjectMeta.Name)
 		if err != nil {
 			return nil, fmt.Errorf("error getting SSH key: %w", err)
 		}
@@ -43,7 +44,7 @@ func (p *HetznerProvider) CreateServer(opts options.ServerCreateOpts) (*types.Se
 		"image", opts.Image,
 		"location", opts.Location,
 		"ssh_keys", sshKeyNames)
-	server, _, err := p.Client.Server.Create(context.Background(), serverOpts)
+	server, _, err := p.Client.Server.Create(ctx, serverOpts)
 	if err != nil {
 		return nil, fmt.Errorf("error creating server: %w", err)
 	}
@@ -51,19 +52,19 @@ func (p *HetznerProvider) CreateServer(opts options.ServerCreateOpts) (*types.Se
 		"name", opts.Name,
 		"ip", server.Server.PublicNet.IPv4.IP)
 
-	return p.mapServer(server.Server), nil
+	return p.mapServer(ctx, server.Server), nil
 }
 
-func (p *HetznerProvider) GetServer(serverName string) (*types.Server, error) {
-	server, _, err := p.Client.Server.Get(context.Background(), serverName)
+func (p *HetznerProvider) GetServer(ctx context.Context, serverName string) (*types.Server, error) {
+	server, _, err := p.Client.Server.Get(ctx, serverName)
 	if err != nil {
 		return nil, fmt.Errorf("error getting server: %w", err)
 	}
-	return p.mapServer(server), nil
+	return p.mapServer(ctx, server), nil
 }
 
-func (p *HetznerProvider) ListServers(opts options.ServerListOpts) ([]*types.Server, error) {
-	servers, _, err := p.Client.Server.List(context.Background(), hcloud.ServerListOpts{
+func (p *HetznerProvider) ListServers(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+	servers, _, err := p.Client.Server.List(ctx, hcloud.ServerListOpts{
 		ListOpts: hcloud.ListOpts{
 			LabelSelector: opts.LabelSelector,
 		},
@@ -71,25 +72,25 @@ func (p *HetznerProvider) ListServers(opts options.ServerListOpts) ([]*types.Ser
 	if err != nil {
 		return nil, fmt.Errorf("error listing servers: %w", err)
 	}
-	return p.mapServers(servers), nil
+	return p.mapServers(ctx, servers), nil
 }
 
-func (p *HetznerProvider) AllServers() ([]*types.Server, error) {
-	servers, err := p.Client.Server.All(context.Background())
+func (p *HetznerProvider) AllServers(ctx context.Context) ([]*types.Server, error) {
+	servers, err := p.Client.Server.All(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error listing servers: %w", err)
 	}
 
-	return p.mapServers(servers), nil
+	return p.mapServers(ctx, servers), nil
 }
 
-func (p *HetznerProvider) DeleteServer(serverName string, force bool) *types.ServerDeleteStatus {
+func (p *HetznerProvider) DeleteServer(ctx context.Context, serverName string, force bool) *types.ServerDeleteStatus {
 	if serverName == "" {
 		return &types.ServerDeleteStatus{
 			Error: fmt.Errorf("empty server name provided"),
 		}
 	}
-	server, _, err := p.Client.Server.Get(context.Background(), serverName)
+	server, _, err := p.Client.Server.Get(ctx, serverName)
 	if err != nil {
 		return &types.ServerDeleteStatus{
 			Error: fmt.Errorf("error getting server: %w", err),
@@ -124,14 +125,14 @@ func (p *HetznerProvider) DeleteServer(serverName string, force bool) *types.Ser
 		}
 	}
 	for _, volume := range server.Volumes {
-		_, _, err = p.Client.Volume.Detach(context.Background(), volume)
+		_, _, err = p.Client.Volume.Detach(ctx, volume)
 		if err != nil {
 			return &types.ServerDeleteStatus{
 				Error: fmt.Errorf("error detaching volume: %w", err),
 			}
 		}
 	}
-	_, _, err = p.Client.Server.DeleteWithResult(context.Background(), server)
+	_, _, err = p.Client.Server.DeleteWithResult(ctx, server)
 	if err != nil {
 		return &types.ServerDeleteStatus{
 			Error: fmt.Errorf("error deleting server: %w", err),
@@ -142,8 +143,8 @@ func (p *HetznerProvider) DeleteServer(serverName string, force bool) *types.Ser
 	}
 }
 
-func (p *HetznerProvider) ServerToCreateOpts(server *types.Server) (options.ServerCreateOpts, error) {
-	sshKeys, err := p.KeyNamesToSSHKeys(server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
+func (p *HetznerProvider) ServerToCreateOpts(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error) {
+	sshKeys, err := p.KeyNamesToSSHKeys(ctx, server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
 		Labels: server.ObjectMeta.Labels,
 	})
 	if err != nil {
@@ -161,15 +162,35 @@ func (p *HetznerProvider) ServerToCreateOpts(server *types.Server) (options.Serv
 	}, nil
 }
 
+func (p *HetznerProvider) UpdateServerLabels(ctx context.Context, serverName string, labels map[string]string) error {
+	server, _, err := p.Client.Server.Get(ctx, serverName)
+	if err != nil {
+		return fmt.Errorf("error getting server: %w", err)
+	}
+	if server == nil {
+		return fmt.Errorf("server not found: %s", serverName)
+	}
+	p.logger.Debug("updating server labels",
+		"server", serverName,
+		"labels", labels)
+	_, _, err = p.Client.Server.Update(ctx, server, hcloud.ServerUpdateOpts{
+		Labels: labelutil.MergeLabels(server.Labels, labels),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating server labels: %w", err)
+	}
+	return nil
+}
+
 // mapServer converts a Hetzner-specific server to our generic Server type
-func (p *HetznerProvider) mapServer(s *hcloud.Server) *types.Server {
+func (p *HetznerProvider) mapServer(ctx context.Context, s *hcloud.Server) *types.Server {
 	if s == nil {
 		return nil
 	}
 
 	volumes := make([]*hcloud.Volume, 0)
 	for _, volume := range s.Volumes {
-		v, _, err := p.Client.Volume.Get(context.Background(), fmt.Sprintf("%d", volume.ID))
+		v, _, err := p.Client.Volume.Get(ctx, fmt.Sprintf("%d", volume.ID))
 		if err != nil {
 			p.logger.Error("error getting volume",
 				"volume", volume.ID,
@@ -191,9 +212,9 @@ func (p *HetznerProvider) mapServer(s *hcloud.Server) *types.Server {
 			ServerType: s.ServerType.Name,
 			Location:   s.Datacenter.Location.Name,
 			Provider:   "hetzner",
-			Image:      s.Image.Name,
+			Image:      imageName(s.Image),
 			Labels:     s.Labels,
-			Volumes:    p.mapVolumes(volumes),
+			Volumes:    p.mapVolumes(ctx, volumes),
 			TTL:        s.Labels["ttl"],
 		},
 		Status: types.ServerStatus{
@@ -210,14 +231,14 @@ func (p *HetznerProvider) mapServer(s *hcloud.Server) *types.Server {
 }
 
 // mapServers converts a slice of Hetzner servers
-func (p *HetznerProvider) mapServers(servers []*hcloud.Server) []*types.Server {
+func (p *HetznerProvider) mapServers(ctx context.Context, servers []*hcloud.Server) []*types.Server {
 	if servers == nil {
 		return nil
 	}
 
 	result := make([]*types.Server, len(servers))
 	for i, s := range servers {
-		result[i] = p.mapServer(s)
+		result[i] = p.mapServer(ctx, s)
 	}
 	return result
 }
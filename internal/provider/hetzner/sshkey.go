@@ -10,14 +10,15 @@ import (
 	"github.com/pavelanni/storctl/internal/provider/options"
 	"github.com/pavelanni/storctl/internal/ssh"
 	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
 	"github.com/pavelanni/storctl/internal/util/timeutil"
 )
 
-func (p *HetznerProvider) CreateSSHKey(opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
+func (p *HetznerProvider) CreateSSHKey(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
 	p.logger.Debug("creating SSH key",
 		"name", opts.Name,
 		"public_key", opts.PublicKey)
-	sshKey, _, err := p.Client.SSHKey.Create(context.Background(), hcloud.SSHKeyCreateOpts{
+	sshKey, _, err := p.Client.SSHKey.Create(ctx, hcloud.SSHKeyCreateOpts{
 		Name:      opts.Name,
 		PublicKey: opts.PublicKey,
 		Labels:    opts.Labels,
@@ -28,10 +29,10 @@ func (p *HetznerProvider) CreateSSHKey(opts options.SSHKeyCreateOpts) (*types.SS
 	return mapSSHKey(sshKey), nil
 }
 
-func (p *HetznerProvider) GetSSHKey(name string) (*types.SSHKey, error) {
+func (p *HetznerProvider) GetSSHKey(ctx context.Context, name string) (*types.SSHKey, error) {
 	p.logger.Debug("getting SSH key",
 		"key", name)
-	sshKey, _, err := p.Client.SSHKey.GetByName(context.Background(), name)
+	sshKey, _, err := p.Client.SSHKey.GetByName(ctx, name)
 	if err != nil {
 		return nil, fmt.Errorf("error getting SSH key: %w", err)
 	}
@@ -46,8 +47,8 @@ func (p *HetznerProvider) GetSSHKey(name string) (*types.SSHKey, error) {
 	return mapSSHKey(sshKey), nil
 }
 
-func (p *HetznerProvider) ListSSHKeys(opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
-	sshKeys, _, err := p.Client.SSHKey.List(context.Background(), hcloud.SSHKeyListOpts{
+func (p *HetznerProvider) ListSSHKeys(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
+	sshKeys, _, err := p.Client.SSHKey.List(ctx, hcloud.SSHKeyListOpts{
 		ListOpts: hcloud.ListOpts{
 			LabelSelector: opts.LabelSelector,
 		},
@@ -58,22 +59,22 @@ func (p *HetznerProvider) ListSSHKeys(opts options.SSHKeyListOpts) ([]*types.SSH
 	return mapSSHKeys(sshKeys), nil
 }
 
-func (p *HetznerProvider) AllSSHKeys() ([]*types.SSHKey, error) {
-	sshKeys, err := p.Client.SSHKey.All(context.Background())
+func (p *HetznerProvider) AllSSHKeys(ctx context.Context) ([]*types.SSHKey, error) {
+	sshKeys, err := p.Client.SSHKey.All(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return mapSSHKeys(sshKeys), nil
 }
 
-func (p *HetznerProvider) DeleteSSHKey(name string, force bool) *types.SSHKeyDeleteStatus {
+func (p *HetznerProvider) DeleteSSHKey(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus {
 	if name == "" {
 		return &types.SSHKeyDeleteStatus{
 			Error: fmt.Errorf("empty SSH key name provided"),
 		}
 	}
 
-	keyExists, err := p.CloudKeyExists(name)
+	keyExists, err := p.CloudKeyExists(ctx, name)
 	if err != nil {
 		return &types.SSHKeyDeleteStatus{
 			Error: fmt.Errorf("error checking if SSH key exists: %w", err),
@@ -87,7 +88,7 @@ func (p *HetznerProvider) DeleteSSHKey(name string, force bool) *types.SSHKeyDel
 		}
 	}
 
-	sshKey, _, err := p.Client.SSHKey.GetByName(context.Background(), name)
+	sshKey, _, err := p.Client.SSHKey.GetByName(ctx, name)
 	if err != nil {
 		p.logger.Error("failed to get SSH key",
 			"key", name)
@@ -113,7 +114,7 @@ func (p *HetznerProvider) DeleteSSHKey(name string, force bool) *types.SSHKeyDel
 	p.logger.Debug("deleting cloud SSH key",
 		"key", name)
 
-	_, err = p.Client.SSHKey.Delete(context.Background(), sshKey)
+	_, err = p.Client.SSHKey.Delete(ctx, sshKey)
 	if err != nil {
 		p.logger.Error("failed to delete cloud SSH key",
 			"key", name)
@@ -126,9 +127,9 @@ func (p *HetznerProvider) DeleteSSHKey(name string, force bool) *types.SSHKeyDel
 	}
 }
 
-func (p *HetznerProvider) CloudKeyExists(name string) (bool, error) {
+func (p *HetznerProvider) CloudKeyExists(ctx context.Context, name string) (bool, error) {
 	// check if the cloud key exists
-	cloudKey, _, err := p.Client.SSHKey.GetByName(context.Background(), name)
+	cloudKey, _, err := p.Client.SSHKey.GetByName(ctx, name)
 	if err != nil {
 		return false, fmt.Errorf("failed to check SSH key existence: %w", err)
 	}
@@ -136,20 +137,40 @@ func (p *HetznerProvider) CloudKeyExists(name string) (bool, error) {
 	return cloudKey != nil, nil
 }
 
+func (p *HetznerProvider) UpdateSSHKeyLabels(ctx context.Context, name string, labels map[string]string) error {
+	sshKey, _, err := p.Client.SSHKey.GetByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("error getting SSH key: %w", err)
+	}
+	if sshKey == nil {
+		return fmt.Errorf("SSH key not found: %s", name)
+	}
+	p.logger.Debug("updating SSH key labels",
+		"key", name,
+		"labels", labels)
+	_, _, err = p.Client.SSHKey.Update(ctx, sshKey, hcloud.SSHKeyUpdateOpts{
+		Labels: labelutil.MergeLabels(sshKey.Labels, labels),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating SSH key labels: %w", err)
+	}
+	return nil
+}
+
 // KeyNamesToSSHKeys converts a list of SSH key names to a list of SSH keys
 // It will upload local SSH keys to the cloud if they don't exist
 // It adds the default admin key to the list
-func (p *HetznerProvider) KeyNamesToSSHKeys(keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
+func (p *HetznerProvider) KeyNamesToSSHKeys(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
 	sshManager := ssh.NewManager(p.config)
 	sshKeys := make([]*types.SSHKey, 0)
-	adminKey, err := p.GetSSHKey(config.DefaultAdminKeyName)
+	adminKey, err := p.GetSSHKey(ctx, config.DefaultAdminKeyName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get admin key: %w", err)
 	}
 	sshKeys = append(sshKeys, adminKey)
 
 	for _, keyName := range keyNames {
-		cloudKeyExists, err := p.CloudKeyExists(keyName)
+		cloudKeyExists, err := p.CloudKeyExists(ctx, keyName)
 		if err != nil {
 			return nil, fmt.Errorf("error checking if SSH key exists: %w", err)
 		}
@@ -168,7 +189,7 @@ func (p *HetznerProvider) KeyNamesToSSHKeys(keyNames []string, opts options.SSHK
 				return nil, fmt.Errorf("failed to read local public key: %w", err)
 			}
 			opts.PublicKey = pubKey
-			newKey, err := p.CreateSSHKey(opts)
+			newKey, err := p.CreateSSHKey(ctx, opts)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create SSH key: %w", err)
 			}
@@ -9,10 +9,11 @@ import (
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 	"github.com/pavelanni/storctl/internal/provider/options"
 	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
 	"github.com/pavelanni/storctl/internal/util/timeutil"
 )
 
-func (p *HetznerProvider) CreateVolume(opts options.VolumeCreateOpts) (*types.Volume, error) {
+func (p *HetznerProvider) CreateVolume(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
 	var hCloudServer *hcloud.Server
 	var hCloudLocation *hcloud.Location
 	var err error
@@ -23,7 +24,7 @@ func (p *HetznerProvider) CreateVolume(opts options.VolumeCreateOpts) (*types.Vo
 		p.logger.Debug("server name is empty, using location instead",
 			"location", opts.Location)
 	} else {
-		hCloudServer, _, err = p.Client.Server.GetByName(context.Background(), opts.ServerName)
+		hCloudServer, _, err = p.Client.Server.GetByName(ctx, opts.ServerName)
 		if err != nil {
 			return nil, fmt.Errorf("error getting server: %w", err)
 		}
@@ -34,7 +35,7 @@ func (p *HetznerProvider) CreateVolume(opts options.VolumeCreateOpts) (*types.Vo
 		if location == "" {
 			return nil, fmt.Errorf("server %s has no location", opts.ServerName)
 		}
-		hCloudLocation, _, err = p.Client.Location.GetByName(context.Background(), location)
+		hCloudLocation, _, err = p.Client.Location.GetByName(ctx, location)
 		if err != nil {
 			return nil, fmt.Errorf("error getting location: %w", err)
 		}
@@ -57,17 +58,17 @@ func (p *HetznerProvider) CreateVolume(opts options.VolumeCreateOpts) (*types.Vo
 		"automount", *volumeOpts.Automount,
 		"format", *volumeOpts.Format)
 
-	volume, _, err := p.Client.Volume.Create(context.Background(), volumeOpts)
+	volume, _, err := p.Client.Volume.Create(ctx, volumeOpts)
 	if err != nil {
 		return nil, fmt.Errorf("error creating volume: %w", err)
 	}
 	p.logger.Debug("successfully created volume",
 		"name", volumeOpts.Name)
-	return p.mapVolume(volume.Volume), nil
+	return p.mapVolume(ctx, volume.Volume), nil
 }
 
-func (p *HetznerProvider) AttachVolume(volumeName, serverName string) error {
-	volume, _, err := p.Client.Volume.Get(context.Background(), volumeName)
+func (p *HetznerProvider) AttachVolume(ctx context.Context, volumeName, serverName string) error {
+	volume, _, err := p.Client.Volume.Get(ctx, volumeName)
 	if err != nil {
 		return fmt.Errorf("error getting volume: %w", err)
 	}
@@ -77,27 +78,27 @@ func (p *HetznerProvider) AttachVolume(volumeName, serverName string) error {
 	if volume.Server != nil {
 		return fmt.Errorf("volume already attached to server: %s", volume.Server.Name)
 	}
-	hCloudServer, _, err := p.Client.Server.GetByName(context.Background(), serverName)
+	hCloudServer, _, err := p.Client.Server.GetByName(ctx, serverName)
 	if err != nil {
 		return fmt.Errorf("error getting server: %w", err)
 	}
 	if hCloudServer == nil {
 		return fmt.Errorf("server not found: %s", serverName)
 	}
-	_, _, err = p.Client.Volume.Attach(context.Background(), volume, hCloudServer)
+	_, _, err = p.Client.Volume.Attach(ctx, volume, hCloudServer)
 	return fmt.Errorf("error attaching volume: %w", err)
 }
 
-func (p *HetznerProvider) GetVolume(volumeName string) (*types.Volume, error) {
-	volume, _, err := p.Client.Volume.Get(context.Background(), volumeName)
+func (p *HetznerProvider) GetVolume(ctx context.Context, volumeName string) (*types.Volume, error) {
+	volume, _, err := p.Client.Volume.Get(ctx, volumeName)
 	if err != nil {
 		return nil, fmt.Errorf("error getting volume: %w", err)
 	}
-	return p.mapVolume(volume), nil
+	return p.mapVolume(ctx, volume), nil
 }
 
-func (p *HetznerProvider) ListVolumes(opts options.VolumeListOpts) ([]*types.Volume, error) {
-	volumes, _, err := p.Client.Volume.List(context.Background(), hcloud.VolumeListOpts{
+func (p *HetznerProvider) ListVolumes(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+	volumes, _, err := p.Client.Volume.List(ctx, hcloud.VolumeListOpts{
 		ListOpts: hcloud.ListOpts{
 			LabelSelector: opts.LabelSelector,
 		},
@@ -105,25 +106,25 @@ func (p *HetznerProvider) ListVolumes(opts options.VolumeListOpts) ([]*types.Vol
 	if err != nil {
 		return nil, fmt.Errorf("error listing volumes: %w", err)
 	}
-	return p.mapVolumes(volumes), nil
+	return p.mapVolumes(ctx, volumes), nil
 }
 
-func (p *HetznerProvider) AllVolumes() ([]*types.Volume, error) {
-	volumes, err := p.Client.Volume.All(context.Background())
+func (p *HetznerProvider) AllVolumes(ctx context.Context) ([]*types.Volume, error) {
+	volumes, err := p.Client.Volume.All(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error listing volumes: %w", err)
 	}
 
-	return p.mapVolumes(volumes), nil
+	return p.mapVolumes(ctx, volumes), nil
 }
 
-func (p *HetznerProvider) DeleteVolume(volumeName string, force bool) *types.VolumeDeleteStatus {
+func (p *HetznerProvider) DeleteVolume(ctx context.Context, volumeName string, force bool) *types.VolumeDeleteStatus {
 	if volumeName == "" {
 		return &types.VolumeDeleteStatus{
 			Error: fmt.Errorf("empty volume name provided"),
 		}
 	}
-	volume, _, err := p.Client.Volume.Get(context.Background(), volumeName)
+	volume, _, err := p.Client.Volume.Get(ctx, volumeName)
 	if err != nil {
 		return &types.VolumeDeleteStatus{
 			Error: fmt.Errorf("error getting volume: %w", err),
@@ -163,14 +164,14 @@ func (p *HetznerProvider) DeleteVolume(volumeName string, force bool) *types.Vol
 		p.logger.Debug("volume is attached to server, detaching",
 			"volume", volume.Name,
 			"server", volume.Server.Name)
-		_, _, err = p.Client.Volume.Detach(context.Background(), volume)
+		_, _, err = p.Client.Volume.Detach(ctx, volume)
 		if err != nil {
 			return &types.VolumeDeleteStatus{
 				Error: fmt.Errorf("error detaching volume: %w", err),
 			}
 		}
 	}
-	_, err = p.Client.Volume.Delete(context.Background(), volume)
+	_, err = p.Client.Volume.Delete(ctx, volume)
 	if err != nil {
 		return &types.VolumeDeleteStatus{
 			Error: fmt.Errorf("error deleting volume: %w", err),
@@ -181,8 +182,58 @@ func (p *HetznerProvider) DeleteVolume(volumeName string, force bool) *types.Vol
 	}
 }
 
+// ResizeVolume grows a volume to the new size in GB.
+// Hetzner volumes can't be shrunk, so a smaller size returns an error.
+func (p *HetznerProvider) ResizeVolume(ctx context.Context, volumeName string, size int) (*types.Volume, error) {
+	volume, _, err := p.Client.Volume.Get(ctx, volumeName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume: %w", err)
+	}
+	if volume == nil {
+		return nil, fmt.Errorf("volume not found: %s", volumeName)
+	}
+	if size < volume.Size {
+		return nil, fmt.Errorf("volume %s can't be shrunk from %dGB to %dGB", volumeName, volume.Size, size)
+	}
+	if size == volume.Size {
+		return p.mapVolume(ctx, volume), nil
+	}
+	p.logger.Debug("resizing volume",
+		"volume", volumeName,
+		"from", volume.Size,
+		"to", size)
+	action, _, err := p.Client.Volume.Resize(ctx, volume, size)
+	if err != nil {
+		return nil, fmt.Errorf("error resizing volume: %w", err)
+	}
+	if err := p.Client.Action.WaitFor(ctx, action); err != nil {
+		return nil, fmt.Errorf("error waiting for volume resize: %w", err)
+	}
+	return p.GetVolume(ctx, volumeName)
+}
+
+func (p *HetznerProvider) UpdateVolumeLabels(ctx context.Context, volumeName string, labels map[string]string) error {
+	volume, _, err := p.Client.Volume.Get(ctx, volumeName)
+	if err != nil {
+		return fmt.Errorf("error getting volume: %w", err)
+	}
+	if volume == nil {
+		return fmt.Errorf("volume not found: %s", volumeName)
+	}
+	p.logger.Debug("updating volume labels",
+		"volume", volumeName,
+		"labels", labels)
+	_, _, err = p.Client.Volume.Update(ctx, volume, hcloud.VolumeUpdateOpts{
+		Labels: labelutil.MergeLabels(volume.Labels, labels),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating volume labels: %w", err)
+	}
+	return nil
+}
+
 // mapVolume converts a Hetzner-specific volume to our generic Volume type
-func (p *HetznerProvider) mapVolume(v *hcloud.Volume) *types.Volume {
+func (p *HetznerProvider) mapVolume(ctx context.Context, v *hcloud.Volume) *types.Volume {
 	if v == nil {
 		return nil
 	}
@@ -203,7 +254,7 @@ func (p *HetznerProvider) mapVolume(v *hcloud.Volume) *types.Volume {
 	if v.Server != nil {
 		serverID = int64(v.Server.ID)
 		// Fetch server details from Hetzner
-		if server, _, err := p.Client.Server.GetByID(context.Background(), v.Server.ID); err == nil && server != nil {
+		if server, _, err := p.Client.Server.GetByID(ctx, v.Server.ID); err == nil && server != nil {
 			serverName = server.Name
 		}
 	}
@@ -234,14 +285,14 @@ func (p *HetznerProvider) mapVolume(v *hcloud.Volume) *types.Volume {
 }
 
 // mapVolumes converts a slice of Hetzner volumes
-func (p *HetznerProvider) mapVolumes(volumes []*hcloud.Volume) []*types.Volume {
+func (p *HetznerProvider) mapVolumes(ctx context.Context, volumes []*hcloud.Volume) []*types.Volume {
 	if volumes == nil {
 		return nil
 	}
 
 	result := make([]*types.Volume, len(volumes))
 	for i, v := range volumes {
-		result[i] = p.mapVolume(v)
+		result[i] = p.mapVolume(ctx, v)
 	}
 	return result
 }
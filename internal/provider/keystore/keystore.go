@@ -0,0 +1,246 @@
+// Package keystore keeps the SSH public keys for the providers that have no cloud key store.
+// Each key is a JSON file with its labels, so the keys can be listed and expire like cloud keys.
+package keystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/ssh"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// Key is a public key kept in the store
+type Key struct {
+	Name      string            `json:"name"`
+	PublicKey string            `json:"publicKey"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Created   time.Time         `json:"created"`
+}
+
+type Store struct {
+	dir string
+}
+
+// New returns a key store in the directory
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) Create(opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("SSH key name is required")
+	}
+	if opts.PublicKey == "" {
+		return nil, fmt.Errorf("public key is required")
+	}
+	existing, err := s.read(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("SSH key %s already exists", opts.Name)
+	}
+	key := &Key{
+		Name:      opts.Name,
+		PublicKey: strings.TrimSpace(opts.PublicKey),
+		Labels:    opts.Labels,
+		Created:   time.Now().UTC(),
+	}
+	if err := s.write(key); err != nil {
+		return nil, err
+	}
+	return mapSSHKey(key), nil
+}
+
+// Get returns the stored SSH key or nil if it doesn't exist
+func (s *Store) Get(name string) (*types.SSHKey, error) {
+	key, err := s.read(name)
+	if err != nil {
+		return nil, err
+	}
+	return mapSSHKey(key), nil
+}
+
+// List returns the stored SSH keys matching the label selector
+func (s *Store) List(labelSelector string) ([]*types.SSHKey, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing SSH keys: %w", err)
+	}
+	sort.Strings(files)
+	keys := []*types.SSHKey{}
+	for _, file := range files {
+		key, err := s.read(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if key != nil && labelutil.MatchSelector(key.Labels, labelSelector) {
+			keys = append(keys, mapSSHKey(key))
+		}
+	}
+	return keys, nil
+}
+
+// UpdateLabels merges the labels into the labels of the stored SSH key
+func (s *Store) UpdateLabels(name string, labels map[string]string) error {
+	key, err := s.read(name)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return fmt.Errorf("SSH key not found: %s", name)
+	}
+	key.Labels = labelutil.MergeLabels(key.Labels, labels)
+	return s.write(key)
+}
+
+// Delete deletes the SSH key. Without force, keys are kept until their delete_after label.
+func (s *Store) Delete(name string, force bool) *types.SSHKeyDeleteStatus {
+	if name == "" {
+		return &types.SSHKeyDeleteStatus{
+			Error: fmt.Errorf("empty SSH key name provided"),
+		}
+	}
+	key, err := s.read(name)
+	if err != nil {
+		return &types.SSHKeyDeleteStatus{Error: err}
+	}
+	if key == nil {
+		return &types.SSHKeyDeleteStatus{Deleted: true}
+	}
+	deleteAfter := timeutil.ParseDeleteAfter(key.Labels["delete_after"])
+	if !force && time.Now().UTC().Before(deleteAfter) {
+		return &types.SSHKeyDeleteStatus{DeleteAfter: deleteAfter}
+	}
+	if err := os.Remove(s.file(name)); err != nil && !os.IsNotExist(err) {
+		return &types.SSHKeyDeleteStatus{Error: fmt.Errorf("error deleting SSH key: %w", err)}
+	}
+	return &types.SSHKeyDeleteStatus{Deleted: true}
+}
+
+// KeyNamesToSSHKeys converts a list of SSH key names to a list of SSH keys.
+// Local SSH keys are added to the store if they aren't there.
+// The default admin key is added to the list if it exists.
+func (s *Store) KeyNamesToSSHKeys(sshManager *ssh.Manager, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
+	sshKeys := make([]*types.SSHKey, 0)
+	for _, keyName := range append([]string{config.DefaultAdminKeyName}, keyNames...) {
+		key, err := s.Get(keyName)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			localKeyExists, err := sshManager.LocalKeyExists(keyName)
+			if err != nil {
+				return nil, err
+			}
+			if !localKeyExists {
+				fmt.Printf("SSH key %s not found locally, skipping it\n", keyName)
+				continue
+			}
+			pubKey, err := sshManager.ReadLocalPublicKey(keyName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read local public key: %w", err)
+			}
+			opts.Name = keyName
+			opts.PublicKey = pubKey
+			key, err = s.Create(opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create SSH key: %w", err)
+			}
+		}
+		sshKeys = append(sshKeys, key)
+	}
+	return sshKeys, nil
+}
+
+// PublicKeys returns the public keys of the SSH keys.
+// Keys given only by name are looked up in the store and in the local keys directory;
+// the names of the keys that aren't found are returned as missing.
+func (s *Store) PublicKeys(sshManager *ssh.Manager, sshKeys []*types.SSHKey) (publicKeys, missing []string) {
+	for _, key := range sshKeys {
+		if key == nil {
+			continue
+		}
+		publicKey := key.Spec.PublicKey
+		if publicKey == "" {
+			if stored, err := s.read(key.ObjectMeta.Name); err == nil && stored != nil {
+				publicKey = stored.PublicKey
+			} else if local, err := sshManager.ReadLocalPublicKey(key.ObjectMeta.Name); err == nil {
+				publicKey = local
+			} else {
+				missing = append(missing, key.ObjectMeta.Name)
+				continue
+			}
+		}
+		publicKeys = append(publicKeys, strings.TrimSpace(publicKey))
+	}
+	return publicKeys, missing
+}
+
+func (s *Store) file(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// read returns the stored key or nil if it doesn't exist
+func (s *Store) read(name string) (*Key, error) {
+	data, err := os.ReadFile(s.file(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading SSH key: %w", err)
+	}
+	key := &Key{}
+	if err := json.Unmarshal(data, key); err != nil {
+		return nil, fmt.Errorf("error unmarshalling SSH key %s: %w", name, err)
+	}
+	return key, nil
+}
+
+func (s *Store) write(key *Key) error {
+	data, err := json.MarshalIndent(key, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling SSH key: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("error creating keys directory: %w", err)
+	}
+	if err := os.WriteFile(s.file(key.Name), data, 0600); err != nil {
+		return fmt.Errorf("error writing SSH key: %w", err)
+	}
+	return nil
+}
+
+func mapSSHKey(k *Key) *types.SSHKey {
+	if k == nil {
+		return nil
+	}
+
+	return &types.SSHKey{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "SSHKey",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name: k.Name,
+		},
+		Spec: types.SSHKeySpec{
+			PublicKey: k.PublicKey,
+			Labels:    k.Labels,
+		},
+		Status: types.SSHKeyStatus{
+			Created:     k.Created,
+			DeleteAfter: timeutil.ParseDeleteAfter(k.Labels["delete_after"]),
+		},
+	}
+}
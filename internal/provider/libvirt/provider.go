@@ -0,0 +1,173 @@
+// Package libvirt contains the libvirt/QEMU implementation of the provider interface for the storctl tool.
+// Servers are domains created from Ubuntu cloud images with cloud-init, volumes are qcow2 disks
+// in a libvirt storage pool attached as virtio devices, and SSH keys are kept in the local key store.
+// It drives virsh and virt-install, so it works on any Linux workstation with libvirt installed.
+package libvirt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/keystore"
+	"github.com/pavelanni/storctl/internal/provider/volumestore"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// runFunc runs a command with the given arguments and returns its output
+type runFunc func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// downloadFunc downloads the URL to the file
+type downloadFunc func(ctx context.Context, url, path string) error
+
+type LibvirtProvider struct {
+	config   *config.Config
+	logger   *slog.Logger
+	uri      string // libvirt connection URI
+	pool     string // storage pool for the server and volume disks
+	network  string // network the servers are connected to
+	arch     string
+	dir      string // directory with the volume metadata
+	keys     *keystore.Store
+	volumes  *volumestore.Store
+	run      runFunc
+	download downloadFunc
+
+	mu      sync.Mutex
+	targets map[string]map[string]bool // virtio targets reserved for the attachments by server
+}
+
+func New(cfg *config.Config) (*LibvirtProvider, error) {
+	providerConfig := getProviderConfig(cfg, "libvirt")
+	if providerConfig == nil {
+		providerConfig = &config.ProviderConfig{Name: "libvirt"}
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+
+	p := &LibvirtProvider{
+		config:   cfg,
+		logger:   logger.Get(),
+		uri:      defaultIfEmpty(providerConfig.URI, config.DefaultLibvirtURI),
+		pool:     defaultIfEmpty(providerConfig.Pool, config.DefaultLibvirtPool),
+		network:  defaultIfEmpty(providerConfig.Network, config.DefaultLibvirtNetwork),
+		arch:     runtime.GOARCH,
+		dir:      filepath.Join(homeDir, config.DefaultConfigDir, config.DefaultLibvirtDir),
+		run:      runCommand,
+		download: downloadFile,
+	}
+	p.keys = keystore.New(filepath.Join(p.dir, "keys"))
+	p.volumes = volumestore.New(filepath.Join(p.dir, "volumes"))
+	p.logger.Info("Initializing libvirt provider")
+	p.logger.Debug("Using configuration",
+		"uri", p.uri,
+		"pool", p.pool,
+		"network", p.network)
+	return p, nil
+}
+
+func (p *LibvirtProvider) Name() string {
+	return "libvirt"
+}
+
+// Capabilities of libvirt: volumes are attached to running VMs that have no public DNS names
+func (p *LibvirtProvider) Capabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{
+		VolumesAttachAfterBoot: true,
+		ReadinessCheck:         true,
+		SupportsLabels:         true,
+		SupportsTTLLabels:      true,
+		MutableLabels:          true,
+		ManagesSSHKeys:         true,
+		SSHUser:                config.DefaultAdminUser,
+	}
+}
+
+// virsh runs virsh connected to the provider URI
+func (p *LibvirtProvider) virsh(ctx context.Context, args ...string) ([]byte, error) {
+	return p.run(ctx, "virsh", append([]string{"--connect", p.uri}, args...)...)
+}
+
+// runCommand runs the command and returns its standard output.
+// The error includes the standard error output.
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return stdout.Bytes(), fmt.Errorf("timeout running %s: %w", name, err)
+		}
+		return stdout.Bytes(), fmt.Errorf("%s: %w, output: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// downloadFile downloads the URL to the file
+func downloadFile(ctx context.Context, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return fmt.Errorf("downloading %s: %w", url, err)
+	}
+	return f.Close()
+}
+
+// isNotFound reports whether the virsh error means the domain or volume doesn't exist
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"failed to get domain", "domain not found", "storage vol not found", "no storage vol", "metadata not found"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultIfEmpty(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func getProviderConfig(cfg *config.Config, providerName string) *config.ProviderConfig {
+	for _, provider := range cfg.Providers {
+		if provider.Name == providerName {
+			return &provider
+		}
+	}
+	return nil
+}
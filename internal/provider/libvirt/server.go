@@ -0,0 +1,505 @@
+package libvirt
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/ssh"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+var serverTypes = map[string]ConfigServer{
+	"cx22":  {CPUs: 2, Memory: 4096, Disk: 40},
+	"cx32":  {CPUs: 4, Memory: 8192, Disk: 80},
+	"cx42":  {CPUs: 8, Memory: 16384, Disk: 160},
+	"cpx21": {CPUs: 2, Memory: 4096, Disk: 80},
+	"cpx31": {CPUs: 4, Memory: 8192, Disk: 160},
+	"cpx41": {CPUs: 8, Memory: 16384, Disk: 240},
+}
+
+// ipPollInterval is how often the DHCP leases are checked for the IP of a new server
+var ipPollInterval = 3 * time.Second
+
+func (p *LibvirtProvider) CreateServer(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+	defer cancel()
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if opts.Type == "" {
+		return nil, fmt.Errorf("type is required")
+	}
+	if opts.Image == "" {
+		return nil, fmt.Errorf("image is required")
+	}
+	serverType, ok := serverTypes[opts.Type]
+	if !ok {
+		return nil, fmt.Errorf("invalid server type: %s", opts.Type)
+	}
+	checkServer, err := p.GetServer(ctx, opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("checking server: %w", err)
+	}
+	if checkServer != nil {
+		return nil, fmt.Errorf("server %s already exists", opts.Name)
+	}
+	userData := opts.UserData
+	if userData == "" {
+		userData, err = p.userData(opts.SSHKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+	baseImage, err := p.ensureBaseImage(ctx, opts.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	diskName := opts.Name + ".qcow2"
+	_, err = p.virsh(ctx, "vol-create-as", p.pool, diskName, fmt.Sprintf("%dG", serverType.Disk),
+		"--format", "qcow2", "--backing-vol", baseImage, "--backing-vol-format", "qcow2")
+	if err != nil {
+		return nil, fmt.Errorf("error creating disk for %s: %w", opts.Name, err)
+	}
+	fmt.Printf("Creating VM %s...\n", opts.Name)
+	if err := p.installDomain(ctx, opts, serverType, userData); err != nil {
+		// don't leave a half-created domain behind, even if the creation was canceled
+		p.removeDomain(context.WithoutCancel(ctx), opts.Name)
+		return nil, err
+	}
+	fmt.Printf("Successfully created VM %s\n", opts.Name)
+
+	return p.GetServer(ctx, opts.Name)
+}
+
+func (p *LibvirtProvider) GetServer(ctx context.Context, name string) (*types.Server, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	return p.getServer(ctx, name)
+}
+
+func (p *LibvirtProvider) ListServers(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+	output, err := p.virsh(ctx, "list", "--all", "--name")
+	if err != nil {
+		return nil, fmt.Errorf("error listing servers: %w", err)
+	}
+	names := strings.Fields(string(output))
+	sort.Strings(names)
+	servers := []*types.Server{}
+	for _, name := range names {
+		meta, err := p.getMetadata(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		// domains without the storctl metadata weren't created by storctl
+		if meta == nil || !labelutil.MatchSelector(meta.labels(), opts.ListOpts.LabelSelector) {
+			continue
+		}
+		server, err := p.getServer(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if server != nil {
+			servers = append(servers, server)
+		}
+	}
+	return servers, nil
+}
+
+func (p *LibvirtProvider) AllServers(ctx context.Context) ([]*types.Server, error) {
+	servers, err := p.ListServers(ctx, options.ServerListOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing servers: %w", err)
+	}
+	return servers, nil
+}
+
+func (p *LibvirtProvider) DeleteServer(ctx context.Context, name string, force bool) *types.ServerDeleteStatus {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if name == "" {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("name is required"),
+		}
+	}
+	server, err := p.getServer(ctx, name)
+	if err != nil {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("error getting server: %w", err),
+		}
+	}
+	if server == nil {
+		p.logger.Debug("server not found, skipping", "server", name)
+		return &types.ServerDeleteStatus{Deleted: true}
+	}
+	if !force && time.Now().UTC().Before(server.Status.DeleteAfter) {
+		p.logger.Warn("server not ready for deletion",
+			"server", name,
+			"delete_after", server.Status.DeleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.ServerDeleteStatus{
+			DeleteAfter: server.Status.DeleteAfter,
+		}
+	}
+	// keep the volumes: only the server's own disk goes away with it
+	if err := p.detachServerVolumes(ctx, name); err != nil {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("error detaching volumes: %w", err),
+		}
+	}
+	if err := p.removeDomain(ctx, name); err != nil {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("deleting server: %w", err),
+		}
+	}
+	p.releaseTargets(name)
+	return &types.ServerDeleteStatus{
+		Deleted: true,
+	}
+}
+
+func (p *LibvirtProvider) ServerToCreateOpts(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error) {
+	sshKeys, err := p.KeyNamesToSSHKeys(ctx, server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
+		Labels: server.ObjectMeta.Labels,
+	})
+	if err != nil {
+		return options.ServerCreateOpts{}, fmt.Errorf("error converting SSH keys: %w", err)
+	}
+	userData, err := p.userData(sshKeys)
+	if err != nil {
+		return options.ServerCreateOpts{}, err
+	}
+	return options.ServerCreateOpts{
+		Name:     server.ObjectMeta.Name,
+		Type:     server.Spec.ServerType,
+		Image:    server.Spec.Image,
+		Location: server.Spec.Location,
+		Provider: "libvirt",
+		SSHKeys:  sshKeys,
+		Labels:   server.ObjectMeta.Labels,
+		UserData: userData,
+	}, nil
+}
+
+// installDomain defines and starts the domain with virt-install, saves the storctl metadata,
+// and waits for the DHCP lease
+func (p *LibvirtProvider) installDomain(ctx context.Context, opts options.ServerCreateOpts, serverType ConfigServer, userData string) error {
+	cloudInitDir, err := os.MkdirTemp("", "storctl-cloud-init-")
+	if err != nil {
+		return fmt.Errorf("error creating cloud-init directory: %w", err)
+	}
+	defer os.RemoveAll(cloudInitDir)
+	userDataFile := filepath.Join(cloudInitDir, "user-data")
+	metaDataFile := filepath.Join(cloudInitDir, "meta-data")
+	if err := os.WriteFile(userDataFile, []byte(userData), 0600); err != nil {
+		return fmt.Errorf("error writing cloud-init user data: %w", err)
+	}
+	metaData := fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", opts.Name, opts.Name)
+	if err := os.WriteFile(metaDataFile, []byte(metaData), 0600); err != nil {
+		return fmt.Errorf("error writing cloud-init meta data: %w", err)
+	}
+
+	_, err = p.run(ctx, "virt-install",
+		"--connect", p.uri,
+		"--name", opts.Name,
+		"--memory", strconv.Itoa(serverType.Memory),
+		"--vcpus", strconv.Itoa(serverType.CPUs),
+		"--import",
+		"--disk", fmt.Sprintf("vol=%s/%s.qcow2,bus=virtio", p.pool, opts.Name),
+		"--network", fmt.Sprintf("network=%s,model=virtio", p.network),
+		"--osinfo", "detect=on,require=off",
+		"--cloud-init", fmt.Sprintf("user-data=%s,meta-data=%s", userDataFile, metaDataFile),
+		"--graphics", "none",
+		"--noautoconsole")
+	if err != nil {
+		return fmt.Errorf("error creating VM %s: %w", opts.Name, err)
+	}
+
+	meta := &DomainMetadata{
+		ServerType: opts.Type,
+		Image:      opts.Image,
+		Created:    time.Now().UTC(),
+	}
+	meta.setLabels(opts.Labels)
+	if err := p.setMetadata(ctx, opts.Name, meta); err != nil {
+		return err
+	}
+
+	fmt.Printf("Waiting for the IP address of %s...\n", opts.Name)
+	ticker := time.NewTicker(ipPollInterval)
+	defer ticker.Stop()
+	for {
+		if ip := p.domainIP(ctx, opts.Name); ip != "" {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for the IP address of %s", opts.Name)
+		case <-ticker.C:
+		}
+	}
+}
+
+// removeDomain stops and undefines the domain and deletes its disk
+func (p *LibvirtProvider) removeDomain(ctx context.Context, name string) error {
+	if _, err := p.virsh(ctx, "destroy", name); err != nil && !isNotFound(err) && !strings.Contains(err.Error(), "not running") {
+		return err
+	}
+	if _, err := p.virsh(ctx, "undefine", name, "--nvram"); err != nil && !isNotFound(err) {
+		return err
+	}
+	if _, err := p.virsh(ctx, "vol-delete", "--pool", p.pool, name+".qcow2"); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// getServer returns the server or nil if the domain doesn't exist
+func (p *LibvirtProvider) getServer(ctx context.Context, name string) (*types.Server, error) {
+	output, err := p.virsh(ctx, "dominfo", name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting server %s: %w", name, err)
+	}
+	info := parseDomainInfo(string(output))
+	meta, err := p.getMetadata(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = &DomainMetadata{}
+	}
+	volumes, err := p.serverVolumes(name)
+	if err != nil {
+		return nil, err
+	}
+	return mapServer(name, info, meta, p.domainIP(ctx, name), volumes), nil
+}
+
+// UpdateServerLabels merges the labels into the domain metadata
+func (p *LibvirtProvider) UpdateServerLabels(ctx context.Context, name string, labels map[string]string) error {
+	meta, err := p.getMetadata(ctx, name)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return fmt.Errorf("server %s not found", name)
+	}
+	meta.setLabels(labelutil.MergeLabels(meta.labels(), labels))
+	return p.setMetadata(ctx, name, meta)
+}
+
+// setMetadata saves the storctl metadata in the domain XML.
+// The metadata of a running domain is changed live too.
+func (p *LibvirtProvider) setMetadata(ctx context.Context, name string, meta *DomainMetadata) error {
+	data, err := xml.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("error marshalling metadata: %w", err)
+	}
+	args := []string{"metadata", name, "--uri", metadataURI, "--key", "storctl", "--set", string(data), "--config"}
+	if output, err := p.virsh(ctx, "domstate", name); err == nil && strings.TrimSpace(string(output)) == "running" {
+		args = append(args, "--live")
+	}
+	_, err = p.virsh(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("error setting metadata for %s: %w", name, err)
+	}
+	return nil
+}
+
+// getMetadata returns the storctl metadata of the domain or nil if it has none
+func (p *LibvirtProvider) getMetadata(ctx context.Context, name string) (*DomainMetadata, error) {
+	output, err := p.virsh(ctx, "metadata", name, "--uri", metadataURI)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting metadata for %s: %w", name, err)
+	}
+	meta := &DomainMetadata{}
+	if err := xml.Unmarshal(output, meta); err != nil {
+		return nil, fmt.Errorf("error unmarshalling metadata for %s: %w", name, err)
+	}
+	return meta, nil
+}
+
+// setLabels replaces the metadata labels, sorted by key
+func (m *DomainMetadata) setLabels(labels map[string]string) {
+	m.Labels = make([]MetadataLabel, 0, len(labels))
+	for _, key := range sortedKeys(labels) {
+		m.Labels = append(m.Labels, MetadataLabel{Key: key, Value: labels[key]})
+	}
+}
+
+func (m *DomainMetadata) labels() map[string]string {
+	labels := make(map[string]string, len(m.Labels))
+	for _, label := range m.Labels {
+		labels[label.Key] = label.Value
+	}
+	return labels
+}
+
+// domainIP returns the IPv4 address of the domain from the DHCP lease or "" if it has none yet
+func (p *LibvirtProvider) domainIP(ctx context.Context, name string) string {
+	output, err := p.virsh(ctx, "domifaddr", name, "--source", "lease")
+	if err != nil {
+		p.logger.Debug("no DHCP lease", "server", name, "error", err)
+		return ""
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && fields[2] == "ipv4" {
+			ip, _, _ := strings.Cut(fields[3], "/")
+			return ip
+		}
+	}
+	return ""
+}
+
+// userData returns the cloud-init user data with the public keys of the SSH keys for the admin user
+func (p *LibvirtProvider) userData(sshKeys []*types.SSHKey) (string, error) {
+	publicKeys, missing := p.keys.PublicKeys(ssh.NewManager(p.config), sshKeys)
+	for _, name := range missing {
+		p.logger.Warn("SSH key not found, skipping it", "key", name)
+	}
+	if len(publicKeys) == 0 {
+		return "", fmt.Errorf("no SSH keys for the %s user", config.DefaultAdminUser)
+	}
+	return fmt.Sprintf(config.DefaultCloudInitUserData, strings.Join(publicKeys, "\n  - ")), nil
+}
+
+// ensureBaseImage uploads the Ubuntu cloud image to the storage pool if it isn't there and returns the volume name
+func (p *LibvirtProvider) ensureBaseImage(ctx context.Context, image string) (string, error) {
+	version, ok := strings.CutPrefix(image, "ubuntu-")
+	if !ok {
+		return "", fmt.Errorf("unsupported image: %s", image)
+	}
+	arch := getArchForImage(p.arch)
+	volumeName := fmt.Sprintf("storctl-%s-%s.img", image, arch)
+	if _, err := p.virsh(ctx, "vol-info", "--pool", p.pool, volumeName); err == nil {
+		return volumeName, nil
+	} else if !isNotFound(err) {
+		return "", fmt.Errorf("error checking image %s: %w", volumeName, err)
+	}
+
+	url := fmt.Sprintf("https://cloud-images.ubuntu.com/releases/%s/release/ubuntu-%s-server-cloudimg-%s.img", version, version, arch)
+	downloadDir, err := os.MkdirTemp("", "storctl-image-")
+	if err != nil {
+		return "", fmt.Errorf("error creating download directory: %w", err)
+	}
+	defer os.RemoveAll(downloadDir)
+	imageFile := filepath.Join(downloadDir, volumeName)
+	fmt.Printf("Downloading image %s...\n", url)
+	if err := p.download(ctx, url, imageFile); err != nil {
+		return "", fmt.Errorf("error downloading image: %w", err)
+	}
+	info, err := os.Stat(imageFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading image: %w", err)
+	}
+	if _, err := p.virsh(ctx, "vol-create-as", p.pool, volumeName, strconv.FormatInt(info.Size(), 10), "--format", "qcow2"); err != nil {
+		return "", fmt.Errorf("error creating image volume %s: %w", volumeName, err)
+	}
+	if _, err := p.virsh(ctx, "vol-upload", "--pool", p.pool, volumeName, imageFile); err != nil {
+		if _, delErr := p.virsh(ctx, "vol-delete", "--pool", p.pool, volumeName); delErr != nil {
+			p.logger.Warn("failed to delete image volume", "volume", volumeName, "error", delErr)
+		}
+		return "", fmt.Errorf("error uploading image %s: %w", volumeName, err)
+	}
+	return volumeName, nil
+}
+
+// parseDomainInfo parses the "Key: value" lines of virsh dominfo
+func parseDomainInfo(output string) DomainInfo {
+	info := DomainInfo{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "State":
+			info.State = value
+		case "CPU(s)":
+			info.CPUs, _ = strconv.Atoi(value)
+		case "Max memory":
+			info.Memory, _ = strconv.ParseInt(strings.TrimSuffix(value, " KiB"), 10, 64)
+		}
+	}
+	return info
+}
+
+func mapServer(name string, info DomainInfo, meta *DomainMetadata, ip string, volumes []*types.Volume) *types.Server {
+	labels := meta.labels()
+	return &types.Server{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Server",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: types.ServerSpec{
+			ServerType: meta.ServerType,
+			Image:      meta.Image,
+			Location:   config.DefaultLocalLocation,
+			Provider:   "libvirt",
+			Labels:     labels,
+			Volumes:    volumes,
+			TTL:        labels["ttl"],
+		},
+		Status: types.ServerStatus{
+			Status:      info.State,
+			Owner:       labels["owner"],
+			Cores:       info.CPUs,
+			Memory:      float32(info.Memory) / 1024 / 1024,
+			Disk:        serverTypes[meta.ServerType].Disk,
+			Created:     meta.Created,
+			DeleteAfter: timeutil.ParseDeleteAfter(labels["delete_after"]),
+			PublicNet: &types.PublicNet{
+				FQDN: name,
+				IPv4: &struct {
+					IP string `json:"ip"`
+				}{
+					IP: ip,
+				},
+			},
+		},
+	}
+}
+
+// Ubuntu cloud images use amd64 and arm64
+func getArchForImage(arch string) string {
+	switch arch {
+	case "arm64", "aarch64":
+		return "arm64"
+	default:
+		return "amd64"
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,269 @@
+package libvirt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/provider/keystore"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/provider/volumestore"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVirsh records the commands and answers them with respond.
+// The virsh connection flags are left out of the recorded calls.
+type fakeVirsh struct {
+	mu        sync.Mutex
+	calls     []string
+	downloads []string
+	respond   func(name string, args []string) (string, error)
+}
+
+func (f *fakeVirsh) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if name == "virsh" && len(args) >= 2 && args[0] == "--connect" {
+		args = args[2:]
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, strings.Join(append([]string{name}, args...), " "))
+	f.mu.Unlock()
+	output, err := f.respond(name, args)
+	return []byte(output), err
+}
+
+func (f *fakeVirsh) download(ctx context.Context, url, path string) error {
+	f.downloads = append(f.downloads, url)
+	return os.WriteFile(path, []byte("qcow2 image"), 0644)
+}
+
+func (f *fakeVirsh) called(prefix string) bool {
+	for _, call := range f.calls {
+		if strings.HasPrefix(call, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestProvider(t *testing.T, respond func(name string, args []string) (string, error)) (*LibvirtProvider, *fakeVirsh) {
+	t.Setenv("HOME", t.TempDir())
+	fake := &fakeVirsh{respond: respond}
+	dir := t.TempDir()
+	p := &LibvirtProvider{
+		config:   &config.Config{},
+		logger:   logger.Get(),
+		uri:      config.DefaultLibvirtURI,
+		pool:     config.DefaultLibvirtPool,
+		network:  config.DefaultLibvirtNetwork,
+		arch:     "amd64",
+		dir:      dir,
+		keys:     keystore.New(filepath.Join(dir, "keys")),
+		volumes:  volumestore.New(filepath.Join(dir, "volumes")),
+		run:      fake.run,
+		download: fake.download,
+	}
+	if err := os.MkdirAll(p.volumes.Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return p, fake
+}
+
+const testDominfo = `Id:             3
+Name:           test-cp
+UUID:           0e4d1f0a-2b6c-4c1e-9d7a-5f1c2b3a4d5e
+OS Type:        hvm
+State:          running
+CPU(s):         2
+Max memory:     4194304 KiB
+Used memory:    4194304 KiB
+Persistent:     yes
+Autostart:      disable
+`
+
+const testMetadata = `<storctl serverType="cx22" image="ubuntu-24.04" created="2025-01-02T03:04:05Z">
+  <label key="delete_after" value="2099-01-01-00-00"></label>
+  <label key="lab_name" value="test"></label>
+  <label key="owner" value="me"></label>
+</storctl>`
+
+const testDomifaddr = ` Name       MAC address          Protocol     Address
+-------------------------------------------------------------------------------
+ vnet0      52:54:00:8d:8b:49    ipv4         192.168.122.45/24
+`
+
+var (
+	errNoDomain = errors.New("virsh: exit status 1, output: error: failed to get domain 'test-cp'")
+	errNoVolume = errors.New("virsh: exit status 1, output: error: Storage volume not found: no storage vol with matching path")
+)
+
+// respondServer answers the commands for an existing test-cp domain
+func respondServer(name string, args []string) (string, error) {
+	switch {
+	case name != "virsh":
+		return "", nil
+	case args[0] == "dominfo":
+		return testDominfo, nil
+	case args[0] == "metadata":
+		return testMetadata, nil
+	case args[0] == "domifaddr":
+		return testDomifaddr, nil
+	}
+	return "", nil
+}
+
+func TestCreateServer(t *testing.T) {
+	sshKeys := []*types.SSHKey{{
+		ObjectMeta: types.ObjectMeta{Name: "test-admin"},
+		Spec:       types.SSHKeySpec{PublicKey: "ssh-ed25519 AAAA test-admin"},
+	}}
+
+	t.Run("invalid server type", func(t *testing.T) {
+		p, fake := newTestProvider(t, respondServer)
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "invalid-type", Image: "ubuntu-24.04"})
+		assert.ErrorContains(t, err, "invalid server type")
+		assert.Nil(t, server)
+		assert.Empty(t, fake.calls)
+	})
+
+	t.Run("creates domain from cloud image", func(t *testing.T) {
+		created := false
+		p, fake := newTestProvider(t, func(name string, args []string) (string, error) {
+			if name == "virt-install" {
+				created = true
+				return "", nil
+			}
+			switch args[0] {
+			case "dominfo", "domifaddr", "metadata":
+				if !created {
+					return "", errNoDomain
+				}
+			case "vol-info":
+				return "", errNoVolume
+			}
+			return respondServer(name, args)
+		})
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{
+			Name:    "test-cp",
+			Type:    "cx22",
+			Image:   "ubuntu-24.04",
+			SSHKeys: sshKeys,
+			Labels:  map[string]string{"lab_name": "test", "owner": "me"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https://cloud-images.ubuntu.com/releases/24.04/release/ubuntu-24.04-server-cloudimg-amd64.img"}, fake.downloads)
+		assert.True(t, fake.called("virsh vol-upload --pool default storctl-ubuntu-24.04-amd64.img"))
+		assert.True(t, fake.called("virsh vol-create-as default test-cp.qcow2 40G --format qcow2 --backing-vol storctl-ubuntu-24.04-amd64.img"))
+		assert.True(t, fake.called("virt-install --connect qemu:///system --name test-cp --memory 4096 --vcpus 2 --import"))
+		assert.True(t, fake.called("virsh metadata test-cp --uri "+metadataURI+" --key storctl --set <storctl serverType=\"cx22\""))
+		if assert.NotNil(t, server) {
+			assert.Equal(t, "192.168.122.45", server.Status.PublicNet.IPv4.IP)
+			assert.Equal(t, "running", server.Status.Status)
+			assert.Equal(t, 2, server.Status.Cores)
+			assert.Equal(t, float32(4), server.Status.Memory)
+			assert.Equal(t, "test", server.ObjectMeta.Labels["lab_name"])
+			assert.Equal(t, "libvirt", server.Spec.Provider)
+		}
+	})
+
+	t.Run("cleans up on failure", func(t *testing.T) {
+		p, fake := newTestProvider(t, func(name string, args []string) (string, error) {
+			if name == "virt-install" {
+				return "", errors.New("virt-install: exit status 1, output: ERROR network 'default' is not active")
+			}
+			if args[0] == "dominfo" || args[0] == "destroy" || args[0] == "undefine" {
+				return "", errNoDomain
+			}
+			return "", nil
+		})
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "cx22", Image: "ubuntu-24.04", SSHKeys: sshKeys})
+		assert.ErrorContains(t, err, "is not active")
+		assert.Nil(t, server)
+		assert.Empty(t, fake.downloads)
+		assert.True(t, fake.called("virsh vol-delete --pool default test-cp.qcow2"))
+	})
+}
+
+func TestGetServer(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(name string, args []string) (string, error) { return "", errNoDomain })
+		server, err := p.GetServer(context.Background(), "test-cp")
+		assert.NoError(t, err)
+		assert.Nil(t, server)
+	})
+
+	t.Run("maps domain", func(t *testing.T) {
+		p, _ := newTestProvider(t, respondServer)
+		server, err := p.GetServer(context.Background(), "test-cp")
+		assert.NoError(t, err)
+		if assert.NotNil(t, server) {
+			assert.Equal(t, "cx22", server.Spec.ServerType)
+			assert.Equal(t, "ubuntu-24.04", server.Spec.Image)
+			assert.Equal(t, "me", server.Status.Owner)
+			assert.Equal(t, 40, server.Status.Disk)
+			assert.Equal(t, 2099, server.Status.DeleteAfter.Year())
+		}
+	})
+}
+
+func TestListServers(t *testing.T) {
+	p, _ := newTestProvider(t, func(name string, args []string) (string, error) {
+		switch args[0] {
+		case "list":
+			return "test-cp\nother-vm\n\n", nil
+		case "metadata":
+			if args[1] == "other-vm" {
+				return "", errors.New("virsh: exit status 1, output: error: metadata not found: Requested metadata element is not present")
+			}
+		}
+		return respondServer(name, args)
+	})
+
+	servers, err := p.ListServers(context.Background(), options.ServerListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	if assert.Len(t, servers, 1) {
+		assert.Equal(t, "test-cp", servers[0].ObjectMeta.Name)
+	}
+
+	servers, err = p.ListServers(context.Background(), options.ServerListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=other"}})
+	assert.NoError(t, err)
+	assert.Empty(t, servers)
+}
+
+func TestDeleteServer(t *testing.T) {
+	t.Run("not ready without force", func(t *testing.T) {
+		p, fake := newTestProvider(t, respondServer)
+		status := p.DeleteServer(context.Background(), "test-cp", false)
+		assert.False(t, status.Deleted)
+		assert.NoError(t, status.Error)
+		assert.False(t, fake.called("virsh undefine"))
+	})
+
+	t.Run("force detaches volumes and deletes disk", func(t *testing.T) {
+		p, fake := newTestProvider(t, respondServer)
+		assert.NoError(t, p.volumes.Write(&volumestore.Meta{Name: "test-cp-volume-1", Size: 10, Server: "test-cp", Target: "vdb"}))
+
+		status := p.DeleteServer(context.Background(), "test-cp", true)
+		assert.True(t, status.Deleted)
+		assert.NoError(t, status.Error)
+		assert.True(t, fake.called("virsh detach-disk test-cp vdb --persistent"))
+		assert.True(t, fake.called("virsh destroy test-cp"))
+		assert.True(t, fake.called("virsh undefine test-cp --nvram"))
+		assert.True(t, fake.called("virsh vol-delete --pool default test-cp.qcow2"))
+
+		volume, err := p.GetVolume(context.Background(), "test-cp-volume-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "", volume.Spec.ServerName)
+	})
+}
+
+func TestParseDomainInfo(t *testing.T) {
+	info := parseDomainInfo(testDominfo)
+	assert.Equal(t, DomainInfo{State: "running", CPUs: 2, Memory: 4194304}, info)
+}
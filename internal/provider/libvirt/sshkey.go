@@ -0,0 +1,64 @@
+package libvirt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/ssh"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// libvirt has no key store, so the public keys are kept in the local key store
+// and passed to cloud-init when the servers are created.
+
+func (p *LibvirtProvider) CreateSSHKey(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
+	return p.keys.Create(opts)
+}
+
+func (p *LibvirtProvider) GetSSHKey(ctx context.Context, name string) (*types.SSHKey, error) {
+	key, err := p.keys.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("SSH key %s not found", name)
+	}
+	return key, nil
+}
+
+func (p *LibvirtProvider) ListSSHKeys(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
+	return p.keys.List(opts.LabelSelector)
+}
+
+func (p *LibvirtProvider) AllSSHKeys(ctx context.Context) ([]*types.SSHKey, error) {
+	return p.keys.List("")
+}
+
+func (p *LibvirtProvider) DeleteSSHKey(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus {
+	status := p.keys.Delete(name, force)
+	if !status.Deleted && status.Error == nil {
+		p.logger.Warn("key not ready for deletion",
+			"key", name,
+			"delete_after", status.DeleteAfter.Format("2006-01-02 15:04:05"))
+	}
+	return status
+}
+
+func (p *LibvirtProvider) CloudKeyExists(ctx context.Context, name string) (bool, error) {
+	key, err := p.keys.Get(name)
+	if err != nil {
+		return false, err
+	}
+	return key != nil, nil
+}
+
+// KeyNamesToSSHKeys converts a list of SSH key names to a list of SSH keys.
+// Local SSH keys are added to the key store if they aren't there.
+func (p *LibvirtProvider) KeyNamesToSSHKeys(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
+	return p.keys.KeyNamesToSSHKeys(ssh.NewManager(p.config), keyNames, opts)
+}
+
+func (p *LibvirtProvider) UpdateSSHKeyLabels(ctx context.Context, name string, labels map[string]string) error {
+	return p.keys.UpdateLabels(name, labels)
+}
@@ -0,0 +1,37 @@
+package libvirt
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// metadataURI is the namespace of the storctl domain metadata
+const metadataURI = "https://github.com/pavelanni/storctl"
+
+// ConfigServer is the resources given to a domain of a server type
+type ConfigServer struct {
+	CPUs   int
+	Memory int // in MiB
+	Disk   int // in GiB
+}
+
+// DomainMetadata is kept in the domain XML under the storctl namespace
+type DomainMetadata struct {
+	XMLName    xml.Name        `xml:"storctl"`
+	ServerType string          `xml:"serverType,attr"`
+	Image      string          `xml:"image,attr"`
+	Created    time.Time       `xml:"created,attr"`
+	Labels     []MetadataLabel `xml:"label"`
+}
+
+type MetadataLabel struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// DomainInfo is the part of the virsh dominfo output we use
+type DomainInfo struct {
+	State  string
+	CPUs   int
+	Memory int64 // in KiB
+}
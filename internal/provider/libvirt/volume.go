@@ -0,0 +1,323 @@
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/provider/volumestore"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// CreateVolume creates a qcow2 volume in the storage pool.
+// If the server exists, the volume is attached to it as a virtio disk.
+func (p *LibvirtProvider) CreateVolume(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("volume name is required")
+	}
+	if opts.Size == 0 {
+		return nil, fmt.Errorf("volume size is required")
+	}
+	existing, err := p.volumes.Read(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("volume %s already exists", opts.Name)
+	}
+	if err := os.MkdirAll(p.volumes.Dir(), 0755); err != nil {
+		return nil, fmt.Errorf("error creating volumes directory: %w", err)
+	}
+	volumeName := opts.Name + ".qcow2"
+	if _, err := p.virsh(ctx, "vol-create-as", p.pool, volumeName, fmt.Sprintf("%dG", opts.Size), "--format", "qcow2"); err != nil {
+		return nil, fmt.Errorf("error creating volume %s: %w", opts.Name, err)
+	}
+	meta := &volumestore.Meta{
+		Name:    opts.Name,
+		Size:    opts.Size,
+		Format:  opts.Format,
+		Labels:  opts.Labels,
+		Created: time.Now().UTC(),
+	}
+	if opts.ServerName != "" {
+		server, err := p.GetServer(ctx, opts.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("error getting server %s: %w", opts.ServerName, err)
+		}
+		if server != nil {
+			target, err := p.attachVolume(ctx, opts.ServerName, opts.Name)
+			if err != nil {
+				if _, delErr := p.virsh(ctx, "vol-delete", "--pool", p.pool, volumeName); delErr != nil {
+					p.logger.Warn("failed to delete volume", "volume", opts.Name, "error", delErr)
+				}
+				return nil, err
+			}
+			meta.Server, meta.Target = opts.ServerName, target
+		}
+	}
+	if err := p.volumes.Write(meta); err != nil {
+		return nil, err
+	}
+	return p.mapVolume(meta), nil
+}
+
+func (p *LibvirtProvider) GetVolume(ctx context.Context, name string) (*types.Volume, error) {
+	meta, err := p.volumes.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.mapVolume(meta), nil
+}
+
+func (p *LibvirtProvider) ListVolumes(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+	metas, err := p.volumes.List()
+	if err != nil {
+		return nil, err
+	}
+	volumes := []*types.Volume{}
+	for _, meta := range metas {
+		if labelutil.MatchSelector(meta.Labels, opts.ListOpts.LabelSelector) {
+			volumes = append(volumes, p.mapVolume(meta))
+		}
+	}
+	return volumes, nil
+}
+
+func (p *LibvirtProvider) AllVolumes(ctx context.Context) ([]*types.Volume, error) {
+	return p.ListVolumes(ctx, options.VolumeListOpts{})
+}
+
+func (p *LibvirtProvider) DeleteVolume(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	meta, err := p.volumes.Read(name)
+	if err != nil {
+		return &types.VolumeDeleteStatus{Error: err}
+	}
+	if meta == nil {
+		p.logger.Debug("volume not found, skipping", "volume", name)
+		return &types.VolumeDeleteStatus{Deleted: true}
+	}
+	deleteAfter := timeutil.ParseDeleteAfter(meta.Labels["delete_after"])
+	if !force && time.Now().UTC().Before(deleteAfter) {
+		p.logger.Warn("volume not ready for deletion",
+			"volume", name,
+			"delete_after", deleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.VolumeDeleteStatus{DeleteAfter: deleteAfter}
+	}
+	if err := p.detachVolume(ctx, meta); err != nil {
+		return &types.VolumeDeleteStatus{Error: err}
+	}
+	if _, err := p.virsh(ctx, "vol-delete", "--pool", p.pool, name+".qcow2"); err != nil && !isNotFound(err) {
+		return &types.VolumeDeleteStatus{Error: fmt.Errorf("error deleting volume %s: %w", name, err)}
+	}
+	if err := p.volumes.Delete(name); err != nil {
+		return &types.VolumeDeleteStatus{Error: err}
+	}
+	return &types.VolumeDeleteStatus{Deleted: true}
+}
+
+// ResizeVolume grows the volume to the new size in GiB.
+// Volumes attached to a running server are resized live, so the guest sees the new size.
+func (p *LibvirtProvider) ResizeVolume(ctx context.Context, name string, size int) (*types.Volume, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	meta, err := p.volumes.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("volume %s not found", name)
+	}
+	if size < meta.Size {
+		return nil, fmt.Errorf("volume %s can't shrink from %dGB to %dGB", name, meta.Size, size)
+	}
+	newSize := fmt.Sprintf("%dG", size)
+	resized := false
+	if meta.Target != "" {
+		output, err := p.virsh(ctx, "domstate", meta.Server)
+		if err == nil && strings.TrimSpace(string(output)) == "running" {
+			if _, err := p.virsh(ctx, "blockresize", meta.Server, meta.Target, newSize); err != nil {
+				return nil, fmt.Errorf("error resizing volume %s: %w", name, err)
+			}
+			resized = true
+		}
+	}
+	if !resized {
+		if _, err := p.virsh(ctx, "vol-resize", "--pool", p.pool, name+".qcow2", newSize); err != nil {
+			return nil, fmt.Errorf("error resizing volume %s: %w", name, err)
+		}
+	}
+	meta.Size = size
+	if err := p.volumes.Write(meta); err != nil {
+		return nil, err
+	}
+	return p.mapVolume(meta), nil
+}
+
+// UpdateVolumeLabels merges the labels into the volume metadata
+func (p *LibvirtProvider) UpdateVolumeLabels(ctx context.Context, name string, labels map[string]string) error {
+	return p.volumes.UpdateLabels(name, labels)
+}
+
+// attachVolume attaches the volume to the next free virtio target of the server and returns the target
+func (p *LibvirtProvider) attachVolume(ctx context.Context, serverName, name string) (string, error) {
+	output, err := p.virsh(ctx, "vol-path", "--pool", p.pool, name+".qcow2")
+	if err != nil {
+		return "", fmt.Errorf("error getting path of volume %s: %w", name, err)
+	}
+	path := strings.TrimSpace(string(output))
+	output, err = p.virsh(ctx, "domblklist", serverName)
+	if err != nil {
+		return "", fmt.Errorf("error listing disks of %s: %w", serverName, err)
+	}
+	target, err := p.reserveTarget(serverName, string(output))
+	if err != nil {
+		return "", fmt.Errorf("error attaching volume %s to %s: %w", name, serverName, err)
+	}
+	_, err = p.virsh(ctx, "attach-disk", serverName, path, target,
+		"--driver", "qemu", "--subdriver", "qcow2", "--targetbus", "virtio", "--persistent")
+	if err != nil {
+		p.releaseTarget(serverName, target)
+		return "", fmt.Errorf("error attaching volume %s to %s: %w", name, serverName, err)
+	}
+	return target, nil
+}
+
+// detachVolume detaches the volume from its server.
+// The disk is gone already if the server doesn't exist.
+func (p *LibvirtProvider) detachVolume(ctx context.Context, meta *volumestore.Meta) error {
+	if meta.Target == "" {
+		return nil
+	}
+	if _, err := p.virsh(ctx, "detach-disk", meta.Server, meta.Target, "--persistent"); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error detaching volume %s from %s: %w", meta.Name, meta.Server, err)
+	}
+	p.releaseTarget(meta.Server, meta.Target)
+	meta.Server, meta.Target = "", ""
+	return p.volumes.Write(meta)
+}
+
+// detachServerVolumes detaches all volumes attached to the server
+func (p *LibvirtProvider) detachServerVolumes(ctx context.Context, serverName string) error {
+	metas, err := p.volumes.List()
+	if err != nil {
+		return err
+	}
+	for _, meta := range metas {
+		if meta.Server == serverName {
+			if err := p.detachVolume(ctx, meta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// serverVolumes returns the volumes attached to the server
+func (p *LibvirtProvider) serverVolumes(serverName string) ([]*types.Volume, error) {
+	metas, err := p.volumes.List()
+	if err != nil {
+		return nil, err
+	}
+	volumes := []*types.Volume{}
+	for _, meta := range metas {
+		if meta.Server == serverName {
+			volumes = append(volumes, p.mapVolume(meta))
+		}
+	}
+	return volumes, nil
+}
+
+// nextTarget returns the first virtio target not used in the virsh domblklist output
+func nextTarget(domblklist string, reserved map[string]bool) (string, error) {
+	used := map[string]bool{}
+	for _, line := range strings.Split(domblklist, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			used[fields[0]] = true
+		}
+	}
+	for c := 'b'; c <= 'z'; c++ {
+		target := "vd" + string(c)
+		if !used[target] && !reserved[target] {
+			return target, nil
+		}
+	}
+	return "", fmt.Errorf("no free virtio targets")
+}
+
+// reserveTarget picks a target that is neither in the server disks nor being attached by another goroutine,
+// as the volumes of a server are created in parallel
+func (p *LibvirtProvider) reserveTarget(serverName, domblklist string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.targets == nil {
+		p.targets = make(map[string]map[string]bool)
+	}
+	if p.targets[serverName] == nil {
+		p.targets[serverName] = make(map[string]bool)
+	}
+	target, err := nextTarget(domblklist, p.targets[serverName])
+	if err != nil {
+		return "", err
+	}
+	p.targets[serverName][target] = true
+	return target, nil
+}
+
+func (p *LibvirtProvider) releaseTarget(serverName, target string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.targets[serverName], target)
+}
+
+func (p *LibvirtProvider) releaseTargets(serverName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.targets, serverName)
+}
+
+func (p *LibvirtProvider) mapVolume(meta *volumestore.Meta) *types.Volume {
+	if meta == nil {
+		return nil
+	}
+	status := "available"
+	if meta.Target != "" {
+		status = "attached"
+	}
+	return &types.Volume{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Volume",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name:   meta.Name,
+			Labels: meta.Labels,
+		},
+		Spec: types.VolumeSpec{
+			ServerName: meta.Server,
+			Location:   config.DefaultLocalLocation,
+			Provider:   "libvirt",
+			Size:       meta.Size,
+			Format:     meta.Format,
+			Labels:     meta.Labels,
+		},
+		Status: types.VolumeStatus{
+			Status:      status,
+			Owner:       meta.Labels["owner"],
+			Created:     meta.Created,
+			DeleteAfter: timeutil.ParseDeleteAfter(meta.Labels["delete_after"]),
+		},
+	}
+}
@@ -0,0 +1,179 @@
+package libvirt
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/provider/volumestore"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDomblklist = ` Target   Source
+------------------------------------------------
+ vda      /var/lib/libvirt/images/test-cp.qcow2
+ sda      /var/lib/libvirt/images/test-cp-cidata.iso
+`
+
+func respondVolumes(name string, args []string) (string, error) {
+	switch args[0] {
+	case "vol-path":
+		return "/var/lib/libvirt/images/" + args[3] + "\n", nil
+	case "domblklist":
+		return testDomblklist, nil
+	case "domstate":
+		return "running\n\n", nil
+	}
+	return respondServer(name, args)
+}
+
+func TestCreateVolume(t *testing.T) {
+	t.Run("attaches to server", func(t *testing.T) {
+		p, fake := newTestProvider(t, respondVolumes)
+		volume, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{
+			Name:       "test-cp-volume-1",
+			Size:       10,
+			ServerName: "test-cp",
+			Labels:     map[string]string{"lab_name": "test"},
+		})
+		assert.NoError(t, err)
+		assert.True(t, fake.called("virsh vol-create-as default test-cp-volume-1.qcow2 10G --format qcow2"))
+		assert.True(t, fake.called("virsh attach-disk test-cp /var/lib/libvirt/images/test-cp-volume-1.qcow2 vdb --driver qemu --subdriver qcow2 --targetbus virtio --persistent"))
+		if assert.NotNil(t, volume) {
+			assert.Equal(t, "attached", volume.Status.Status)
+			assert.Equal(t, "test-cp", volume.Spec.ServerName)
+		}
+
+		_, err = p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-cp-volume-1", Size: 10})
+		assert.ErrorContains(t, err, "already exists")
+	})
+
+	t.Run("server not found", func(t *testing.T) {
+		p, fake := newTestProvider(t, func(name string, args []string) (string, error) {
+			if args[0] == "dominfo" {
+				return "", errNoDomain
+			}
+			return "", nil
+		})
+		volume, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-cp-volume-1", Size: 10, ServerName: "test-cp"})
+		assert.NoError(t, err)
+		assert.False(t, fake.called("virsh attach-disk"))
+		assert.Equal(t, "available", volume.Status.Status)
+	})
+}
+
+func TestListVolumes(t *testing.T) {
+	p, _ := newTestProvider(t, respondVolumes)
+	assert.NoError(t, p.volumes.Write(&volumestore.Meta{Name: "a", Size: 10, Labels: map[string]string{"lab_name": "test"}}))
+	assert.NoError(t, p.volumes.Write(&volumestore.Meta{Name: "b", Size: 10, Labels: map[string]string{"lab_name": "other"}}))
+
+	volumes, err := p.ListVolumes(context.Background(), options.VolumeListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	if assert.Len(t, volumes, 1) {
+		assert.Equal(t, "a", volumes[0].ObjectMeta.Name)
+	}
+	volumes, err = p.AllVolumes(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, volumes, 2)
+}
+
+func TestResizeVolume(t *testing.T) {
+	t.Run("attached to running server", func(t *testing.T) {
+		p, fake := newTestProvider(t, respondVolumes)
+		assert.NoError(t, p.volumes.Write(&volumestore.Meta{Name: "test-cp-volume-1", Size: 10, Server: "test-cp", Target: "vdb"}))
+
+		volume, err := p.ResizeVolume(context.Background(), "test-cp-volume-1", 20)
+		assert.NoError(t, err)
+		assert.Equal(t, 20, volume.Spec.Size)
+		assert.True(t, fake.called("virsh blockresize test-cp vdb 20G"))
+		assert.False(t, fake.called("virsh vol-resize"))
+	})
+
+	t.Run("detached", func(t *testing.T) {
+		p, fake := newTestProvider(t, respondVolumes)
+		assert.NoError(t, p.volumes.Write(&volumestore.Meta{Name: "test-cp-volume-1", Size: 10}))
+
+		_, err := p.ResizeVolume(context.Background(), "test-cp-volume-1", 20)
+		assert.NoError(t, err)
+		assert.True(t, fake.called("virsh vol-resize --pool default test-cp-volume-1.qcow2 20G"))
+	})
+
+	t.Run("shrink", func(t *testing.T) {
+		p, _ := newTestProvider(t, respondVolumes)
+		assert.NoError(t, p.volumes.Write(&volumestore.Meta{Name: "test-cp-volume-1", Size: 10}))
+
+		_, err := p.ResizeVolume(context.Background(), "test-cp-volume-1", 5)
+		assert.ErrorContains(t, err, "can't shrink")
+	})
+}
+
+func TestDeleteVolume(t *testing.T) {
+	p, fake := newTestProvider(t, respondVolumes)
+	assert.NoError(t, p.volumes.Write(&volumestore.Meta{
+		Name:   "test-cp-volume-1",
+		Size:   10,
+		Server: "test-cp",
+		Target: "vdb",
+		Labels: map[string]string{"delete_after": "2099-01-01-00-00"},
+	}))
+
+	status := p.DeleteVolume(context.Background(), "test-cp-volume-1", false)
+	assert.False(t, status.Deleted)
+	assert.False(t, fake.called("virsh detach-disk"))
+
+	status = p.DeleteVolume(context.Background(), "test-cp-volume-1", true)
+	assert.True(t, status.Deleted)
+	assert.NoError(t, status.Error)
+	assert.True(t, fake.called("virsh detach-disk test-cp vdb --persistent"))
+	assert.True(t, fake.called("virsh vol-delete --pool default test-cp-volume-1.qcow2"))
+
+	volume, err := p.GetVolume(context.Background(), "test-cp-volume-1")
+	assert.NoError(t, err)
+	assert.Nil(t, volume)
+}
+
+func TestNextTarget(t *testing.T) {
+	target, err := nextTarget(testDomblklist, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "vdb", target)
+
+	target, err = nextTarget(testDomblklist+" vdb      /var/lib/libvirt/images/test-cp-volume-1.qcow2\n", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "vdc", target)
+}
+
+func TestCreateVolumesInParallel(t *testing.T) {
+	p, fake := newTestProvider(t, respondVolumes)
+	names := []string{"test-cp-volume-1", "test-cp-volume-2", "test-cp-volume-3", "test-cp-volume-4"}
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: name, Size: 10, ServerName: "test-cp"})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// domblklist shows only the server disk, so the targets being attached must not be picked twice
+	targets := make(map[string]bool)
+	for _, call := range fake.calls {
+		if strings.HasPrefix(call, "virsh attach-disk") {
+			targets[strings.Fields(call)[4]] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{"vdb": true, "vdc": true, "vdd": true, "vde": true}, targets)
+	metas, err := p.volumes.List()
+	assert.NoError(t, err)
+	assert.Len(t, metas, len(names))
+
+	// a detached target can be used again
+	detached := metas[0].Target
+	assert.NoError(t, p.detachVolume(context.Background(), metas[0]))
+	target, err := p.reserveTarget("test-cp", testDomblklist)
+	assert.NoError(t, err)
+	assert.Equal(t, detached, target)
+}
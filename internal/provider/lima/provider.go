@@ -6,10 +6,13 @@ package lima
 import (
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"runtime"
 
 	"github.com/pavelanni/storctl/internal/config"
 	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/types"
 )
 
 type LimaProvider struct {
@@ -37,6 +40,19 @@ func (p *LimaProvider) Name() string {
 	return "lima"
 }
 
+// Capabilities of Lima: disks are attached when the VMs are created,
+// the VMs are found by name and can be deleted any time, and Lima manages the SSH keys and the user
+func (p *LimaProvider) Capabilities() types.ProviderCapabilities {
+	sshKeyPath := ""
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		sshKeyPath = filepath.Join(homeDir, ".lima", "_config", "user")
+	}
+	return types.ProviderCapabilities{
+		SSHUser:    os.Getenv("USER"),
+		SSHKeyPath: sshKeyPath,
+	}
+}
+
 func getProviderConfig(cfg *config.Config, providerName string) *config.ProviderConfig {
 	for _, provider := range cfg.Providers {
 		if provider.Name == providerName {
@@ -51,10 +51,7 @@ var serverTypes = map[string]ConfigServer{
 	},
 }
 
-func (p *LimaProvider) CreateServer(opts options.ServerCreateOpts) (*types.Server, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
+func (p *LimaProvider) CreateServer(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
 	if opts.Name == "" {
 		return nil, fmt.Errorf("name is required")
 	}
@@ -64,7 +61,7 @@ func (p *LimaProvider) CreateServer(opts options.ServerCreateOpts) (*types.Serve
 	if opts.Image == "" {
 		return nil, fmt.Errorf("image is required")
 	}
-	checkServer, err := p.GetServer(opts.Name)
+	checkServer, err := p.GetServer(ctx, opts.Name)
 	if err != nil {
 		if !strings.Contains(err.Error(), "not found") {
 			return nil, fmt.Errorf("checking server: %w", err)
@@ -116,7 +113,7 @@ func (p *LimaProvider) CreateServer(opts options.ServerCreateOpts) (*types.Serve
 		return nil, fmt.Errorf("error creating VM for %s: %w", opts.Name, err)
 	}
 
-	newServer, err := p.GetServer(opts.Name)
+	newServer, err := p.GetServer(ctx, opts.Name)
 	if err != nil {
 		return nil, fmt.Errorf("error getting server for %s: %w", opts.Name, err)
 	}
@@ -125,10 +122,7 @@ func (p *LimaProvider) CreateServer(opts options.ServerCreateOpts) (*types.Serve
 	return newServer, nil
 }
 
-func (p *LimaProvider) GetServer(name string) (*types.Server, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
+func (p *LimaProvider) GetServer(ctx context.Context, name string) (*types.Server, error) {
 	if name == "" {
 		return nil, fmt.Errorf("name is required")
 	}
@@ -168,10 +162,7 @@ func (p *LimaProvider) GetServer(name string) (*types.Server, error) {
 	return nil, nil
 }
 
-func (p *LimaProvider) ListServers(opts options.ServerListOpts) ([]*types.Server, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
+func (p *LimaProvider) ListServers(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
 	var labName string
 
 	if opts.ListOpts.LabelSelector != "" {
@@ -204,18 +195,15 @@ func (p *LimaProvider) ListServers(opts options.ServerListOpts) ([]*types.Server
 	return servers, nil
 }
 
-func (p *LimaProvider) AllServers() ([]*types.Server, error) {
-	servers, err := p.ListServers(options.ServerListOpts{})
+func (p *LimaProvider) AllServers(ctx context.Context) ([]*types.Server, error) {
+	servers, err := p.ListServers(ctx, options.ServerListOpts{})
 	if err != nil {
 		return nil, fmt.Errorf("error listing servers: %w", err)
 	}
 	return servers, nil
 }
 
-func (p *LimaProvider) DeleteServer(name string, force bool) *types.ServerDeleteStatus {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
+func (p *LimaProvider) DeleteServer(ctx context.Context, name string, force bool) *types.ServerDeleteStatus {
 	if name == "" {
 		return &types.ServerDeleteStatus{
 			Deleted: false,
@@ -234,8 +222,8 @@ func (p *LimaProvider) DeleteServer(name string, force bool) *types.ServerDelete
 	}
 }
 
-func (p *LimaProvider) ServerToCreateOpts(server *types.Server) (options.ServerCreateOpts, error) {
-	sshKeys, err := p.KeyNamesToSSHKeys(server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
+func (p *LimaProvider) ServerToCreateOpts(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error) {
+	sshKeys, err := p.KeyNamesToSSHKeys(ctx, server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
 		Labels: server.ObjectMeta.Labels,
 	})
 	if err != nil {
@@ -253,6 +241,11 @@ func (p *LimaProvider) ServerToCreateOpts(server *types.Server) (options.ServerC
 	}, nil
 }
 
+// UpdateServerLabels returns an error, as Lima VMs have no labels
+func (p *LimaProvider) UpdateServerLabels(ctx context.Context, name string, labels map[string]string) error {
+	return fmt.Errorf("Lima VMs have no labels")
+}
+
 func createLimaConfig(server ConfigServer) LimaConfig {
 	// Parse Ubuntu version from image string
 	version := strings.TrimPrefix(server.Image, "ubuntu-")
@@ -296,8 +289,8 @@ func createVM(ctx context.Context, name, configPath string) error {
 
 	fmt.Printf("Creating VM %s...\n", name)
 	if err := createCmd.Run(); err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("timeout while creating VM: %w", err)
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted while creating VM: %w", ctx.Err())
 		}
 		return fmt.Errorf("error creating VM: %w", err)
 	}
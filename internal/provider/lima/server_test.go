@@ -1,6 +1,7 @@
 package lima
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -32,8 +33,8 @@ func TestCreateServer(t *testing.T) {
 			Image: "ubuntu-22.04",
 		}
 		// cleanup before test
-		_ = provider.DeleteServer("test-server", true)
-		server, err := provider.CreateServer(opts)
+		_ = provider.DeleteServer(context.Background(), "test-server", true)
+		server, err := provider.CreateServer(context.Background(), opts)
 		assert.NoError(t, err)
 		assert.NotNil(t, server)
 
@@ -46,11 +47,11 @@ func TestCreateServer(t *testing.T) {
 			Type:  "cx22",
 			Image: "ubuntu-22.04",
 		}
-		_, err := provider.CreateServer(opts)
+		_, err := provider.CreateServer(context.Background(), opts)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "already exists")
 		// Cleanup
-		_ = provider.DeleteServer("test-server", true)
+		_ = provider.DeleteServer(context.Background(), "test-server", true)
 	})
 
 	// Test case 2: Invalid server type
@@ -61,7 +62,7 @@ func TestCreateServer(t *testing.T) {
 			Image: "ubuntu-22.04",
 		}
 
-		server, err := provider.CreateServer(opts)
+		server, err := provider.CreateServer(context.Background(), opts)
 		assert.Error(t, err)
 		assert.Nil(t, server)
 		assert.Contains(t, err.Error(), "invalid server type")
@@ -82,13 +83,13 @@ func TestCreateServer(t *testing.T) {
 			},
 		}
 		// cleanup before test
-		_ = provider.DeleteServer("test-server-disks", true)
-		server, err := provider.CreateServer(opts)
+		_ = provider.DeleteServer(context.Background(), "test-server-disks", true)
+		server, err := provider.CreateServer(context.Background(), opts)
 		assert.NoError(t, err)
 		assert.NotNil(t, server)
 
 		// Cleanup
-		_ = provider.DeleteServer("test-server-disks", true)
+		_ = provider.DeleteServer(context.Background(), "test-server-disks", true)
 	})
 }
 
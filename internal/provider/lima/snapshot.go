@@ -0,0 +1,125 @@
+package lima
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// dataDiskFile is the disk image in the Lima disk directory
+const dataDiskFile = "datadisk"
+
+// disksDir returns ~/.lima/_disks where Lima keeps the disks
+func disksDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".lima", "_disks"), nil
+}
+
+// SnapshotVolume copies the Lima disk into opts.Dir.
+// The VM using the disk must be stopped, so the copy is consistent.
+func (p *LimaProvider) SnapshotVolume(ctx context.Context, name string, opts options.SnapshotOpts) (string, error) {
+	volume, err := p.GetVolume(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if instance := volume.Spec.ServerName; instance != "" {
+		status, err := getStatusFromVM(instance)
+		if err != nil {
+			return "", err
+		}
+		if status.Status == "Running" {
+			return "", fmt.Errorf("disk %s is used by the running VM %s, stop it with 'limactl stop %s' first", name, instance, instance)
+		}
+	}
+	dir, err := disksDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating directory %s: %w", opts.Dir, err)
+	}
+	dst := filepath.Join(opts.Dir, name)
+	p.logger.Debug("copying disk", "disk", name, "to", dst)
+	if err := copySparse(ctx, filepath.Join(dir, name, dataDiskFile), dst); err != nil {
+		return "", fmt.Errorf("error copying disk %s: %w", name, err)
+	}
+	return dst, nil
+}
+
+// RestoreVolume creates the Lima disk opts.Name from the copy
+func (p *LimaProvider) RestoreVolume(ctx context.Context, snapshot string, opts options.VolumeCreateOpts) (*types.Volume, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("volume name is required")
+	}
+	dir, err := disksDir()
+	if err != nil {
+		return nil, err
+	}
+	diskDir := filepath.Join(dir, opts.Name)
+	if _, err := os.Stat(diskDir); err == nil {
+		return nil, fmt.Errorf("disk %s already exists", opts.Name)
+	}
+	if err := os.MkdirAll(diskDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating disk directory: %w", err)
+	}
+	if err := copySparse(ctx, snapshot, filepath.Join(diskDir, dataDiskFile)); err != nil {
+		os.RemoveAll(diskDir)
+		return nil, fmt.Errorf("error restoring disk %s: %w", opts.Name, err)
+	}
+	return p.GetVolume(ctx, opts.Name)
+}
+
+// copySparse copies the file skipping the blocks of zeros, so sparse disk images stay small
+func copySparse(ctx context.Context, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	buf := make([]byte, 1024*1024)
+	zeros := make([]byte, len(buf))
+	var size int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(in, buf)
+		if n > 0 {
+			if bytes.Equal(buf[:n], zeros[:n]) {
+				if _, err := out.Seek(int64(n), io.SeekCurrent); err != nil {
+					return err
+				}
+			} else if _, err := out.Write(buf[:n]); err != nil {
+				return err
+			}
+			size += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// a trailing hole isn't written, so set the size explicitly
+	if err := out.Truncate(size); err != nil {
+		return err
+	}
+	return out.Close()
+}
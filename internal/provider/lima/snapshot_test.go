@@ -0,0 +1,26 @@
+package lima
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopySparse(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "datadisk")
+	data := make([]byte, 3*1024*1024+10)
+	copy(data[1024*1024:], "partition table")
+	assert.NoError(t, os.WriteFile(src, data, 0644))
+
+	dst := filepath.Join(dir, "copy")
+	assert.NoError(t, copySparse(context.Background(), src, dst))
+	copied, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, data, copied)
+
+	assert.Error(t, copySparse(context.Background(), src, dst), "the copy doesn't overwrite files")
+}
@@ -1,6 +1,7 @@
 package lima
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -20,15 +21,15 @@ type LimaSSHKey struct {
 	Labels    map[string]string
 }
 
-func (p *LimaProvider) CreateSSHKey(opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
-	key, err := p.GetSSHKey("default")
+func (p *LimaProvider) CreateSSHKey(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
+	key, err := p.GetSSHKey(ctx, "default")
 	if err != nil {
 		return nil, fmt.Errorf("error getting SSH key: %w", err)
 	}
 	return key, nil
 }
 
-func (p *LimaProvider) GetSSHKey(name string) (*types.SSHKey, error) {
+func (p *LimaProvider) GetSSHKey(ctx context.Context, name string) (*types.SSHKey, error) {
 	// This always returns the default key located in ~/.lima/_config/user.pub
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -42,8 +43,8 @@ func (p *LimaProvider) GetSSHKey(name string) (*types.SSHKey, error) {
 	return mapSSHKey(&LimaSSHKey{Name: "default", PublicKey: string(userKey)}), nil
 }
 
-func (p *LimaProvider) ListSSHKeys(opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
-	defaultKey, err := p.GetSSHKey("default")
+func (p *LimaProvider) ListSSHKeys(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
+	defaultKey, err := p.GetSSHKey(ctx, "default")
 	if err != nil {
 		return nil, fmt.Errorf("error getting SSH key: %w", err)
 	}
@@ -53,8 +54,8 @@ func (p *LimaProvider) ListSSHKeys(opts options.SSHKeyListOpts) ([]*types.SSHKey
 	return keys, nil
 }
 
-func (p *LimaProvider) AllSSHKeys() ([]*types.SSHKey, error) {
-	defaultKey, err := p.GetSSHKey("default")
+func (p *LimaProvider) AllSSHKeys(ctx context.Context) ([]*types.SSHKey, error) {
+	defaultKey, err := p.GetSSHKey(ctx, "default")
 	if err != nil {
 		return nil, fmt.Errorf("error getting SSH key: %w", err)
 	}
@@ -64,26 +65,31 @@ func (p *LimaProvider) AllSSHKeys() ([]*types.SSHKey, error) {
 	return keys, nil
 }
 
-func (p *LimaProvider) DeleteSSHKey(name string, force bool) *types.SSHKeyDeleteStatus {
+func (p *LimaProvider) DeleteSSHKey(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus {
 	return &types.SSHKeyDeleteStatus{
 		Deleted: false,
 	}
 }
 
-func (p *LimaProvider) CloudKeyExists(name string) (bool, error) {
+func (p *LimaProvider) CloudKeyExists(ctx context.Context, name string) (bool, error) {
 	// This always returns true because the default key is always created
 	return true, nil
 }
 
-func (p *LimaProvider) KeyNamesToSSHKeys(keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
+func (p *LimaProvider) KeyNamesToSSHKeys(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
 	// This always returns the default key because the default key is always created
-	defaultKey, err := p.GetSSHKey("default")
+	defaultKey, err := p.GetSSHKey(ctx, "default")
 	if err != nil {
 		return nil, fmt.Errorf("error getting SSH key: %w", err)
 	}
 	return []*types.SSHKey{defaultKey}, nil
 }
 
+// UpdateSSHKeyLabels returns an error, as Lima SSH keys have no labels
+func (p *LimaProvider) UpdateSSHKeyLabels(ctx context.Context, name string, labels map[string]string) error {
+	return fmt.Errorf("Lima SSH keys have no labels")
+}
+
 func mapSSHKey(sk *LimaSSHKey) *types.SSHKey {
 	if sk == nil {
 		return nil
@@ -1,6 +1,7 @@
 package lima
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -56,7 +57,7 @@ func TestGetSSHKey(t *testing.T) {
 	}()
 
 	// Test getting the default key
-	key, err := provider.GetSSHKey("default")
+	key, err := provider.GetSSHKey(context.Background(), "default")
 	assert.NoError(t, err)
 	assert.NotNil(t, key)
 	assert.Equal(t, "default", key.Name)
@@ -77,7 +78,7 @@ func TestListSSHKeys(t *testing.T) {
 	}()
 
 	// Test listing keys
-	keys, err := provider.ListSSHKeys(options.SSHKeyListOpts{})
+	keys, err := provider.ListSSHKeys(context.Background(), options.SSHKeyListOpts{})
 	assert.NoError(t, err)
 	assert.Len(t, keys, 1)
 	assert.Equal(t, "default", keys[0].Name)
@@ -97,7 +98,7 @@ func TestCreateSSHKey(t *testing.T) {
 	}()
 
 	// Test creating a key (should return the default key)
-	key, err := provider.CreateSSHKey(options.SSHKeyCreateOpts{
+	key, err := provider.CreateSSHKey(context.Background(), options.SSHKeyCreateOpts{
 		Name: "test-key",
 	})
 	assert.NoError(t, err)
@@ -109,7 +110,7 @@ func TestDeleteSSHKey(t *testing.T) {
 	provider := &LimaProvider{}
 
 	// Test deleting a key (should always return not deleted)
-	status := provider.DeleteSSHKey("test-key", false)
+	status := provider.DeleteSSHKey(context.Background(), "test-key", false)
 	assert.NotNil(t, status)
 	assert.False(t, status.Deleted)
 }
@@ -118,7 +119,7 @@ func TestCloudKeyExists(t *testing.T) {
 	provider := &LimaProvider{}
 
 	// Test checking if key exists (should always return true)
-	exists, err := provider.CloudKeyExists("any-key")
+	exists, err := provider.CloudKeyExists(context.Background(), "any-key")
 	assert.NoError(t, err)
 	assert.True(t, exists)
 }
@@ -137,7 +138,7 @@ func TestKeyNamesToSSHKeys(t *testing.T) {
 	}()
 
 	// Test converting key names to SSH keys
-	keys, err := provider.KeyNamesToSSHKeys([]string{"key1", "key2"}, options.SSHKeyCreateOpts{})
+	keys, err := provider.KeyNamesToSSHKeys(context.Background(), []string{"key1", "key2"}, options.SSHKeyCreateOpts{})
 	assert.NoError(t, err)
 	assert.Len(t, keys, 1)
 	assert.Equal(t, "default", keys[0].Name)
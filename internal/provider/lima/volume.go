@@ -12,10 +12,7 @@ import (
 	"github.com/pavelanni/storctl/internal/types"
 )
 
-func (p *LimaProvider) CreateVolume(opts options.VolumeCreateOpts) (*types.Volume, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
+func (p *LimaProvider) CreateVolume(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
 	if opts.Name == "" {
 		return nil, fmt.Errorf("volume name is required")
 	}
@@ -43,10 +40,7 @@ func (p *LimaProvider) CreateVolume(opts options.VolumeCreateOpts) (*types.Volum
 	return volume, nil
 }
 
-func (p *LimaProvider) GetVolume(name string) (*types.Volume, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
+func (p *LimaProvider) GetVolume(ctx context.Context, name string) (*types.Volume, error) {
 	listCmd := exec.CommandContext(ctx, "limactl", "disk", "list", "--json", name)
 	output, err := listCmd.CombinedOutput()
 	if err != nil {
@@ -60,10 +54,7 @@ func (p *LimaProvider) GetVolume(name string) (*types.Volume, error) {
 	return p.mapVolume(disk), nil
 }
 
-func (p *LimaProvider) ListVolumes(opts options.VolumeListOpts) ([]*types.Volume, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
+func (p *LimaProvider) ListVolumes(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
 	var labName string
 	if opts.ListOpts.LabelSelector != "" {
 		label := opts.ListOpts.LabelSelector
@@ -91,21 +82,18 @@ func (p *LimaProvider) ListVolumes(opts options.VolumeListOpts) ([]*types.Volume
 	return p.mapVolumes(disks), nil
 }
 
-func (p *LimaProvider) AllVolumes() ([]*types.Volume, error) {
-	return p.ListVolumes(options.VolumeListOpts{})
+func (p *LimaProvider) AllVolumes(ctx context.Context) ([]*types.Volume, error) {
+	return p.ListVolumes(ctx, options.VolumeListOpts{})
 }
 
-func (p *LimaProvider) DeleteVolume(name string, force bool) *types.VolumeDeleteStatus {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
+func (p *LimaProvider) DeleteVolume(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
 	deleteCmd := exec.CommandContext(ctx, "limactl", "disk", "delete", name)
 	output, err := deleteCmd.CombinedOutput()
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+		if ctx.Err() != nil {
 			return &types.VolumeDeleteStatus{
 				Deleted: false,
-				Error:   fmt.Errorf("timeout while deleting disk: %w", err),
+				Error:   fmt.Errorf("interrupted while deleting disk: %w", ctx.Err()),
 			}
 		}
 		return &types.VolumeDeleteStatus{
@@ -116,6 +104,28 @@ func (p *LimaProvider) DeleteVolume(name string, force bool) *types.VolumeDelete
 	return &types.VolumeDeleteStatus{Deleted: true}
 }
 
+// ResizeVolume grows a Lima disk to the new size in GiB.
+// The disk must not be in use by a running VM.
+func (p *LimaProvider) ResizeVolume(ctx context.Context, name string, size int) (*types.Volume, error) {
+	if name == "" {
+		return nil, fmt.Errorf("volume name is required")
+	}
+	resizeCmd := exec.CommandContext(ctx, "limactl", "disk", "resize", name, "--size", fmt.Sprintf("%dGiB", size))
+	output, err := resizeCmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("interrupted while resizing disk: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("error resizing disk: %w, output: %s", err, output)
+	}
+	return p.GetVolume(ctx, name)
+}
+
+// UpdateVolumeLabels returns an error, as Lima disks have no labels
+func (p *LimaProvider) UpdateVolumeLabels(ctx context.Context, name string, labels map[string]string) error {
+	return fmt.Errorf("Lima disks have no labels")
+}
+
 // createDisk creates a disk using limactl command
 func createDisk(ctx context.Context, diskName, size string) error {
 	// Check if disk already exists using limactl disk list
@@ -135,8 +145,8 @@ func createDisk(ctx context.Context, diskName, size string) error {
 	createCmd := exec.CommandContext(ctx, "limactl", "disk", "create", "--size", size, diskName)
 	output, err = createCmd.CombinedOutput()
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("timeout while creating disk: %w", err)
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted while creating disk: %w", ctx.Err())
 		}
 		return fmt.Errorf("error creating disk: %w, output: %s", err, output)
 	}
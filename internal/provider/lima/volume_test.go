@@ -1,6 +1,7 @@
 package lima
 
 import (
+	"context"
 	"os/exec"
 	"testing"
 
@@ -40,7 +41,7 @@ func TestCreateVolume(t *testing.T) {
 	provider := &LimaProvider{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			volume, err := provider.CreateVolume(tt.opts)
+			volume, err := provider.CreateVolume(context.Background(), tt.opts)
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
@@ -78,7 +79,7 @@ func TestGetVolume(t *testing.T) {
 	provider := &LimaProvider{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			volume, err := provider.GetVolume(tt.volName)
+			volume, err := provider.GetVolume(context.Background(), tt.volName)
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
@@ -96,7 +97,7 @@ func TestListVolumes(t *testing.T) {
 	}
 
 	provider := &LimaProvider{}
-	volumes, err := provider.ListVolumes(options.VolumeListOpts{})
+	volumes, err := provider.ListVolumes(context.Background(), options.VolumeListOpts{})
 	assert.NoError(t, err)
 	assert.NotNil(t, volumes)
 }
@@ -129,7 +130,7 @@ func TestDeleteVolume(t *testing.T) {
 	provider := &LimaProvider{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			status := provider.DeleteVolume(tt.volName, tt.force)
+			status := provider.DeleteVolume(context.Background(), tt.volName, tt.force)
 			if tt.wantError {
 				assert.False(t, status.Deleted)
 				assert.Error(t, status.Error)
@@ -3,6 +3,7 @@
 package mock
 
 import (
+	"context"
 	"github.com/pavelanni/storctl/internal/provider"
 	"github.com/pavelanni/storctl/internal/provider/options"
 	"github.com/pavelanni/storctl/internal/types"
@@ -10,31 +11,36 @@ import (
 
 // MockProvider implements the CloudProvider interface for testing
 type MockProvider struct {
-	NameFunc func() string
+	NameFunc         func() string
+	CapabilitiesFunc func() types.ProviderCapabilities
 	// Function fields to customize behavior
-	CreateServerFunc       func(opts options.ServerCreateOpts) (*types.Server, error)
-	GetServerFunc          func(name string) (*types.Server, error)
-	ListServersFunc        func(opts options.ServerListOpts) ([]*types.Server, error)
-	AllServersFunc         func() ([]*types.Server, error)
-	DeleteServerFunc       func(name string, force bool) *types.ServerDeleteStatus
-	ServerToCreateOptsFunc func(server *types.Server) (options.ServerCreateOpts, error)
-	CreateVolumeFunc       func(opts options.VolumeCreateOpts) (*types.Volume, error)
-	GetVolumeFunc          func(name string) (*types.Volume, error)
-	ListVolumesFunc        func(opts options.VolumeListOpts) ([]*types.Volume, error)
-	AllVolumesFunc         func() ([]*types.Volume, error)
-	DeleteVolumeFunc       func(name string, force bool) *types.VolumeDeleteStatus
+	CreateServerFunc       func(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error)
+	GetServerFunc          func(ctx context.Context, name string) (*types.Server, error)
+	ListServersFunc        func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error)
+	AllServersFunc         func(ctx context.Context) ([]*types.Server, error)
+	DeleteServerFunc       func(ctx context.Context, name string, force bool) *types.ServerDeleteStatus
+	ServerToCreateOptsFunc func(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error)
+	CreateVolumeFunc       func(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error)
+	GetVolumeFunc          func(ctx context.Context, name string) (*types.Volume, error)
+	ListVolumesFunc        func(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error)
+	AllVolumesFunc         func(ctx context.Context) ([]*types.Volume, error)
+	DeleteVolumeFunc       func(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus
+	ResizeVolumeFunc       func(ctx context.Context, name string, size int) (*types.Volume, error)
 	CreateLabOnCloudFunc   func(lab *types.Lab) error
 	GetLabFromCloudFunc    func(name string) (*types.Lab, error)
 	ListLabsFunc           func(opts options.LabListOpts) ([]*types.Lab, error)
 	DeleteLabFromCloudFunc func(name string, force bool) *types.LabDeleteStatus
 	SyncLabsFunc           func() error
-	AllSSHKeysFunc         func() ([]*types.SSHKey, error)
-	CreateSSHKeyFunc       func(opts options.SSHKeyCreateOpts) (*types.SSHKey, error)
-	DeleteSSHKeyFunc       func(name string, force bool) *types.SSHKeyDeleteStatus
-	GetSSHKeyFunc          func(name string) (*types.SSHKey, error)
-	CloudKeyExistsFunc     func(name string) (bool, error)
-	ListSSHKeysFunc        func(opts options.SSHKeyListOpts) ([]*types.SSHKey, error)
-	KeyNamesToSSHKeysFunc  func(keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error)
+	AllSSHKeysFunc         func(ctx context.Context) ([]*types.SSHKey, error)
+	CreateSSHKeyFunc       func(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error)
+	DeleteSSHKeyFunc       func(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus
+	GetSSHKeyFunc          func(ctx context.Context, name string) (*types.SSHKey, error)
+	CloudKeyExistsFunc     func(ctx context.Context, name string) (bool, error)
+	ListSSHKeysFunc        func(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error)
+	KeyNamesToSSHKeysFunc  func(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error)
+	UpdateServerLabelsFunc func(ctx context.Context, name string, labels map[string]string) error
+	UpdateVolumeLabelsFunc func(ctx context.Context, name string, labels map[string]string) error
+	UpdateSSHKeyLabelsFunc func(ctx context.Context, name string, labels map[string]string) error
 }
 
 // Ensure MockProvider implements CloudProvider interface
@@ -47,84 +53,119 @@ func (m *MockProvider) Name() string {
 	return "mock"
 }
 
+func (m *MockProvider) Capabilities() types.ProviderCapabilities {
+	if m.CapabilitiesFunc != nil {
+		return m.CapabilitiesFunc()
+	}
+	return types.ProviderCapabilities{}
+}
+
 // Implementation of interface methods
-func (m *MockProvider) CreateServer(opts options.ServerCreateOpts) (*types.Server, error) {
+func (m *MockProvider) CreateServer(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
 	if m.CreateServerFunc != nil {
-		return m.CreateServerFunc(opts)
+		return m.CreateServerFunc(ctx, opts)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) GetServer(name string) (*types.Server, error) {
+func (m *MockProvider) GetServer(ctx context.Context, name string) (*types.Server, error) {
 	if m.GetServerFunc != nil {
-		return m.GetServerFunc(name)
+		return m.GetServerFunc(ctx, name)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) ListServers(opts options.ServerListOpts) ([]*types.Server, error) {
+func (m *MockProvider) ListServers(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
 	if m.ListServersFunc != nil {
-		return m.ListServersFunc(opts)
+		return m.ListServersFunc(ctx, opts)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) AllServers() ([]*types.Server, error) {
+func (m *MockProvider) AllServers(ctx context.Context) ([]*types.Server, error) {
 	if m.AllServersFunc != nil {
-		return m.AllServersFunc()
+		return m.AllServersFunc(ctx)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) DeleteServer(name string, force bool) *types.ServerDeleteStatus {
+func (m *MockProvider) DeleteServer(ctx context.Context, name string, force bool) *types.ServerDeleteStatus {
 	if m.DeleteServerFunc != nil {
-		return m.DeleteServerFunc(name, force)
+		return m.DeleteServerFunc(ctx, name, force)
 	}
 	return &types.ServerDeleteStatus{}
 }
 
-func (m *MockProvider) ServerToCreateOpts(server *types.Server) (options.ServerCreateOpts, error) {
+func (m *MockProvider) ServerToCreateOpts(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error) {
 	if m.ServerToCreateOptsFunc != nil {
-		return m.ServerToCreateOptsFunc(server)
+		return m.ServerToCreateOptsFunc(ctx, server)
 	}
 	return options.ServerCreateOpts{}, nil
 }
 
-func (m *MockProvider) CreateVolume(opts options.VolumeCreateOpts) (*types.Volume, error) {
+func (m *MockProvider) CreateVolume(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
 	if m.CreateVolumeFunc != nil {
-		return m.CreateVolumeFunc(opts)
+		return m.CreateVolumeFunc(ctx, opts)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) GetVolume(name string) (*types.Volume, error) {
+func (m *MockProvider) GetVolume(ctx context.Context, name string) (*types.Volume, error) {
 	if m.GetVolumeFunc != nil {
-		return m.GetVolumeFunc(name)
+		return m.GetVolumeFunc(ctx, name)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) ListVolumes(opts options.VolumeListOpts) ([]*types.Volume, error) {
+func (m *MockProvider) ListVolumes(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
 	if m.ListVolumesFunc != nil {
-		return m.ListVolumesFunc(opts)
+		return m.ListVolumesFunc(ctx, opts)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) AllVolumes() ([]*types.Volume, error) {
+func (m *MockProvider) AllVolumes(ctx context.Context) ([]*types.Volume, error) {
 	if m.AllVolumesFunc != nil {
-		return m.AllVolumesFunc()
+		return m.AllVolumesFunc(ctx)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) DeleteVolume(name string, force bool) *types.VolumeDeleteStatus {
+func (m *MockProvider) DeleteVolume(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
 	if m.DeleteVolumeFunc != nil {
-		return m.DeleteVolumeFunc(name, force)
+		return m.DeleteVolumeFunc(ctx, name, force)
 	}
 	return &types.VolumeDeleteStatus{}
 }
 
+func (m *MockProvider) ResizeVolume(ctx context.Context, name string, size int) (*types.Volume, error) {
+	if m.ResizeVolumeFunc != nil {
+		return m.ResizeVolumeFunc(ctx, name, size)
+	}
+	return nil, nil
+}
+
+func (m *MockProvider) UpdateServerLabels(ctx context.Context, name string, labels map[string]string) error {
+	if m.UpdateServerLabelsFunc != nil {
+		return m.UpdateServerLabelsFunc(ctx, name, labels)
+	}
+	return nil
+}
+
+func (m *MockProvider) UpdateVolumeLabels(ctx context.Context, name string, labels map[string]string) error {
+	if m.UpdateVolumeLabelsFunc != nil {
+		return m.UpdateVolumeLabelsFunc(ctx, name, labels)
+	}
+	return nil
+}
+
+func (m *MockProvider) UpdateSSHKeyLabels(ctx context.Context, name string, labels map[string]string) error {
+	if m.UpdateSSHKeyLabelsFunc != nil {
+		return m.UpdateSSHKeyLabelsFunc(ctx, name, labels)
+	}
+	return nil
+}
+
 func (m *MockProvider) CreateLabOnCloud(lab *types.Lab) error {
 	if m.CreateLabOnCloudFunc != nil {
 		return m.CreateLabOnCloudFunc(lab)
@@ -160,51 +201,51 @@ func (m *MockProvider) SyncLabs() error {
 	return nil
 }
 
-func (m *MockProvider) AllSSHKeys() ([]*types.SSHKey, error) {
+func (m *MockProvider) AllSSHKeys(ctx context.Context) ([]*types.SSHKey, error) {
 	if m.AllSSHKeysFunc != nil {
-		return m.AllSSHKeysFunc()
+		return m.AllSSHKeysFunc(ctx)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) CreateSSHKey(opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
+func (m *MockProvider) CreateSSHKey(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
 	if m.CreateSSHKeyFunc != nil {
-		return m.CreateSSHKeyFunc(opts)
+		return m.CreateSSHKeyFunc(ctx, opts)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) DeleteSSHKey(name string, force bool) *types.SSHKeyDeleteStatus {
+func (m *MockProvider) DeleteSSHKey(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus {
 	if m.DeleteSSHKeyFunc != nil {
-		return m.DeleteSSHKeyFunc(name, force)
+		return m.DeleteSSHKeyFunc(ctx, name, force)
 	}
 	return &types.SSHKeyDeleteStatus{}
 }
 
-func (m *MockProvider) GetSSHKey(name string) (*types.SSHKey, error) {
+func (m *MockProvider) GetSSHKey(ctx context.Context, name string) (*types.SSHKey, error) {
 	if m.GetSSHKeyFunc != nil {
-		return m.GetSSHKeyFunc(name)
+		return m.GetSSHKeyFunc(ctx, name)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) CloudKeyExists(name string) (bool, error) {
+func (m *MockProvider) CloudKeyExists(ctx context.Context, name string) (bool, error) {
 	if m.CloudKeyExistsFunc != nil {
-		return m.CloudKeyExistsFunc(name)
+		return m.CloudKeyExistsFunc(ctx, name)
 	}
 	return false, nil
 }
 
-func (m *MockProvider) ListSSHKeys(opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
+func (m *MockProvider) ListSSHKeys(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
 	if m.ListSSHKeysFunc != nil {
-		return m.ListSSHKeysFunc(opts)
+		return m.ListSSHKeysFunc(ctx, opts)
 	}
 	return nil, nil
 }
 
-func (m *MockProvider) KeyNamesToSSHKeys(keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
+func (m *MockProvider) KeyNamesToSSHKeys(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
 	if m.KeyNamesToSSHKeysFunc != nil {
-		return m.KeyNamesToSSHKeysFunc(keyNames, opts)
+		return m.KeyNamesToSSHKeysFunc(ctx, keyNames, opts)
 	}
 	return nil, nil
 }
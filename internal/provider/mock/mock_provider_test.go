@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -11,7 +12,7 @@ import (
 func TestMockProvider(t *testing.T) {
 	t.Run("CreateServer", func(t *testing.T) {
 		mock := &MockProvider{
-			CreateServerFunc: func(opts options.ServerCreateOpts) (*types.Server, error) {
+			CreateServerFunc: func(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
 				if opts.Name == "test-server" {
 					return &types.Server{
 						ObjectMeta: types.ObjectMeta{
@@ -24,7 +25,7 @@ func TestMockProvider(t *testing.T) {
 		}
 
 		// Test successful case
-		server, err := mock.CreateServer(options.ServerCreateOpts{Name: "test-server"})
+		server, err := mock.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-server"})
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
@@ -33,7 +34,7 @@ func TestMockProvider(t *testing.T) {
 		}
 
 		// Test error case
-		_, err = mock.CreateServer(options.ServerCreateOpts{Name: "invalid-server"})
+		_, err = mock.CreateServer(context.Background(), options.ServerCreateOpts{Name: "invalid-server"})
 		if err == nil {
 			t.Error("Expected error, got nil")
 		}
@@ -41,7 +42,7 @@ func TestMockProvider(t *testing.T) {
 
 	t.Run("DeleteServer", func(t *testing.T) {
 		mock := &MockProvider{
-			DeleteServerFunc: func(name string, force bool) *types.ServerDeleteStatus {
+			DeleteServerFunc: func(ctx context.Context, name string, force bool) *types.ServerDeleteStatus {
 				if name == "test-server" {
 					return &types.ServerDeleteStatus{
 						Deleted: true,
@@ -54,7 +55,7 @@ func TestMockProvider(t *testing.T) {
 		}
 
 		// Test successful deletion
-		status := mock.DeleteServer("test-server", false)
+		status := mock.DeleteServer(context.Background(), "test-server", false)
 		if !status.Deleted {
 			t.Error("Expected server to be deleted")
 		}
@@ -63,7 +64,7 @@ func TestMockProvider(t *testing.T) {
 		}
 
 		// Test failed deletion
-		status = mock.DeleteServer("nonexistent-server", false)
+		status = mock.DeleteServer(context.Background(), "nonexistent-server", false)
 		if status.Deleted {
 			t.Error("Expected server not to be deleted")
 		}
@@ -83,7 +84,7 @@ func TestMockProvider(t *testing.T) {
 		}
 
 		mock := &MockProvider{
-			GetVolumeFunc: func(name string) (*types.Volume, error) {
+			GetVolumeFunc: func(ctx context.Context, name string) (*types.Volume, error) {
 				if name == "test-volume" {
 					return expectedVolume, nil
 				}
@@ -92,7 +93,7 @@ func TestMockProvider(t *testing.T) {
 		}
 
 		// Test successful case
-		volume, err := mock.GetVolume("test-volume")
+		volume, err := mock.GetVolume(context.Background(), "test-volume")
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
@@ -104,7 +105,7 @@ func TestMockProvider(t *testing.T) {
 		}
 
 		// Test error case
-		_, err = mock.GetVolume("nonexistent-volume")
+		_, err = mock.GetVolume(context.Background(), "nonexistent-volume")
 		if err == nil {
 			t.Error("Expected error, got nil")
 		}
@@ -0,0 +1,219 @@
+package openstack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service types in the Keystone catalog
+const (
+	computeService = "compute"
+	volumeService  = "volumev3"
+	imageService   = "image"
+	networkService = "network"
+)
+
+// AuthOptions are the Keystone v3 credentials.
+// Either a user name and password or an application credential is required.
+type AuthOptions struct {
+	AuthURL                     string
+	Username                    string
+	Password                    string
+	UserDomainName              string
+	ProjectName                 string
+	ProjectID                   string
+	ProjectDomainName           string
+	ApplicationCredentialID     string
+	ApplicationCredentialSecret string
+	Region                      string
+}
+
+// client is a minimal OpenStack API client.
+// It gets a token and the service endpoints from Keystone and authenticates again when the token expires.
+type client struct {
+	http *http.Client
+	auth AuthOptions
+
+	mu        sync.Mutex
+	token     string
+	endpoints map[string]string
+}
+
+// httpError is returned for the API responses with an error status
+type httpError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	Body       string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// isNotFound reports whether the API returned 404 Not Found
+func isNotFound(err error) bool {
+	var httpErr *httpError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+func newClient(auth AuthOptions) *client {
+	return &client{
+		http: &http.Client{Timeout: 60 * time.Second},
+		auth: auth,
+	}
+}
+
+// authenticate gets a new token and the service endpoints
+func (c *client) authenticate(ctx context.Context) error {
+	identity := map[string]any{}
+	if c.auth.ApplicationCredentialID != "" {
+		identity["methods"] = []string{"application_credential"}
+		identity["application_credential"] = map[string]any{
+			"id":     c.auth.ApplicationCredentialID,
+			"secret": c.auth.ApplicationCredentialSecret,
+		}
+	} else {
+		identity["methods"] = []string{"password"}
+		identity["password"] = map[string]any{
+			"user": map[string]any{
+				"name":     c.auth.Username,
+				"domain":   map[string]string{"name": c.auth.UserDomainName},
+				"password": c.auth.Password,
+			},
+		}
+	}
+	auth := map[string]any{"identity": identity}
+	// application credentials are scoped to their project already
+	if c.auth.ApplicationCredentialID == "" {
+		project := map[string]any{"name": c.auth.ProjectName, "domain": map[string]string{"name": c.auth.ProjectDomainName}}
+		if c.auth.ProjectID != "" {
+			project = map[string]any{"id": c.auth.ProjectID}
+		}
+		auth["scope"] = map[string]any{"project": project}
+	}
+	data, err := json.Marshal(map[string]any{"auth": auth})
+	if err != nil {
+		return fmt.Errorf("error marshalling auth request: %w", err)
+	}
+	url := strings.TrimSuffix(c.auth.AuthURL, "/") + "/auth/tokens"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error authenticating: %w", &httpError{StatusCode: resp.StatusCode, Method: req.Method, URL: url, Body: string(body)})
+	}
+	var tokenResp struct {
+		Token struct {
+			Catalog []struct {
+				Type      string `json:"type"`
+				Endpoints []struct {
+					Interface string `json:"interface"`
+					Region    string `json:"region"`
+					URL       string `json:"url"`
+				} `json:"endpoints"`
+			} `json:"catalog"`
+		} `json:"token"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return fmt.Errorf("error unmarshalling token: %w", err)
+	}
+	endpoints := make(map[string]string)
+	for _, service := range tokenResp.Token.Catalog {
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Interface == "public" && (c.auth.Region == "" || endpoint.Region == c.auth.Region) {
+				endpoints[service.Type] = strings.TrimSuffix(endpoint.URL, "/")
+				break
+			}
+		}
+	}
+	c.token = resp.Header.Get("X-Subject-Token")
+	c.endpoints = endpoints
+	return nil
+}
+
+// session returns the token and the endpoint of the service, authenticating first if needed
+func (c *client) session(ctx context.Context, service string, renew bool) (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" || renew {
+		if err := c.authenticate(ctx); err != nil {
+			return "", "", err
+		}
+	}
+	endpoint, ok := c.endpoints[service]
+	if !ok {
+		return "", "", fmt.Errorf("no %s endpoint in the service catalog", service)
+	}
+	return c.token, endpoint, nil
+}
+
+// do sends the request to the service and decodes the JSON response into out if it's not nil.
+// The header is optional, like the microversion header.
+func (c *client) do(ctx context.Context, method, service, path string, body, out any, header ...string) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshalling request: %w", err)
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		token, endpoint, err := c.session(ctx, service, attempt > 0)
+		if err != nil {
+			return err
+		}
+		url := endpoint + path
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Auth-Token", token)
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", method, url, err)
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", method, url, err)
+		}
+		// the token has expired, get a new one and try again
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return &httpError{StatusCode: resp.StatusCode, Method: method, URL: url, Body: strings.TrimSpace(string(respBody))}
+		}
+		if out != nil && len(respBody) > 0 {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("error unmarshalling %s response: %w", url, err)
+			}
+		}
+		return nil
+	}
+}
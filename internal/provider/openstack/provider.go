@@ -0,0 +1,109 @@
+// Package openstack contains the OpenStack implementation of the provider interface for the storctl tool.
+// Servers are Nova instances, volumes are Cinder volumes, and SSH keys are Nova keypairs.
+// Labels are kept in the server and volume metadata, so the label selectors work like on Hetzner.
+// It talks to the OpenStack APIs directly, authenticating with Keystone v3.
+package openstack
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/types"
+)
+
+// pollInterval is how often the status of new servers and volumes is checked
+var pollInterval = 5 * time.Second
+
+type OpenStackProvider struct {
+	client  *client
+	config  *config.Config
+	logger  *slog.Logger
+	network string // network name the servers are connected to, optional
+}
+
+func New(cfg *config.Config) (*OpenStackProvider, error) {
+	providerConfig := getProviderConfig(cfg, "openstack")
+	if providerConfig == nil {
+		return nil, fmt.Errorf("provider config not found for openstack")
+	}
+	auth := authOptions(providerConfig.Credentials)
+	if auth.AuthURL == "" {
+		return nil, fmt.Errorf("OpenStack auth_url is required")
+	}
+	if auth.ApplicationCredentialID == "" && (auth.Username == "" || auth.Password == "") {
+		return nil, fmt.Errorf("OpenStack username and password or application credential are required")
+	}
+
+	logger := logger.Get()
+	logger.Info("Initializing OpenStack provider")
+	logger.Debug("Using configuration",
+		"auth_url", auth.AuthURL,
+		"region", auth.Region,
+		"project", auth.ProjectName,
+		"network", providerConfig.Network)
+
+	return &OpenStackProvider{
+		client:  newClient(auth),
+		config:  cfg,
+		logger:  logger,
+		network: providerConfig.Network,
+	}, nil
+}
+
+func (p *OpenStackProvider) Name() string {
+	return "openstack"
+}
+
+// Capabilities of OpenStack: volumes are attached to running servers that get public DNS names
+func (p *OpenStackProvider) Capabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{
+		VolumesAttachAfterBoot: true,
+		ReadinessCheck:         true,
+		NeedsPublicDNS:         true,
+		SupportsLabels:         true,
+		SupportsTTLLabels:      true,
+		MutableLabels:          true,
+		ManagesSSHKeys:         true,
+		SSHUser:                config.DefaultAdminUser,
+	}
+}
+
+// authOptions reads the credentials from the provider config.
+// Missing credentials are taken from the OS_* environment variables set by the OpenStack RC files.
+func authOptions(credentials map[string]string) AuthOptions {
+	get := func(key, defaultValue string) string {
+		if value := credentials[key]; value != "" {
+			return value
+		}
+		if value := os.Getenv("OS_" + strings.ToUpper(key)); value != "" {
+			return value
+		}
+		return defaultValue
+	}
+	return AuthOptions{
+		AuthURL:                     get("auth_url", ""),
+		Username:                    get("username", ""),
+		Password:                    get("password", ""),
+		UserDomainName:              get("user_domain_name", "Default"),
+		ProjectName:                 get("project_name", ""),
+		ProjectID:                   get("project_id", ""),
+		ProjectDomainName:           get("project_domain_name", "Default"),
+		ApplicationCredentialID:     get("application_credential_id", ""),
+		ApplicationCredentialSecret: get("application_credential_secret", ""),
+		Region:                      get("region_name", ""),
+	}
+}
+
+func getProviderConfig(cfg *config.Config, providerName string) *config.ProviderConfig {
+	for _, provider := range cfg.Providers {
+		if provider.Name == providerName {
+			return &provider
+		}
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubCloud is an in-memory OpenStack cloud serving the API calls the provider makes.
+// New servers and volumes become ready on the next GET.
+type stubCloud struct {
+	*httptest.Server
+	mu       sync.Mutex
+	token    string
+	tokens   int
+	nextID   int
+	servers  map[string]*Server
+	volumes  map[string]*Volume
+	keypairs map[string]*Keypair
+	requests []map[string]any // bodies of the POST requests
+}
+
+func newStubCloud(t *testing.T) *stubCloud {
+	pollInterval = time.Millisecond
+	s := &stubCloud{
+		servers:  map[string]*Server{},
+		volumes:  map[string]*Volume{},
+		keypairs: map[string]*Keypair{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /identity/v3/auth/tokens", s.authTokens)
+	mux.HandleFunc("GET /compute/servers/detail", s.listServers)
+	mux.HandleFunc("POST /compute/servers", s.createServer)
+	mux.HandleFunc("GET /compute/servers/{id}", s.getServer)
+	mux.HandleFunc("DELETE /compute/servers/{id}", s.deleteServer)
+	mux.HandleFunc("POST /compute/servers/{id}/metadata", s.updateServerMetadata)
+	mux.HandleFunc("POST /compute/servers/{id}/os-volume_attachments", s.attachVolume)
+	mux.HandleFunc("DELETE /compute/servers/{id}/os-volume_attachments/{volume}", s.detachVolume)
+	mux.HandleFunc("GET /compute/flavors/detail", s.listFlavors)
+	mux.HandleFunc("GET /compute/flavors/{id}", s.getFlavor)
+	mux.HandleFunc("POST /compute/os-keypairs", s.createKeypair)
+	mux.HandleFunc("GET /compute/os-keypairs", s.listKeypairs)
+	mux.HandleFunc("GET /compute/os-keypairs/{name}", s.getKeypair)
+	mux.HandleFunc("DELETE /compute/os-keypairs/{name}", s.deleteKeypair)
+	mux.HandleFunc("POST /volume/volumes", s.createVolume)
+	mux.HandleFunc("GET /volume/volumes/detail", s.listVolumes)
+	mux.HandleFunc("GET /volume/volumes/{id}", s.getVolume)
+	mux.HandleFunc("DELETE /volume/volumes/{id}", s.deleteVolume)
+	mux.HandleFunc("POST /volume/volumes/{id}/action", s.volumeAction)
+	mux.HandleFunc("GET /image/v2/images", s.listImages)
+	mux.HandleFunc("GET /image/v2/images/{id}", s.getImage)
+	mux.HandleFunc("GET /network/v2.0/networks", s.listNetworks)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r.URL.Path != "/identity/v3/auth/tokens" && r.Header.Get("X-Auth-Token") != s.token {
+			http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			body := map[string]any{}
+			json.NewDecoder(r.Body).Decode(&body)
+			s.requests = append(s.requests, body)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestProvider(t *testing.T) (*OpenStackProvider, *stubCloud) {
+	t.Setenv("HOME", t.TempDir())
+	stub := newStubCloud(t)
+	cfg := &config.Config{Providers: []config.ProviderConfig{{
+		Name: "openstack",
+		Credentials: map[string]string{
+			"auth_url":     stub.URL + "/identity/v3",
+			"username":     "demo",
+			"password":     "secret",
+			"project_name": "demo",
+			"region_name":  "RegionOne",
+		},
+		Network: "private",
+	}}}
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.logger = logger.Get()
+	return p, stub
+}
+
+// lastRequest returns the body of the last POST request with the key
+func (s *stubCloud) lastRequest(key string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.requests) - 1; i >= 0; i-- {
+		if body, ok := s.requests[i][key].(map[string]any); ok {
+			return body
+		}
+	}
+	return nil
+}
+
+func (s *stubCloud) id() string {
+	s.nextID++
+	return fmt.Sprintf("id-%d", s.nextID)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *stubCloud) authTokens(w http.ResponseWriter, r *http.Request) {
+	s.tokens++
+	s.token = fmt.Sprintf("token-%d", s.tokens)
+	catalog := []map[string]any{}
+	for service, path := range map[string]string{"compute": "/compute", "volumev3": "/volume", "image": "/image", "network": "/network"} {
+		catalog = append(catalog, map[string]any{
+			"type": service,
+			"endpoints": []map[string]string{
+				{"interface": "internal", "region": "RegionOne", "url": "http://internal.invalid"},
+				{"interface": "public", "region": "RegionTwo", "url": "http://other.invalid"},
+				{"interface": "public", "region": "RegionOne", "url": s.URL + path},
+			},
+		})
+	}
+	w.Header().Set("X-Subject-Token", s.token)
+	writeJSON(w, http.StatusCreated, map[string]any{"token": map[string]any{"catalog": catalog}})
+}
+
+func (s *stubCloud) listServers(w http.ResponseWriter, r *http.Request) {
+	servers := []*Server{}
+	for _, server := range s.servers {
+		// the stub only handles the exact name filters the provider sends
+		if name := r.URL.Query().Get("name"); name != "" && "^"+server.Name+"$" != strings.ReplaceAll(name, `\`, "") {
+			continue
+		}
+		servers = append(servers, server)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"servers": servers})
+}
+
+func (s *stubCloud) createServer(w http.ResponseWriter, r *http.Request) {
+	body := s.requests[len(s.requests)-1]["server"].(map[string]any)
+	metadata := map[string]string{}
+	for k, v := range body["metadata"].(map[string]any) {
+		metadata[k] = v.(string)
+	}
+	server := &Server{
+		ID:               s.id(),
+		Name:             body["name"].(string),
+		Status:           "BUILD",
+		Created:          time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Metadata:         metadata,
+		Flavor:           Reference{ID: body["flavorRef"].(string)},
+		Image:            Reference{ID: body["imageRef"].(string)},
+		AvailabilityZone: "nova",
+		Addresses: map[string][]Address{"private": {
+			{Addr: "10.0.0.5", Version: 4, Type: "fixed"},
+			{Addr: "fd00::5", Version: 6, Type: "fixed"},
+			{Addr: "203.0.113.5", Version: 4, Type: "floating"},
+		}},
+	}
+	s.servers[server.ID] = server
+	writeJSON(w, http.StatusAccepted, map[string]any{"server": map[string]string{"id": server.ID}})
+}
+
+func (s *stubCloud) getServer(w http.ResponseWriter, r *http.Request) {
+	server, ok := s.servers[r.PathValue("id")]
+	if !ok {
+		http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+		return
+	}
+	if server.Status == "BUILD" {
+		server.Status = "ACTIVE"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"server": server})
+}
+
+func (s *stubCloud) deleteServer(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.servers[r.PathValue("id")]; !ok {
+		http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+		return
+	}
+	delete(s.servers, r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *stubCloud) updateServerMetadata(w http.ResponseWriter, r *http.Request) {
+	server, ok := s.servers[r.PathValue("id")]
+	if !ok {
+		http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+		return
+	}
+	for k, v := range s.requests[len(s.requests)-1]["metadata"].(map[string]any) {
+		server.Metadata[k] = v.(string)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"metadata": server.Metadata})
+}
+
+func (s *stubCloud) attachVolume(w http.ResponseWriter, r *http.Request) {
+	server := s.servers[r.PathValue("id")]
+	volumeID := s.requests[len(s.requests)-1]["volumeAttachment"].(map[string]any)["volumeId"].(string)
+	volume := s.volumes[volumeID]
+	volume.Status = "in-use"
+	volume.Attachments = []VolumeAttachment{{ServerID: server.ID, Device: "/dev/vdb"}}
+	server.VolumesAttached = append(server.VolumesAttached, Reference{ID: volumeID})
+	writeJSON(w, http.StatusOK, map[string]any{"volumeAttachment": map[string]string{"id": volumeID}})
+}
+
+func (s *stubCloud) detachVolume(w http.ResponseWriter, r *http.Request) {
+	server := s.servers[r.PathValue("id")]
+	volume := s.volumes[r.PathValue("volume")]
+	if server == nil || volume == nil {
+		http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+		return
+	}
+	volume.Status = "available"
+	volume.Attachments = nil
+	server.VolumesAttached = nil
+	w.WriteHeader(http.StatusAccepted)
+}
+
+var stubFlavors = []*Flavor{
+	{ID: "f1", Name: "m1.small", VCPUs: 1, RAM: 2048, Disk: 20},
+	{ID: "f2", Name: "m1.large", VCPUs: 4, RAM: 8192, Disk: 80},
+}
+
+func (s *stubCloud) listFlavors(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"flavors": stubFlavors})
+}
+
+func (s *stubCloud) getFlavor(w http.ResponseWriter, r *http.Request) {
+	for _, flavor := range stubFlavors {
+		if flavor.ID == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, map[string]any{"flavor": flavor})
+			return
+		}
+	}
+	http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+}
+
+func (s *stubCloud) createKeypair(w http.ResponseWriter, r *http.Request) {
+	body := s.requests[len(s.requests)-1]["keypair"].(map[string]any)
+	keypair := &Keypair{Name: body["name"].(string), PublicKey: body["public_key"].(string), Created: "2025-01-02T03:04:05.000000"}
+	s.keypairs[keypair.Name] = keypair
+	writeJSON(w, http.StatusOK, map[string]any{"keypair": keypair})
+}
+
+func (s *stubCloud) listKeypairs(w http.ResponseWriter, r *http.Request) {
+	keypairs := []map[string]any{}
+	for _, keypair := range s.keypairs {
+		keypairs = append(keypairs, map[string]any{"keypair": keypair})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keypairs": keypairs})
+}
+
+func (s *stubCloud) getKeypair(w http.ResponseWriter, r *http.Request) {
+	keypair, ok := s.keypairs[r.PathValue("name")]
+	if !ok {
+		http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keypair": keypair})
+}
+
+func (s *stubCloud) deleteKeypair(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.keypairs[r.PathValue("name")]; !ok {
+		http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+		return
+	}
+	delete(s.keypairs, r.PathValue("name"))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *stubCloud) createVolume(w http.ResponseWriter, r *http.Request) {
+	body := s.requests[len(s.requests)-1]["volume"].(map[string]any)
+	metadata := map[string]string{}
+	for k, v := range body["metadata"].(map[string]any) {
+		metadata[k] = v.(string)
+	}
+	volume := &Volume{
+		ID:       s.id(),
+		Name:     body["name"].(string),
+		Size:     int(body["size"].(float64)),
+		Status:   "creating",
+		Created:  "2025-01-02T03:04:05.000000",
+		Metadata: metadata,
+		Zone:     "nova",
+	}
+	s.volumes[volume.ID] = volume
+	writeJSON(w, http.StatusAccepted, map[string]any{"volume": volume})
+}
+
+func (s *stubCloud) listVolumes(w http.ResponseWriter, r *http.Request) {
+	volumes := []*Volume{}
+	for _, volume := range s.volumes {
+		if name := r.URL.Query().Get("name"); name == "" || name == volume.Name {
+			volumes = append(volumes, volume)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"volumes": volumes})
+}
+
+func (s *stubCloud) getVolume(w http.ResponseWriter, r *http.Request) {
+	volume, ok := s.volumes[r.PathValue("id")]
+	if !ok {
+		http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+		return
+	}
+	if volume.Status == "creating" {
+		volume.Status = "available"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"volume": volume})
+}
+
+func (s *stubCloud) deleteVolume(w http.ResponseWriter, r *http.Request) {
+	volume, ok := s.volumes[r.PathValue("id")]
+	if !ok {
+		http.Error(w, `{"itemNotFound": {}}`, http.StatusNotFound)
+		return
+	}
+	if volume.Status == "in-use" {
+		http.Error(w, `{"badRequest": {"message": "volume is attached"}}`, http.StatusBadRequest)
+		return
+	}
+	delete(s.volumes, volume.ID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *stubCloud) volumeAction(w http.ResponseWriter, r *http.Request) {
+	volume := s.volumes[r.PathValue("id")]
+	extend := s.requests[len(s.requests)-1]["os-extend"].(map[string]any)
+	if volume.Status == "in-use" && r.Header.Get("OpenStack-API-Version") != "volume 3.42" {
+		http.Error(w, `{"badRequest": {"message": "in-use volumes need 3.42"}}`, http.StatusBadRequest)
+		return
+	}
+	volume.Size = int(extend["new_size"].(float64))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *stubCloud) listImages(w http.ResponseWriter, r *http.Request) {
+	images := []*Image{}
+	if r.URL.Query().Get("name") == "ubuntu-24.04" {
+		images = append(images, &Image{ID: "img-1", Name: "ubuntu-24.04"})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"images": images})
+}
+
+func (s *stubCloud) getImage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &Image{ID: "img-1", Name: "ubuntu-24.04"})
+}
+
+func (s *stubCloud) listNetworks(w http.ResponseWriter, r *http.Request) {
+	networks := []*Network{}
+	if r.URL.Query().Get("name") == "private" {
+		networks = append(networks, &Network{ID: "net-1", Name: "private"})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"networks": networks})
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("OS_AUTH_URL", "")
+	t.Setenv("OS_USERNAME", "")
+	t.Setenv("OS_PASSWORD", "")
+
+	_, err := New(&config.Config{})
+	assert.ErrorContains(t, err, "provider config not found")
+
+	_, err = New(&config.Config{Providers: []config.ProviderConfig{{Name: "openstack"}}})
+	assert.ErrorContains(t, err, "auth_url is required")
+
+	t.Setenv("OS_AUTH_URL", "https://keystone.example.com/v3")
+	t.Setenv("OS_APPLICATION_CREDENTIAL_ID", "app-id")
+	t.Setenv("OS_APPLICATION_CREDENTIAL_SECRET", "app-secret")
+	p, err := New(&config.Config{Providers: []config.ProviderConfig{{Name: "openstack"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "openstack", p.Name())
+	assert.Equal(t, "app-id", p.client.auth.ApplicationCredentialID)
+	assert.Equal(t, "Default", p.client.auth.UserDomainName)
+}
+
+func TestClientAuthentication(t *testing.T) {
+	p, stub := newTestProvider(t)
+
+	_, err := p.AllServers(context.Background())
+	assert.NoError(t, err)
+	auth := stub.lastRequest("auth")
+	identity := auth["identity"].(map[string]any)
+	assert.Equal(t, []any{"password"}, identity["methods"])
+	assert.Equal(t, "demo", auth["scope"].(map[string]any)["project"].(map[string]any)["name"])
+	assert.Equal(t, 1, stub.tokens)
+
+	// the token expires, the client gets a new one and retries
+	stub.mu.Lock()
+	stub.token = "expired"
+	stub.mu.Unlock()
+	_, err = p.AllServers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stub.tokens)
+}
@@ -0,0 +1,444 @@
+package openstack
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+func (p *OpenStackProvider) CreateServer(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+	defer cancel()
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if opts.Type == "" {
+		return nil, fmt.Errorf("type is required")
+	}
+	if opts.Image == "" {
+		return nil, fmt.Errorf("image is required")
+	}
+	existing, err := p.findServer(ctx, opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting server: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("server %s already exists", opts.Name)
+	}
+	flavor, err := p.findFlavor(ctx, opts.Type)
+	if err != nil {
+		return nil, err
+	}
+	imageID, err := p.findImage(ctx, opts.Image)
+	if err != nil {
+		return nil, err
+	}
+	// Nova takes a single keypair, the other keys come with the cloud-init user data
+	keyName := ""
+	for _, sshKey := range opts.SSHKeys {
+		exists, err := p.CloudKeyExists(ctx, sshKey.ObjectMeta.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error getting SSH key: %w", err)
+		}
+		if exists {
+			keyName = sshKey.ObjectMeta.Name
+			break
+		}
+	}
+	if keyName == "" && opts.UserData == "" {
+		return nil, fmt.Errorf("no SSH keys provided")
+	}
+
+	metadata := opts.Labels
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	serverOpts := map[string]any{
+		"name":      opts.Name,
+		"flavorRef": flavor.ID,
+		"imageRef":  imageID,
+		"metadata":  metadata,
+	}
+	if keyName != "" {
+		serverOpts["key_name"] = keyName
+	}
+	if opts.UserData != "" {
+		serverOpts["user_data"] = base64.StdEncoding.EncodeToString([]byte(opts.UserData))
+	}
+	if opts.Location != "" {
+		serverOpts["availability_zone"] = opts.Location
+	}
+	if p.network != "" {
+		networkID, err := p.findNetwork(ctx, p.network)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts["networks"] = []map[string]string{{"uuid": networkID}}
+	}
+	p.logger.Debug("creating server",
+		"name", opts.Name,
+		"flavor", opts.Type,
+		"image", opts.Image,
+		"availability_zone", opts.Location,
+		"key_name", keyName)
+	var resp struct {
+		Server Reference `json:"server"`
+	}
+	if err := p.client.do(ctx, "POST", computeService, "/servers", map[string]any{"server": serverOpts}, &resp); err != nil {
+		return nil, fmt.Errorf("error creating server: %w", err)
+	}
+	server, err := p.waitForServer(ctx, resp.Server.ID)
+	if err != nil {
+		// don't leave a failed server behind, even if the creation was canceled
+		if delErr := p.client.do(context.WithoutCancel(ctx), "DELETE", computeService, "/servers/"+resp.Server.ID, nil, nil); delErr != nil {
+			p.logger.Warn("failed to delete server", "server", opts.Name, "error", delErr)
+		}
+		return nil, err
+	}
+	p.logger.Debug("successfully created server",
+		"name", opts.Name,
+		"id", server.ID)
+
+	return p.mapServer(ctx, server)
+}
+
+func (p *OpenStackProvider) GetServer(ctx context.Context, serverName string) (*types.Server, error) {
+	server, err := p.findServer(ctx, serverName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting server: %w", err)
+	}
+	if server == nil {
+		return nil, nil
+	}
+	return p.mapServer(ctx, server)
+}
+
+func (p *OpenStackProvider) ListServers(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+	servers, err := p.listServers(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("error listing servers: %w", err)
+	}
+	result := []*types.Server{}
+	for _, server := range servers {
+		if !labelutil.MatchSelector(server.Metadata, opts.LabelSelector) {
+			continue
+		}
+		mapped, err := p.mapServer(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, mapped)
+	}
+	return result, nil
+}
+
+func (p *OpenStackProvider) AllServers(ctx context.Context) ([]*types.Server, error) {
+	return p.ListServers(ctx, options.ServerListOpts{})
+}
+
+func (p *OpenStackProvider) DeleteServer(ctx context.Context, serverName string, force bool) *types.ServerDeleteStatus {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if serverName == "" {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("empty server name provided"),
+		}
+	}
+	server, err := p.findServer(ctx, serverName)
+	if err != nil {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("error getting server: %w", err),
+		}
+	}
+	if server == nil {
+		p.logger.Debug("Server not found, skipping",
+			"server", serverName)
+		return &types.ServerDeleteStatus{
+			Deleted: true,
+		}
+	}
+	deleteAfter := timeutil.ParseDeleteAfter(server.Metadata["delete_after"])
+	if !force && time.Now().UTC().Before(deleteAfter) {
+		p.logger.Warn("server not ready for deletion",
+			"server", serverName,
+			"delete_after", deleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.ServerDeleteStatus{
+			DeleteAfter: deleteAfter,
+		}
+	}
+	for _, volume := range server.VolumesAttached {
+		if err := p.client.do(ctx, "DELETE", computeService, "/servers/"+server.ID+"/os-volume_attachments/"+volume.ID, nil, nil); err != nil && !isNotFound(err) {
+			return &types.ServerDeleteStatus{
+				Error: fmt.Errorf("error detaching volume: %w", err),
+			}
+		}
+	}
+	if err := p.client.do(ctx, "DELETE", computeService, "/servers/"+server.ID, nil, nil); err != nil && !isNotFound(err) {
+		return &types.ServerDeleteStatus{
+			Error: fmt.Errorf("error deleting server: %w", err),
+		}
+	}
+	return &types.ServerDeleteStatus{
+		Deleted: true,
+	}
+}
+
+// UpdateServerLabels merges the labels into the server metadata
+func (p *OpenStackProvider) UpdateServerLabels(ctx context.Context, serverName string, labels map[string]string) error {
+	server, err := p.findServer(ctx, serverName)
+	if err != nil {
+		return fmt.Errorf("error getting server: %w", err)
+	}
+	if server == nil {
+		return fmt.Errorf("server not found: %s", serverName)
+	}
+	p.logger.Debug("updating server metadata",
+		"server", serverName,
+		"labels", labels)
+	body := map[string]any{"metadata": labels}
+	if err := p.client.do(ctx, "POST", computeService, "/servers/"+server.ID+"/metadata", body, nil); err != nil {
+		return fmt.Errorf("error updating server metadata: %w", err)
+	}
+	return nil
+}
+
+func (p *OpenStackProvider) ServerToCreateOpts(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error) {
+	sshKeys, err := p.KeyNamesToSSHKeys(ctx, server.Spec.SSHKeyNames, options.SSHKeyCreateOpts{
+		Labels: server.ObjectMeta.Labels,
+	})
+	if err != nil {
+		return options.ServerCreateOpts{}, fmt.Errorf("error converting SSH keys: %w", err)
+	}
+	cloudInitUserData := fmt.Sprintf(config.DefaultCloudInitUserData, sshKeys[0].Spec.PublicKey)
+	return options.ServerCreateOpts{
+		Name:     server.ObjectMeta.Name,
+		Type:     server.Spec.ServerType,
+		Image:    server.Spec.Image,
+		Location: server.Spec.Location,
+		Provider: "openstack",
+		SSHKeys:  sshKeys,
+		Labels:   server.ObjectMeta.Labels,
+		UserData: cloudInitUserData,
+	}, nil
+}
+
+// waitForServer waits until the server is active
+func (p *OpenStackProvider) waitForServer(ctx context.Context, id string) (*Server, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		var resp struct {
+			Server *Server `json:"server"`
+		}
+		if err := p.client.do(ctx, "GET", computeService, "/servers/"+id, nil, &resp); err != nil {
+			return nil, fmt.Errorf("error getting server: %w", err)
+		}
+		switch resp.Server.Status {
+		case "ACTIVE":
+			return resp.Server, nil
+		case "ERROR":
+			message := "unknown error"
+			if resp.Server.Fault != nil {
+				message = resp.Server.Fault.Message
+			}
+			return nil, fmt.Errorf("server %s failed: %s", resp.Server.Name, message)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for server %s to become active", resp.Server.Name)
+		case <-ticker.C:
+		}
+	}
+}
+
+// findServer returns the server with the name or nil if it doesn't exist
+func (p *OpenStackProvider) findServer(ctx context.Context, name string) (*Server, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty server name provided")
+	}
+	// Nova matches the name as a regular expression
+	servers, err := p.listServers(ctx, "^"+regexp.QuoteMeta(name)+"$")
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		if server.Name == name {
+			return server, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *OpenStackProvider) listServers(ctx context.Context, nameFilter string) ([]*Server, error) {
+	path := "/servers/detail"
+	if nameFilter != "" {
+		path += "?name=" + url.QueryEscape(nameFilter)
+	}
+	var resp struct {
+		Servers []*Server `json:"servers"`
+	}
+	if err := p.client.do(ctx, "GET", computeService, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	sort.Slice(resp.Servers, func(i, j int) bool { return resp.Servers[i].Name < resp.Servers[j].Name })
+	return resp.Servers, nil
+}
+
+// findFlavor returns the flavor with the name, the server types are flavor names
+func (p *OpenStackProvider) findFlavor(ctx context.Context, name string) (*Flavor, error) {
+	var resp struct {
+		Flavors []*Flavor `json:"flavors"`
+	}
+	if err := p.client.do(ctx, "GET", computeService, "/flavors/detail", nil, &resp); err != nil {
+		return nil, fmt.Errorf("error listing flavors: %w", err)
+	}
+	for _, flavor := range resp.Flavors {
+		if flavor.Name == name || flavor.ID == name {
+			return flavor, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid server type: flavor %s not found", name)
+}
+
+func (p *OpenStackProvider) getFlavor(ctx context.Context, id string) (*Flavor, error) {
+	var resp struct {
+		Flavor *Flavor `json:"flavor"`
+	}
+	if err := p.client.do(ctx, "GET", computeService, "/flavors/"+id, nil, &resp); err != nil {
+		return nil, fmt.Errorf("error getting flavor %s: %w", id, err)
+	}
+	return resp.Flavor, nil
+}
+
+// findImage returns the ID of the Glance image with the name
+func (p *OpenStackProvider) findImage(ctx context.Context, name string) (string, error) {
+	var resp struct {
+		Images []*Image `json:"images"`
+	}
+	if err := p.client.do(ctx, "GET", imageService, "/v2/images?name="+url.QueryEscape(name), nil, &resp); err != nil {
+		return "", fmt.Errorf("error listing images: %w", err)
+	}
+	if len(resp.Images) == 0 {
+		return "", fmt.Errorf("image %s not found", name)
+	}
+	return resp.Images[0].ID, nil
+}
+
+// imageName returns the name of the image or its ID if the image is gone
+func (p *OpenStackProvider) imageName(ctx context.Context, id string) string {
+	if id == "" {
+		return ""
+	}
+	image := &Image{}
+	if err := p.client.do(ctx, "GET", imageService, "/v2/images/"+id, nil, image); err != nil {
+		p.logger.Debug("error getting image", "image", id, "error", err)
+		return id
+	}
+	return image.Name
+}
+
+// findNetwork returns the ID of the Neutron network with the name
+func (p *OpenStackProvider) findNetwork(ctx context.Context, name string) (string, error) {
+	var resp struct {
+		Networks []*Network `json:"networks"`
+	}
+	if err := p.client.do(ctx, "GET", networkService, "/v2.0/networks?name="+url.QueryEscape(name), nil, &resp); err != nil {
+		return "", fmt.Errorf("error listing networks: %w", err)
+	}
+	if len(resp.Networks) == 0 {
+		return "", fmt.Errorf("network %s not found", name)
+	}
+	return resp.Networks[0].ID, nil
+}
+
+// mapServer converts a Nova server to our generic Server type
+func (p *OpenStackProvider) mapServer(ctx context.Context, s *Server) (*types.Server, error) {
+	flavor, err := p.getFlavor(ctx, s.Flavor.ID)
+	if err != nil {
+		return nil, err
+	}
+	volumes := make([]*types.Volume, 0, len(s.VolumesAttached))
+	for _, attached := range s.VolumesAttached {
+		volume, err := p.getVolume(ctx, attached.ID)
+		if err != nil {
+			p.logger.Error("error getting volume",
+				"volume", attached.ID,
+				"error", err)
+			continue
+		}
+		volumes = append(volumes, mapVolume(volume, s.Name))
+	}
+	return &types.Server{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Server",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name:   s.Name,
+			Labels: s.Metadata,
+		},
+		Spec: types.ServerSpec{
+			ServerType: flavor.Name,
+			Location:   s.AvailabilityZone,
+			Provider:   "openstack",
+			Image:      p.imageName(ctx, s.Image.ID),
+			Labels:     s.Metadata,
+			Volumes:    volumes,
+			TTL:        s.Metadata["ttl"],
+		},
+		Status: types.ServerStatus{
+			Status:      s.Status,
+			Owner:       s.Metadata["owner"],
+			Cores:       flavor.VCPUs,
+			Memory:      float32(flavor.RAM) / 1024,
+			Disk:        flavor.Disk,
+			PublicNet:   mapPublicNet(s.Addresses),
+			Created:     s.Created,
+			DeleteAfter: timeutil.ParseDeleteAfter(s.Metadata["delete_after"]),
+		},
+	}, nil
+}
+
+// mapPublicNet returns the floating IPv4 address of the server or the fixed one if it has none
+func mapPublicNet(addresses map[string][]Address) *types.PublicNet {
+	networks := make([]string, 0, len(addresses))
+	for network := range addresses {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	floatingIP, fixedIP := "", ""
+	for _, network := range networks {
+		for _, address := range addresses[network] {
+			if address.Version != 4 {
+				continue
+			}
+			if address.Type == "floating" && floatingIP == "" {
+				floatingIP = address.Addr
+			}
+			if address.Type != "floating" && fixedIP == "" {
+				fixedIP = address.Addr
+			}
+		}
+	}
+	ip := floatingIP
+	if ip == "" {
+		ip = fixedIP
+	}
+	return &types.PublicNet{
+		IPv4: &struct {
+			IP string `json:"ip"`
+		}{IP: ip},
+	}
+}
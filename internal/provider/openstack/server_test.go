@@ -0,0 +1,159 @@
+package openstack
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateServer(t *testing.T) {
+	sshKeys := []*types.SSHKey{
+		{ObjectMeta: types.ObjectMeta{Name: "admin"}},
+		{ObjectMeta: types.ObjectMeta{Name: "test-admin"}},
+	}
+
+	t.Run("invalid flavor", func(t *testing.T) {
+		p, _ := newTestProvider(t)
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "cx22", Image: "ubuntu-24.04", UserData: "#cloud-config"})
+		assert.ErrorContains(t, err, "invalid server type")
+		assert.Nil(t, server)
+	})
+
+	t.Run("no SSH keys", func(t *testing.T) {
+		p, _ := newTestProvider(t)
+		_, err := p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "m1.large", Image: "ubuntu-24.04", SSHKeys: sshKeys})
+		assert.ErrorContains(t, err, "no SSH keys provided")
+	})
+
+	t.Run("creates server with labels as metadata", func(t *testing.T) {
+		p, stub := newTestProvider(t)
+		stub.keypairs["test-admin"] = &Keypair{Name: "test-admin", PublicKey: "ssh-ed25519 AAAA test-admin"}
+
+		server, err := p.CreateServer(context.Background(), options.ServerCreateOpts{
+			Name:     "test-cp",
+			Type:     "m1.large",
+			Image:    "ubuntu-24.04",
+			Location: "nova",
+			SSHKeys:  sshKeys,
+			Labels:   map[string]string{"lab_name": "test", "owner": "me", "delete_after": "2099-01-01-00-00"},
+			UserData: "#cloud-config",
+		})
+		assert.NoError(t, err)
+
+		body := stub.lastRequest("server")
+		assert.Equal(t, "f2", body["flavorRef"])
+		assert.Equal(t, "img-1", body["imageRef"])
+		assert.Equal(t, "test-admin", body["key_name"])
+		assert.Equal(t, "nova", body["availability_zone"])
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("#cloud-config")), body["user_data"])
+		assert.Equal(t, []any{map[string]any{"uuid": "net-1"}}, body["networks"])
+		assert.Equal(t, map[string]any{"lab_name": "test", "owner": "me", "delete_after": "2099-01-01-00-00"}, body["metadata"])
+
+		if assert.NotNil(t, server) {
+			assert.Equal(t, "ACTIVE", server.Status.Status)
+			assert.Equal(t, "m1.large", server.Spec.ServerType)
+			assert.Equal(t, "ubuntu-24.04", server.Spec.Image)
+			assert.Equal(t, "openstack", server.Spec.Provider)
+			assert.Equal(t, 4, server.Status.Cores)
+			assert.Equal(t, float32(8), server.Status.Memory)
+			assert.Equal(t, "me", server.Status.Owner)
+			assert.Equal(t, "203.0.113.5", server.Status.PublicNet.IPv4.IP)
+		}
+
+		_, err = p.CreateServer(context.Background(), options.ServerCreateOpts{Name: "test-cp", Type: "m1.large", Image: "ubuntu-24.04", UserData: "#cloud-config"})
+		assert.ErrorContains(t, err, "already exists")
+	})
+}
+
+func TestListServers(t *testing.T) {
+	p, stub := newTestProvider(t)
+	stub.servers["a"] = &Server{ID: "a", Name: "lab1-cp", Status: "ACTIVE", Flavor: Reference{ID: "f1"}, Metadata: map[string]string{"lab_name": "lab1"}}
+	stub.servers["b"] = &Server{ID: "b", Name: "lab2-cp", Status: "ACTIVE", Flavor: Reference{ID: "f1"}, Metadata: map[string]string{"lab_name": "lab2"}}
+	stub.servers["c"] = &Server{ID: "c", Name: "unmanaged", Status: "ACTIVE", Flavor: Reference{ID: "f1"}}
+
+	servers, err := p.ListServers(context.Background(), options.ServerListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=lab1"}})
+	assert.NoError(t, err)
+	if assert.Len(t, servers, 1) {
+		assert.Equal(t, "lab1-cp", servers[0].ObjectMeta.Name)
+		assert.Equal(t, "m1.small", servers[0].Spec.ServerType)
+	}
+
+	servers, err = p.AllServers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, servers, 3)
+}
+
+func TestDeleteServer(t *testing.T) {
+	p, stub := newTestProvider(t)
+	stub.servers["a"] = &Server{
+		ID:              "a",
+		Name:            "test-cp",
+		Status:          "ACTIVE",
+		Flavor:          Reference{ID: "f1"},
+		Metadata:        map[string]string{"delete_after": "2099-01-01-00-00"},
+		VolumesAttached: []Reference{{ID: "v"}},
+	}
+	stub.volumes["v"] = &Volume{ID: "v", Name: "test-cp-volume-1", Status: "in-use", Attachments: []VolumeAttachment{{ServerID: "a"}}}
+
+	status := p.DeleteServer(context.Background(), "test-cp", false)
+	assert.False(t, status.Deleted)
+	assert.NoError(t, status.Error)
+	assert.Equal(t, 2099, status.DeleteAfter.Year())
+
+	status = p.DeleteServer(context.Background(), "test-cp", true)
+	assert.True(t, status.Deleted)
+	assert.NoError(t, status.Error)
+	assert.Empty(t, stub.servers)
+	assert.Equal(t, "available", stub.volumes["v"].Status)
+
+	status = p.DeleteServer(context.Background(), "test-cp", true)
+	assert.True(t, status.Deleted)
+}
+
+func TestUpdateServerLabels(t *testing.T) {
+	p, stub := newTestProvider(t)
+	stub.servers["a"] = &Server{ID: "a", Name: "test-cp", Status: "ACTIVE", Flavor: Reference{ID: "f1"}, Metadata: map[string]string{"lab_name": "test", "delete_after": "2025-01-01-00-00"}}
+
+	err := p.UpdateServerLabels(context.Background(), "test-cp", map[string]string{"delete_after": "2099-01-01-00-00"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lab_name": "test", "delete_after": "2099-01-01-00-00"}, stub.servers["a"].Metadata)
+
+	err = p.UpdateServerLabels(context.Background(), "missing", map[string]string{"delete_after": "2099-01-01-00-00"})
+	assert.ErrorContains(t, err, "server not found")
+}
+
+func TestMapPublicNet(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses map[string][]Address
+		want      string
+	}{
+		{
+			name:      "no addresses",
+			addresses: nil,
+			want:      "",
+		},
+		{
+			name:      "fixed only",
+			addresses: map[string][]Address{"private": {{Addr: "fd00::5", Version: 6}, {Addr: "10.0.0.5", Version: 4, Type: "fixed"}}},
+			want:      "10.0.0.5",
+		},
+		{
+			name: "floating wins",
+			addresses: map[string][]Address{
+				"a": {{Addr: "10.0.0.5", Version: 4, Type: "fixed"}},
+				"b": {{Addr: "203.0.113.5", Version: 4, Type: "floating"}},
+			},
+			want: "203.0.113.5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mapPublicNet(tt.addresses).IPv4.IP)
+		})
+	}
+}
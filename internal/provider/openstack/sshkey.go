@@ -0,0 +1,197 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/ssh"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+)
+
+// Nova keypairs have no metadata, so SSH keys carry no labels
+// and they are deleted without waiting for delete_after.
+
+func (p *OpenStackProvider) CreateSSHKey(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error) {
+	p.logger.Debug("creating SSH key",
+		"name", opts.Name,
+		"public_key", opts.PublicKey)
+	body := map[string]any{"keypair": map[string]string{
+		"name":       opts.Name,
+		"public_key": opts.PublicKey,
+	}}
+	var resp struct {
+		Keypair *Keypair `json:"keypair"`
+	}
+	if err := p.client.do(ctx, "POST", computeService, "/os-keypairs", body, &resp); err != nil {
+		return nil, fmt.Errorf("error creating SSH key: %w", err)
+	}
+	return mapSSHKey(resp.Keypair), nil
+}
+
+func (p *OpenStackProvider) GetSSHKey(ctx context.Context, name string) (*types.SSHKey, error) {
+	p.logger.Debug("getting SSH key",
+		"key", name)
+	keypair, err := p.getKeypair(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting SSH key: %w", err)
+	}
+	if keypair == nil {
+		p.logger.Debug("SSH key not found",
+			"key", name)
+		return nil, fmt.Errorf("SSH key not found")
+	}
+	return mapSSHKey(keypair), nil
+}
+
+func (p *OpenStackProvider) ListSSHKeys(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error) {
+	sshKeys, err := p.AllSSHKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []*types.SSHKey{}
+	for _, sshKey := range sshKeys {
+		if labelutil.MatchSelector(sshKey.Spec.Labels, opts.LabelSelector) {
+			result = append(result, sshKey)
+		}
+	}
+	return result, nil
+}
+
+func (p *OpenStackProvider) AllSSHKeys(ctx context.Context) ([]*types.SSHKey, error) {
+	var resp struct {
+		Keypairs []struct {
+			Keypair *Keypair `json:"keypair"`
+		} `json:"keypairs"`
+	}
+	if err := p.client.do(ctx, "GET", computeService, "/os-keypairs", nil, &resp); err != nil {
+		return nil, fmt.Errorf("error listing SSH keys: %w", err)
+	}
+	sshKeys := make([]*types.SSHKey, 0, len(resp.Keypairs))
+	for _, keypair := range resp.Keypairs {
+		sshKeys = append(sshKeys, mapSSHKey(keypair.Keypair))
+	}
+	return sshKeys, nil
+}
+
+func (p *OpenStackProvider) DeleteSSHKey(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus {
+	if name == "" {
+		return &types.SSHKeyDeleteStatus{
+			Error: fmt.Errorf("empty SSH key name provided"),
+		}
+	}
+	p.logger.Debug("deleting cloud SSH key",
+		"key", name)
+	err := p.client.do(ctx, "DELETE", computeService, "/os-keypairs/"+url.PathEscape(name), nil, nil)
+	if err != nil && !isNotFound(err) {
+		p.logger.Error("failed to delete cloud SSH key",
+			"key", name)
+		return &types.SSHKeyDeleteStatus{
+			Error: fmt.Errorf("error deleting SSH key: %w", err),
+		}
+	}
+	return &types.SSHKeyDeleteStatus{
+		Deleted: true,
+	}
+}
+
+func (p *OpenStackProvider) CloudKeyExists(ctx context.Context, name string) (bool, error) {
+	keypair, err := p.getKeypair(ctx, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to check SSH key existence: %w", err)
+	}
+	return keypair != nil, nil
+}
+
+// UpdateSSHKeyLabels does nothing, as keypairs have no metadata
+func (p *OpenStackProvider) UpdateSSHKeyLabels(ctx context.Context, name string, labels map[string]string) error {
+	return nil
+}
+
+// KeyNamesToSSHKeys converts a list of SSH key names to a list of SSH keys
+// It will upload local SSH keys to the cloud if they don't exist
+// It adds the default admin key to the list
+func (p *OpenStackProvider) KeyNamesToSSHKeys(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error) {
+	sshManager := ssh.NewManager(p.config)
+	sshKeys := make([]*types.SSHKey, 0)
+	adminKey, err := p.GetSSHKey(ctx, config.DefaultAdminKeyName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin key: %w", err)
+	}
+	sshKeys = append(sshKeys, adminKey)
+
+	for _, keyName := range keyNames {
+		cloudKeyExists, err := p.CloudKeyExists(ctx, keyName)
+		if err != nil {
+			return nil, fmt.Errorf("error checking if SSH key exists: %w", err)
+		}
+		if cloudKeyExists {
+			sshKey, err := p.GetSSHKey(ctx, keyName)
+			if err != nil {
+				return nil, err
+			}
+			sshKeys = append(sshKeys, sshKey)
+			continue
+		}
+		// check if the key exists locally
+		localKeyExists, err := sshManager.LocalKeyExists(keyName)
+		if err != nil {
+			return nil, err
+		}
+		if !localKeyExists {
+			fmt.Printf("SSH key %s not found locally, skipping it\n", keyName)
+			continue
+		}
+		pubKey, err := sshManager.ReadLocalPublicKey(keyName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local public key: %w", err)
+		}
+		opts.Name = keyName
+		opts.PublicKey = pubKey
+		newKey, err := p.CreateSSHKey(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SSH key: %w", err)
+		}
+		sshKeys = append(sshKeys, newKey)
+	}
+	return sshKeys, nil
+}
+
+// getKeypair returns the keypair or nil if it doesn't exist
+func (p *OpenStackProvider) getKeypair(ctx context.Context, name string) (*Keypair, error) {
+	var resp struct {
+		Keypair *Keypair `json:"keypair"`
+	}
+	if err := p.client.do(ctx, "GET", computeService, "/os-keypairs/"+url.PathEscape(name), nil, &resp); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return resp.Keypair, nil
+}
+
+func mapSSHKey(k *Keypair) *types.SSHKey {
+	if k == nil {
+		return nil
+	}
+
+	return &types.SSHKey{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "SSHKey",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name: k.Name,
+		},
+		Spec: types.SSHKeySpec{
+			PublicKey: k.PublicKey,
+		},
+		Status: types.SSHKeyStatus{
+			Created: parseTime(k.Created),
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package openstack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHKeys(t *testing.T) {
+	p, stub := newTestProvider(t)
+
+	_, err := p.GetSSHKey(context.Background(), "test-admin")
+	assert.ErrorContains(t, err, "SSH key not found")
+	exists, err := p.CloudKeyExists(context.Background(), "test-admin")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	key, err := p.CreateSSHKey(context.Background(), options.SSHKeyCreateOpts{Name: "test-admin", PublicKey: "ssh-ed25519 AAAA test-admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test-admin", key.ObjectMeta.Name)
+	assert.Equal(t, 2025, key.Status.Created.Year())
+
+	key, err = p.GetSSHKey(context.Background(), "test-admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh-ed25519 AAAA test-admin", key.Spec.PublicKey)
+
+	keys, err := p.AllSSHKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	// keypairs have no labels
+	keys, err = p.ListSSHKeys(context.Background(), options.SSHKeyListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	status := p.DeleteSSHKey(context.Background(), "test-admin", false)
+	assert.True(t, status.Deleted)
+	assert.Empty(t, stub.keypairs)
+	status = p.DeleteSSHKey(context.Background(), "test-admin", false)
+	assert.True(t, status.Deleted)
+}
+
+func TestKeyNamesToSSHKeys(t *testing.T) {
+	p, stub := newTestProvider(t)
+
+	_, err := p.KeyNamesToSSHKeys(context.Background(), []string{"user"}, options.SSHKeyCreateOpts{})
+	assert.ErrorContains(t, err, "failed to get admin key")
+
+	stub.keypairs[config.DefaultAdminKeyName] = &Keypair{Name: config.DefaultAdminKeyName, PublicKey: "ssh-ed25519 AAAA admin"}
+	stub.keypairs["user"] = &Keypair{Name: "user", PublicKey: "ssh-ed25519 AAAA user"}
+	keys, err := p.KeyNamesToSSHKeys(context.Background(), []string{"user", "missing"}, options.SSHKeyCreateOpts{})
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, config.DefaultAdminKeyName, keys[0].ObjectMeta.Name)
+		assert.Equal(t, "user", keys[1].ObjectMeta.Name)
+	}
+}
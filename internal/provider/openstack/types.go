@@ -0,0 +1,70 @@
+package openstack
+
+import "time"
+
+// Server is the part of the Nova server we use
+type Server struct {
+	ID               string                    `json:"id"`
+	Name             string                    `json:"name"`
+	Status           string                    `json:"status"`
+	Created          time.Time                 `json:"created"`
+	Metadata         map[string]string         `json:"metadata"`
+	Addresses        map[string][]Address      `json:"addresses"`
+	Flavor           Reference                 `json:"flavor"`
+	Image            Reference                 `json:"image"`
+	AvailabilityZone string                    `json:"OS-EXT-AZ:availability_zone"`
+	VolumesAttached  []Reference               `json:"os-extended-volumes:volumes_attached"`
+	Fault            *struct{ Message string } `json:"fault,omitempty"`
+}
+
+type Address struct {
+	Addr    string `json:"addr"`
+	Version int    `json:"version"`
+	Type    string `json:"OS-EXT-IPS:type"` // fixed or floating
+}
+
+// Reference is an ID reference to another resource
+type Reference struct {
+	ID string `json:"id"`
+}
+
+type Flavor struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	VCPUs int    `json:"vcpus"`
+	RAM   int    `json:"ram"`  // in MiB
+	Disk  int    `json:"disk"` // in GB
+}
+
+type Image struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Network struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Volume is the part of the Cinder volume we use
+type Volume struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Size        int                `json:"size"` // in GB
+	Status      string             `json:"status"`
+	Created     string             `json:"created_at"` // Cinder timestamps have no time zone
+	Metadata    map[string]string  `json:"metadata"`
+	Attachments []VolumeAttachment `json:"attachments"`
+	Zone        string             `json:"availability_zone"`
+}
+
+type VolumeAttachment struct {
+	ServerID string `json:"server_id"`
+	Device   string `json:"device"`
+}
+
+type Keypair struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"`
+	Created   string `json:"created_at,omitempty"`
+}
@@ -0,0 +1,352 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// extendMicroversion allows extending volumes attached to servers
+const extendMicroversion = "volume 3.42"
+
+func (p *OpenStackProvider) CreateVolume(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("volume name is required")
+	}
+	if opts.Size == 0 {
+		return nil, fmt.Errorf("volume size is required")
+	}
+	var server *Server
+	if opts.ServerName != "" {
+		var err error
+		server, err = p.findServer(ctx, opts.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("error getting server: %w", err)
+		}
+		if server == nil {
+			return nil, fmt.Errorf("server not found: %s", opts.ServerName)
+		}
+	}
+	existing, err := p.findVolume(ctx, opts.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("volume %s already exists", opts.Name)
+	}
+	metadata := opts.Labels
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	volumeOpts := map[string]any{
+		"name":     opts.Name,
+		"size":     opts.Size,
+		"metadata": metadata,
+	}
+	if server != nil && server.AvailabilityZone != "" {
+		volumeOpts["availability_zone"] = server.AvailabilityZone
+	}
+	p.logger.Debug("creating volume",
+		"name", opts.Name,
+		"size", opts.Size,
+		"server", opts.ServerName)
+	var resp struct {
+		Volume *Volume `json:"volume"`
+	}
+	if err := p.client.do(ctx, "POST", volumeService, "/volumes", map[string]any{"volume": volumeOpts}, &resp); err != nil {
+		return nil, fmt.Errorf("error creating volume: %w", err)
+	}
+	volume, err := p.waitForVolume(ctx, resp.Volume.ID, "available")
+	if err != nil {
+		return nil, err
+	}
+	serverName := ""
+	if server != nil {
+		attachment := map[string]any{"volumeAttachment": map[string]string{"volumeId": volume.ID}}
+		if err := p.client.do(ctx, "POST", computeService, "/servers/"+server.ID+"/os-volume_attachments", attachment, nil); err != nil {
+			return nil, fmt.Errorf("error attaching volume: %w", err)
+		}
+		if volume, err = p.waitForVolume(ctx, volume.ID, "in-use"); err != nil {
+			return nil, err
+		}
+		serverName = server.Name
+	}
+	p.logger.Debug("successfully created volume",
+		"name", opts.Name)
+	return mapVolume(volume, serverName), nil
+}
+
+func (p *OpenStackProvider) GetVolume(ctx context.Context, volumeName string) (*types.Volume, error) {
+	volume, err := p.findVolume(ctx, volumeName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume: %w", err)
+	}
+	if volume == nil {
+		return nil, nil
+	}
+	return p.mapVolumeWithServer(ctx, volume), nil
+}
+
+func (p *OpenStackProvider) ListVolumes(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+	volumes, err := p.listVolumes(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("error listing volumes: %w", err)
+	}
+	result := []*types.Volume{}
+	for _, volume := range volumes {
+		if labelutil.MatchSelector(volume.Metadata, opts.LabelSelector) {
+			result = append(result, p.mapVolumeWithServer(ctx, volume))
+		}
+	}
+	return result, nil
+}
+
+func (p *OpenStackProvider) AllVolumes(ctx context.Context) ([]*types.Volume, error) {
+	return p.ListVolumes(ctx, options.VolumeListOpts{})
+}
+
+func (p *OpenStackProvider) DeleteVolume(ctx context.Context, volumeName string, force bool) *types.VolumeDeleteStatus {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	if volumeName == "" {
+		return &types.VolumeDeleteStatus{
+			Error: fmt.Errorf("empty volume name provided"),
+		}
+	}
+	volume, err := p.findVolume(ctx, volumeName)
+	if err != nil {
+		return &types.VolumeDeleteStatus{
+			Error: fmt.Errorf("error getting volume: %w", err),
+		}
+	}
+	if volume == nil {
+		p.logger.Debug("Volume not found, skipping",
+			"volume", volumeName)
+		return &types.VolumeDeleteStatus{
+			Deleted: true,
+		}
+	}
+	deleteAfter := timeutil.ParseDeleteAfter(volume.Metadata["delete_after"])
+	if !force && time.Now().UTC().Before(deleteAfter) {
+		p.logger.Warn("volume not ready for deletion",
+			"volume", volumeName,
+			"delete_after", deleteAfter.Format("2006-01-02 15:04:05"))
+		return &types.VolumeDeleteStatus{
+			DeleteAfter: deleteAfter,
+		}
+	}
+	if len(volume.Attachments) > 0 {
+		for _, attachment := range volume.Attachments {
+			p.logger.Debug("volume is attached to server, detaching",
+				"volume", volume.Name,
+				"server", attachment.ServerID)
+			err := p.client.do(ctx, "DELETE", computeService, "/servers/"+attachment.ServerID+"/os-volume_attachments/"+volume.ID, nil, nil)
+			if err != nil && !isNotFound(err) {
+				return &types.VolumeDeleteStatus{
+					Error: fmt.Errorf("error detaching volume: %w", err),
+				}
+			}
+		}
+		if _, err := p.waitForVolume(ctx, volume.ID, "available"); err != nil {
+			return &types.VolumeDeleteStatus{Error: err}
+		}
+	}
+	if err := p.client.do(ctx, "DELETE", volumeService, "/volumes/"+volume.ID, nil, nil); err != nil && !isNotFound(err) {
+		return &types.VolumeDeleteStatus{
+			Error: fmt.Errorf("error deleting volume: %w", err),
+		}
+	}
+	return &types.VolumeDeleteStatus{
+		Deleted: true,
+	}
+}
+
+// ResizeVolume grows a volume to the new size in GB.
+// Cinder volumes can't be shrunk, so a smaller size returns an error.
+func (p *OpenStackProvider) ResizeVolume(ctx context.Context, volumeName string, size int) (*types.Volume, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	volume, err := p.findVolume(ctx, volumeName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume: %w", err)
+	}
+	if volume == nil {
+		return nil, fmt.Errorf("volume not found: %s", volumeName)
+	}
+	if size < volume.Size {
+		return nil, fmt.Errorf("volume %s can't be shrunk from %dGB to %dGB", volumeName, volume.Size, size)
+	}
+	if size == volume.Size {
+		return p.mapVolumeWithServer(ctx, volume), nil
+	}
+	p.logger.Debug("resizing volume",
+		"volume", volumeName,
+		"from", volume.Size,
+		"to", size)
+	status := volume.Status
+	action := map[string]any{"os-extend": map[string]int{"new_size": size}}
+	if err := p.client.do(ctx, "POST", volumeService, "/volumes/"+volume.ID+"/action", action, nil, "OpenStack-API-Version", extendMicroversion); err != nil {
+		return nil, fmt.Errorf("error resizing volume: %w", err)
+	}
+	volume, err = p.waitForVolumeFunc(ctx, volume.ID, fmt.Sprintf("%s with %dGB", status, size), func(v *Volume) bool {
+		return v.Status == status && v.Size == size
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.mapVolumeWithServer(ctx, volume), nil
+}
+
+// UpdateVolumeLabels merges the labels into the volume metadata
+func (p *OpenStackProvider) UpdateVolumeLabels(ctx context.Context, volumeName string, labels map[string]string) error {
+	volume, err := p.findVolume(ctx, volumeName)
+	if err != nil {
+		return fmt.Errorf("error getting volume: %w", err)
+	}
+	if volume == nil {
+		return fmt.Errorf("volume not found: %s", volumeName)
+	}
+	p.logger.Debug("updating volume metadata",
+		"volume", volumeName,
+		"labels", labels)
+	body := map[string]any{"metadata": labels}
+	if err := p.client.do(ctx, "POST", volumeService, "/volumes/"+volume.ID+"/metadata", body, nil); err != nil {
+		return fmt.Errorf("error updating volume metadata: %w", err)
+	}
+	return nil
+}
+
+// waitForVolume waits until the volume has the status
+func (p *OpenStackProvider) waitForVolume(ctx context.Context, id, status string) (*Volume, error) {
+	return p.waitForVolumeFunc(ctx, id, status, func(v *Volume) bool { return v.Status == status })
+}
+
+// waitForVolumeFunc waits until done returns true for the volume.
+// The state is only used in the timeout error.
+func (p *OpenStackProvider) waitForVolumeFunc(ctx context.Context, id, state string, done func(v *Volume) bool) (*Volume, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		volume, err := p.getVolume(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if done(volume) {
+			return volume, nil
+		}
+		if volume.Status == "error" || volume.Status == "error_extending" {
+			return nil, fmt.Errorf("volume %s failed with status %s", volume.Name, volume.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for volume %s to become %s", volume.Name, state)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *OpenStackProvider) getVolume(ctx context.Context, id string) (*Volume, error) {
+	var resp struct {
+		Volume *Volume `json:"volume"`
+	}
+	if err := p.client.do(ctx, "GET", volumeService, "/volumes/"+id, nil, &resp); err != nil {
+		return nil, fmt.Errorf("error getting volume %s: %w", id, err)
+	}
+	return resp.Volume, nil
+}
+
+// findVolume returns the volume with the name or nil if it doesn't exist
+func (p *OpenStackProvider) findVolume(ctx context.Context, name string) (*Volume, error) {
+	volumes, err := p.listVolumes(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, volume := range volumes {
+		if volume.Name == name {
+			return volume, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *OpenStackProvider) listVolumes(ctx context.Context, name string) ([]*Volume, error) {
+	path := "/volumes/detail"
+	if name != "" {
+		path += "?name=" + url.QueryEscape(name)
+	}
+	var resp struct {
+		Volumes []*Volume `json:"volumes"`
+	}
+	if err := p.client.do(ctx, "GET", volumeService, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Volumes, nil
+}
+
+// mapVolumeWithServer converts a Cinder volume looking up the name of the server it's attached to
+func (p *OpenStackProvider) mapVolumeWithServer(ctx context.Context, v *Volume) *types.Volume {
+	serverName := ""
+	if len(v.Attachments) > 0 {
+		var resp struct {
+			Server *Server `json:"server"`
+		}
+		if err := p.client.do(ctx, "GET", computeService, "/servers/"+v.Attachments[0].ServerID, nil, &resp); err != nil {
+			p.logger.Error("error getting server",
+				"server", v.Attachments[0].ServerID,
+				"error", err)
+		} else {
+			serverName = resp.Server.Name
+		}
+	}
+	return mapVolume(v, serverName)
+}
+
+// mapVolume converts a Cinder volume to our generic Volume type
+func mapVolume(v *Volume, serverName string) *types.Volume {
+	if v == nil {
+		return nil
+	}
+	return &types.Volume{
+		TypeMeta: types.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Volume",
+		},
+		ObjectMeta: types.ObjectMeta{
+			Name:   v.Name,
+			Labels: v.Metadata,
+		},
+		Spec: types.VolumeSpec{
+			Size:       v.Size,
+			ServerName: serverName,
+			Location:   v.Zone,
+			Provider:   "openstack",
+			Labels:     v.Metadata,
+		},
+		Status: types.VolumeStatus{
+			Status:      v.Status,
+			Owner:       v.Metadata["owner"],
+			Created:     parseTime(v.Created),
+			DeleteAfter: timeutil.ParseDeleteAfter(v.Metadata["delete_after"]),
+		},
+	}
+}
+
+// parseTime parses the Cinder and Nova keypair timestamps, which have no time zone
+func parseTime(value string) time.Time {
+	t, err := time.Parse("2006-01-02T15:04:05.999999", value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,98 @@
+package openstack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateVolume(t *testing.T) {
+	t.Run("server not found", func(t *testing.T) {
+		p, _ := newTestProvider(t)
+		_, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-cp-volume-1", Size: 10, ServerName: "test-cp"})
+		assert.ErrorContains(t, err, "server not found")
+	})
+
+	t.Run("attaches to server", func(t *testing.T) {
+		p, stub := newTestProvider(t)
+		stub.servers["a"] = &Server{ID: "a", Name: "test-cp", Status: "ACTIVE", Flavor: Reference{ID: "f1"}, AvailabilityZone: "az1"}
+
+		volume, err := p.CreateVolume(context.Background(), options.VolumeCreateOpts{
+			Name:       "test-cp-volume-1",
+			Size:       10,
+			ServerName: "test-cp",
+			Labels:     map[string]string{"lab_name": "test"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "az1", stub.lastRequest("volume")["availability_zone"])
+		if assert.NotNil(t, volume) {
+			assert.Equal(t, "in-use", volume.Status.Status)
+			assert.Equal(t, "test-cp", volume.Spec.ServerName)
+			assert.Equal(t, "test", volume.ObjectMeta.Labels["lab_name"])
+			assert.Equal(t, 2025, volume.Status.Created.Year())
+		}
+
+		volume, err = p.GetVolume(context.Background(), "test-cp-volume-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "test-cp", volume.Spec.ServerName)
+
+		_, err = p.CreateVolume(context.Background(), options.VolumeCreateOpts{Name: "test-cp-volume-1", Size: 10})
+		assert.ErrorContains(t, err, "already exists")
+	})
+}
+
+func TestListVolumes(t *testing.T) {
+	p, stub := newTestProvider(t)
+	stub.volumes["a"] = &Volume{ID: "a", Name: "a", Size: 10, Status: "available", Metadata: map[string]string{"lab_name": "test"}}
+	stub.volumes["b"] = &Volume{ID: "b", Name: "b", Size: 10, Status: "available", Metadata: map[string]string{"lab_name": "other"}}
+
+	volumes, err := p.ListVolumes(context.Background(), options.VolumeListOpts{ListOpts: options.ListOpts{LabelSelector: "lab_name=test"}})
+	assert.NoError(t, err)
+	if assert.Len(t, volumes, 1) {
+		assert.Equal(t, "a", volumes[0].ObjectMeta.Name)
+	}
+}
+
+func TestResizeVolume(t *testing.T) {
+	p, stub := newTestProvider(t)
+	stub.servers["s"] = &Server{ID: "s", Name: "test-cp"}
+	stub.volumes["v"] = &Volume{ID: "v", Name: "test-cp-volume-1", Size: 10, Status: "in-use", Attachments: []VolumeAttachment{{ServerID: "s"}}}
+
+	_, err := p.ResizeVolume(context.Background(), "test-cp-volume-1", 5)
+	assert.ErrorContains(t, err, "can't be shrunk")
+
+	volume, err := p.ResizeVolume(context.Background(), "test-cp-volume-1", 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, volume.Spec.Size)
+	assert.Equal(t, "test-cp", volume.Spec.ServerName)
+
+	_, err = p.ResizeVolume(context.Background(), "missing", 20)
+	assert.ErrorContains(t, err, "volume not found")
+}
+
+func TestDeleteVolume(t *testing.T) {
+	p, stub := newTestProvider(t)
+	stub.servers["s"] = &Server{ID: "s", Name: "test-cp", VolumesAttached: []Reference{{ID: "v"}}}
+	stub.volumes["v"] = &Volume{
+		ID:          "v",
+		Name:        "test-cp-volume-1",
+		Size:        10,
+		Status:      "in-use",
+		Metadata:    map[string]string{"delete_after": "2099-01-01-00-00"},
+		Attachments: []VolumeAttachment{{ServerID: "s"}},
+	}
+
+	status := p.DeleteVolume(context.Background(), "test-cp-volume-1", false)
+	assert.False(t, status.Deleted)
+	assert.Len(t, stub.volumes, 1)
+
+	status = p.DeleteVolume(context.Background(), "test-cp-volume-1", true)
+	assert.True(t, status.Deleted)
+	assert.NoError(t, status.Error)
+	assert.Empty(t, stub.volumes)
+
+	status = p.DeleteVolume(context.Background(), "test-cp-volume-1", true)
+	assert.True(t, status.Deleted)
+}
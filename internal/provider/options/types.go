@@ -32,6 +32,13 @@ type VolumeCreateOpts struct {
 	Format     string
 }
 
+// SnapshotOpts are the options for the server and volume snapshots of a lab backup
+type SnapshotOpts struct {
+	Description string
+	Labels      map[string]string
+	Dir         string // where copies of the volumes are saved
+}
+
 type SSHKeyCreateOpts struct {
 	Name      string
 	PublicKey string
@@ -1,32 +1,55 @@
 package provider
 
 import (
+	"context"
 	"github.com/pavelanni/storctl/internal/provider/options"
 	"github.com/pavelanni/storctl/internal/types"
 )
 
 type CloudProvider interface {
 	Name() string
+	// Capabilities describe how the lab manager works with the provider
+	Capabilities() types.ProviderCapabilities
 	// Server operations
-	CreateServer(opts options.ServerCreateOpts) (*types.Server, error)
-	GetServer(name string) (*types.Server, error)
-	ListServers(opts options.ServerListOpts) ([]*types.Server, error)
-	AllServers() ([]*types.Server, error)
-	DeleteServer(name string, force bool) *types.ServerDeleteStatus
-	ServerToCreateOpts(server *types.Server) (options.ServerCreateOpts, error)
+	CreateServer(ctx context.Context, opts options.ServerCreateOpts) (*types.Server, error)
+	GetServer(ctx context.Context, name string) (*types.Server, error)
+	ListServers(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error)
+	AllServers(ctx context.Context) ([]*types.Server, error)
+	DeleteServer(ctx context.Context, name string, force bool) *types.ServerDeleteStatus
+	ServerToCreateOpts(ctx context.Context, server *types.Server) (options.ServerCreateOpts, error)
+	// UpdateServerLabels merges the labels into the labels of an existing server
+	UpdateServerLabels(ctx context.Context, name string, labels map[string]string) error
 	// Volume operations
-	CreateVolume(opts options.VolumeCreateOpts) (*types.Volume, error)
-	GetVolume(name string) (*types.Volume, error)
-	ListVolumes(opts options.VolumeListOpts) ([]*types.Volume, error)
-	AllVolumes() ([]*types.Volume, error)
-	DeleteVolume(name string, force bool) *types.VolumeDeleteStatus
+	CreateVolume(ctx context.Context, opts options.VolumeCreateOpts) (*types.Volume, error)
+	GetVolume(ctx context.Context, name string) (*types.Volume, error)
+	ListVolumes(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error)
+	AllVolumes(ctx context.Context) ([]*types.Volume, error)
+	DeleteVolume(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus
+	ResizeVolume(ctx context.Context, name string, size int) (*types.Volume, error)
+	UpdateVolumeLabels(ctx context.Context, name string, labels map[string]string) error
 
 	// SSH Key operations
-	CreateSSHKey(opts options.SSHKeyCreateOpts) (*types.SSHKey, error)
-	GetSSHKey(name string) (*types.SSHKey, error)
-	ListSSHKeys(opts options.SSHKeyListOpts) ([]*types.SSHKey, error)
-	AllSSHKeys() ([]*types.SSHKey, error)
-	DeleteSSHKey(name string, force bool) *types.SSHKeyDeleteStatus
-	CloudKeyExists(name string) (bool, error)
-	KeyNamesToSSHKeys(keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error)
+	CreateSSHKey(ctx context.Context, opts options.SSHKeyCreateOpts) (*types.SSHKey, error)
+	GetSSHKey(ctx context.Context, name string) (*types.SSHKey, error)
+	ListSSHKeys(ctx context.Context, opts options.SSHKeyListOpts) ([]*types.SSHKey, error)
+	AllSSHKeys(ctx context.Context) ([]*types.SSHKey, error)
+	DeleteSSHKey(ctx context.Context, name string, force bool) *types.SSHKeyDeleteStatus
+	CloudKeyExists(ctx context.Context, name string) (bool, error)
+	KeyNamesToSSHKeys(ctx context.Context, keyNames []string, opts options.SSHKeyCreateOpts) ([]*types.SSHKey, error)
+	UpdateSSHKeyLabels(ctx context.Context, name string, labels map[string]string) error
+}
+
+// ServerSnapshotter is implemented by the providers that can save server disks as images for lab backups
+type ServerSnapshotter interface {
+	// SnapshotServer saves the server disk and returns the image to create servers from
+	SnapshotServer(ctx context.Context, name string, opts options.SnapshotOpts) (string, error)
+	DeleteServerSnapshot(ctx context.Context, image string) error
+}
+
+// VolumeSnapshotter is implemented by the providers that can copy volumes for lab backups
+type VolumeSnapshotter interface {
+	// SnapshotVolume copies the volume into opts.Dir and returns the path of the copy
+	SnapshotVolume(ctx context.Context, name string, opts options.SnapshotOpts) (string, error)
+	// RestoreVolume creates the volume from the copy
+	RestoreVolume(ctx context.Context, snapshot string, opts options.VolumeCreateOpts) (*types.Volume, error)
 }
@@ -0,0 +1,132 @@
+// Package volumestore keeps the volume metadata for the providers whose volumes have no labels.
+// Each volume is a JSON file with its size, attachment and labels, next to the volume files if the provider keeps them.
+package volumestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+)
+
+// Meta is stored for each volume
+type Meta struct {
+	Name    string            `json:"name"`
+	Size    int               `json:"size"` // in GiB
+	Server  string            `json:"server,omitempty"`
+	Device  string            `json:"device,omitempty"` // loop device in the server container
+	Target  string            `json:"target,omitempty"` // virtio device in the libvirt server, like vdb
+	Format  string            `json:"format,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Created time.Time         `json:"created"`
+}
+
+type Store struct {
+	dir string
+}
+
+// New returns a volume metadata store in the directory
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the store
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// File returns the metadata file of the volume
+func (s *Store) File(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// Read returns the volume metadata or nil if the volume doesn't exist
+func (s *Store) Read(name string) (*Meta, error) {
+	data, err := os.ReadFile(s.File(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading volume metadata: %w", err)
+	}
+	meta := &Meta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("error unmarshalling volume metadata %s: %w", name, err)
+	}
+	return meta, nil
+}
+
+// Write writes the metadata to a temporary file and renames it,
+// so List running in parallel never reads a partly written file
+func (s *Store) Write(meta *Meta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling volume metadata: %w", err)
+	}
+	// the temporary file doesn't match the *.json files List reads
+	file, err := os.CreateTemp(s.dir, "."+meta.Name+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing volume metadata: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), s.File(meta.Name))
+	}
+	if err != nil {
+		return fmt.Errorf("error writing volume metadata: %w", err)
+	}
+	return nil
+}
+
+// List returns the metadata of all volumes sorted by name
+func (s *Store) List() ([]*Meta, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing volumes: %w", err)
+	}
+	sort.Strings(files)
+	metas := make([]*Meta, 0, len(files))
+	for _, file := range files {
+		meta, err := s.Read(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if meta != nil {
+			metas = append(metas, meta)
+		}
+	}
+	return metas, nil
+}
+
+// UpdateLabels merges the labels into the volume metadata
+func (s *Store) UpdateLabels(name string, labels map[string]string) error {
+	meta, err := s.Read(name)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return fmt.Errorf("volume %s not found", name)
+	}
+	meta.Labels = labelutil.MergeLabels(meta.Labels, labels)
+	return s.Write(meta)
+}
+
+// Delete deletes the volume metadata. Deleting missing metadata is a no-op.
+func (s *Store) Delete(name string) error {
+	if err := os.Remove(s.File(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting volume metadata: %w", err)
+	}
+	return nil
+}
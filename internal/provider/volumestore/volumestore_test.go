@@ -0,0 +1,68 @@
+package volumestore
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMissingVolume(t *testing.T) {
+	s := New(t.TempDir())
+	meta, err := s.Read("test-volume-01")
+	assert.NoError(t, err)
+	assert.Nil(t, meta)
+	assert.NoError(t, s.Delete("test-volume-01"))
+	assert.Error(t, s.UpdateLabels("test-volume-01", map[string]string{"owner": "test"}))
+}
+
+func TestUpdateLabels(t *testing.T) {
+	s := New(t.TempDir())
+	assert.NoError(t, s.Write(&Meta{Name: "test-volume-01", Size: 1, Target: "vdb", Labels: map[string]string{"lab_name": "test"}}))
+	assert.NoError(t, s.UpdateLabels("test-volume-01", map[string]string{"owner": "test"}))
+
+	meta, err := s.Read("test-volume-01")
+	assert.NoError(t, err)
+	assert.Equal(t, "vdb", meta.Target)
+	assert.Equal(t, map[string]string{"lab_name": "test", "owner": "test"}, meta.Labels)
+}
+
+func TestWriteConcurrently(t *testing.T) {
+	s := New(t.TempDir())
+	names := []string{"test-volume-01", "test-volume-02", "test-volume-03", "test-volume-04"}
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, s.Write(&Meta{Name: name, Size: i + 1, Labels: map[string]string{"lab_name": "test"}}))
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		_, err := s.List()
+		assert.NoError(t, err, "a meta file is read while it's written")
+	}
+
+	metas, err := s.List()
+	assert.NoError(t, err)
+	assert.Len(t, metas, len(names))
+	for _, meta := range metas {
+		assert.Equal(t, 50, meta.Size)
+	}
+	leftovers, err := filepath.Glob(filepath.Join(s.Dir(), "*.tmp"))
+	assert.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
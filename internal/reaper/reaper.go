@@ -0,0 +1,226 @@
+// Package reaper deletes the labs and resources whose TTL has expired.
+// It reads the delete_after labels from the providers, so expired labs are found
+// even if they are not in the local lab storage.
+package reaper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/logger"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+)
+
+// Opts are the options of the reaper
+type Opts struct {
+	// DryRun only logs the labs and resources that would be deleted
+	DryRun bool
+	// Owner limits the reaper to the labs and resources of this owner, empty means all.
+	// It's sanitized like the owner label, so the owner from the config file matches.
+	Owner string
+	// Grace is the period before the expiration when a warning is logged
+	Grace time.Duration
+}
+
+// Reaper deletes the expired labs of each provider through its lab manager
+type Reaper struct {
+	Managers []*lab.ManagerSvc
+	Opts     Opts
+	Logger   *slog.Logger
+	now      func() time.Time
+}
+
+// Report lists what a reaper pass found
+type Report struct {
+	Deleted  []string // expired labs and resources, deleted or, in dry-run mode, to be deleted
+	Expiring []string // labs and resources that expire within the grace period
+}
+
+// resource is a provider resource with its labels
+type resource struct {
+	kind   string
+	name   string
+	labels map[string]string
+}
+
+// New returns a reaper for the lab managers, one for each provider
+func New(managers []*lab.ManagerSvc, opts Opts) *Reaper {
+	return &Reaper{
+		Managers: managers,
+		Opts:     opts,
+		Logger:   logger.Get(),
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run reaps the expired labs every interval until ctx is canceled.
+// Errors are logged and don't stop the reaper.
+func (r *Reaper) Run(ctx context.Context, interval time.Duration) error {
+	r.Logger.Info("reaper started", "interval", interval, "dry_run", r.Opts.DryRun, "owner", r.Opts.Owner)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Reap(ctx); err != nil {
+			r.Logger.Error("reaper pass failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			r.Logger.Info("reaper stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reap makes one pass over all providers.
+// It returns the errors of all providers joined.
+func (r *Reaper) Reap(ctx context.Context) (*Report, error) {
+	report := &Report{}
+	var errs []error
+	for _, m := range r.Managers {
+		if err := r.reapProvider(ctx, m, report); err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: %w", m.Provider.Name(), err))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// reapProvider deletes the expired labs of the provider with the lab manager
+// and the expired resources that don't belong to a lab with the provider
+func (r *Reaper) reapProvider(ctx context.Context, m *lab.ManagerSvc, report *Report) error {
+	providerName := m.Provider.Name()
+	if !m.Provider.Capabilities().SupportsTTLLabels {
+		r.Logger.Debug("provider has no TTL labels, skipping", "provider", providerName)
+		return nil
+	}
+	resources, err := listResources(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	// a lab expires when its first resource expires
+	labs := make(map[string]resource)
+	orphans := make([]resource, 0)
+	for _, res := range resources {
+		labName := res.labels["lab_name"]
+		if labName == "" {
+			orphans = append(orphans, res)
+			continue
+		}
+		if existing, ok := labs[labName]; !ok || expiresFirst(res, existing) {
+			labs[labName] = resource{kind: "Lab", name: labName, labels: res.labels}
+		}
+	}
+	labNames := make([]string, 0, len(labs))
+	for labName := range labs {
+		labNames = append(labNames, labName)
+	}
+	sort.Strings(labNames)
+
+	var errs []error
+	for _, labName := range labNames {
+		res := labs[labName]
+		if !r.expired(providerName, res, report) {
+			continue
+		}
+		if err := m.Delete(ctx, labName, false); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete lab %s: %w", labName, err))
+		}
+	}
+	for _, res := range orphans {
+		if !r.expired(providerName, res, report) {
+			continue
+		}
+		if err := deleteResource(ctx, m, res); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", res.kind, res.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// expired returns true if the resource must be deleted now.
+// Resources without a TTL or of other owners are skipped,
+// resources that expire within the grace period are logged.
+func (r *Reaper) expired(providerName string, res resource, report *Report) bool {
+	if r.Opts.Owner != "" && res.labels["owner"] != labelutil.SanitizeValue(r.Opts.Owner) {
+		return false
+	}
+	expires := deleteAfter(res)
+	if expires.IsZero() {
+		return false
+	}
+	now := r.now()
+	name := res.kind + " " + res.name
+	if expires.After(now) {
+		if left := expires.Sub(now); left <= r.Opts.Grace {
+			r.Logger.Warn("expires soon", "resource", name, "provider", providerName,
+				"owner", res.labels["owner"], "delete_after", expires, "left", left.Round(time.Minute))
+			report.Expiring = append(report.Expiring, name)
+		}
+		return false
+	}
+	report.Deleted = append(report.Deleted, name)
+	if r.Opts.DryRun {
+		fmt.Printf("Would delete expired %s on %s, expired at %s\n", name, providerName, expires.Format(time.RFC3339))
+		return false
+	}
+	fmt.Printf("Deleting expired %s on %s, expired at %s...\n", name, providerName, expires.Format(time.RFC3339))
+	return true
+}
+
+// listResources returns all servers, volumes, and SSH keys of the provider
+func listResources(ctx context.Context, m *lab.ManagerSvc) ([]resource, error) {
+	servers, err := m.Provider.AllServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+	volumes, err := m.Provider.AllVolumes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	keys, err := m.Provider.AllSSHKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SSH keys: %w", err)
+	}
+	resources := make([]resource, 0, len(servers)+len(volumes)+len(keys))
+	for _, server := range servers {
+		resources = append(resources, resource{kind: "Server", name: server.ObjectMeta.Name, labels: server.ObjectMeta.Labels})
+	}
+	for _, volume := range volumes {
+		resources = append(resources, resource{kind: "Volume", name: volume.ObjectMeta.Name, labels: volume.ObjectMeta.Labels})
+	}
+	for _, key := range keys {
+		resources = append(resources, resource{kind: "SSHKey", name: key.ObjectMeta.Name, labels: key.Spec.Labels})
+	}
+	return resources, nil
+}
+
+// deleteResource deletes a resource that doesn't belong to a lab.
+// The provider checks the TTL again.
+func deleteResource(ctx context.Context, m *lab.ManagerSvc, res resource) error {
+	switch res.kind {
+	case "Server":
+		return m.Provider.DeleteServer(ctx, res.name, false).Error
+	case "Volume":
+		return m.Provider.DeleteVolume(ctx, res.name, false).Error
+	case "SSHKey":
+		return m.Provider.DeleteSSHKey(ctx, res.name, false).Error
+	}
+	return fmt.Errorf("unknown resource kind %s", res.kind)
+}
+
+func deleteAfter(res resource) time.Time {
+	return timeutil.ParseDeleteAfter(res.labels["delete_after"])
+}
+
+// expiresFirst returns true if a has a TTL that ends before the TTL of b or b has no TTL
+func expiresFirst(a, b resource) bool {
+	aExpires, bExpires := deleteAfter(a), deleteAfter(b)
+	return !aExpires.IsZero() && (bExpires.IsZero() || aExpires.Before(bExpires))
+}
@@ -0,0 +1,157 @@
+package reaper
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavelanni/storctl/internal/config"
+	"github.com/pavelanni/storctl/internal/lab"
+	"github.com/pavelanni/storctl/internal/provider/mock"
+	"github.com/pavelanni/storctl/internal/provider/options"
+	"github.com/pavelanni/storctl/internal/types"
+	"github.com/pavelanni/storctl/internal/util/labelutil"
+	"github.com/pavelanni/storctl/internal/util/timeutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func testLabels(labName, owner string, deleteAfter time.Time) map[string]string {
+	labels := map[string]string{"owner": owner, "delete_after": timeutil.FormatDeleteAfter(deleteAfter)}
+	if labName != "" {
+		labels["lab_name"] = labName
+	}
+	return labels
+}
+
+// newTestReaper returns a reaper for a provider with an expired lab, a lab that expires soon,
+// a lab that expires later, and an expired volume without a lab
+func newTestReaper(t *testing.T, opts Opts) (*Reaper, *[]string) {
+	servers := []*types.Server{
+		{ObjectMeta: types.ObjectMeta{Name: "old-cp", Labels: testLabels("old", "alice", testNow.Add(-time.Hour))}},
+		{ObjectMeta: types.ObjectMeta{Name: "soon-cp", Labels: testLabels("soon", "bobexamplecom", testNow.Add(30*time.Minute))}},
+		{ObjectMeta: types.ObjectMeta{Name: "later-cp", Labels: testLabels("later", "alice", testNow.Add(48*time.Hour))}},
+		{ObjectMeta: types.ObjectMeta{Name: "pet"}},
+	}
+	for _, server := range servers {
+		server.Status.DeleteAfter = timeutil.ParseDeleteAfter(server.ObjectMeta.Labels["delete_after"])
+	}
+	volumes := []*types.Volume{
+		{ObjectMeta: types.ObjectMeta{Name: "old-volume-01", Labels: testLabels("old", "alice", testNow.Add(-time.Hour))}},
+		{ObjectMeta: types.ObjectMeta{Name: "scratch", Labels: testLabels("", "bobexamplecom", testNow.Add(-time.Minute))}},
+	}
+	var deleted []string
+	provider := &mock.MockProvider{
+		NameFunc: func() string { return "hetzner" },
+		CapabilitiesFunc: func() types.ProviderCapabilities {
+			return types.ProviderCapabilities{SupportsLabels: true, SupportsTTLLabels: true, VolumesAttachAfterBoot: true, ManagesSSHKeys: true}
+		},
+		AllServersFunc: func(ctx context.Context) ([]*types.Server, error) { return servers, nil },
+		AllVolumesFunc: func(ctx context.Context) ([]*types.Volume, error) { return volumes, nil },
+		ListServersFunc: func(ctx context.Context, opts options.ServerListOpts) ([]*types.Server, error) {
+			selected := make([]*types.Server, 0)
+			for _, server := range servers {
+				if labelutil.MatchSelector(server.ObjectMeta.Labels, opts.LabelSelector) {
+					selected = append(selected, server)
+				}
+			}
+			return selected, nil
+		},
+		ListVolumesFunc: func(ctx context.Context, opts options.VolumeListOpts) ([]*types.Volume, error) {
+			selected := make([]*types.Volume, 0)
+			for _, volume := range volumes {
+				if labelutil.MatchSelector(volume.ObjectMeta.Labels, opts.LabelSelector) {
+					selected = append(selected, volume)
+				}
+			}
+			return selected, nil
+		},
+		DeleteServerFunc: func(ctx context.Context, name string, force bool) *types.ServerDeleteStatus {
+			deleted = append(deleted, "Server "+name)
+			return &types.ServerDeleteStatus{Deleted: true}
+		},
+		DeleteVolumeFunc: func(ctx context.Context, name string, force bool) *types.VolumeDeleteStatus {
+			assert.False(t, force)
+			deleted = append(deleted, "Volume "+name)
+			return &types.VolumeDeleteStatus{Deleted: true}
+		},
+	}
+	cfg := &config.Config{Storage: config.StorageConfig{Path: filepath.Join(t.TempDir(), "labs.db"), Bucket: "labs"}}
+	storage, err := lab.NewLabStorage(cfg)
+	assert.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+
+	r := New([]*lab.ManagerSvc{lab.NewManagerWithStorage(provider, cfg, storage)}, opts)
+	r.now = func() time.Time { return testNow }
+	return r, &deleted
+}
+
+func TestReap(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         Opts
+		wantReport   *Report
+		wantProvider []string
+	}{
+		{
+			name: "deletes expired labs and resources",
+			opts: Opts{Grace: time.Hour},
+			wantReport: &Report{
+				Deleted:  []string{"Lab old", "Volume scratch"},
+				Expiring: []string{"Lab soon"},
+			},
+			wantProvider: []string{"Volume old-volume-01", "Server old-cp", "Volume scratch"},
+		},
+		{
+			name: "dry run",
+			opts: Opts{DryRun: true},
+			wantReport: &Report{
+				Deleted: []string{"Lab old", "Volume scratch"},
+			},
+		},
+		{
+			name: "owner filter",
+			opts: Opts{Owner: "bob@example.com", Grace: time.Hour},
+			wantReport: &Report{
+				Deleted:  []string{"Volume scratch"},
+				Expiring: []string{"Lab soon"},
+			},
+			wantProvider: []string{"Volume scratch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, deleted := newTestReaper(t, tt.opts)
+			report, err := r.Reap(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantReport, report)
+			assert.Equal(t, tt.wantProvider, *deleted)
+		})
+	}
+}
+
+func TestReapSkipsProvidersWithoutTTL(t *testing.T) {
+	provider := &mock.MockProvider{
+		NameFunc: func() string { return "lima" },
+		AllServersFunc: func(ctx context.Context) ([]*types.Server, error) {
+			t.Error("servers of a provider without TTL labels should not be listed")
+			return nil, nil
+		},
+	}
+	r := New([]*lab.ManagerSvc{{Provider: provider}}, Opts{})
+	report, err := r.Reap(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, report.Deleted)
+}
+
+func TestRun(t *testing.T) {
+	r, deleted := newTestReaper(t, Opts{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// one pass runs before the canceled context stops the reaper
+	assert.NoError(t, r.Run(ctx, time.Hour))
+	assert.Contains(t, *deleted, "Server old-cp")
+}
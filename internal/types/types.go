@@ -14,6 +14,9 @@ type TypeMeta struct {
 type ObjectMeta struct {
 	Name   string            `json:"name,omitempty"`
 	Labels map[string]string `json:"labels,omitempty"`
+	// ResourceVersion is the version of the stored object this one was read or saved as.
+	// It isn't stored; the shared lab storage uses it to save the object through another client.
+	ResourceVersion string `json:"-"`
 }
 
 type Server struct {
@@ -103,12 +106,81 @@ type LabSpec struct {
 }
 
 type LabStatus struct {
-	State       string    `json:"state"`
-	Owner       string    `json:"owner"`
-	Servers     []*Server `json:"servers"`
-	Volumes     []*Volume `json:"volumes"`
-	Created     time.Time `json:"created"`
-	DeleteAfter time.Time `json:"deleteAfter"`
+	State       string          `json:"state"`
+	Owner       string          `json:"owner"`
+	Servers     []*Server       `json:"servers"`
+	Volumes     []*Volume       `json:"volumes"`
+	Created     time.Time       `json:"created"`
+	DeleteAfter time.Time       `json:"deleteAfter"`
+	Resources   []*LabResource  `json:"resources,omitempty"`  // resources created for the lab, in creation order
+	Phases      []*LabPhase     `json:"phases,omitempty"`     // lab creation progress
+	Error       string          `json:"error,omitempty"`      // why the lab is in the Failed state
+	Extensions  []*LabExtension `json:"extensions,omitempty"` // TTL extensions, oldest first
+	// PlaybookRuns are the latest Ansible playbook runs, oldest first
+	PlaybookRuns []*PlaybookRun `json:"playbookRuns,omitempty"`
+	// ExpiryNotified is the delete_after the owner was notified about, so each deletion time is notified once
+	ExpiryNotified time.Time `json:"expiryNotified,omitempty"`
+}
+
+// LabExtension records who pushed the lab delete_after forward
+type LabExtension struct {
+	By          string    `json:"by"`
+	At          time.Time `json:"at"`
+	DeleteAfter time.Time `json:"deleteAfter"` // the new delete_after
+}
+
+// PlaybookRun is a run of an Ansible playbook on the lab
+type PlaybookRun struct {
+	Playbook    string        `json:"playbook"`
+	LogPath     string        `json:"log"` // path to the run's log file; the JSON name is kept for the stored labs
+	Started     time.Time     `json:"started"`
+	Finished    time.Time     `json:"finished,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	ExitCode    int           `json:"exitCode"`
+	FailedTasks []string      `json:"failedTasks,omitempty"` // task (host)
+}
+
+// Lab states set by storctl; otherwise the state is the provider status of the lab servers
+const (
+	// LabStateFailed is the state of a lab whose creation failed and wasn't rolled back
+	LabStateFailed = "Failed"
+	// LabStateGone is the state of a lab whose resources weren't found by sync
+	LabStateGone = "Gone"
+	// LabStateDiscovered is the state of a lab found by sync that wasn't created with this storage
+	LabStateDiscovered = "Discovered"
+)
+
+// Lab creation phases
+const (
+	PhaseKeys      = "keys"
+	PhaseServers   = "servers"
+	PhaseReadiness = "readiness"
+	PhaseVolumes   = "volumes"
+	PhaseDNS       = "dns"
+	PhaseInventory = "inventory"
+	PhasePlaybook  = "playbook"
+)
+
+// Lab creation phase states
+const (
+	PhaseRunning = "Running"
+	PhaseDone    = "Done"
+	PhaseFailed  = "Failed"
+)
+
+// LabPhase is a step of lab creation
+type LabPhase struct {
+	Name     string    `json:"name"`
+	State    string    `json:"state"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// LabResource is a resource created for a lab
+type LabResource struct {
+	Kind string `json:"kind"` // SSHKey, LocalSSHKey, Server, or Volume
+	Name string `json:"name"`
 }
 
 type LabServerSpec struct {
@@ -192,3 +264,27 @@ type PublicNet struct {
 type IPv4 struct {
 	IP string `json:"ip"`
 }
+
+// ProviderCapabilities describe how a provider creates and deletes lab resources.
+// The lab manager uses them instead of checking the provider name.
+type ProviderCapabilities struct {
+	// VolumesAttachAfterBoot is true if volumes are attached to running servers.
+	// Otherwise volumes are created first and attached as disks when the servers are created.
+	VolumesAttachAfterBoot bool `json:"volumesAttachAfterBoot"`
+	// ReadinessCheck is true if new servers have to be checked over SSH before volumes are attached
+	ReadinessCheck bool `json:"readinessCheck"`
+	// NeedsPublicDNS is true if the servers get public DNS records and TLS certificates
+	NeedsPublicDNS bool `json:"needsPublicDNS"`
+	// SupportsLabels is true if the resources keep their labels, so labs can be found by the lab_name label
+	SupportsLabels bool `json:"supportsLabels"`
+	// SupportsTTLLabels is true if the resources are kept until their delete_after label unless forced
+	SupportsTTLLabels bool `json:"supportsTTLLabels"`
+	// MutableLabels is true if the labels of existing resources can be changed, so labs can be extended
+	MutableLabels bool `json:"mutableLabels"`
+	// ManagesSSHKeys is true if the lab admin key is uploaded to the provider and passed to the servers
+	ManagesSSHKeys bool `json:"managesSSHKeys"`
+	// SSHUser is the user to log in to the servers as
+	SSHUser string `json:"sshUser"`
+	// SSHKeyPath is the private key to log in to the servers with; the lab admin key if empty
+	SSHKeyPath string `json:"sshKeyPath,omitempty"`
+}
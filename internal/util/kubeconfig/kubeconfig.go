@@ -0,0 +1,183 @@
+// Package kubeconfig reads, rewrites, and merges kubeconfig files.
+// Only the fields it changes are typed; everything else is kept as is.
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a kubeconfig file
+type Config struct {
+	APIVersion     string         `yaml:"apiVersion,omitempty"`
+	Kind           string         `yaml:"kind,omitempty"`
+	Clusters       []NamedEntry   `yaml:"clusters"`
+	Contexts       []NamedEntry   `yaml:"contexts"`
+	Users          []NamedEntry   `yaml:"users"`
+	CurrentContext string         `yaml:"current-context"`
+	Rest           map[string]any `yaml:",inline"`
+}
+
+// NamedEntry is a cluster, context, or user with its name
+type NamedEntry struct {
+	Name    string         `yaml:"name"`
+	Cluster map[string]any `yaml:"cluster,omitempty"`
+	Context map[string]any `yaml:"context,omitempty"`
+	User    map[string]any `yaml:"user,omitempty"`
+}
+
+// DefaultPath returns ~/.kube/config
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".kube", "config"), nil
+}
+
+// Parse parses the kubeconfig data
+func Parse(data []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("error parsing kubeconfig: %w", err)
+	}
+	return c, nil
+}
+
+// Load reads the kubeconfig file. A missing file is an empty config.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{APIVersion: "v1", Kind: "Config"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading kubeconfig %s: %w", path, err)
+	}
+	return Parse(data)
+}
+
+// Save writes the kubeconfig file readable only by the user, creating its directory
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error marshalling kubeconfig: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing kubeconfig %s: %w", path, err)
+	}
+	return nil
+}
+
+// SetServerHost replaces the host in the server URLs of all clusters, keeping the scheme and port.
+// It returns true if any URL changed.
+func (c *Config) SetServerHost(host string) (bool, error) {
+	changed := false
+	for _, cluster := range c.Clusters {
+		server, ok := cluster.Cluster["server"].(string)
+		if !ok {
+			continue
+		}
+		u, err := url.Parse(server)
+		if err != nil {
+			return false, fmt.Errorf("invalid server URL of cluster %s: %w", cluster.Name, err)
+		}
+		if port := u.Port(); port != "" {
+			u.Host = net.JoinHostPort(host, port)
+		} else {
+			u.Host = host
+		}
+		if u.String() != server {
+			cluster.Cluster["server"] = u.String()
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// Merge adds the current context of src with its cluster and user to c, all under the given name.
+// Entries with the same name are replaced.
+func (c *Config) Merge(src *Config, name string) error {
+	contextEntry := find(src.Contexts, src.CurrentContext)
+	if contextEntry == nil {
+		if len(src.Contexts) != 1 {
+			return fmt.Errorf("kubeconfig has no current context")
+		}
+		contextEntry = &src.Contexts[0]
+	}
+	clusterName, _ := contextEntry.Context["cluster"].(string)
+	userName, _ := contextEntry.Context["user"].(string)
+	cluster := find(src.Clusters, clusterName)
+	if cluster == nil {
+		return fmt.Errorf("kubeconfig has no cluster %s", clusterName)
+	}
+	user := find(src.Users, userName)
+	if user == nil {
+		return fmt.Errorf("kubeconfig has no user %s", userName)
+	}
+
+	context := make(map[string]any, len(contextEntry.Context))
+	for k, v := range contextEntry.Context {
+		context[k] = v
+	}
+	context["cluster"] = name
+	context["user"] = name
+	c.Clusters = upsert(c.Clusters, NamedEntry{Name: name, Cluster: cluster.Cluster})
+	c.Users = upsert(c.Users, NamedEntry{Name: name, User: user.User})
+	c.Contexts = upsert(c.Contexts, NamedEntry{Name: name, Context: context})
+	if c.APIVersion == "" {
+		c.APIVersion = "v1"
+		c.Kind = "Config"
+	}
+	return nil
+}
+
+// Remove deletes the context with its cluster and user by name.
+// It returns false if there is no such context.
+func (c *Config) Remove(name string) bool {
+	if find(c.Contexts, name) == nil {
+		return false
+	}
+	c.Contexts = remove(c.Contexts, name)
+	c.Clusters = remove(c.Clusters, name)
+	c.Users = remove(c.Users, name)
+	if c.CurrentContext == name {
+		c.CurrentContext = ""
+	}
+	return true
+}
+
+func find(entries []NamedEntry, name string) *NamedEntry {
+	for i := range entries {
+		if entries[i].Name == name {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+func upsert(entries []NamedEntry, entry NamedEntry) []NamedEntry {
+	if existing := find(entries, entry.Name); existing != nil {
+		*existing = entry
+		return entries
+	}
+	return append(entries, entry)
+}
+
+func remove(entries []NamedEntry, name string) []NamedEntry {
+	kept := make([]NamedEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Name != name {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
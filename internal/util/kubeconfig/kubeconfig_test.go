@@ -0,0 +1,106 @@
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const k3sKubeconfig = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Q0EK
+    server: https://127.0.0.1:6443
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+kind: Config
+preferences: {}
+users:
+- name: default
+  user:
+    client-certificate-data: Q0VSVAo=
+    client-key-data: S0VZCg==
+`
+
+func TestSetServerHost(t *testing.T) {
+	c, err := Parse([]byte(k3sKubeconfig))
+	assert.NoError(t, err)
+
+	changed, err := c.SetServerHost("cp.mylab.example.com")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "https://cp.mylab.example.com:6443", c.Clusters[0].Cluster["server"])
+
+	changed, err = c.SetServerHost("cp.mylab.example.com")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestMergeAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".kube", "config")
+	user, err := Load(path)
+	assert.NoError(t, err, "a missing kubeconfig is empty")
+	assert.NoError(t, user.Merge(&Config{
+		Clusters:       []NamedEntry{{Name: "work", Cluster: map[string]any{"server": "https://work:6443"}}},
+		Contexts:       []NamedEntry{{Name: "work", Context: map[string]any{"cluster": "work", "user": "work", "namespace": "dev"}}},
+		Users:          []NamedEntry{{Name: "work", User: map[string]any{"token": "secret"}}},
+		CurrentContext: "work",
+	}, "work"))
+	user.CurrentContext = "work"
+
+	lab, err := Parse([]byte(k3sKubeconfig))
+	assert.NoError(t, err)
+	assert.NoError(t, user.Merge(lab, "storctl-mylab"))
+	// merging again replaces the entries
+	assert.NoError(t, user.Merge(lab, "storctl-mylab"))
+	assert.NoError(t, user.Save(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	saved, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "work", saved.CurrentContext)
+	assert.Len(t, saved.Clusters, 2)
+	assert.Len(t, saved.Users, 2)
+	assert.Equal(t, []NamedEntry{
+		{Name: "work", Context: map[string]any{"cluster": "work", "user": "work", "namespace": "dev"}},
+		{Name: "storctl-mylab", Context: map[string]any{"cluster": "storctl-mylab", "user": "storctl-mylab"}},
+	}, saved.Contexts)
+	assert.Equal(t, "https://127.0.0.1:6443", saved.Clusters[1].Cluster["server"])
+	assert.Equal(t, "Q0VSVAo=", saved.Users[1].User["client-certificate-data"])
+
+	assert.True(t, saved.Remove("storctl-mylab"))
+	assert.False(t, saved.Remove("storctl-mylab"))
+	assert.Equal(t, []string{"work"}, names(saved.Clusters))
+	assert.Equal(t, []string{"work"}, names(saved.Users))
+	assert.Equal(t, []string{"work"}, names(saved.Contexts))
+}
+
+func TestKeepUnknownFields(t *testing.T) {
+	c, err := Parse([]byte(k3sKubeconfig))
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "config")
+	assert.NoError(t, c.Save(path))
+
+	saved, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"preferences": map[string]any{}}, saved.Rest)
+	assert.Equal(t, "Q0EK", saved.Clusters[0].Cluster["certificate-authority-data"])
+}
+
+func names(entries []NamedEntry) []string {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.Name)
+	}
+	return result
+}
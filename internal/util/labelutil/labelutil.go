@@ -1,7 +1,10 @@
-// Package labelutil contains the functions to sanitize and merge labels.
+// Package labelutil contains the functions to sanitize, merge, and select labels.
 package labelutil
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+)
 
 var labelSanitizer = regexp.MustCompile(`^[_-]|[^a-zA-Z0-9_-]|[_-]$`)
 
@@ -28,3 +31,27 @@ func MergeLabels(base, override map[string]string) map[string]string {
 
 	return result
 }
+
+// SelectorTerms splits a label selector like "lab_name=test,owner=me" into its terms.
+func SelectorTerms(selector string) []string {
+	terms := []string{}
+	for _, term := range strings.Split(selector, ",") {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// MatchSelector reports whether the labels match all terms of the label selector.
+// A term is either key=value or a key that must be present. An empty selector matches everything.
+func MatchSelector(labels map[string]string, selector string) bool {
+	for _, term := range SelectorTerms(selector) {
+		key, value, hasValue := strings.Cut(term, "=")
+		actual, ok := labels[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
@@ -117,3 +117,28 @@ func TestMergeLabels(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchSelector(t *testing.T) {
+	labels := map[string]string{"lab_name": "test", "owner": "me"}
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		selector string
+		expected bool
+	}{
+		{name: "empty selector", labels: labels, selector: "", expected: true},
+		{name: "matching value", labels: labels, selector: "lab_name=test", expected: true},
+		{name: "value and key", labels: labels, selector: "lab_name=test, owner", expected: true},
+		{name: "other value", labels: labels, selector: "lab_name=other", expected: false},
+		{name: "missing key", labels: labels, selector: "lab_name=test,team=storage", expected: false},
+		{name: "no labels", labels: nil, selector: "lab_name=test", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSelector(tt.labels, tt.selector); got != tt.expected {
+				t.Errorf("MatchSelector(%q) = %v, want %v", tt.selector, got, tt.expected)
+			}
+		})
+	}
+}
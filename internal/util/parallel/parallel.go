@@ -0,0 +1,79 @@
+// Package parallel runs tasks with bounded concurrency and an optional rate limit.
+// It's used to create lab servers and volumes in parallel without exceeding provider API limits.
+package parallel
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Run calls fn for the indexes 0..n-1 with at most concurrency calls running at a time.
+// If limiter is not nil, each call waits for it first.
+// After the first error or when ctx is canceled no new calls are started; the running ones finish.
+// It returns the errors of all calls joined.
+func Run(ctx context.Context, n, concurrency int, limiter *rate.Limiter, fn func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []error
+		failed bool
+	)
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			<-sem
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			break
+		}
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				<-sem
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				break
+			}
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				failed = true
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// NewLimiter returns a limiter for the given number of requests per second.
+// It returns nil, meaning no limit, if requestsPerSecond is not positive.
+func NewLimiter(requestsPerSecond float64, burst int) *rate.Limiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+}
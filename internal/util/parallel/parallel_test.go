@@ -0,0 +1,79 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	var running, maxRunning int32
+	var mu sync.Mutex
+	done := make([]bool, 10)
+	err := Run(context.Background(), 10, 3, nil, func(i int) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		done[i] = true
+		mu.Unlock()
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, maxRunning, int32(3))
+	for i, d := range done {
+		assert.True(t, d, "task %d not done", i)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	var started int32
+	err := Run(context.Background(), 10, 2, nil, func(i int) error {
+		atomic.AddInt32(&started, 1)
+		time.Sleep(10 * time.Millisecond)
+		if i < 2 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.ErrorContains(t, err, "failed")
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 2)
+	assert.Less(t, atomic.LoadInt32(&started), int32(10), "no new tasks should start after an error")
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started int32
+	err := Run(ctx, 10, 1, nil, func(i int) error {
+		if atomic.AddInt32(&started, 1) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&started))
+}
+
+func TestRunRateLimit(t *testing.T) {
+	start := time.Now()
+	err := Run(context.Background(), 3, 3, NewLimiter(20, 1), func(i int) error { return nil })
+	assert.NoError(t, err)
+	// 3 calls with 20 requests per second and a burst of 1 take at least 2 intervals
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestNewLimiter(t *testing.T) {
+	assert.Nil(t, NewLimiter(0, 1))
+	assert.NotNil(t, NewLimiter(1, 0))
+}
@@ -3,10 +3,15 @@ package serverchecker
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
 )
 
 type RealSSHClient struct {
@@ -16,6 +21,15 @@ type RealSSHClient struct {
 	client  *ssh.Client
 }
 
+// NewSSHClient returns a client for host:port that logs in as user with the private key at keyPath
+func NewSSHClient(host, user, keyPath string) *RealSSHClient {
+	return &RealSSHClient{
+		host:    host,
+		user:    user,
+		keyPath: keyPath,
+	}
+}
+
 func (r *RealSSHClient) Connect() error {
 	key, err := os.ReadFile(r.keyPath)
 	if err != nil {
@@ -72,3 +86,101 @@ func (r *RealSSHClient) ExecCommand(cmd string) (string, error) {
 	}
 	return output.String(), nil
 }
+
+// Dial opens a connection to addr from the server, like ssh -L
+func (r *RealSSHClient) Dial(network, addr string) (net.Conn, error) {
+	if r.client == nil {
+		return nil, fmt.Errorf("client not connected")
+	}
+	return r.client.Dial(network, addr)
+}
+
+// Run runs cmd and streams its output to stdout and stderr
+func (r *RealSSHClient) Run(cmd string, stdout, stderr io.Writer) error {
+	if r.client == nil {
+		return fmt.Errorf("client not connected")
+	}
+
+	session, err := r.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	return session.Run(cmd)
+}
+
+// Shell starts an interactive login shell.
+// If stdin is a terminal, it's switched to raw mode and the shell gets a PTY of the same size,
+// resized with the terminal.
+func (r *RealSSHClient) Shell(stdin *os.File, stdout, stderr io.Writer) error {
+	if r.client == nil {
+		return fmt.Errorf("client not connected")
+	}
+
+	session, err := r.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+	fd := int(stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("set terminal to raw mode: %w", err)
+		}
+		defer term.Restore(fd, state)
+
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		if err := session.RequestPty(termType, height, width, modes); err != nil {
+			return fmt.Errorf("request PTY: %w", err)
+		}
+		defer watchWindowSize(session, fd)()
+	}
+	if err := session.Shell(); err != nil {
+		return fmt.Errorf("start shell: %w", err)
+	}
+	return session.Wait()
+}
+
+// watchWindowSize sends the new terminal size to the session when the terminal is resized.
+// The returned function stops watching.
+func watchWindowSize(session *ssh.Session, fd int) func() {
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-resized:
+				if width, height, err := term.GetSize(fd); err == nil {
+					session.WindowChange(height, width)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(resized)
+		close(done)
+	}
+}
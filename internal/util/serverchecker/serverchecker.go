@@ -38,14 +38,8 @@ func NewServerChecker(host string, user string, keyPath string, timeout time.Dur
 		return nil, fmt.Errorf("key file does not exist: %s", keyPath)
 	}
 
-	client := &RealSSHClient{
-		host:    host,
-		user:    user,
-		keyPath: keyPath,
-	}
-
 	return &ServerChecker{
-		client:   client,
+		client:   NewSSHClient(host, user, keyPath),
 		host:     host,
 		attempts: attempts,
 		timeout:  timeout,
@@ -53,8 +47,16 @@ func NewServerChecker(host string, user string, keyPath string, timeout time.Dur
 	}, nil
 }
 
-func CheckServers(servers []*types.Server, logger *slog.Logger, timeout time.Duration, attempts int) ([]ServerResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+// AdminKeyPath returns the private key of the lab admin key
+func AdminKeyPath(labName string) string {
+	return filepath.Join(os.Getenv("HOME"),
+		config.DefaultConfigDir,
+		config.DefaultKeysDir,
+		strings.Join([]string{labName, "admin"}, "-"))
+}
+
+func CheckServers(ctx context.Context, servers []*types.Server, logger *slog.Logger, timeout time.Duration, attempts int) ([]ServerResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -62,10 +64,7 @@ func CheckServers(servers []*types.Server, logger *slog.Logger, timeout time.Dur
 	for i, server := range servers {
 		wg.Add(1)
 		serverIP := server.Status.PublicNet.IPv4.IP
-		serverPrivateKeyPath := filepath.Join(os.Getenv("HOME"),
-			config.DefaultConfigDir,
-			config.DefaultKeysDir,
-			strings.Join([]string{server.ObjectMeta.Labels["lab_name"], "admin"}, "-"))
+		serverPrivateKeyPath := AdminKeyPath(server.ObjectMeta.Labels["lab_name"])
 		if serverIP == "" {
 			results[i] = ServerResult{Server: server, Error: fmt.Errorf("server IP is empty")}
 			continue
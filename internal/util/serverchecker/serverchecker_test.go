@@ -70,7 +70,7 @@ func TestCheckServers(t *testing.T) {
 	}
 
 	// Use shorter timeout for tests
-	results, err := CheckServers(servers, logger, 100*time.Millisecond, 2)
+	results, err := CheckServers(context.Background(), servers, logger, 100*time.Millisecond, 2)
 	t.Logf("results: %+v, err: %+v", results, err)
 
 	// Expect error because these are not real servers